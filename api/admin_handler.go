@@ -0,0 +1,172 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"pansou/model"
+	"pansou/plugin"
+	"pansou/service"
+)
+
+// pluginManagerOrAbort 获取插件管理器，插件功能未启用时返回错误响应
+func pluginManagerOrAbort(c *gin.Context) *plugin.PluginManager {
+	if searchService == nil || searchService.GetPluginManager() == nil {
+		c.JSON(http.StatusServiceUnavailable, model.NewErrorResponse(503, "插件功能未启用"))
+		return nil
+	}
+	return searchService.GetPluginManager()
+}
+
+// PluginHealthHandler 返回已启用插件的健康统计和熔断状态
+func PluginHealthHandler(c *gin.Context) {
+	pm := pluginManagerOrAbort(c)
+	if pm == nil {
+		return
+	}
+
+	names := make([]string, 0)
+	for _, p := range pm.GetPlugins() {
+		names = append(names, p.Name())
+	}
+
+	statuses := pm.HealthStatus(names)
+	openCount := 0
+	for _, status := range statuses {
+		if status.State != plugin.CircuitClosed {
+			openCount++
+		}
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"total":   len(statuses),
+		"open":    openCount,
+		"plugins": statuses,
+	}))
+}
+
+// ResetPluginCircuitHandler 手动关闭指定插件的熔断
+func ResetPluginCircuitHandler(c *gin.Context) {
+	pm := pluginManagerOrAbort(c)
+	if pm == nil {
+		return
+	}
+
+	name := c.Param("name")
+	if !pm.ResetCircuit(name) {
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(404, "插件没有健康记录: "+name))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(pm.HealthStatus([]string{name})[0]))
+}
+
+// adminPluginInfo 管理接口返回的插件状态
+type adminPluginInfo struct {
+	Name            string `json:"name"`
+	Enabled         bool   `json:"enabled"`
+	Priority        int    `json:"priority"`         // 实际优先级
+	DefaultPriority int    `json:"default_priority"` // 插件自身的优先级
+}
+
+// AdminPluginsHandler 列出所有已注册插件及其启用状态和优先级
+func AdminPluginsHandler(c *gin.Context) {
+	pm := pluginManagerOrAbort(c)
+	if pm == nil {
+		return
+	}
+
+	registered := plugin.GetRegisteredPlugins()
+	infos := make([]adminPluginInfo, 0, len(registered))
+	enabledCount := 0
+	for _, p := range registered {
+		enabled := pm.IsEnabled(p.Name())
+		if enabled {
+			enabledCount++
+		}
+		infos = append(infos, adminPluginInfo{
+			Name:            p.Name(),
+			Enabled:         enabled,
+			Priority:        plugin.EffectivePriority(p),
+			DefaultPriority: p.Priority(),
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"total":   len(infos),
+		"enabled": enabledCount,
+		"plugins": infos,
+	}))
+}
+
+// EnablePluginHandler 在运行时启用插件
+func EnablePluginHandler(c *gin.Context) {
+	pm := pluginManagerOrAbort(c)
+	if pm == nil {
+		return
+	}
+
+	name := c.Param("name")
+	if err := pm.EnablePlugin(name); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "启用插件失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{"name": name, "enabled": true}))
+}
+
+// DisablePluginHandler 在运行时禁用插件
+func DisablePluginHandler(c *gin.Context) {
+	pm := pluginManagerOrAbort(c)
+	if pm == nil {
+		return
+	}
+
+	name := c.Param("name")
+	if err := pm.DisablePlugin(name); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "禁用插件失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{"name": name, "enabled": false}))
+}
+
+// SetPluginPriorityHandler 在运行时调整插件优先级
+func SetPluginPriorityHandler(c *gin.Context) {
+	pm := pluginManagerOrAbort(c)
+	if pm == nil {
+		return
+	}
+
+	var req struct {
+		Priority int `json:"priority"` // 1-4，0表示恢复默认
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	name := c.Param("name")
+	if err := pm.SetPluginPriority(name, req.Priority); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "调整优先级失败: "+err.Error()))
+		return
+	}
+
+	p, _ := plugin.GetPluginByName(name)
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{"name": name, "priority": plugin.EffectivePriority(p)}))
+}
+
+// PluginCacheStatsHandler 返回插件结果缓存的容量和命中统计
+func PluginCacheStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, model.NewSuccessResponse(plugin.GetResultCacheStats()))
+}
+
+// SearchStatsHandler 返回并发搜索合并的统计
+func SearchStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, model.NewSuccessResponse(service.GetFlightStats()))
+}
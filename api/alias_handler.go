@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"pansou/model"
+	"pansou/util/alias"
+)
+
+// AliasListHandler 列出别名词典中的作品，q参数按任一名称筛选
+func AliasListHandler(c *gin.Context) {
+	entries := alias.Default().List(c.Query("q"))
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"total":   len(entries),
+		"entries": entries,
+	}))
+}
+
+// AliasPutHandler 新增或更新作品的英文名和别名，已学习的别名保留
+func AliasPutHandler(c *gin.Context) {
+	var req struct {
+		Title   string   `json:"title"`
+		TitleEn string   `json:"title_en"`
+		Aliases []string `json:"aliases"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	entry, err := alias.Default().Put(alias.Entry{Title: req.Title, TitleEn: req.TitleEn, Aliases: req.Aliases})
+	switch {
+	case errors.Is(err, alias.ErrConflict):
+		c.JSON(http.StatusConflict, model.NewErrorResponse(409, err.Error()))
+		return
+	case errors.Is(err, alias.ErrEmptyTitle):
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, err.Error()))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(500, "保存别名词典失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(entry))
+}
+
+// AliasDeleteHandler 删除作品，指定alias参数时只删除该别名
+// 删除的学习别名不会再次学习
+func AliasDeleteHandler(c *gin.Context) {
+	title := c.Param("title")
+	dict := alias.Default()
+
+	if name := c.Query("alias"); name != "" {
+		entry, ok, err := dict.RemoveAlias(title, name)
+		if !ok {
+			c.JSON(http.StatusNotFound, model.NewErrorResponse(404, "作品或别名不存在: "+title+" / "+name))
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.NewErrorResponse(500, "保存别名词典失败: "+err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, model.NewSuccessResponse(entry))
+		return
+	}
+
+	ok, err := dict.Delete(title)
+	if !ok {
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(404, "作品不存在: "+title))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(500, "保存别名词典失败: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{"title": title, "deleted": true}))
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"pansou/model"
+	"pansou/plugin"
+	"pansou/service"
+	"pansou/util/cache"
+)
+
+// defaultCacheListLimit 缓存列表默认返回的条目数
+const defaultCacheListLimit = 100
+
+// mainCacheOrAbort 获取主缓存，缓存未启用时返回错误响应
+func mainCacheOrAbort(c *gin.Context) *cache.EnhancedTwoLevelCache {
+	mainCache := service.GetEnhancedTwoLevelCache()
+	if mainCache == nil {
+		c.JSON(http.StatusServiceUnavailable, model.NewErrorResponse(503, "缓存未启用"))
+		return nil
+	}
+	return mainCache
+}
+
+// cacheFilterFromQuery 从查询参数读取缓存筛选条件
+func cacheFilterFromQuery(c *gin.Context) (cache.CacheFilter, bool) {
+	filter := cache.CacheFilter{
+		Keyword: c.Query("keyword"),
+		Source:  c.Query("source"),
+		Plugin:  c.Query("plugin"),
+		Channel: c.Query("channel"),
+	}
+	if filter.Source != "" && filter.Source != cache.KeySourceTG && filter.Source != cache.KeySourcePlugin {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "source必须为tg或plugin"))
+		return filter, false
+	}
+	return filter, true
+}
+
+// CacheListHandler 按关键词、来源、插件或频道列出缓存项
+func CacheListHandler(c *gin.Context) {
+	mainCache := mainCacheOrAbort(c)
+	if mainCache == nil {
+		return
+	}
+	filter, ok := cacheFilterFromQuery(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultCacheListLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultCacheListLimit
+	}
+
+	entries := mainCache.ListEntries(filter)
+	total := len(entries)
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"total":   total,
+		"entries": entries,
+	}))
+}
+
+// CachePurgeHandler 删除满足条件的缓存项，至少需要一个筛选条件
+func CachePurgeHandler(c *gin.Context) {
+	mainCache := mainCacheOrAbort(c)
+	if mainCache == nil {
+		return
+	}
+	filter, ok := cacheFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	if filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "需要指定keyword、source、plugin或channel，清空全部缓存请使用flush接口"))
+		return
+	}
+
+	removed, err := mainCache.Purge(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(500, "删除缓存失败: "+err.Error()))
+		return
+	}
+
+	// 插件自身的结果缓存按"插件名:关键词"保存，TG条件与其无关
+	pluginRemoved := 0
+	if filter.Channel == "" && filter.Source != cache.KeySourceTG {
+		pluginRemoved = plugin.PurgeResultCache(filter.Plugin, filter.Keyword)
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"removed":        removed,
+		"plugin_removed": pluginRemoved,
+	}))
+}
+
+// CacheFlushHandler 清空主缓存（内存、磁盘及Redis）、插件结果缓存和无结果缓存
+func CacheFlushHandler(c *gin.Context) {
+	mainCache := mainCacheOrAbort(c)
+	if mainCache == nil {
+		return
+	}
+
+	if err := mainCache.Clear(); err != nil {
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(500, "清空缓存失败: "+err.Error()))
+		return
+	}
+	pluginRemoved := plugin.PurgeResultCache("", "")
+	service.ClearNegativeCache()
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"flushed":        true,
+		"plugin_removed": pluginRemoved,
+	}))
+}
+
+// CacheExportHandler 以tar.gz格式下载本地磁盘缓存快照
+func CacheExportHandler(c *gin.Context) {
+	mainCache := mainCacheOrAbort(c)
+	if mainCache == nil {
+		return
+	}
+
+	filename := fmt.Sprintf("pansou-cache-%s.tar.gz", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，导出失败只能记录日志
+	count, err := mainCache.ExportSnapshot(c.Writer)
+	if err != nil {
+		fmt.Printf("[Cache] 导出缓存快照失败: %v\n", err)
+		return
+	}
+	fmt.Printf("[Cache] 导出缓存快照: %d 项\n", count)
+}
+
+// CacheImportHandler 导入export接口生成的缓存快照，请求体为tar.gz文件
+func CacheImportHandler(c *gin.Context) {
+	mainCache := mainCacheOrAbort(c)
+	if mainCache == nil {
+		return
+	}
+
+	imported, skipped, err := mainCache.ImportSnapshot(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, fmt.Sprintf("导入缓存快照失败（已导入 %d 项）: %v", imported, err)))
+		return
+	}
+	fmt.Printf("[Cache] 导入缓存快照: %d 项，跳过已过期 %d 项\n", imported, skipped)
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"imported": imported,
+		"skipped":  skipped,
+	}))
+}
+
+// NegativeCacheStatsHandler 返回无结果缓存的统计
+func NegativeCacheStatsHandler(c *gin.Context) {
+	stats, enabled := service.GetNegativeCacheStats()
+	if !enabled {
+		c.JSON(http.StatusServiceUnavailable, model.NewErrorResponse(503, "无结果缓存未启用"))
+		return
+	}
+	c.JSON(http.StatusOK, model.NewSuccessResponse(stats))
+}
+
+// CacheWarmupStatusHandler 返回缓存预热状态和下一轮的预热候选
+func CacheWarmupStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, model.NewSuccessResponse(service.GetWarmupStatus()))
+}
+
+// CacheWarmupTriggerHandler 立即执行一轮缓存预热，不受预热时段限制
+func CacheWarmupTriggerHandler(c *gin.Context) {
+	started, err := service.TriggerWarmup()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, model.NewErrorResponse(503, err.Error()))
+		return
+	}
+	if !started {
+		c.JSON(http.StatusConflict, model.NewErrorResponse(409, "预热正在执行"))
+		return
+	}
+	c.JSON(http.StatusAccepted, model.NewSuccessResponse(gin.H{"started": true}))
+}
@@ -1,31 +1,177 @@
 package api
 
 import (
+	"fmt"
 	"pansou/model"
+	"pansou/util/mediainfo"
+	"pansou/util/textnorm"
 	"strings"
 )
 
+// mediaFilter 按媒体属性过滤的条件，已转为标准写法
+type mediaFilter struct {
+	minResolution int
+	hdr           bool
+	codecs        []string
+	audio         []string
+	season        int
+	episode       int
+	complete      bool
+	minSize       int64
+	maxSize       int64
+	minYear       int
+	maxYear       int
+}
+
+// parseMediaFilter 解析过滤配置中的媒体属性条件，没有设置任何条件时返回nil
+func parseMediaFilter(filter *model.FilterConfig) (*mediaFilter, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	f := &mediaFilter{
+		hdr:      filter.HDR,
+		season:   filter.Season,
+		episode:  filter.Episode,
+		complete: filter.Complete,
+		minYear:  filter.MinYear,
+		maxYear:  filter.MaxYear,
+	}
+	if filter.MinResolution != "" {
+		if f.minResolution = mediainfo.ResolutionRank(filter.MinResolution); f.minResolution == 0 {
+			return nil, fmt.Errorf("无效的min_res: %s", filter.MinResolution)
+		}
+	}
+	for _, codec := range filter.Codecs {
+		normalized := mediainfo.NormalizeCodec(codec)
+		if normalized == "" {
+			return nil, fmt.Errorf("无效的codec: %s", codec)
+		}
+		f.codecs = append(f.codecs, normalized)
+	}
+	for _, audio := range filter.Audio {
+		normalized := mediainfo.NormalizeAudio(audio)
+		if normalized == "" {
+			return nil, fmt.Errorf("无效的audio: %s", audio)
+		}
+		f.audio = append(f.audio, normalized)
+	}
+	for _, bound := range []struct {
+		name  string
+		value string
+		size  *int64
+	}{{"min_size", filter.MinSize, &f.minSize}, {"max_size", filter.MaxSize, &f.maxSize}} {
+		if bound.value == "" {
+			continue
+		}
+		size, ok := mediainfo.ParseSize(bound.value)
+		if !ok {
+			return nil, fmt.Errorf("无效的%s: %s，应为带单位的大小，如10GB", bound.name, bound.value)
+		}
+		*bound.size = size
+	}
+
+	if f.minResolution == 0 && !f.hdr && len(f.codecs) == 0 && len(f.audio) == 0 && f.season == 0 && f.episode == 0 &&
+		!f.complete && f.minSize == 0 && f.maxSize == 0 && f.minYear == 0 && f.maxYear == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// match 检查媒体属性是否满足全部条件，标题中没有相应属性时不满足
+func (f *mediaFilter) match(info *model.MediaInfo) bool {
+	if f == nil {
+		return true
+	}
+	if info == nil {
+		return false
+	}
+	switch {
+	case f.minResolution > 0 && mediainfo.ResolutionRank(info.Resolution) < f.minResolution:
+		return false
+	case f.hdr && len(info.HDR) == 0:
+		return false
+	case len(f.codecs) > 0 && !containsString(f.codecs, info.Codec):
+		return false
+	case len(f.audio) > 0 && !containsAnyString(f.audio, info.Audio):
+		return false
+	case f.season > 0 && !inRange(f.season, info.Season, info.SeasonEnd):
+		return false
+	case f.episode > 0 && !containsEpisode(info, f.episode):
+		return false
+	case f.complete && info.Status != "complete":
+		return false
+	case f.minSize > 0 && info.Size < f.minSize:
+		return false
+	case f.maxSize > 0 && (info.Size == 0 || info.Size > f.maxSize):
+		return false
+	case f.minYear > 0 && info.Year < f.minYear:
+		return false
+	case f.maxYear > 0 && (info.Year == 0 || info.Year > f.maxYear):
+		return false
+	}
+	return true
+}
+
+// inRange 检查value是否在start到end的范围内，end为0时只与start比较
+func inRange(value, start, end int) bool {
+	if start == 0 {
+		return false
+	}
+	return value == start || (value > start && value <= end)
+}
+
+// containsEpisode 检查资源是否包含第episode集：集范围包含该集，
+// 或没有集信息、只标了总集数的全集（如"全40集"）总集数不小于该集；单集标题中的总集数不算
+func containsEpisode(info *model.MediaInfo, episode int) bool {
+	if info.Episode > 0 {
+		return inRange(episode, info.Episode, info.EpisodeEnd)
+	}
+	return episode <= info.Episodes
+}
+
+// containsString 检查切片是否包含字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAnyString 检查两个切片是否有相同的字符串
+func containsAnyString(values []string, others []string) bool {
+	for _, v := range others {
+		if containsString(values, v) {
+			return true
+		}
+	}
+	return false
+}
+
 // applyResultFilter 应用过滤器到搜索响应
 func applyResultFilter(response model.SearchResponse, filter *model.FilterConfig, resultType string) model.SearchResponse {
-	if filter == nil || (len(filter.Include) == 0 && len(filter.Exclude) == 0) {
+	// 媒体属性条件已在处理请求时校验
+	media, _ := parseMediaFilter(filter)
+	if filter == nil || (len(filter.Include) == 0 && len(filter.Exclude) == 0 && media == nil) {
 		return response
 	}
 
-	// 预处理关键词（转小写）
+	// 预处理关键词（繁简、全半角、大小写归一化）
 	includeKeywords := make([]string, len(filter.Include))
 	for i, kw := range filter.Include {
-		includeKeywords[i] = strings.ToLower(kw)
+		includeKeywords[i] = textnorm.Fold(kw)
 	}
 
 	excludeKeywords := make([]string, len(filter.Exclude))
 	for i, kw := range filter.Exclude {
-		excludeKeywords[i] = strings.ToLower(kw)
+		excludeKeywords[i] = textnorm.Fold(kw)
 	}
 
 	// 根据结果类型决定过滤策略
 	if resultType == "merged_by_type" || resultType == "" {
 		// 过滤 merged_by_type 的 note 字段
-		response.MergedByType = filterMergedByType(response.MergedByType, includeKeywords, excludeKeywords)
+		response.MergedByType = filterMergedByType(response.MergedByType, includeKeywords, excludeKeywords, media)
 
 		// 重新计算 total
 		total := 0
@@ -35,12 +181,12 @@ func applyResultFilter(response model.SearchResponse, filter *model.FilterConfig
 		response.Total = total
 	} else if resultType == "all" || resultType == "results" {
 		// 过滤 results 的 title 和 links 的 work_title
-		response.Results = filterResults(response.Results, includeKeywords, excludeKeywords)
+		response.Results = filterResults(response.Results, includeKeywords, excludeKeywords, media)
 		response.Total = len(response.Results)
 
 		// 如果是 all 类型，也需要过滤 merged_by_type
 		if resultType == "all" {
-			response.MergedByType = filterMergedByType(response.MergedByType, includeKeywords, excludeKeywords)
+			response.MergedByType = filterMergedByType(response.MergedByType, includeKeywords, excludeKeywords, media)
 		}
 	}
 
@@ -48,7 +194,7 @@ func applyResultFilter(response model.SearchResponse, filter *model.FilterConfig
 }
 
 // filterMergedByType 过滤 merged_by_type 中的链接
-func filterMergedByType(mergedLinks model.MergedLinks, includeKeywords, excludeKeywords []string) model.MergedLinks {
+func filterMergedByType(mergedLinks model.MergedLinks, includeKeywords, excludeKeywords []string, media *mediaFilter) model.MergedLinks {
 	if mergedLinks == nil {
 		return nil
 	}
@@ -59,7 +205,7 @@ func filterMergedByType(mergedLinks model.MergedLinks, includeKeywords, excludeK
 		filteredLinks := make([]model.MergedLink, 0)
 
 		for _, link := range links {
-			if matchFilter(link.Note, includeKeywords, excludeKeywords) {
+			if matchFilter(link.Note, includeKeywords, excludeKeywords) && media.match(link.Media) {
 				filteredLinks = append(filteredLinks, link)
 			}
 		}
@@ -74,7 +220,7 @@ func filterMergedByType(mergedLinks model.MergedLinks, includeKeywords, excludeK
 }
 
 // filterResults 过滤 results 数组
-func filterResults(results []model.SearchResult, includeKeywords, excludeKeywords []string) []model.SearchResult {
+func filterResults(results []model.SearchResult, includeKeywords, excludeKeywords []string, media *mediaFilter) []model.SearchResult {
 	if results == nil {
 		return nil
 	}
@@ -82,8 +228,8 @@ func filterResults(results []model.SearchResult, includeKeywords, excludeKeyword
 	filtered := make([]model.SearchResult, 0)
 
 	for _, result := range results {
-		// 先检查 title 是否匹配
-		if !matchFilter(result.Title, includeKeywords, excludeKeywords) {
+		// 先检查 title 是否匹配，媒体属性按 title 解析，对整条结果生效
+		if !matchFilter(result.Title, includeKeywords, excludeKeywords) || !media.match(result.Media) {
 			continue
 		}
 
@@ -113,11 +259,11 @@ func filterResults(results []model.SearchResult, includeKeywords, excludeKeyword
 
 // matchFilter 检查文本是否匹配过滤条件
 func matchFilter(text string, includeKeywords, excludeKeywords []string) bool {
-	lowerText := strings.ToLower(text)
+	foldedText := textnorm.Fold(text)
 
 	// 检查 exclude（任一匹配则排除）
 	for _, kw := range excludeKeywords {
-		if strings.Contains(lowerText, kw) {
+		if kw != "" && strings.Contains(foldedText, kw) {
 			return false
 		}
 	}
@@ -126,7 +272,7 @@ func matchFilter(text string, includeKeywords, excludeKeywords []string) bool {
 	if len(includeKeywords) > 0 {
 		matched := false
 		for _, kw := range includeKeywords {
-			if strings.Contains(lowerText, kw) {
+			if textnorm.Contains(foldedText, kw) {
 				matched = true
 				break
 			}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"testing"
+
+	"pansou/model"
+)
+
+func TestParseMediaFilter(t *testing.T) {
+	if f, err := parseMediaFilter(&model.FilterConfig{Include: []string{"4k"}}); f != nil || err != nil {
+		t.Errorf("没有媒体属性条件时应返回nil: %+v %v", f, err)
+	}
+
+	f, err := parseMediaFilter(&model.FilterConfig{MinResolution: "4K", Codecs: []string{"HEVC"}, Audio: []string{"全景声"}, MinSize: "10GB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.codecs[0] != "h265" || f.audio[0] != "atmos" || f.minSize != 10<<30 {
+		t.Errorf("条件应转为标准写法: %+v", f)
+	}
+
+	for name, bad := range map[string]*model.FilterConfig{
+		"min_res":  {MinResolution: "8k5"},
+		"codec":    {Codecs: []string{"divx3000"}},
+		"audio":    {Audio: []string{"mono-ish"}},
+		"min_size": {MinSize: "10"},
+		"max_size": {MaxSize: "big"},
+	} {
+		if _, err := parseMediaFilter(bad); err == nil {
+			t.Errorf("%s: 无效条件应返回错误", name)
+		}
+	}
+}
+
+func TestMediaFilterMatch(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter model.FilterConfig
+		info   *model.MediaInfo
+		want   bool
+	}{
+		{"无属性", model.FilterConfig{MinResolution: "1080p"}, nil, false},
+		{"分辨率达到", model.FilterConfig{MinResolution: "1080p"}, &model.MediaInfo{Resolution: "2160p"}, true},
+		{"分辨率不足", model.FilterConfig{MinResolution: "1080p"}, &model.MediaInfo{Resolution: "720p"}, false},
+		{"HDR", model.FilterConfig{HDR: true}, &model.MediaInfo{HDR: []string{"dv"}}, true},
+		{"无HDR", model.FilterConfig{HDR: true}, &model.MediaInfo{Resolution: "2160p"}, false},
+		{"季范围", model.FilterConfig{Season: 2}, &model.MediaInfo{Season: 1, SeasonEnd: 3}, true},
+		{"其他季", model.FilterConfig{Season: 2}, &model.MediaInfo{Season: 1}, false},
+		{"大小上限", model.FilterConfig{MaxSize: "50GB"}, &model.MediaInfo{Size: 60 << 30}, false},
+		{"未知大小", model.FilterConfig{MaxSize: "50GB"}, &model.MediaInfo{Resolution: "1080p"}, false},
+		{"年份范围", model.FilterConfig{MinYear: 2020, MaxYear: 2024}, &model.MediaInfo{Year: 2023}, true},
+		{"完结", model.FilterConfig{Complete: true}, &model.MediaInfo{Status: "ongoing"}, false},
+	}
+	for _, c := range cases {
+		f, err := parseMediaFilter(&c.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := f.match(c.info); got != c.want {
+			t.Errorf("%s: match = %v，应为 %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestMediaFilterEpisode(t *testing.T) {
+	f, _ := parseMediaFilter(&model.FilterConfig{Episode: 5})
+	cases := []struct {
+		name string
+		info model.MediaInfo
+		want bool
+	}{
+		{"单集", model.MediaInfo{Episode: 5}, true},
+		{"其他单集", model.MediaInfo{Episode: 6}, false},
+		{"集范围", model.MediaInfo{Episode: 1, EpisodeEnd: 10}, true},
+		{"更新至4集", model.MediaInfo{Episode: 1, EpisodeEnd: 4, Status: "ongoing"}, false},
+		{"全40集", model.MediaInfo{Episodes: 40, Status: "complete"}, true},
+		{"全3集", model.MediaInfo{Episodes: 3}, false},
+		// 单集标题中的总集数不代表包含其他集
+		{"第6集共40集", model.MediaInfo{Episode: 6, Episodes: 40}, false},
+		{"第5集共40集", model.MediaInfo{Episode: 5, Episodes: 40}, true},
+		{"没有集信息", model.MediaInfo{Resolution: "1080p"}, false},
+	}
+	for _, c := range cases {
+		if got := f.match(&c.info); got != c.want {
+			t.Errorf("%s: match = %v，应为 %v", c.name, got, c.want)
+		}
+	}
+}
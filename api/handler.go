@@ -11,6 +11,7 @@ import (
 	"pansou/service"
 	"pansou/util"
 	jsonutil "pansou/util/json"
+	"pansou/util/query"
 	"strings"
 )
 
@@ -142,6 +143,13 @@ func SearchHandler(c *gin.Context) {
 			}
 		}
 
+		// 处理流式返回
+		stream := c.Query("stream") == "true"
+
+		// 处理匹配模式和排序字段
+		match := strings.TrimSpace(c.Query("match"))
+		sortBy := c.Query("sort")
+
 		req = model.SearchRequest{
 			Keyword:      keyword,
 			Channels:     channels,
@@ -153,6 +161,9 @@ func SearchHandler(c *gin.Context) {
 			CloudTypes:   cloudTypes, // 添加cloud_types到请求中
 			Ext:          ext,
 			Filter:       filter,
+			Stream:       stream,
+			Match:        match,
+			Sort:         sortBy,
 		}
 	} else {
 		// POST方式：从请求体获取
@@ -168,6 +179,35 @@ func SearchHandler(c *gin.Context) {
 		}
 	}
 
+	// 检查匹配模式
+	req.Match = strings.ToLower(req.Match)
+	if req.Match != "" && req.Match != query.MatchStrict && req.Match != query.MatchFuzzy {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "无效的match参数: "+req.Match+"，可选值为strict、fuzzy"))
+		return
+	}
+
+	// 检查媒体属性过滤条件和排序字段
+	if _, err := parseMediaFilter(req.Filter); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, err.Error()))
+		return
+	}
+	sortKeys, err := parseSortKeys(req.Sort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, err.Error()))
+		return
+	}
+	// 流式结果按来源完成顺序推送，无法整体排序
+	if req.Stream && len(sortKeys) > 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, "流式模式不支持sort参数"))
+		return
+	}
+
+	// 检查查询语法，语法错误不发起搜索
+	if _, err := query.Parse(req.Keyword); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(400, err.Error()))
+		return
+	}
+
 	// 检查并设置默认值
 	if len(req.Channels) == 0 {
 		req.Channels = config.AppConfig.DefaultChannels
@@ -202,8 +242,14 @@ func SearchHandler(c *gin.Context) {
 	// fmt.Printf("🔧 [调试] 搜索参数: keyword=%s, channels=%v, concurrency=%d, refresh=%v, resultType=%s, sourceType=%s, plugins=%v, cloudTypes=%v, ext=%v\n",
 	//	req.Keyword, req.Channels, req.Concurrency, req.ForceRefresh, req.ResultType, req.SourceType, req.Plugins, req.CloudTypes, req.Ext)
 
+	// 流式返回：每个来源完成后推送增量结果
+	if req.Stream {
+		searchStreamHandler(c, &req)
+		return
+	}
+
 	// 执行搜索
-	result, err := searchService.Search(req.Keyword, req.Channels, req.Concurrency, req.ForceRefresh, req.ResultType, req.SourceType, req.Plugins, req.CloudTypes, req.Ext)
+	result, err := searchService.SearchWithContext(c.Request.Context(), req.Keyword, req.Channels, req.Concurrency, req.ForceRefresh, req.ResultType, req.SourceType, req.Plugins, req.CloudTypes, req.Ext, req.Match)
 
 	if err != nil {
 		response := model.NewErrorResponse(500, "搜索失败: "+err.Error())
@@ -217,8 +263,52 @@ func SearchHandler(c *gin.Context) {
 		result = applyResultFilter(result, req.Filter, req.ResultType)
 	}
 
+	// 按媒体属性等字段排序
+	result = applyResultSort(result, sortKeys)
+
 	// 包装SearchResponse到标准响应格式中
 	response := model.NewSuccessResponse(result)
 	jsonData, _ := jsonutil.Marshal(response)
 	c.Data(http.StatusOK, "application/json", jsonData)
 }
+
+// searchStreamHandler 以SSE方式返回流式搜索结果
+func searchStreamHandler(c *gin.Context, req *model.SearchRequest) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁止nginx缓冲
+	c.Status(http.StatusOK)
+
+	total := 0
+	emit := func(event model.SearchStreamEvent) error {
+		// 增量结果同样应用过滤器
+		if event.Event == service.StreamEventDelta && req.Filter != nil {
+			filtered := applyResultFilter(model.SearchResponse{MergedByType: event.MergedByType}, req.Filter, "merged_by_type")
+			if filtered.Total == 0 {
+				return nil
+			}
+			event.MergedByType = filtered.MergedByType
+			total += filtered.Total
+			event.Total = total
+		} else if req.Filter != nil {
+			event.Total = total
+		}
+
+		data, err := jsonutil.Marshal(event)
+		if err != nil {
+			return err
+		}
+		c.SSEvent(event.Event, string(data))
+		c.Writer.Flush()
+		// 客户端断开时停止推送
+		return c.Request.Context().Err()
+	}
+
+	err := searchService.SearchStream(c.Request.Context(), req.Keyword, req.Channels, req.Concurrency, req.ForceRefresh, req.SourceType, req.Plugins, req.CloudTypes, req.Ext, req.Match, emit)
+	if err != nil && c.Request.Context().Err() == nil {
+		data, _ := jsonutil.Marshal(model.NewErrorResponse(500, "搜索失败: "+err.Error()))
+		c.SSEvent("error", string(data))
+		c.Writer.Flush()
+	}
+}
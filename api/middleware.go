@@ -2,6 +2,7 @@ package api
 
 import (
 	"pansou/config"
+	"pansou/plugin"
 	"pansou/util"
 	"strings"
 
@@ -12,7 +13,7 @@ import (
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Token")
 
 		if c.Request.Method == "OPTIONS" {
@@ -89,3 +90,45 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminMiddleware 管理接口中间件
+// 管理接口只在启用认证时开放，且只允许AUTH_ADMINS中的用户调用，认证本身由AuthMiddleware完成
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.AppConfig.AuthEnabled {
+			c.JSON(403, gin.H{
+				"error": "管理接口需要启用认证（AUTH_ENABLED=true）",
+				"code":  "ADMIN_AUTH_DISABLED",
+			})
+			c.Abort()
+			return
+		}
+
+		if !config.AppConfig.AuthAdmins[c.GetString("username")] {
+			c.JSON(403, gin.H{
+				"error": "无权访问管理接口：当前用户不在AUTH_ADMINS中",
+				"code":  "ADMIN_FORBIDDEN",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// PluginEnabledMiddleware 插件Web路由中间件，插件未启用时返回404
+func PluginEnabledMiddleware(pluginManager *plugin.PluginManager, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !pluginManager.IsEnabled(name) {
+			c.JSON(404, gin.H{
+				"error": "插件未启用: " + name,
+				"code":  "PLUGIN_DISABLED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
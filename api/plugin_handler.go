@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+)
+
+// PluginsHandler 返回已启用插件的描述信息
+func PluginsHandler(c *gin.Context) {
+	pluginsEnabled := config.AppConfig.AsyncPluginEnabled
+	metadata := make([]plugin.PluginMetadata, 0)
+
+	if pluginsEnabled && searchService != nil && searchService.GetPluginManager() != nil {
+		for _, p := range searchService.GetPluginManager().GetPlugins() {
+			metadata = append(metadata, plugin.GetPluginMetadata(p))
+		}
+	}
+
+	// 按优先级排序，同优先级按名称排序
+	sort.Slice(metadata, func(i, j int) bool {
+		if metadata[i].Priority != metadata[j].Priority {
+			return metadata[i].Priority < metadata[j].Priority
+		}
+		return metadata[i].Name < metadata[j].Name
+	})
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"plugins_enabled": pluginsEnabled,
+		"total":           len(metadata),
+		"plugins":         metadata,
+	}))
+}
@@ -25,15 +25,15 @@ func SetupRouter(searchService *service.SearchService) *gin.Engine {
 	}
 	engine.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
 		gin.DefaultWriter.Write(
-			[]byte(fmt.Sprintf("\n", c.Request.Method, " ", c.Request.URL.String(), "\n--------------------\n",
+			[]byte(fmt.Sprint("\n", c.Request.Method, " ", c.Request.URL.String(), "\n--------------------\n",
 				//c.Request.Header, "\n--------------------\n",
 				warp.NewStackError(err, 3).Error(), "\n")))
 	}))
 
 	// 添加中间件
 	engine.Use(CORSMiddleware())
-	engine.Use(util.GzipMiddleware()) // 添加压缩中间件
-	engine.Use(AuthMiddleware())      // 添加认证中间件
+	engine.Use(util.CompressionMiddleware()) // 添加压缩中间件
+	engine.Use(AuthMiddleware())             // 添加认证中间件
 
 	// 定义API路由组
 	api := engine.Group("/api")
@@ -50,6 +50,33 @@ func SetupRouter(searchService *service.SearchService) *gin.Engine {
 		api.POST("/search", SearchHandler)
 		api.GET("/search", SearchHandler) // 添加GET方式支持
 
+		// 插件信息接口
+		api.GET("/plugins", PluginsHandler)
+
+		// 管理接口（需要启用认证）
+		admin := api.Group("/admin", AdminMiddleware())
+		{
+			admin.GET("/plugins", AdminPluginsHandler)
+			admin.GET("/plugins/health", PluginHealthHandler)
+			admin.POST("/plugins/:name/reset", ResetPluginCircuitHandler)
+			admin.POST("/plugins/:name/enable", EnablePluginHandler)
+			admin.POST("/plugins/:name/disable", DisablePluginHandler)
+			admin.POST("/plugins/:name/priority", SetPluginPriorityHandler)
+			admin.GET("/plugins/cache", PluginCacheStatsHandler)
+			admin.GET("/search/stats", SearchStatsHandler)
+			admin.GET("/cache", CacheListHandler)
+			admin.DELETE("/cache", CachePurgeHandler)
+			admin.POST("/cache/flush", CacheFlushHandler)
+			admin.GET("/cache/export", CacheExportHandler)
+			admin.POST("/cache/import", CacheImportHandler)
+			admin.GET("/cache/negative", NegativeCacheStatsHandler)
+			admin.GET("/cache/warmup", CacheWarmupStatusHandler)
+			admin.POST("/cache/warmup", CacheWarmupTriggerHandler)
+			admin.GET("/aliases", AliasListHandler)
+			admin.PUT("/aliases", AliasPutHandler)
+			admin.DELETE("/aliases/:title", AliasDeleteHandler)
+		}
+
 		// 健康检查接口
 		api.GET("/health", func(c *gin.Context) {
 			// 根据配置决定是否返回插件信息
@@ -88,12 +115,13 @@ func SetupRouter(searchService *service.SearchService) *gin.Engine {
 	}
 
 	// 注册插件的Web路由（如果插件实现了PluginWithWebHandler接口）
-	// 只有当插件功能启用且插件在启用列表中时才注册路由
+	// gin不支持运行时增删路由，因此为所有已注册的插件注册路由，请求时再检查插件是否启用，
+	// 这样通过管理接口运行时启用或禁用插件后，路由随之生效或失效
 	if config.AppConfig.AsyncPluginEnabled && searchService != nil && searchService.GetPluginManager() != nil {
-		enabledPlugins := searchService.GetPluginManager().GetPlugins()
-		for _, p := range enabledPlugins {
+		pluginManager := searchService.GetPluginManager()
+		for _, p := range plugin.GetRegisteredPlugins() {
 			if webPlugin, ok := p.(plugin.PluginWithWebHandler); ok {
-				webPlugin.RegisterWebRoutes(engine.Group(""))
+				webPlugin.RegisterWebRoutes(engine.Group("", PluginEnabledMiddleware(pluginManager, p.Name())))
 			}
 		}
 	}
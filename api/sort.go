@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"pansou/model"
+	"pansou/util/mediainfo"
+)
+
+// sortKey 排序字段
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// sortFields 支持的排序字段
+var sortFields = map[string]bool{
+	"resolution": true,
+	"size":       true,
+	"year":       true,
+	"episode":    true,
+	"score":      true,
+	"datetime":   true,
+}
+
+// parseSortKeys 解析逗号分隔的排序字段，字段前加-表示降序
+func parseSortKeys(value string) ([]sortKey, error) {
+	var keys []sortKey
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		key := sortKey{field: strings.TrimPrefix(part, "-"), desc: strings.HasPrefix(part, "-")}
+		if !sortFields[key.field] {
+			return nil, fmt.Errorf("无效的sort字段: %s，可选值为resolution、size、year、episode、score、datetime", key.field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortValue 返回排序字段的值，标题中没有该属性时返回false
+func sortValue(field string, media *model.MediaInfo, score float64, datetime time.Time) (float64, bool) {
+	switch field {
+	case "score":
+		return score, true
+	case "datetime":
+		return float64(datetime.Unix()), !datetime.IsZero()
+	}
+	if media == nil {
+		return 0, false
+	}
+	var value float64
+	switch field {
+	case "resolution":
+		value = float64(mediainfo.ResolutionRank(media.Resolution))
+	case "size":
+		value = float64(media.Size)
+	case "year":
+		value = float64(media.Year)
+	case "episode":
+		// 按包含的最新一集排序，单集标题中的总集数不算，只标了总集数的全集按总集数
+		if media.Episode > 0 {
+			value = float64(max(media.Episode, media.EpisodeEnd))
+		} else {
+			value = float64(media.Episodes)
+		}
+	}
+	return value, value > 0
+}
+
+// lessBy 按排序字段依次比较，没有该属性的排在最后
+func lessBy(keys []sortKey, a, b func(string) (float64, bool)) bool {
+	for _, key := range keys {
+		va, oka := a(key.field)
+		vb, okb := b(key.field)
+		switch {
+		case oka != okb:
+			return oka
+		case va == vb:
+			continue
+		case key.desc:
+			return va > vb
+		default:
+			return va < vb
+		}
+	}
+	return false
+}
+
+// applyResultSort 按排序字段对结果和每种网盘的链接排序，值相同的保持原有顺序
+func applyResultSort(response model.SearchResponse, keys []sortKey) model.SearchResponse {
+	if len(keys) == 0 {
+		return response
+	}
+
+	results := response.Results
+	sort.SliceStable(results, func(i, j int) bool {
+		return lessBy(keys,
+			func(field string) (float64, bool) {
+				return sortValue(field, results[i].Media, results[i].Score, results[i].Datetime)
+			},
+			func(field string) (float64, bool) {
+				return sortValue(field, results[j].Media, results[j].Score, results[j].Datetime)
+			})
+	})
+
+	for _, links := range response.MergedByType {
+		sort.SliceStable(links, func(i, j int) bool {
+			return lessBy(keys,
+				func(field string) (float64, bool) {
+					return sortValue(field, links[i].Media, links[i].Score, links[i].Datetime)
+				},
+				func(field string) (float64, bool) {
+					return sortValue(field, links[j].Media, links[j].Score, links[j].Datetime)
+				})
+		})
+	}
+	return response
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"pansou/model"
+)
+
+func TestParseSortKeys(t *testing.T) {
+	keys, err := parseSortKeys(" -Resolution, size ,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != (sortKey{field: "resolution", desc: true}) || keys[1] != (sortKey{field: "size"}) {
+		t.Errorf("parseSortKeys = %+v", keys)
+	}
+	if _, err := parseSortKeys("-bitrate"); err == nil {
+		t.Error("未知字段应返回错误")
+	}
+}
+
+func TestApplyResultSort(t *testing.T) {
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	response := model.SearchResponse{
+		Results: []model.SearchResult{
+			{UniqueID: "no-media", Score: 0.9},
+			{UniqueID: "1080p-small", Media: &model.MediaInfo{Resolution: "1080p", Size: 2 << 30}},
+			{UniqueID: "2160p", Media: &model.MediaInfo{Resolution: "2160p", Size: 1 << 30}},
+			{UniqueID: "1080p-big", Media: &model.MediaInfo{Resolution: "1080p", Size: 8 << 30}},
+		},
+		MergedByType: model.MergedLinks{
+			"quark": {
+				{URL: "old", Datetime: day},
+				{URL: "unknown"},
+				{URL: "new", Datetime: day.Add(24 * time.Hour)},
+			},
+		},
+	}
+
+	keys, _ := parseSortKeys("-resolution,-size")
+	sorted := applyResultSort(response, keys)
+	want := []string{"2160p", "1080p-big", "1080p-small", "no-media"}
+	for i, r := range sorted.Results {
+		if r.UniqueID != want[i] {
+			t.Fatalf("按分辨率、大小降序排列，没有属性的排在最后: 第%d项为 %s，应为 %s", i, r.UniqueID, want[i])
+		}
+	}
+
+	keys, _ = parseSortKeys("-datetime")
+	sorted = applyResultSort(response, keys)
+	links := sorted.MergedByType["quark"]
+	if links[0].URL != "new" || links[1].URL != "old" || links[2].URL != "unknown" {
+		t.Errorf("合并链接应按时间降序排列，没有时间的排在最后: %v %v %v", links[0].URL, links[1].URL, links[2].URL)
+	}
+}
+
+func TestSortValueEpisode(t *testing.T) {
+	cases := []struct {
+		info model.MediaInfo
+		want float64
+	}{
+		{model.MediaInfo{Episode: 6}, 6},
+		{model.MediaInfo{Episode: 1, EpisodeEnd: 12}, 12},
+		{model.MediaInfo{Episode: 6, Episodes: 40}, 6},
+		{model.MediaInfo{Episodes: 40}, 40},
+	}
+	for _, c := range cases {
+		if got, ok := sortValue("episode", &c.info, 0, time.Time{}); !ok || got != c.want {
+			t.Errorf("%+v: episode = %v，应为 %v", c.info, got, c.want)
+		}
+	}
+	if _, ok := sortValue("episode", &model.MediaInfo{Year: 2024}, 0, time.Time{}); ok {
+		t.Error("没有集信息时应排在最后")
+	}
+}
@@ -27,9 +27,37 @@ type Config struct {
 	CachePath       string
 	CacheMaxSizeMB  int
 	CacheTTLMinutes int
+	// Redis共享缓存配置（为空表示只使用本地磁盘）
+	CacheRedisURL       string
+	CacheRedisPoolSize  int
+	CacheRedisKeyPrefix string
+	// 无结果缓存配置（TTL为0表示不缓存无结果的搜索）
+	NegativeCacheTTL      time.Duration
+	NegativeCacheMaxItems int
+	// 缓存预热配置（在低峰时段重新搜索热门关键词，高峰期用户直接命中缓存）
+	WarmupEnabled  bool
+	WarmupInterval time.Duration // 两轮预热之间的间隔
+	WarmupTopN     int           // 每轮预热的热门关键词数
+	WarmupKeywords []string      // 始终预热的种子关键词
+	WarmupWindow   string        // 允许预热的时段，如"02:00-06:00"，为空表示不限
+	WarmupStagger  time.Duration // 相邻两次预热搜索的间隔
+	// 缓存有效期策略配置
+	CacheTTLRules       map[string]time.Duration // 按插件名、频道名或内容分类覆盖有效期，如"plugin:nyaa"
+	CacheTTLAdaptiveMax time.Duration            // 结果连续不变时有效期可延长到的上限，0表示不延长
 	// 压缩相关配置
-	EnableCompression bool
-	MinSizeToCompress int // 最小压缩大小（字节）
+	EnableCompression    bool
+	MinSizeToCompress    int      // 最小压缩大小（字节）
+	CompressionEncodings []string // HTTP响应可用的压缩编码，按优先级排列
+	CacheCompression     string   // 二级缓存（磁盘/Redis）数据的压缩方式：none、gzip、zstd
+	CacheZstdDictPath    string   // zstd字典文件路径，为空表示不使用字典
+	// 关键词匹配配置
+	PinyinMatchEnabled bool    // 是否允许用拼音全拼或首字母匹配汉字标题
+	MatchMode          string  // 默认匹配模式：strict（关键词需完整出现）或 fuzzy（容忍拼写错误）
+	FuzzyThreshold     float64 // 模糊匹配时关键词的最低相似度，取值(0,1]
+	// 作品别名配置
+	AliasDictPath      string // 别名词典文件路径，为空时使用CACHE_PATH下的title_aliases.json
+	AliasMaxExpansions int    // 每个关键词最多追加搜索的别名数，0表示不按别名扩展
+	AliasLearnEnabled  bool   // 是否从搜索结果中学习英文别名
 	// GC相关配置
 	GCPercent      int  // GC触发阈值百分比
 	OptimizeMemory bool // 是否启用内存优化
@@ -45,6 +73,15 @@ type Config struct {
 	AsyncMaxBackgroundTasks   int           // 最大后台任务数量
 	AsyncCacheTTLHours        int           // 异步缓存有效期（小时）
 	AsyncLogEnabled           bool          // 是否启用异步插件详细日志
+	PluginCacheMaxItems       int           // 插件结果缓存最大条目数
+	PluginCacheMaxSizeMB      int           // 插件结果缓存最大内存（MB）
+	// 插件熔断配置
+	PluginCircuitFailureThreshold int           // 连续失败多少次后熔断（0表示不熔断）
+	PluginCircuitOpenDuration     time.Duration // 熔断持续时间，到期后放行一次探测请求
+	// 声明式与脚本插件配置
+	PluginDefinitionsDir string        // YAML站点定义和JS脚本所在目录
+	PluginScriptTimeout  time.Duration // 脚本插件单次搜索的执行时间上限
+	ExternalPlugins      []string      // 外部插件命令行列表
 	// HTTP服务器配置
 	HTTPReadTimeout  time.Duration // 读取超时
 	HTTPWriteTimeout time.Duration // 写入超时
@@ -53,6 +90,7 @@ type Config struct {
 	// 认证相关配置
 	AuthEnabled     bool              // 是否启用认证
 	AuthUsers       map[string]string // 用户名:密码映射
+	AuthAdmins      map[string]bool   // 可以调用管理接口的用户名
 	AuthTokenExpiry time.Duration     // Token有效期
 	AuthJWTSecret   string            // JWT签名密钥
 
@@ -81,9 +119,37 @@ func Init() {
 		CachePath:       getCachePath(),
 		CacheMaxSizeMB:  getCacheMaxSize(),
 		CacheTTLMinutes: getCacheTTL(),
+		// Redis共享缓存配置
+		CacheRedisURL:       os.Getenv("REDIS_URL"),
+		CacheRedisPoolSize:  getRedisPoolSize(),
+		CacheRedisKeyPrefix: getRedisKeyPrefix(),
+		// 无结果缓存配置
+		NegativeCacheTTL:      getNegativeCacheTTL(),
+		NegativeCacheMaxItems: getNegativeCacheMaxItems(),
+		// 缓存预热配置
+		WarmupEnabled:  getWarmupEnabled(),
+		WarmupInterval: getWarmupInterval(),
+		WarmupTopN:     getWarmupTopN(),
+		WarmupKeywords: getWarmupKeywords(),
+		WarmupWindow:   strings.TrimSpace(os.Getenv("WARMUP_WINDOW")),
+		WarmupStagger:  getWarmupStagger(),
+		// 缓存有效期策略配置
+		CacheTTLRules:       getCacheTTLRules(),
+		CacheTTLAdaptiveMax: getCacheTTLAdaptiveMax(),
 		// 压缩相关配置
-		EnableCompression: getEnableCompression(),
-		MinSizeToCompress: getMinSizeToCompress(),
+		EnableCompression:    getEnableCompression(),
+		MinSizeToCompress:    getMinSizeToCompress(),
+		CompressionEncodings: getCompressionEncodings(),
+		CacheCompression:     getCacheCompression(),
+		CacheZstdDictPath:    strings.TrimSpace(os.Getenv("CACHE_ZSTD_DICT")),
+		// 关键词匹配配置
+		PinyinMatchEnabled: getPinyinMatchEnabled(),
+		MatchMode:          getMatchMode(),
+		FuzzyThreshold:     getFuzzyThreshold(),
+		// 作品别名配置
+		AliasDictPath:      strings.TrimSpace(os.Getenv("ALIAS_DICT_PATH")),
+		AliasMaxExpansions: getAliasMaxExpansions(),
+		AliasLearnEnabled:  getAliasLearnEnabled(),
 		// GC相关配置
 		GCPercent:      getGCPercent(),
 		OptimizeMemory: getOptimizeMemory(),
@@ -99,6 +165,15 @@ func Init() {
 		AsyncMaxBackgroundTasks:   getAsyncMaxBackgroundTasks(),
 		AsyncCacheTTLHours:        getAsyncCacheTTLHours(),
 		AsyncLogEnabled:           getAsyncLogEnabled(),
+		PluginCacheMaxItems:       getPluginCacheMaxItems(),
+		PluginCacheMaxSizeMB:      getPluginCacheMaxSizeMB(),
+		// 插件熔断配置
+		PluginCircuitFailureThreshold: getPluginCircuitFailureThreshold(),
+		PluginCircuitOpenDuration:     getPluginCircuitOpenDuration(),
+		// 声明式与脚本插件配置
+		PluginDefinitionsDir: getPluginDefinitionsDir(),
+		PluginScriptTimeout:  getPluginScriptTimeout(),
+		ExternalPlugins:      getExternalPlugins(),
 		// HTTP服务器配置
 		HTTPReadTimeout:  getHTTPReadTimeout(),
 		HTTPWriteTimeout: getHTTPWriteTimeout(),
@@ -107,6 +182,7 @@ func Init() {
 		// 认证相关配置
 		AuthEnabled:     getAuthEnabled(),
 		AuthUsers:       getAuthUsers(),
+		AuthAdmins:      getAuthAdmins(),
 		AuthTokenExpiry: getAuthTokenExpiry(),
 		AuthJWTSecret:   getAuthJWTSecret(),
 	}
@@ -283,6 +359,59 @@ func getEnableCompression() bool {
 	return enabled == "true" || enabled == "1"
 }
 
+// 从环境变量获取是否启用拼音匹配，如果未设置则默认禁用
+func getPinyinMatchEnabled() bool {
+	enabled := os.Getenv("PINYIN_MATCH_ENABLED")
+	if enabled == "" {
+		return false // 默认禁用，短的字母关键词可能误匹配较多汉字标题
+	}
+	return enabled == "true" || enabled == "1"
+}
+
+// 从环境变量获取默认匹配模式，如果未设置或无效则使用严格匹配
+func getMatchMode() string {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("MATCH_MODE")))
+	if mode == "fuzzy" {
+		return mode
+	}
+	return "strict"
+}
+
+// 从环境变量获取模糊匹配的相似度阈值，如果未设置则使用默认值
+func getFuzzyThreshold() float64 {
+	value := os.Getenv("FUZZY_THRESHOLD")
+	if value == "" {
+		return 0.75 // 默认0.75，四字标题错一个字仍能匹配
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		return 0.75
+	}
+	return threshold
+}
+
+// 从环境变量获取每个关键词最多追加的别名数，如果未设置则使用默认值
+func getAliasMaxExpansions() int {
+	value := os.Getenv("ALIAS_MAX_EXPANSIONS")
+	if value == "" {
+		return 2 // 默认2个，每个别名都会向所有来源多发起一次搜索
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 2
+	}
+	return n
+}
+
+// 从环境变量获取是否从搜索结果中学习别名，如果未设置则默认禁用
+func getAliasLearnEnabled() bool {
+	enabled := os.Getenv("ALIAS_LEARN_ENABLED")
+	if enabled == "" {
+		return false
+	}
+	return enabled == "true" || enabled == "1"
+}
+
 // 从环境变量获取最小压缩大小，如果未设置则使用默认值
 func getMinSizeToCompress() int {
 	sizeEnv := os.Getenv("MIN_SIZE_TO_COMPRESS")
@@ -296,6 +425,31 @@ func getMinSizeToCompress() int {
 	return size
 }
 
+// 从环境变量获取HTTP响应可用的压缩编码，逗号分隔，排在前面的优先
+func getCompressionEncodings() []string {
+	var encodings []string
+	for _, name := range strings.Split(os.Getenv("COMPRESSION_ENCODINGS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "zstd" || name == "br" || name == "gzip" {
+			encodings = append(encodings, name)
+		}
+	}
+	if len(encodings) == 0 {
+		return []string{"zstd", "br", "gzip"} // 默认zstd优先
+	}
+	return encodings
+}
+
+// 从环境变量获取二级缓存数据的压缩方式，如果未设置或无法识别则不压缩
+func getCacheCompression() string {
+	switch compression := strings.ToLower(strings.TrimSpace(os.Getenv("CACHE_COMPRESSION"))); compression {
+	case "gzip", "zstd":
+		return compression
+	default:
+		return "none"
+	}
+}
+
 // 从环境变量获取GC百分比，如果未设置则使用默认值
 func getGCPercent() int {
 	percentEnv := os.Getenv("GC_PERCENT")
@@ -439,6 +593,177 @@ func getAsyncCacheTTLHours() int {
 	return ttl
 }
 
+// 从环境变量获取Redis连接池大小，如果未设置则使用默认值
+func getRedisPoolSize() int {
+	sizeEnv := os.Getenv("REDIS_POOL_SIZE")
+	if sizeEnv == "" {
+		return 10 // 默认10个连接
+	}
+	size, err := strconv.Atoi(sizeEnv)
+	if err != nil || size <= 0 {
+		return 10
+	}
+	return size
+}
+
+// 从环境变量获取Redis键前缀，多个PanSou集群共用一个Redis时用于区分
+func getRedisKeyPrefix() string {
+	prefix := os.Getenv("REDIS_KEY_PREFIX")
+	if prefix == "" {
+		return "pansou:"
+	}
+	return prefix
+}
+
+// 从环境变量获取无结果缓存有效期（秒），设置为0关闭无结果缓存
+func getNegativeCacheTTL() time.Duration {
+	ttlEnv := os.Getenv("NEGATIVE_CACHE_TTL")
+	if ttlEnv == "" {
+		return 5 * time.Minute // 默认5分钟
+	}
+	ttl, err := strconv.Atoi(ttlEnv)
+	if err != nil || ttl < 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// 从环境变量获取无结果缓存最大条目数，如果未设置则使用默认值
+func getNegativeCacheMaxItems() int {
+	itemsEnv := os.Getenv("NEGATIVE_CACHE_MAX_ITEMS")
+	if itemsEnv == "" {
+		return 100000 // 默认10万条
+	}
+	items, err := strconv.Atoi(itemsEnv)
+	if err != nil || items <= 0 {
+		return 100000
+	}
+	return items
+}
+
+// 从环境变量获取是否启用缓存预热，默认关闭
+func getWarmupEnabled() bool {
+	return strings.ToLower(os.Getenv("WARMUP_ENABLED")) == "true"
+}
+
+// 从环境变量获取缓存预热间隔（分钟），如果未设置则使用默认值
+func getWarmupInterval() time.Duration {
+	intervalEnv := os.Getenv("WARMUP_INTERVAL")
+	if intervalEnv == "" {
+		return 30 * time.Minute // 默认30分钟
+	}
+	minutes, err := strconv.Atoi(intervalEnv)
+	if err != nil || minutes <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// 从环境变量获取每轮预热的热门关键词数，如果未设置则使用默认值
+func getWarmupTopN() int {
+	topEnv := os.Getenv("WARMUP_TOP_N")
+	if topEnv == "" {
+		return 50 // 默认50个
+	}
+	top, err := strconv.Atoi(topEnv)
+	if err != nil || top < 0 {
+		return 50
+	}
+	return top
+}
+
+// 从环境变量获取预热种子关键词，逗号分隔
+func getWarmupKeywords() []string {
+	var keywords []string
+	for _, keyword := range strings.Split(os.Getenv("WARMUP_KEYWORDS"), ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// 从环境变量获取相邻两次预热搜索的间隔（秒），如果未设置则使用默认值
+func getWarmupStagger() time.Duration {
+	staggerEnv := os.Getenv("WARMUP_STAGGER")
+	if staggerEnv == "" {
+		return 5 * time.Second // 默认5秒
+	}
+	seconds, err := strconv.Atoi(staggerEnv)
+	if err != nil || seconds < 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// 从环境变量获取缓存有效期规则，格式为"plugin:nyaa=10m,channel:xxx=2h,category:magnet=15m"
+// 有效期可以是Go时长格式或分钟数，格式错误的规则会被忽略
+func getCacheTTLRules() map[string]time.Duration {
+	rules := make(map[string]time.Duration)
+	for _, item := range strings.Split(os.Getenv("CACHE_TTL_RULES"), ",") {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		if !strings.HasPrefix(key, "plugin:") && !strings.HasPrefix(key, "channel:") && !strings.HasPrefix(key, "category:") {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			minutes, convErr := strconv.Atoi(value)
+			if convErr != nil {
+				continue
+			}
+			ttl = time.Duration(minutes) * time.Minute
+		}
+		if ttl > 0 {
+			rules[key] = ttl
+		}
+	}
+	return rules
+}
+
+// 从环境变量获取自适应有效期上限（分钟），如果未设置则使用默认值
+func getCacheTTLAdaptiveMax() time.Duration {
+	maxEnv := os.Getenv("CACHE_TTL_ADAPTIVE_MAX")
+	if maxEnv == "" {
+		return 360 * time.Minute // 默认6小时
+	}
+	minutes, err := strconv.Atoi(maxEnv)
+	if err != nil || minutes < 0 {
+		return 360 * time.Minute
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// 从环境变量获取插件结果缓存最大条目数，如果未设置则使用默认值
+func getPluginCacheMaxItems() int {
+	itemsEnv := os.Getenv("PLUGIN_CACHE_MAX_ITEMS")
+	if itemsEnv == "" {
+		return 10000 // 默认10000条
+	}
+	items, err := strconv.Atoi(itemsEnv)
+	if err != nil || items <= 0 {
+		return 10000
+	}
+	return items
+}
+
+// 从环境变量获取插件结果缓存最大内存（MB），如果未设置则使用默认值
+func getPluginCacheMaxSizeMB() int {
+	sizeEnv := os.Getenv("PLUGIN_CACHE_MAX_SIZE")
+	if sizeEnv == "" {
+		return 64 // 默认64MB
+	}
+	size, err := strconv.Atoi(sizeEnv)
+	if err != nil || size <= 0 {
+		return 64
+	}
+	return size
+}
+
 // 从环境变量获取HTTP读取超时，如果未设置则自动计算
 func getHTTPReadTimeout() time.Duration {
 	timeoutEnv := os.Getenv("HTTP_READ_TIMEOUT")
@@ -541,6 +866,71 @@ func getAsyncLogEnabled() bool {
 	return enabled
 }
 
+// 从环境变量获取插件熔断的连续失败阈值，如果未设置则使用默认值
+func getPluginCircuitFailureThreshold() int {
+	thresholdEnv := os.Getenv("PLUGIN_CIRCUIT_FAILURE_THRESHOLD")
+	if thresholdEnv == "" {
+		return 5 // 默认连续失败5次熔断
+	}
+	threshold, err := strconv.Atoi(thresholdEnv)
+	if err != nil || threshold < 0 {
+		return 5
+	}
+	return threshold
+}
+
+// 从环境变量获取插件熔断持续时间（秒），如果未设置则使用默认值
+func getPluginCircuitOpenDuration() time.Duration {
+	durationEnv := os.Getenv("PLUGIN_CIRCUIT_OPEN_SECONDS")
+	if durationEnv == "" {
+		return 5 * time.Minute // 默认5分钟
+	}
+	seconds, err := strconv.Atoi(durationEnv)
+	if err != nil || seconds <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// 从环境变量获取脚本插件单次搜索的执行时间上限（秒），如果未设置则使用默认值
+func getPluginScriptTimeout() time.Duration {
+	timeoutEnv := os.Getenv("PLUGIN_SCRIPT_TIMEOUT")
+	if timeoutEnv == "" {
+		return 20 * time.Second // 默认20秒
+	}
+	seconds, err := strconv.Atoi(timeoutEnv)
+	if err != nil || seconds <= 0 {
+		return 20 * time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// 从环境变量获取外部插件命令，多个命令用分号分隔
+func getExternalPlugins() []string {
+	commandsEnv := os.Getenv("EXTERNAL_PLUGINS")
+	if commandsEnv == "" {
+		return nil
+	}
+
+	var commands []string
+	for _, command := range strings.Split(commandsEnv, ";") {
+		command = strings.TrimSpace(command)
+		if command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// 从环境变量获取声明式插件的YAML定义目录，如果未设置则使用默认值
+func getPluginDefinitionsDir() string {
+	dir := os.Getenv("PLUGIN_DEFINITIONS_DIR")
+	if dir == "" {
+		return "./plugins.d"
+	}
+	return dir
+}
+
 // 从环境变量获取认证开关，如果未设置则默认关闭
 func getAuthEnabled() bool {
 	enabled := os.Getenv("AUTH_ENABLED")
@@ -569,6 +959,17 @@ func getAuthUsers() map[string]string {
 	return users
 }
 
+// 从环境变量获取管理员用户名，格式：admin1,admin2，未设置时没有用户可以调用管理接口
+func getAuthAdmins() map[string]bool {
+	admins := make(map[string]bool)
+	for _, name := range strings.Split(os.Getenv("AUTH_ADMINS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			admins[name] = true
+		}
+	}
+	return admins
+}
+
 // 从环境变量获取Token有效期（小时），如果未设置则使用默认值
 func getAuthTokenExpiry() time.Duration {
 	expiryEnv := os.Getenv("AUTH_TOKEN_EXPIRY")
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net"
@@ -19,9 +20,15 @@ import (
 	"pansou/api"
 	"pansou/config"
 	"pansou/plugin"
+	"pansou/plugin/declarative"
+	"pansou/plugin/external"
+	"pansou/plugin/script"
 	"pansou/service"
 	"pansou/util"
+	"pansou/util/alias"
 	"pansou/util/cache"
+	"pansou/util/codec"
+	"pansou/util/textnorm"
 
 	// 以下是插件的空导入，用于触发各插件的init函数，实现自动注册
 	// 添加新插件时，只需在此处添加对应的导入语句即可
@@ -109,6 +116,20 @@ import (
 var globalCacheWriteManager *cache.DelayedBatchWriteManager
 
 func main() {
+	// 子命令：处理本地磁盘缓存后退出
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate-cache":
+			// 迁移缓存格式，用于升级前预先处理缓存
+			runCacheMigration(os.Args[2:])
+			return
+		case "train-zstd-dict":
+			// 用现有缓存训练zstd字典
+			runZstdDictTraining(os.Args[2:])
+			return
+		}
+	}
+
 	// 初始化应用
 	initApp()
 
@@ -116,6 +137,56 @@ func main() {
 	startServer()
 }
 
+// runCacheMigration 检查本地磁盘缓存，旧格式的缓存项按当前格式重写，无法解码的删除
+// 服务运行时读取到旧格式数据也会逐项迁移，此命令用于一次性处理全部缓存
+func runCacheMigration(args []string) {
+	fs := flag.NewFlagSet("migrate-cache", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "只统计，不修改缓存")
+	fs.Parse(args)
+
+	config.Init()
+	mainCache, err := cache.NewEnhancedTwoLevelCache()
+	if err != nil {
+		log.Fatalf("缓存初始化失败: %v", err)
+	}
+	defer mainCache.Close()
+
+	start := time.Now()
+	report, err := mainCache.MigrateLocal(*dryRun)
+	if err != nil {
+		log.Fatalf("缓存迁移失败: %v", err)
+	}
+	fmt.Printf("缓存迁移完成 (耗时: %v, 试运行: %v): 检查 %d, 当前格式 %d, 重写 %d, 删除 %d, 更高版本 %d\n",
+		time.Since(start).Round(time.Millisecond), *dryRun, report.Scanned, report.Current, report.Rewritten, report.Dropped, report.Newer)
+}
+
+// runZstdDictTraining 从本地磁盘缓存中抽样训练zstd字典，写入-o指定的文件
+// 通过CACHE_ZSTD_DICT指定字典后，zstd压缩对体积较小的搜索结果效果更好
+func runZstdDictTraining(args []string) {
+	fs := flag.NewFlagSet("train-zstd-dict", flag.ExitOnError)
+	output := fs.String("o", "zstd.dict", "字典输出文件")
+	samples := fs.Int("samples", 2000, "最多使用的缓存项数")
+	size := fs.Int("size", codec.DefaultDictSize, "字典大小（字节）")
+	fs.Parse(args)
+
+	config.Init()
+	mainCache, err := cache.NewEnhancedTwoLevelCache()
+	if err != nil {
+		log.Fatalf("缓存初始化失败: %v", err)
+	}
+	defer mainCache.Close()
+
+	payloads := mainCache.SamplePayloads(*samples)
+	dictionary, err := codec.TrainZstdDict(payloads, *size)
+	if err != nil {
+		log.Fatalf("训练zstd字典失败: %v", err)
+	}
+	if err := os.WriteFile(*output, dictionary, 0644); err != nil {
+		log.Fatalf("写入字典文件失败: %v", err)
+	}
+	fmt.Printf("zstd字典训练完成: 样本 %d, 字典大小 %d字节, 输出 %s\n", len(payloads), len(dictionary), *output)
+}
+
 // initApp 初始化应用程序
 func initApp() {
 	// 初始化配置
@@ -124,6 +195,12 @@ func initApp() {
 	// 初始化HTTP客户端
 	util.InitHTTPClient()
 
+	// 设置关键词的拼音匹配
+	textnorm.SetPinyinMatch(config.AppConfig.PinyinMatchEnabled)
+
+	// 加载作品别名词典
+	alias.Default()
+
 	// 初始化缓存写入管理器
 	var err error
 	globalCacheWriteManager, err = cache.NewDelayedBatchWriteManager()
@@ -158,7 +235,17 @@ func startServer() {
 
 	// 注册全局插件（根据配置过滤）
 	if config.AppConfig.AsyncPluginEnabled {
+		// 加载声明式、脚本和外部进程插件，之后与编译插件一样参与过滤
+		declarative.RegisterDir(config.AppConfig.PluginDefinitionsDir)
+		script.RegisterDir(config.AppConfig.PluginDefinitionsDir)
+		external.RegisterCommands(config.AppConfig.ExternalPlugins)
+
 		pluginManager.RegisterGlobalPluginsWithFilter(config.AppConfig.EnabledPlugins)
+
+		// 应用管理接口在运行时做出的调整（启用/禁用/优先级）
+		if err := pluginManager.LoadState(); err != nil {
+			fmt.Printf("加载插件运行时状态失败: %v\n", err)
+		}
 	}
 
 	// 更新默认并发数（如果插件被禁用则使用0）
@@ -171,6 +258,9 @@ func startServer() {
 	// 初始化搜索服务
 	searchService := service.NewSearchService(pluginManager)
 
+	// 启动缓存预热（未启用时返回nil）
+	warmup := service.StartWarmup(searchService)
+
 	// 设置路由
 	router := api.SetupRouter(searchService)
 
@@ -222,6 +312,11 @@ func startServer() {
 	<-quit
 	fmt.Println("正在关闭服务器...")
 
+	// 停止缓存预热，避免关闭过程中继续写入缓存
+	if warmup != nil {
+		warmup.Stop()
+	}
+
 	// 优先保存缓存数据到磁盘（数据安全第一）
 	// 增加关闭超时时间，确保数据有足够时间保存
 	shutdownTimeout := 10 * time.Second
@@ -237,8 +332,14 @@ func startServer() {
 		if err := mainCache.FlushMemoryToDisk(); err != nil {
 			log.Printf("内存缓存同步失败: %v", err)
 		}
+		if err := mainCache.Close(); err != nil {
+			log.Printf("磁盘缓存关闭失败: %v", err)
+		}
 	}
 
+	// 停止外部插件进程
+	external.StopAll()
+
 	// 设置关闭超时时间
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -308,8 +409,21 @@ func printServiceInfo(port string, pluginManager *plugin.PluginManager) {
 
 	// 输出压缩信息
 	if config.AppConfig.EnableCompression {
-		fmt.Printf("响应压缩已启用: 最小压缩大小=%d字节\n",
-			config.AppConfig.MinSizeToCompress)
+		fmt.Printf("响应压缩已启用: 编码=%s, 最小压缩大小=%d字节\n",
+			strings.Join(config.AppConfig.CompressionEncodings, ","), config.AppConfig.MinSizeToCompress)
+	}
+	if config.AppConfig.CacheCompression != "none" {
+		if config.AppConfig.CacheZstdDictPath != "" && config.AppConfig.CacheCompression == "zstd" {
+			fmt.Printf("缓存压缩已启用: %s (字典: %s)\n", config.AppConfig.CacheCompression, config.AppConfig.CacheZstdDictPath)
+		} else {
+			fmt.Printf("缓存压缩已启用: %s\n", config.AppConfig.CacheCompression)
+		}
+	}
+	if config.AppConfig.PinyinMatchEnabled {
+		fmt.Println("拼音匹配已启用: 字母关键词可匹配汉字标题的全拼和首字母")
+	}
+	if config.AppConfig.MatchMode == "fuzzy" {
+		fmt.Printf("默认模糊匹配: 相似度阈值=%.2f\n", config.AppConfig.FuzzyThreshold)
 	}
 
 	// 输出GC配置信息
@@ -384,14 +498,14 @@ func printServiceInfo(port string, pluginManager *plugin.PluginManager) {
 			// 按优先级排序（优先级数字越小越靠前）
 			sort.Slice(plugins, func(i, j int) bool {
 				// 优先级相同时按名称排序
-				if plugins[i].Priority() == plugins[j].Priority() {
+				if plugin.EffectivePriority(plugins[i]) == plugin.EffectivePriority(plugins[j]) {
 					return plugins[i].Name() < plugins[j].Name()
 				}
-				return plugins[i].Priority() < plugins[j].Priority()
+				return plugin.EffectivePriority(plugins[i]) < plugin.EffectivePriority(plugins[j])
 			})
 
 			for _, p := range plugins {
-				fmt.Printf("  - %s (优先级: %d)\n", p.Name(), p.Priority())
+				fmt.Printf("  - %s (优先级: %d)\n", p.Name(), plugin.EffectivePriority(p))
 			}
 		} else {
 			// 区分不同的情况
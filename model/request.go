@@ -4,6 +4,19 @@ package model
 type FilterConfig struct {
 	Include []string `json:"include,omitempty"` // 包含关键词列表（OR关系）
 	Exclude []string `json:"exclude,omitempty"` // 排除关键词列表（AND关系）
+
+	// 按标题解析出的媒体属性过滤，标题中没有相应属性的结果不满足条件
+	MinResolution string   `json:"min_res,omitempty"`  // 最低分辨率，如1080p、4k
+	HDR           bool     `json:"hdr,omitempty"`      // 只保留HDR或杜比视界资源
+	Codecs        []string `json:"codec,omitempty"`    // 视频编码，匹配任一即可，如h265、av1
+	Audio         []string `json:"audio,omitempty"`    // 音频格式，匹配任一即可，如atmos、dts-hd
+	Season        int      `json:"season,omitempty"`   // 包含该季
+	Episode       int      `json:"episode,omitempty"`  // 包含该集
+	Complete      bool     `json:"complete,omitempty"` // 只保留已完结资源
+	MinSize       string   `json:"min_size,omitempty"` // 最小文件大小，如10GB
+	MaxSize       string   `json:"max_size,omitempty"` // 最大文件大小，如50GB
+	MinYear       int      `json:"min_year,omitempty"` // 最早年份
+	MaxYear       int      `json:"max_year,omitempty"` // 最晚年份
 }
 
 // SearchRequest 搜索请求参数
@@ -18,4 +31,7 @@ type SearchRequest struct {
 	Ext          map[string]interface{} `json:"ext"`                   // 扩展参数，用于传递给插件的自定义参数
 	CloudTypes   []string               `json:"cloud_types"`           // 指定返回的网盘类型列表，不指定则返回所有类型
 	Filter       *FilterConfig          `json:"filter,omitempty"`      // 过滤配置，用于过滤返回结果
+	Stream       bool                   `json:"stream"`                // 是否以SSE流式返回增量结果
+	Match        string                 `json:"match"`                 // 匹配模式：strict(关键词需完整出现)、fuzzy(容忍拼写错误)，不指定则使用配置的默认值
+	Sort         string                 `json:"sort"`                  // 排序字段，逗号分隔，前加-表示降序：resolution、size、year、episode、score、datetime
 }
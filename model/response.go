@@ -13,26 +13,46 @@ type Link struct {
 
 // SearchResult 搜索结果
 type SearchResult struct {
-	MessageID string    `json:"message_id" sonic:"message_id"`
-	UniqueID  string    `json:"unique_id" sonic:"unique_id"` // 全局唯一ID
-	Channel   string    `json:"channel" sonic:"channel"`
-	Datetime  time.Time `json:"datetime" sonic:"datetime"`
-	Title     string    `json:"title" sonic:"title"`
-	Content   string    `json:"content" sonic:"content"`
-	Links     []Link    `json:"links" sonic:"links"`
-	Tags      []string  `json:"tags,omitempty" sonic:"tags,omitempty"`
-	Images    []string  `json:"images,omitempty" sonic:"images,omitempty"` // TG消息中的图片链接
-	Category  string    `json:"category,omitempty" sonic:"category,omitempty"`
+	MessageID string     `json:"message_id" sonic:"message_id"`
+	UniqueID  string     `json:"unique_id" sonic:"unique_id"` // 全局唯一ID
+	Channel   string     `json:"channel" sonic:"channel"`
+	Datetime  time.Time  `json:"datetime" sonic:"datetime"`
+	Title     string     `json:"title" sonic:"title"`
+	Content   string     `json:"content" sonic:"content"`
+	Links     []Link     `json:"links" sonic:"links"`
+	Tags      []string   `json:"tags,omitempty" sonic:"tags,omitempty"`
+	Images    []string   `json:"images,omitempty" sonic:"images,omitempty"` // TG消息中的图片链接
+	Category  string     `json:"category,omitempty" sonic:"category,omitempty"`
+	Score     float64    `json:"score" sonic:"score"`                     // 与查询的匹配分数，0到1，在本地过滤时计算
+	Media     *MediaInfo `json:"media,omitempty" sonic:"media,omitempty"` // 从标题解析的媒体属性
 }
 
 // MergedLink 合并后的网盘链接
 type MergedLink struct {
-	URL      string    `json:"url" sonic:"url"`
-	Password string    `json:"password" sonic:"password"`
-	Note     string    `json:"note" sonic:"note"`
-	Datetime time.Time `json:"datetime" sonic:"datetime"`
-	Source   string    `json:"source,omitempty" sonic:"source,omitempty"` // 数据来源：tg:频道名 或 plugin:插件名
-	Images   []string  `json:"images,omitempty" sonic:"images,omitempty"` // TG消息中的图片链接
+	URL      string     `json:"url" sonic:"url"`
+	Password string     `json:"password" sonic:"password"`
+	Note     string     `json:"note" sonic:"note"`
+	Datetime time.Time  `json:"datetime" sonic:"datetime"`
+	Source   string     `json:"source,omitempty" sonic:"source,omitempty"` // 数据来源：tg:频道名 或 plugin:插件名
+	Images   []string   `json:"images,omitempty" sonic:"images,omitempty"` // TG消息中的图片链接
+	Score    float64    `json:"score" sonic:"score"`                       // 链接标题与查询的匹配分数，0到1
+	Media    *MediaInfo `json:"media,omitempty" sonic:"media,omitempty"`   // 从标题解析的媒体属性
+}
+
+// MediaInfo 从资源标题中解析出的媒体属性，未识别的属性为零值
+type MediaInfo struct {
+	Resolution string   `json:"resolution,omitempty" sonic:"resolution,omitempty"`   // 分辨率，如2160p、1080p，4K/UHD记为2160p
+	HDR        []string `json:"hdr,omitempty" sonic:"hdr,omitempty"`                 // HDR格式：dv(杜比视界)、hdr10+、hdr10、hdr、hlg
+	Codec      string   `json:"codec,omitempty" sonic:"codec,omitempty"`             // 视频编码：h265、h264、av1、vp9
+	Audio      []string `json:"audio,omitempty" sonic:"audio,omitempty"`             // 音频格式，如atmos、truehd、dts-hd、ddp、aac
+	Season     int      `json:"season,omitempty" sonic:"season,omitempty"`           // 季，季范围时为起始季
+	SeasonEnd  int      `json:"season_end,omitempty" sonic:"season_end,omitempty"`   // 季范围的结束季
+	Episode    int      `json:"episode,omitempty" sonic:"episode,omitempty"`         // 集，集范围时为起始集
+	EpisodeEnd int      `json:"episode_end,omitempty" sonic:"episode_end,omitempty"` // 集范围的结束集，"更新至12集"记为1-12集
+	Episodes   int      `json:"episodes,omitempty" sonic:"episodes,omitempty"`       // 总集数，如"全40集"
+	Status     string   `json:"status,omitempty" sonic:"status,omitempty"`           // 完结状态：complete(已完结)、ongoing(更新中)
+	Size       int64    `json:"size,omitempty" sonic:"size,omitempty"`               // 文件大小（字节）
+	Year       int      `json:"year,omitempty" sonic:"year,omitempty"`               // 年份
 }
 
 // MergedLinks 按网盘类型分组的合并链接
@@ -43,6 +63,36 @@ type SearchResponse struct {
 	Total        int            `json:"total" sonic:"total"`
 	Results      []SearchResult `json:"results,omitempty" sonic:"results,omitempty"`
 	MergedByType MergedLinks    `json:"merged_by_type,omitempty" sonic:"merged_by_type,omitempty"`
+	Sources      []SourceStatus `json:"sources,omitempty" sonic:"sources,omitempty"` // 各来源的数据状态
+	Aliases      []string       `json:"aliases,omitempty" sonic:"aliases,omitempty"` // 按别名词典追加搜索的作品别名
+}
+
+// 来源数据状态
+const (
+	SourceStatusFresh   = "fresh"   // 本次搜索获取
+	SourceStatusCached  = "cached"  // 来自缓存
+	SourceStatusEmpty   = "empty"   // 近期搜索无结果，未重新请求
+	SourceStatusFailed  = "failed"  // 请求失败、超时或插件熔断
+	SourceStatusPending = "pending" // 插件响应超时，仍在后台搜索，完成后写入缓存
+)
+
+// SourceStatus 单个来源（TG频道或插件）在本次搜索中的数据状态
+type SourceStatus struct {
+	Source    string     `json:"source" sonic:"source"`                             // 来源，如 tg:频道名、plugin:插件名
+	Status    string     `json:"status" sonic:"status"`                             // fresh、cached、empty、failed 或 pending
+	Count     int        `json:"count" sonic:"count"`                               // 该来源的结果数
+	UpdatedAt *time.Time `json:"updated_at,omitempty" sonic:"updated_at,omitempty"` // 结果的获取时间，缓存结果为写入缓存的时间
+}
+
+// SearchStreamEvent 流式搜索（SSE）事件
+type SearchStreamEvent struct {
+	Event        string      `json:"event" sonic:"event"`                                       // 事件类型：delta(增量结果)、final(搜索结束)
+	Source       string      `json:"source,omitempty" sonic:"source,omitempty"`                 // 结果来源，如 tg:频道名、plugin:插件名
+	Late         bool        `json:"late,omitempty" sonic:"late,omitempty"`                     // 是否为前台返回后才到达的后台结果
+	Total        int         `json:"total" sonic:"total"`                                       // 截至当前已推送的链接总数
+	Elapsed      int64       `json:"elapsed_ms,omitempty" sonic:"elapsed_ms,omitempty"`         // 搜索总耗时（毫秒），仅final事件
+	Pending      []string    `json:"pending,omitempty" sonic:"pending,omitempty"`               // 达到插件超时时间时仍在后台处理、结果未推送完的来源，仅final事件
+	MergedByType MergedLinks `json:"merged_by_type,omitempty" sonic:"merged_by_type,omitempty"` // 本次新增的按网盘类型分组的链接
 }
 
 // Response API通用响应
@@ -0,0 +1,31 @@
+package plugin
+
+import (
+	"time"
+
+	"pansou/model"
+	"pansou/util/ttlpolicy"
+)
+
+// CacheTTLHinter 可以根据搜索结果建议缓存有效期的插件接口
+// 例如磁力站结果变化快可以建议较短的有效期，资料站结果稳定可以建议较长的有效期
+// 建议值优先于内容分类规则，但低于CACHE_TTL_RULES中按插件名配置的有效期
+type CacheTTLHinter interface {
+	AsyncSearchPlugin // 继承搜索插件接口
+
+	// CacheTTLHint 返回建议的缓存有效期，返回0表示不建议
+	CacheTTLHint(keyword string, results []model.SearchResult) time.Duration
+}
+
+// CacheTTL 按有效期策略计算插件结果的缓存有效期
+func CacheTTL(name, keyword string, results []model.SearchResult) time.Duration {
+	src := ttlpolicy.Source{Kind: ttlpolicy.KindPlugin, Name: name}
+	var hint time.Duration
+	if p, ok := GetPluginByName(name); ok {
+		src.Category = GetPluginMetadata(p).Category
+		if hinter, ok := p.(CacheTTLHinter); ok {
+			hint = hinter.CacheTTLHint(keyword, results)
+		}
+	}
+	return ttlpolicy.Default().TTL(src, keyword, results, hint)
+}
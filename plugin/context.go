@@ -0,0 +1,247 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"pansou/model"
+)
+
+// ContextExtKey ext中保存请求上下文的保留键
+// 现有插件通过Search(keyword, ext)调用，上下文随ext一起传递到BaseAsyncPlugin，
+// 插件自定义参数不应使用该键
+const ContextExtKey = "__context"
+
+// MainCacheKeyExtKey ext中保存本次搜索主缓存键的保留键
+// 插件实例在并发的搜索之间共享，主缓存键随ext按调用传递，
+// 避免不同关键词的搜索互相覆盖插件上的MainCacheKey，后台完成时写入别的关键词的缓存
+const MainCacheKeyExtKey = "__main_cache_key"
+
+// ReservedExtPrefix ext中内部保留键的前缀，这类键不会传给脚本或外部进程
+const ReservedExtPrefix = "__"
+
+// WithContext 返回携带上下文的ext副本，不修改原ext
+func WithContext(ctx context.Context, ext map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(ext)+1)
+	for k, v := range ext {
+		copied[k] = v
+	}
+	if ctx != nil {
+		copied[ContextExtKey] = ctx
+	}
+	return copied
+}
+
+// ContextFromExt 从ext中取出上下文，不存在时返回context.Background()
+func ContextFromExt(ext map[string]interface{}) context.Context {
+	if ext != nil {
+		if ctx, ok := ext[ContextExtKey].(context.Context); ok && ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// WithMainCacheKey 返回携带主缓存键的ext副本，不修改原ext
+func WithMainCacheKey(key string, ext map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(ext)+1)
+	for k, v := range ext {
+		copied[k] = v
+	}
+	copied[MainCacheKeyExtKey] = key
+	return copied
+}
+
+// mainCacheKeyFrom 返回ext中本次调用的主缓存键，不存在时返回插件传入的fallback
+func mainCacheKeyFrom(ext map[string]interface{}, fallback string) string {
+	if key, ok := ext[MainCacheKeyExtKey].(string); ok && key != "" {
+		return key
+	}
+	return fallback
+}
+
+// PublicExt 返回去掉内部保留键（以"__"开头）的ext副本，用于将ext交给脚本或外部进程
+func PublicExt(ext map[string]interface{}) map[string]interface{} {
+	public := make(map[string]interface{}, len(ext))
+	for k, v := range ext {
+		if !strings.HasPrefix(k, ReservedExtPrefix) {
+			public[k] = v
+		}
+	}
+	return public
+}
+
+// OutcomeReport 接收插件搜索的最终结果，用于健康统计
+// 响应超时后转入后台继续的搜索标记为延后，在后台完成时才报告结果。
+// 插件的Search本身可能再调用AsyncSearch/AsyncSearchWithResult，形成嵌套的异步层，
+// 各层共用同一个报告：外层超时后收到的只是内层超时返回的空结果，
+// 因此只由转入后台的最内层在真正完成时报告，且最多报告一次
+type OutcomeReport struct {
+	mu            sync.Mutex
+	layers        int // 已进入的异步层数
+	deferredLayer int // 已转入后台的最内层编号，0表示未转入后台
+	reported      bool
+	report        func(results []model.SearchResult, err error)
+}
+
+// outcomeReportKey 上下文中保存OutcomeReport的键
+type outcomeReportKey struct{}
+
+// NewOutcomeReport 创建结果报告，后台完成时调用report
+func NewOutcomeReport(report func(results []model.SearchResult, err error)) *OutcomeReport {
+	return &OutcomeReport{report: report}
+}
+
+// WithOutcomeReport 返回携带结果报告的上下文
+func WithOutcomeReport(ctx context.Context, r *OutcomeReport) context.Context {
+	return context.WithValue(ctx, outcomeReportKey{}, r)
+}
+
+// Deferred 返回搜索是否已转入后台，结果将在后台完成时报告
+func (r *OutcomeReport) Deferred() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deferredLayer > 0
+}
+
+// outcomeReportFrom 从上下文中取出结果报告，不存在时返回nil
+func outcomeReportFrom(ctx context.Context) *OutcomeReport {
+	r, _ := ctx.Value(outcomeReportKey{}).(*OutcomeReport)
+	return r
+}
+
+// enter 进入一个异步层，返回该层的编号，内层的编号大于外层
+func (r *OutcomeReport) enter() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.layers++
+	return r.layers
+}
+
+// deferToBackground 标记第layer层响应超时、转入后台
+func (r *OutcomeReport) deferToBackground(layer int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if layer > r.deferredLayer {
+		r.deferredLayer = layer
+	}
+}
+
+// finish 第layer层在后台完成时报告结果
+// 只有转入后台的最内层的结果才是插件真正的结果，其他层（包括未转入后台的层）的调用被忽略
+func (r *OutcomeReport) finish(layer int, results []model.SearchResult, err error) {
+	if r == nil || r.report == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.reported || layer == 0 || layer != r.deferredLayer {
+		r.mu.Unlock()
+		return
+	}
+	r.reported = true
+	r.mu.Unlock()
+	r.report(results, err)
+}
+
+// AsContextPlugin 将插件转换为支持上下文的插件
+// 未实现ContextAsyncSearchPlugin的插件通过适配器包装，上下文经ext传递
+func AsContextPlugin(p AsyncSearchPlugin) ContextAsyncSearchPlugin {
+	if cp, ok := p.(ContextAsyncSearchPlugin); ok {
+		return cp
+	}
+	return contextPluginAdapter{AsyncSearchPlugin: p}
+}
+
+// contextPluginAdapter 为旧插件提供SearchWithContext实现
+type contextPluginAdapter struct {
+	AsyncSearchPlugin
+}
+
+// SearchWithContext 将上下文放入ext后调用插件的Search方法
+func (a contextPluginAdapter) SearchWithContext(ctx context.Context, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.Search(keyword, WithContext(ctx, ext))
+}
+
+// newDetachableContext 创建跟随父上下文取消的子上下文
+// 调用detach后与父上下文脱离，不再因客户端断开而取消（用于后台填充缓存）；
+// 使用完毕后必须调用cancel释放资源
+func newDetachableContext(parent context.Context) (ctx context.Context, detach func(), cancel context.CancelFunc) {
+	ctx, cancel = context.WithCancel(context.WithoutCancel(parent))
+	stop := context.AfterFunc(parent, cancel)
+	detach = func() { stop() }
+	return ctx, detach, cancel
+}
+
+// bindClient 返回绑定上下文的HTTP客户端副本
+// 通过该客户端发出的请求在ctx取消时立即中止，插件无需修改请求构造代码
+func bindClient(client *http.Client, ctx context.Context) *http.Client {
+	if client == nil || ctx == nil || ctx.Done() == nil {
+		return client
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	bound := *client
+	bound.Transport = &contextTransport{base: base, ctx: ctx}
+	return &bound
+}
+
+// contextTransport 将额外的上下文合并到每个请求中
+type contextTransport struct {
+	base http.RoundTripper
+	ctx  context.Context
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	reqCtx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(t.ctx, cancel)
+
+	resp, err := t.base.RoundTrip(req.WithContext(reqCtx))
+	if err != nil {
+		stop()
+		cancel()
+		return nil, err
+	}
+
+	// 响应体读取完成前保持上下文有效
+	resp.Body = &contextBody{ReadCloser: resp.Body, stop: stop, cancel: cancel}
+	return resp, nil
+}
+
+// contextBody 关闭响应体时释放合并的上下文
+type contextBody struct {
+	io.ReadCloser
+	stop   func() bool
+	cancel context.CancelFunc
+}
+
+// Close 关闭响应体
+func (b *contextBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.stop()
+	b.cancel()
+	return err
+}
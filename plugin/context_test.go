@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"pansou/model"
+)
+
+func TestPublicExt(t *testing.T) {
+	ext := WithContext(context.Background(), map[string]interface{}{
+		"refresh":  true,
+		"__cookie": "secret",
+		"_page":    2,
+	})
+	public := PublicExt(ext)
+	if len(public) != 2 || public["refresh"] != true || public["_page"] != 2 {
+		t.Errorf("应只去掉以__开头的保留键: %v", public)
+	}
+	if _, ok := ext["__cookie"]; !ok {
+		t.Error("不应修改原ext")
+	}
+}
+
+func TestMainCacheKeyPassedPerCall(t *testing.T) {
+	p := NewBaseAsyncPlugin("percall", 3)
+	// 共享字段上残留的是别的搜索的缓存键
+	p.SetMainCacheKey("stale")
+
+	var mu sync.Mutex
+	written := make(map[string]string)
+	p.SetMainCacheUpdater(func(key string, results []model.SearchResult, ttl time.Duration, isFinal bool, keyword string) error {
+		mu.Lock()
+		written[key] = keyword
+		mu.Unlock()
+		return nil
+	})
+
+	slow := func(client *http.Client, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+		time.Sleep(60 * time.Millisecond)
+		return []model.SearchResult{{UniqueID: keyword, Title: keyword}}, nil
+	}
+	// 插件的Search内部以p.MainCacheKey调用AsyncSearchWithResult
+	search := func(client *http.Client, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+		result, err := p.AsyncSearchWithResult(keyword, slow, p.MainCacheKey, ext)
+		return result.Results, err
+	}
+
+	var wg sync.WaitGroup
+	for _, kw := range []string{"alpha", "beta"} {
+		wg.Add(1)
+		go func(kw string) {
+			defer wg.Done()
+			ext := WithMainCacheKey("key-"+kw, WithContext(context.Background(), nil))
+			p.AsyncSearch(kw, search, "", ext)
+		}(kw)
+	}
+	wg.Wait()
+
+	// 响应超时后在后台完成，结果应写入各自调用传入的缓存键
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(written)
+		mu.Unlock()
+		if n >= 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(written) != 2 || written["key-alpha"] != "alpha" || written["key-beta"] != "beta" {
+		t.Errorf("后台完成应写入本次调用的缓存键: %v", written)
+	}
+}
@@ -0,0 +1,456 @@
+package declarative
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"pansou/model"
+	"pansou/plugin"
+	"pansou/util"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// 默认请求头，站点定义中的headers会覆盖同名项
+var defaultHeaders = map[string]string{
+	"User-Agent":      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
+	"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
+	"Accept-Language": "zh-CN,zh;q=0.9,en;q=0.8",
+	"Connection":      "keep-alive",
+}
+
+// Plugin 由YAML站点定义驱动的搜索插件
+type Plugin struct {
+	*plugin.BaseAsyncPlugin
+	def  *Definition
+	file string // 定义文件路径
+}
+
+// NewPlugin 根据站点定义创建插件
+func NewPlugin(def *Definition, file string) *Plugin {
+	return &Plugin{
+		BaseAsyncPlugin: plugin.NewBaseAsyncPluginWithFilter(def.Name, def.Priority, def.SkipServiceFilter),
+		def:             def,
+		file:            file,
+	}
+}
+
+// Definition 返回插件的站点定义
+func (p *Plugin) Definition() *Definition {
+	return p.def
+}
+
+// File 返回站点定义文件路径
+func (p *Plugin) File() string {
+	return p.file
+}
+
+// Metadata 返回插件描述信息
+func (p *Plugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: p.def.DisplayName,
+		Description: p.def.Description,
+		SiteURL:     p.def.SiteURL,
+		Category:    p.def.Category,
+	}
+}
+
+// Search 执行搜索并返回结果（兼容性方法）
+func (p *Plugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	result, err := p.SearchWithResult(keyword, ext)
+	if err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// SearchWithResult 执行搜索并返回包含IsFinal标记的结果
+func (p *Plugin) SearchWithResult(keyword string, ext map[string]interface{}) (model.PluginSearchResult, error) {
+	return p.AsyncSearchWithResult(keyword, p.searchImpl, p.MainCacheKey, ext)
+}
+
+// searchImpl 实际的搜索实现
+func (p *Plugin) searchImpl(client *http.Client, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	searchURL := expandTemplate(p.def.Search.URL, keyword)
+	var body string
+	if p.def.Search.Method == "POST" {
+		body = expandTemplate(p.def.Search.Body, keyword)
+	}
+
+	doc, err := p.fetchDocument(client, p.def.Search.Method, searchURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] 搜索请求失败: %w", p.Name(), err)
+	}
+
+	results, detailURLs := p.extractListResults(doc, searchURL)
+
+	// 先按标题过滤，避免为无关条目请求详情页
+	if !p.def.SkipServiceFilter {
+		results = plugin.FilterResultsByKeyword(results, keyword)
+	}
+
+	if p.def.Detail != nil {
+		results = p.fillDetails(client, results, detailURLs)
+	}
+
+	// 丢弃没有下载链接的结果
+	finalResults := results[:0]
+	for _, result := range results {
+		if len(result.Links) > 0 {
+			finalResults = append(finalResults, result)
+		}
+	}
+	return finalResults, nil
+}
+
+// expandTemplate 替换模板中的关键词占位符
+func expandTemplate(tmpl, keyword string) string {
+	return strings.NewReplacer(
+		"{keyword}", url.QueryEscape(keyword),
+		"{keyword_path}", url.PathEscape(keyword),
+		"{keyword_raw}", keyword,
+	).Replace(tmpl)
+}
+
+// extractListResults 解析搜索结果列表，返回结果及其对应的详情页地址（以UniqueID为键）
+func (p *Plugin) extractListResults(doc *goquery.Document, pageURL string) ([]model.SearchResult, map[string]string) {
+	var results []model.SearchResult
+	detailURLs := make(map[string]string)
+	seen := make(map[string]bool)
+	rule := &p.def.List
+
+	doc.Find(rule.Item).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if rule.MaxItems > 0 && len(results) >= rule.MaxItems {
+			return false
+		}
+
+		title := rule.Title.first(s)
+		if title == "" {
+			return true
+		}
+
+		link := resolveURL(pageURL, rule.Link.first(s))
+		uniqueID := p.uniqueID(rule.ID.first(s), link, title)
+		if seen[uniqueID] {
+			return true
+		}
+		seen[uniqueID] = true
+
+		result := model.SearchResult{
+			UniqueID: uniqueID,
+			Title:    title,
+			Content:  rule.Content.first(s),
+			Datetime: parseDate(&rule.Date, rule.Date.first(s)),
+			Tags:     rule.Tags.all(s),
+			Channel:  "", // 插件搜索结果必须为空字符串
+		}
+		if image := resolveURL(pageURL, rule.Image.first(s)); image != "" {
+			result.Images = []string{image}
+		}
+
+		if p.def.Detail != nil {
+			if link == "" {
+				return true
+			}
+			detailURLs[uniqueID] = link
+		} else if rule.Links.configured() {
+			result.Links = p.extractLinks(s, &rule.Links, pageURL, false)
+		} else if link != "" {
+			// 未配置详情页和下载链接时，list.link本身就是下载链接
+			if linkType, ok := p.linkType(link); ok {
+				result.Links = []model.Link{{
+					Type:     linkType,
+					URL:      link,
+					Password: util.ExtractPassword(s.Text(), link),
+				}}
+			}
+		}
+
+		results = append(results, result)
+		return true
+	})
+
+	return results, detailURLs
+}
+
+// fillDetails 并发请求详情页，补充下载链接、描述和时间
+func (p *Plugin) fillDetails(client *http.Client, results []model.SearchResult, detailURLs map[string]string) []model.SearchResult {
+	rule := p.def.Detail
+	sem := make(chan struct{}, rule.MaxConcurrency)
+	var wg sync.WaitGroup
+
+	for i := range results {
+		detailURL, ok := detailURLs[results[i].UniqueID]
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *model.SearchResult, detailURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			doc, err := p.fetchDocument(client, "GET", detailURL, "")
+			if err != nil {
+				return
+			}
+			page := doc.Selection
+
+			result.Links = p.extractLinks(page, &rule.Links, detailURL, !rule.Links.configured())
+			if content := rule.Content.first(page); content != "" {
+				result.Content = content
+			}
+			if date := rule.Date.first(page); date != "" {
+				result.Datetime = parseDate(&rule.Date, date)
+			}
+		}(&results[i], detailURL)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// extractLinks 提取下载链接
+// scanText为true时额外扫描页面文本中的网盘链接（用于未配置链接选择器的详情页）
+func (p *Plugin) extractLinks(s *goquery.Selection, field *Field, pageURL string, scanText bool) []model.Link {
+	var links []model.Link
+	seen := make(map[string]bool)
+
+	add := func(rawURL, context string) {
+		linkURL := resolveURL(pageURL, rawURL)
+		if linkURL == "" || seen[linkURL] {
+			return
+		}
+		linkType, ok := p.linkType(linkURL)
+		if !ok {
+			return
+		}
+		seen[linkURL] = true
+		links = append(links, model.Link{
+			Type:     linkType,
+			URL:      linkURL,
+			Password: util.ExtractPassword(context, linkURL),
+		})
+	}
+
+	// 默认提取所有链接的href
+	linkField := *field
+	if linkField.Selector == "" {
+		linkField.Selector = "a[href]"
+	}
+	if linkField.Attr == "" {
+		linkField.Attr = "href"
+	}
+	linkField.nodes(s).Each(func(i int, node *goquery.Selection) {
+		if value := linkField.value(node); value != "" {
+			add(value, node.Parent().Text())
+		}
+	})
+
+	if scanText {
+		text := s.Text()
+		for _, match := range util.ExtractNetDiskLinks(text) {
+			add(match, text)
+		}
+		// ExtractNetDiskLinks不含磁力和ed2k链接
+		for _, match := range util.AllPanLinksPattern.FindAllString(text, -1) {
+			add(match, text)
+		}
+	}
+
+	return links
+}
+
+// linkType 判断链接类型，无法识别的链接返回false
+func (p *Plugin) linkType(linkURL string) (string, bool) {
+	for _, rule := range p.def.LinkTypes {
+		if rule.re.MatchString(linkURL) {
+			return rule.Type, true
+		}
+	}
+	linkType := util.GetLinkType(linkURL)
+	return linkType, linkType != "others"
+}
+
+// uniqueID 生成结果ID，未配置list.id时使用链接或标题的哈希
+func (p *Plugin) uniqueID(id, link, title string) string {
+	if id == "" {
+		source := link
+		if source == "" {
+			source = title
+		}
+		id = fmt.Sprintf("%x", md5.Sum([]byte(source)))[:12]
+	}
+	return fmt.Sprintf("%s-%s", p.Name(), id)
+}
+
+// fetchDocument 请求页面并解析HTML
+func (p *Plugin) fetchDocument(client *http.Client, method, pageURL, body string) (*goquery.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.def.Search.Timeout)*time.Second)
+	defer cancel()
+
+	resp, err := p.doRequestWithRetry(ctx, client, method, pageURL, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("HTML解析失败: %w", err)
+	}
+	return doc, nil
+}
+
+// doRequestWithRetry 带重试机制的HTTP请求
+func (p *Plugin) doRequestWithRetry(ctx context.Context, client *http.Client, method, pageURL, body string) (*http.Response, error) {
+	var lastErr error
+
+	for i := 0; i < p.def.Search.MaxRetries; i++ {
+		if i > 0 {
+			// 指数退避重试
+			backoff := time.Duration(1<<uint(i-1)) * 200 * time.Millisecond
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		// 每次重试重新创建请求，POST请求体不能复用
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, pageURL, reader)
+		if err != nil {
+			return nil, fmt.Errorf("创建请求失败: %w", err)
+		}
+		p.setRequestHeaders(req)
+
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode == 200 {
+			return resp, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+			if err == nil {
+				err = fmt.Errorf("请求返回状态码: %d", resp.StatusCode)
+			}
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("重试 %d 次后仍然失败: %w", p.def.Search.MaxRetries, lastErr)
+}
+
+// setRequestHeaders 设置请求头
+func (p *Plugin) setRequestHeaders(req *http.Request) {
+	for k, v := range defaultHeaders {
+		req.Header.Set(k, v)
+	}
+	if p.def.SiteURL != "" {
+		req.Header.Set("Referer", strings.TrimRight(p.def.SiteURL, "/")+"/")
+	}
+	if req.Method == "POST" {
+		req.Header.Set("Content-Type", p.def.Search.ContentType)
+	}
+	for k, v := range p.def.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// resolveURL 将相对地址转换为绝对地址
+func resolveURL(base, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	if strings.HasPrefix(ref, "magnet:") || strings.HasPrefix(ref, "ed2k:") {
+		return ref
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// parseDate 按字段配置的格式解析时间，失败时使用当前时间
+func parseDate(field *Field, value string) time.Time {
+	if value != "" && field.Format != "" {
+		if t, err := time.ParseInLocation(field.Format, value, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// nodes 返回字段选择器匹配的节点
+func (f *Field) nodes(s *goquery.Selection) *goquery.Selection {
+	if f.Selector == "" {
+		return s
+	}
+	return s.Find(f.Selector)
+}
+
+// value 提取单个节点的值
+func (f *Field) value(node *goquery.Selection) string {
+	var v string
+	if f.Attr != "" {
+		v, _ = node.Attr(f.Attr)
+	} else {
+		v = node.Text()
+	}
+	v = strings.TrimSpace(v)
+
+	if f.re != nil && v != "" {
+		matches := f.re.FindStringSubmatch(v)
+		switch {
+		case matches == nil:
+			v = ""
+		case len(matches) > 1:
+			v = strings.TrimSpace(matches[1])
+		default:
+			v = matches[0]
+		}
+	}
+
+	if v == "" {
+		v = f.Default
+	}
+	return v
+}
+
+// first 提取第一个匹配节点的值
+func (f *Field) first(s *goquery.Selection) string {
+	if !f.configured() {
+		return ""
+	}
+	return f.value(f.nodes(s).First())
+}
+
+// all 提取所有匹配节点的非空值
+func (f *Field) all(s *goquery.Selection) []string {
+	if !f.configured() {
+		return nil
+	}
+	var values []string
+	f.nodes(s).Each(func(i int, node *goquery.Selection) {
+		if v := f.value(node); v != "" {
+			values = append(values, v)
+		}
+	})
+	return values
+}
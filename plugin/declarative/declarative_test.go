@@ -0,0 +1,55 @@
+package declarative
+
+import (
+	"strings"
+	"testing"
+
+	"pansou/plugin/plugintest"
+)
+
+func TestSearch(t *testing.T) {
+	p, err := LoadFile("testdata/demo.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugintest.Run(t, p, plugintest.Case{
+		Name:    "search",
+		Keyword: "凡人修仙传",
+	})
+}
+
+func TestParseDefinition(t *testing.T) {
+	const valid = `
+name: demo
+search:
+  url: "https://example.com/s?q={keyword}"
+list:
+  item: ".item"
+  title: "a"
+  links: "a"
+`
+	def, err := parseDefinition([]byte(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Priority != 3 || def.Search.Method != "GET" || def.Search.Timeout != 30 || def.Search.MaxRetries != 3 {
+		t.Errorf("默认值未填充: %+v", def)
+	}
+
+	cases := map[string]struct {
+		yaml    string
+		wantErr string
+	}{
+		"插件名": {strings.Replace(valid, "name: demo", "name: Demo Site", 1), "插件名无效"},
+		"分类":  {valid + "category: music\n", "未知的插件分类"},
+		"优先级": {valid + "priority: 9\n", "优先级"},
+		"占位符": {strings.Replace(valid, "{keyword}", "x", 1), "关键词占位符"},
+		"详情页": {valid + "detail:\n  content: \".intro\"\n", "list.link"},
+		"正则":  {strings.Replace(valid, `title: "a"`, `title: {selector: a, regex: "("}`, 1), "正则表达式无效"},
+	}
+	for name, c := range cases {
+		if _, err := parseDefinition([]byte(c.yaml)); err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s: 错误 = %v，应包含 %q", name, err, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package declarative
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"pansou/plugin"
+)
+
+// Definition YAML站点定义
+type Definition struct {
+	Name              string            `yaml:"name"`
+	DisplayName       string            `yaml:"display_name"`
+	Description       string            `yaml:"description"`
+	SiteURL           string            `yaml:"site_url"`
+	Category          string            `yaml:"category"`
+	Priority          int               `yaml:"priority"`            // 1-4，默认3
+	SkipServiceFilter bool              `yaml:"skip_service_filter"` // 为true时不按关键词过滤结果
+	Headers           map[string]string `yaml:"headers"`             // 额外请求头，会覆盖默认请求头
+	Search            SearchRule        `yaml:"search"`
+	List              ListRule          `yaml:"list"`
+	Detail            *DetailRule       `yaml:"detail"`     // 为空时不请求详情页
+	LinkTypes         []LinkTypeRule    `yaml:"link_types"` // 自定义链接类型映射，优先于内置识别
+}
+
+// SearchRule 搜索请求规则
+type SearchRule struct {
+	// URL 搜索地址模板，支持占位符：
+	// {keyword} URL查询参数编码的关键词，{keyword_path} 路径编码的关键词，{keyword_raw} 原始关键词
+	URL         string `yaml:"url"`
+	Method      string `yaml:"method"`       // GET或POST，默认GET
+	Body        string `yaml:"body"`         // POST请求体模板，支持与URL相同的占位符
+	ContentType string `yaml:"content_type"` // POST请求体类型，默认application/x-www-form-urlencoded
+	Timeout     int    `yaml:"timeout"`      // 单个页面请求超时（秒，含重试），默认30
+	MaxRetries  int    `yaml:"max_retries"`  // 最大重试次数，默认3
+}
+
+// ListRule 搜索结果列表解析规则
+type ListRule struct {
+	Item     string `yaml:"item"`      // 结果条目选择器
+	ID       Field  `yaml:"id"`        // 结果ID，为空时使用详情页链接的哈希
+	Title    Field  `yaml:"title"`     // 标题
+	Link     Field  `yaml:"link"`      // 详情页链接（配置detail时必填）
+	Date     Field  `yaml:"date"`      // 发布时间
+	Content  Field  `yaml:"content"`   // 描述
+	Tags     Field  `yaml:"tags"`      // 标签，选择器匹配的每个节点为一个标签
+	Image    Field  `yaml:"image"`     // 封面图片
+	Links    Field  `yaml:"links"`     // 列表页直接给出的下载链接
+	MaxItems int    `yaml:"max_items"` // 最多处理的条目数，0表示不限制
+}
+
+// DetailRule 详情页解析规则
+type DetailRule struct {
+	Links          Field `yaml:"links"`           // 下载链接，默认为所有a[href]
+	Content        Field `yaml:"content"`         // 描述，非空时覆盖列表页描述
+	Date           Field `yaml:"date"`            // 发布时间，非空时覆盖列表页时间
+	MaxConcurrency int   `yaml:"max_concurrency"` // 并发请求详情页的数量，默认5
+}
+
+// LinkTypeRule 链接类型映射规则
+type LinkTypeRule struct {
+	Pattern string `yaml:"pattern"` // 匹配链接的正则表达式
+	Type    string `yaml:"type"`    // 匹配后的链接类型
+
+	re *regexp.Regexp
+}
+
+// Field 字段提取规则
+// YAML中可以直接写选择器字符串，等价于只设置selector
+type Field struct {
+	Selector string `yaml:"selector"` // CSS选择器，为空时使用当前节点
+	Attr     string `yaml:"attr"`     // 提取的属性，为空时提取文本
+	Regex    string `yaml:"regex"`    // 对提取值进行匹配，有捕获组时取第一个捕获组
+	Format   string `yaml:"format"`   // 时间格式（Go layout），仅用于date字段
+	Default  string `yaml:"default"`  // 提取为空时的默认值
+
+	re *regexp.Regexp
+}
+
+// UnmarshalYAML 支持字符串简写形式
+func (f *Field) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		f.Selector = value.Value
+		return nil
+	}
+	type rawField Field
+	return value.Decode((*rawField)(f))
+}
+
+// configured 判断字段是否配置
+func (f *Field) configured() bool {
+	return f.Selector != "" || f.Attr != "" || f.Regex != "" || f.Default != ""
+}
+
+// compile 预编译字段的正则表达式
+func (f *Field) compile(name string) error {
+	if f.Regex == "" {
+		return nil
+	}
+	re, err := regexp.Compile(f.Regex)
+	if err != nil {
+		return fmt.Errorf("字段 %s 的正则表达式无效: %w", name, err)
+	}
+	f.re = re
+	return nil
+}
+
+// parseDefinition 解析并校验站点定义
+func parseDefinition(data []byte) (*Definition, error) {
+	var def Definition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("YAML解析失败: %w", err)
+	}
+	if err := def.validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// validate 校验定义并填充默认值
+func (d *Definition) validate() error {
+	priority, err := plugin.ValidateInfo(d.Name, d.Priority, d.Category)
+	if err != nil {
+		return err
+	}
+	d.Priority = priority
+
+	// 搜索请求
+	if d.Search.URL == "" {
+		return fmt.Errorf("缺少search.url")
+	}
+	if !strings.Contains(d.Search.URL+d.Search.Body, "{keyword") {
+		return fmt.Errorf("search.url或search.body中缺少关键词占位符")
+	}
+	d.Search.Method = strings.ToUpper(d.Search.Method)
+	switch d.Search.Method {
+	case "":
+		d.Search.Method = "GET"
+	case "GET", "POST":
+	default:
+		return fmt.Errorf("不支持的请求方法: %s", d.Search.Method)
+	}
+	if d.Search.Method == "POST" && d.Search.ContentType == "" {
+		d.Search.ContentType = "application/x-www-form-urlencoded"
+	}
+	if d.Search.Timeout <= 0 {
+		d.Search.Timeout = 30
+	}
+	if d.Search.MaxRetries <= 0 {
+		d.Search.MaxRetries = 3
+	}
+
+	// 结果列表
+	if d.List.Item == "" {
+		return fmt.Errorf("缺少list.item")
+	}
+	if !d.List.Title.configured() {
+		return fmt.Errorf("缺少list.title")
+	}
+	if d.Detail != nil && !d.List.Link.configured() {
+		return fmt.Errorf("配置detail时必须设置list.link")
+	}
+	if d.Detail == nil && !d.List.Links.configured() && !d.List.Link.configured() {
+		return fmt.Errorf("未配置detail时必须设置list.links或list.link")
+	}
+
+	fields := map[string]*Field{
+		"list.id":      &d.List.ID,
+		"list.title":   &d.List.Title,
+		"list.link":    &d.List.Link,
+		"list.date":    &d.List.Date,
+		"list.content": &d.List.Content,
+		"list.tags":    &d.List.Tags,
+		"list.image":   &d.List.Image,
+		"list.links":   &d.List.Links,
+	}
+	if d.Detail != nil {
+		if d.Detail.MaxConcurrency <= 0 {
+			d.Detail.MaxConcurrency = 5
+		}
+		fields["detail.links"] = &d.Detail.Links
+		fields["detail.content"] = &d.Detail.Content
+		fields["detail.date"] = &d.Detail.Date
+	}
+	for name, field := range fields {
+		if err := field.compile(name); err != nil {
+			return err
+		}
+	}
+
+	for i := range d.LinkTypes {
+		rule := &d.LinkTypes[i]
+		if rule.Pattern == "" || rule.Type == "" {
+			return fmt.Errorf("link_types[%d] 缺少pattern或type", i)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("link_types[%d] 的正则表达式无效: %w", i, err)
+		}
+		rule.re = re
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package declarative
+
+import (
+	"fmt"
+	"os"
+
+	"pansou/plugin"
+)
+
+// loader 声明式插件的目录加载器
+var loader = plugin.DirLoader[*Plugin]{
+	Tag:        "Declarative",
+	Kind:       "声明式插件",
+	Extensions: []string{".yaml", ".yml"},
+	Load:       LoadFile,
+}
+
+// LoadDir 加载目录下所有.yaml/.yml站点定义
+// 单个文件解析失败不影响其他文件，错误合并返回
+func LoadDir(dir string) ([]*Plugin, error) {
+	return loader.LoadDir(dir)
+}
+
+// LoadFile 加载单个站点定义文件
+func LoadFile(file string) (*Plugin, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	def, err := parseDefinition(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return NewPlugin(def, file), nil
+}
+
+// RegisterDir 加载目录中的站点定义并注册到全局插件表，返回注册的插件数量
+// 目录不存在时直接返回；与已注册插件同名的定义会被跳过，不覆盖编译插件
+func RegisterDir(dir string) int {
+	return loader.RegisterDir(dir)
+}
@@ -144,6 +144,20 @@ func NewDiscourseAsyncPlugin() *DiscourseAsyncPlugin {
 	}
 }
 
+// Metadata 返回插件描述信息
+func (p *DiscourseAsyncPlugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: "LINUX DO",
+		Description: "LINUX DO论坛资源板块",
+		SiteURL:     "https://linux.do",
+		CloudTypes:  []string{"quark", "baidu", "aliyun", "tianyi", "uc", "115"},
+		Category:    plugin.CategoryGeneral,
+		ExtParams: []plugin.PluginExtParam{
+			{Key: "max_pages", Type: "int", Description: fmt.Sprintf("最多获取的搜索结果页数，最大%d", maxAllowedPages), Default: defaultMaxPages},
+		},
+	}
+}
+
 // Search 执行搜索并返回结果（兼容性方法）
 func (p *DiscourseAsyncPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
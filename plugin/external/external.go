@@ -0,0 +1,221 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+)
+
+// 协议参数
+const (
+	describeTimeout     = 10 * time.Second // describe调用超时
+	healthCheckInterval = time.Minute      // 健康检查间隔
+	healthCheckTimeout  = 10 * time.Second // 健康检查超时
+)
+
+// errPluginStopped 插件已通过Stop停止，不能再启用
+var errPluginStopped = errors.New("插件已停止")
+
+// describeResult describe方法的返回
+type describeResult struct {
+	Name              string   `json:"name"`
+	DisplayName       string   `json:"display_name"`
+	Description       string   `json:"description"`
+	SiteURL           string   `json:"site_url"`
+	CloudTypes        []string `json:"cloud_types"`
+	Category          string   `json:"category"`
+	Priority          int      `json:"priority"`            // 1-4，默认3
+	SkipServiceFilter bool     `json:"skip_service_filter"` // 是否跳过Service层关键词过滤
+	Timeout           int      `json:"timeout"`             // 单次搜索超时（秒），默认使用PLUGIN_TIMEOUT
+}
+
+// searchParams search方法的参数
+type searchParams struct {
+	Keyword string                 `json:"keyword"`
+	Ext     map[string]interface{} `json:"ext"`
+}
+
+// searchResult search方法的返回
+type searchResult struct {
+	Results []model.SearchResult `json:"results"`
+}
+
+// Plugin 通过子进程提供搜索能力的插件
+type Plugin struct {
+	*plugin.BaseAsyncPlugin
+	info     describeResult
+	proc     *process
+	timeout  time.Duration
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// Start 启动外部插件进程并通过describe获取插件信息
+func Start(args []string) (*Plugin, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("命令为空")
+	}
+
+	proc := newProcess(args)
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+
+	var info describeResult
+	if err := proc.call(ctx, "describe", nil, &info); err != nil {
+		proc.stop()
+		return nil, fmt.Errorf("describe失败: %w", err)
+	}
+	if err := info.validate(); err != nil {
+		proc.stop()
+		return nil, err
+	}
+	proc.mu.Lock()
+	proc.label = info.Name
+	proc.mu.Unlock()
+
+	timeout := time.Duration(info.Timeout) * time.Second
+	if timeout <= 0 && config.AppConfig != nil {
+		timeout = config.AppConfig.PluginTimeout
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	p := &Plugin{
+		BaseAsyncPlugin: plugin.NewBaseAsyncPluginWithFilter(info.Name, info.Priority, info.SkipServiceFilter),
+		info:            info,
+		proc:            proc,
+		timeout:         timeout,
+		stopCh:          make(chan struct{}),
+	}
+	go p.healthLoop()
+	return p, nil
+}
+
+// validate 校验describe返回并填充默认值
+func (d *describeResult) validate() error {
+	priority, err := plugin.ValidateInfo(d.Name, d.Priority, d.Category)
+	if err != nil {
+		return err
+	}
+	d.Priority = priority
+	return nil
+}
+
+// Initialize 实现InitializablePlugin接口，启用插件时确保进程在运行
+// 未在ENABLED_PLUGINS中的插件注册后即停止进程，通过管理接口启用时在这里重新启动
+func (p *Plugin) Initialize() error {
+	return p.Resume()
+}
+
+// Metadata 返回插件描述信息
+func (p *Plugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: p.info.DisplayName,
+		Description: p.info.Description,
+		SiteURL:     p.info.SiteURL,
+		CloudTypes:  p.info.CloudTypes,
+		Category:    p.info.Category,
+	}
+}
+
+// Search 执行搜索并返回结果（兼容性方法）
+func (p *Plugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	result, err := p.SearchWithResult(keyword, ext)
+	if err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// SearchWithResult 执行搜索并返回包含IsFinal标记的结果
+func (p *Plugin) SearchWithResult(keyword string, ext map[string]interface{}) (model.PluginSearchResult, error) {
+	return p.AsyncSearchWithResult(keyword, p.searchImpl, p.MainCacheKey, ext)
+}
+
+// searchImpl 通过search方法调用外部进程，HTTP请求由外部进程自行完成
+func (p *Plugin) searchImpl(_ *http.Client, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	ctx, cancel := context.WithTimeout(plugin.ContextFromExt(ext), p.timeout)
+	defer cancel()
+
+	var result searchResult
+	params := searchParams{Keyword: keyword, Ext: plugin.PublicExt(ext)}
+	if err := p.proc.call(ctx, "search", params, &result); err != nil {
+		return nil, fmt.Errorf("[%s] %w", p.Name(), err)
+	}
+
+	prefix := p.Name() + "-"
+	now := time.Now()
+	results := result.Results[:0]
+	for _, r := range result.Results {
+		if len(r.Links) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(r.UniqueID, prefix) {
+			r.UniqueID = prefix + r.UniqueID
+		}
+		if r.Datetime.IsZero() {
+			r.Datetime = now
+		}
+		r.Channel = "" // 插件搜索结果必须为空字符串
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// Health 调用health方法检查外部进程是否正常
+func (p *Plugin) Health(ctx context.Context) error {
+	return p.proc.call(ctx, "health", nil, nil)
+}
+
+// healthLoop 定期检查外部进程，失败时结束进程，由监管逻辑退避重启
+func (p *Plugin) healthLoop() {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+			err := p.Health(ctx)
+			cancel()
+			if err != nil && err != errProcessUnavailable {
+				fmt.Printf("[External] 插件 %s 健康检查失败: %v，重启进程\n", p.Name(), err)
+				p.proc.restart()
+			}
+		}
+	}
+}
+
+// Suspend 实现SuspendablePlugin接口，插件被禁用时停止进程，健康检查在停止期间跳过
+func (p *Plugin) Suspend() {
+	p.proc.stop()
+}
+
+// Resume 实现SuspendablePlugin接口，插件被重新启用时启动进程
+func (p *Plugin) Resume() error {
+	select {
+	case <-p.stopCh:
+		return errPluginStopped
+	default:
+	}
+	return p.proc.resume()
+}
+
+// Stop 停止外部进程和健康检查，可重复调用；停止后不能再通过Resume恢复
+func (p *Plugin) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+	p.proc.stop()
+}
@@ -0,0 +1,217 @@
+package external
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+	"pansou/plugin/plugintest"
+)
+
+// 测试二进制作为外部插件进程运行时使用的环境变量
+const (
+	helperEnv     = "PANSOU_EXTERNAL_TEST_PLUGIN" // 为1时作为插件进程运行
+	helperNameEnv = "PANSOU_EXTERNAL_TEST_NAME"   // describe返回的插件名
+	helperCatEnv  = "PANSOU_EXTERNAL_TEST_CATEGORY"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) == "1" {
+		runHelperPlugin()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runHelperPlugin 最小的外部插件实现，通过HTTP_PROXY访问示例站点的JSON接口
+func runHelperPlugin() {
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64        `json:"id"`
+			Method string       `json:"method"`
+			Params searchParams `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			continue
+		}
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "describe":
+			resp["result"] = describeResult{
+				Name:        os.Getenv(helperNameEnv),
+				DisplayName: "示例外部源",
+				Category:    os.Getenv(helperCatEnv),
+			}
+		case "search":
+			results, err := helperSearch(req.Params.Keyword)
+			if err != nil {
+				resp["error"] = rpcError{Code: -32000, Message: err.Error()}
+			} else {
+				resp["result"] = searchResult{Results: results}
+			}
+		default:
+			resp["result"] = map[string]string{"status": "ok"}
+		}
+		encoder.Encode(resp)
+	}
+}
+
+// helperSearch 请求示例站点的搜索接口并转换为搜索结果
+func helperSearch(keyword string) ([]model.SearchResult, error) {
+	resp, err := http.Get("http://search.example.com/api/search?kw=" + url.QueryEscape(keyword))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求返回状态码: %d", resp.StatusCode)
+	}
+
+	var data struct {
+		Items []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+			URL   string `json:"url"`
+			Pwd   string `json:"pwd"`
+			Date  string `json:"date"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	var results []model.SearchResult
+	for _, item := range data.Items {
+		r := model.SearchResult{UniqueID: item.ID, Title: item.Title}
+		if item.URL != "" {
+			r.Links = []model.Link{{Type: "quark", URL: item.URL, Password: item.Pwd}}
+		}
+		// 没有时间的结果由PanSou使用当前时间
+		r.Datetime, _ = time.Parse(time.RFC3339, item.Date)
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// startHelper 以测试二进制作为外部插件启动，退出测试时停止进程
+func startHelper(t *testing.T, name, category string) (*Plugin, error) {
+	t.Helper()
+	t.Setenv(helperEnv, "1")
+	t.Setenv(helperNameEnv, name)
+	t.Setenv(helperCatEnv, category)
+	p, err := Start([]string{os.Args[0]})
+	if err == nil {
+		t.Cleanup(p.Stop)
+	}
+	return p, err
+}
+
+func TestSearch(t *testing.T) {
+	plugintest.RunProxied(t, func() plugin.AsyncSearchPlugin {
+		p, err := startHelper(t, "demo_ext", plugin.CategoryFilm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}, plugintest.Case{
+		Name:    "search",
+		Keyword: "凡人修仙传",
+	})
+}
+
+func TestStartValidatesDescribe(t *testing.T) {
+	p, err := startHelper(t, "demo_ext", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Priority() != plugin.DefaultPriority || p.Metadata().DisplayName != "示例外部源" {
+		t.Errorf("describe信息未生效: priority=%d metadata=%+v", p.Priority(), p.Metadata())
+	}
+
+	cases := map[string]struct {
+		name, category, wantErr string
+	}{
+		"插件名": {"Demo Ext", "", "插件名无效"},
+		"分类":  {"demo_ext", "music", "未知的插件分类"},
+	}
+	for name, c := range cases {
+		if _, err := startHelper(t, c.name, c.category); err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s: 错误 = %v，应包含 %q", name, err, c.wantErr)
+		}
+	}
+}
+
+func TestRegisterCommandsStopsDisabledPlugins(t *testing.T) {
+	saved := config.AppConfig
+	defer func() { config.AppConfig = saved }()
+	config.AppConfig = &config.Config{EnabledPlugins: []string{"labi"}}
+	t.Cleanup(StopAll)
+
+	t.Setenv(helperEnv, "1")
+	t.Setenv(helperNameEnv, "demo_ext_idle")
+	if n := RegisterCommands([]string{`"` + os.Args[0] + `"`}); n != 1 {
+		t.Fatalf("注册数量 %d，应为1", n)
+	}
+	registered, ok := plugin.GetPluginByName("demo_ext_idle")
+	if !ok {
+		t.Fatal("未启用的插件也应注册，供管理接口启用")
+	}
+	p := registered.(*Plugin)
+	if err := p.Health(context.Background()); err != errProcessUnavailable {
+		t.Errorf("未启用的插件describe后应停止进程: %v", err)
+	}
+
+	// 通过管理接口启用时重新启动进程
+	if err := p.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Health(context.Background()); err != nil {
+		t.Errorf("启用后进程应在运行: %v", err)
+	}
+}
+
+func TestDisableSuspendsProcess(t *testing.T) {
+	p, err := startHelper(t, "demo_ext_toggle", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin.RegisterGlobalPlugin(p)
+	pm := plugin.NewPluginManager()
+	if err := pm.EnablePlugin(p.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 禁用后停止进程，重新启用时再启动
+	if err := pm.DisablePlugin(p.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Health(context.Background()); err != errProcessUnavailable {
+		t.Errorf("禁用后进程应停止: %v", err)
+	}
+	if err := pm.EnablePlugin(p.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Health(context.Background()); err != nil {
+		t.Errorf("重新启用后进程应在运行: %v", err)
+	}
+
+	// Stop可重复调用，停止后不能再恢复
+	p.Stop()
+	p.Stop()
+	if err := p.Resume(); err != errPluginStopped {
+		t.Errorf("Stop后恢复应失败: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package external
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"pansou/config"
+	"pansou/plugin"
+)
+
+// 已启动的外部插件，用于退出时统一停止
+var (
+	startedMu sync.Mutex
+	started   []*Plugin
+)
+
+// RegisterCommands 启动配置的外部插件命令并注册到全局插件表，返回注册的插件数量
+// 启动或describe失败的命令会被跳过；与已注册插件同名的外部插件不会覆盖原插件；
+// 不在ENABLED_PLUGINS中的插件describe后停止进程，只保留注册信息供管理接口启用
+func RegisterCommands(commands []string) int {
+	enabled := make(map[string]bool)
+	if config.AppConfig != nil {
+		for _, name := range config.AppConfig.EnabledPlugins {
+			enabled[name] = true
+		}
+	}
+
+	count, idle := 0, 0
+	for _, command := range commands {
+		args, err := splitCommand(command)
+		if err != nil || len(args) == 0 {
+			fmt.Printf("[External] 无效的插件命令 %q: %v\n", command, err)
+			continue
+		}
+
+		p, err := Start(args)
+		if err != nil {
+			fmt.Printf("[External] 插件命令 %q 启动失败: %v\n", command, err)
+			continue
+		}
+		if _, exists := plugin.GetPluginByName(p.Name()); exists {
+			fmt.Printf("[External] 插件 %s 已存在，跳过命令 %q\n", p.Name(), command)
+			p.Stop()
+			continue
+		}
+
+		plugin.RegisterGlobalPlugin(p)
+		startedMu.Lock()
+		started = append(started, p)
+		startedMu.Unlock()
+		count++
+
+		if !enabled[p.Name()] {
+			p.proc.stop()
+			idle++
+		}
+	}
+
+	if count > 0 {
+		fmt.Printf("[External] 已注册 %d 个外部插件，其中 %d 个未启用，进程已停止\n", count, idle)
+	}
+	return count
+}
+
+// StopAll 停止所有外部插件进程
+func StopAll() {
+	startedMu.Lock()
+	plugins := started
+	started = nil
+	startedMu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range plugins {
+		wg.Add(1)
+		go func(p *Plugin) {
+			defer wg.Done()
+			p.Stop()
+		}(p)
+	}
+	wg.Wait()
+}
+
+// splitCommand 按空白拆分命令行，支持单引号和双引号
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("引号不匹配")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,302 @@
+package external
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	jsonutil "pansou/util/json"
+)
+
+// 进程监管参数
+const (
+	minRestartBackoff = time.Second     // 首次重启等待时间
+	maxRestartBackoff = time.Minute     // 重启等待时间上限
+	stableRunDuration = time.Minute     // 进程持续运行超过该时间后重置退避
+	maxMessageSize    = 16 << 20        // 单行消息大小上限
+	stopGracePeriod   = 3 * time.Second // 关闭stdin后等待进程退出的时间
+)
+
+// errProcessUnavailable 进程未运行且处于重启退避期
+var errProcessUnavailable = errors.New("插件进程不可用")
+
+// rpcRequest JSON-RPC请求
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// rpcResponse JSON-RPC响应
+type rpcResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// rpcError JSON-RPC错误
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error 实现error接口
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("插件返回错误(%d): %s", e.Code, e.Message)
+}
+
+// process 外部插件进程，负责启动、按ID复用请求和退避重启
+type process struct {
+	args  []string
+	label string // 日志标识，describe成功后为插件名
+
+	mu        sync.Mutex
+	stdin     io.WriteCloser
+	cmd       *exec.Cmd
+	running   bool
+	stopped   bool
+	startedAt time.Time
+	retryAt   time.Time // 退避期结束时间
+	backoff   time.Duration
+	restarts  int
+	nextID    int64
+	pending   map[int64]chan rpcResponse
+
+	writeMu sync.Mutex
+}
+
+// newProcess 创建进程（不立即启动）
+func newProcess(args []string) *process {
+	return &process{
+		args:    args,
+		label:   args[0],
+		backoff: minRestartBackoff,
+		pending: make(map[int64]chan rpcResponse),
+	}
+}
+
+// ensureRunning 确保进程在运行，退避期内返回errProcessUnavailable（调用方需持有p.mu）
+func (p *process) ensureRunning() error {
+	if p.stopped {
+		return errProcessUnavailable
+	}
+	if p.running {
+		return nil
+	}
+	if time.Now().Before(p.retryAt) {
+		return errProcessUnavailable
+	}
+
+	cmd := exec.Command(p.args[0], p.args[1:]...)
+	cmd.Env = os.Environ()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		p.scheduleRestart()
+		return fmt.Errorf("启动插件进程失败: %w", err)
+	}
+
+	p.cmd = cmd
+	p.stdin = stdin
+	p.running = true
+	p.startedAt = time.Now()
+	if p.restarts > 0 {
+		fmt.Printf("[External] 插件进程 %s 已重启（第%d次）\n", p.label, p.restarts)
+	}
+
+	go p.readLoop(cmd, stdout)
+	go p.logStderr(stderr)
+	return nil
+}
+
+// scheduleRestart 进入退避期，退避时间指数增长（调用方需持有p.mu）
+func (p *process) scheduleRestart() {
+	if !p.startedAt.IsZero() && time.Since(p.startedAt) >= stableRunDuration {
+		p.backoff = minRestartBackoff
+	}
+	p.retryAt = time.Now().Add(p.backoff)
+	p.backoff *= 2
+	if p.backoff > maxRestartBackoff {
+		p.backoff = maxRestartBackoff
+	}
+	p.restarts++
+}
+
+// readLoop 读取进程输出并分发响应，进程退出后让所有等待中的请求失败
+func (p *process) readLoop(cmd *exec.Cmd, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var resp rpcResponse
+		if err := jsonutil.Unmarshal(line, &resp); err != nil {
+			fmt.Printf("[External] 插件 %s 输出无法解析: %s\n", p.label, truncate(string(line), 200))
+			continue
+		}
+
+		p.mu.Lock()
+		ch, ok := p.pending[resp.ID]
+		delete(p.pending, resp.ID)
+		p.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+
+	err := cmd.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd != cmd {
+		return
+	}
+	p.running = false
+	p.cmd = nil
+	for id, ch := range p.pending {
+		ch <- rpcResponse{ID: id, Error: &rpcError{Code: -32000, Message: "插件进程已退出"}}
+		delete(p.pending, id)
+	}
+	if p.stopped {
+		return
+	}
+	p.scheduleRestart()
+	fmt.Printf("[External] 插件进程 %s 退出: %v，%v 后重启\n", p.label, err, time.Until(p.retryAt).Round(time.Second))
+}
+
+// logStderr 将进程的标准错误输出转发到日志
+func (p *process) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		fmt.Printf("[External:%s] %s\n", p.label, scanner.Text())
+	}
+}
+
+// call 发送请求并等待响应
+func (p *process) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	p.mu.Lock()
+	if err := p.ensureRunning(); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.nextID++
+	id := p.nextID
+	ch := make(chan rpcResponse, 1)
+	p.pending[id] = ch
+	stdin := p.stdin
+	p.mu.Unlock()
+
+	data, err := jsonutil.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		p.removePending(id)
+		return err
+	}
+
+	p.writeMu.Lock()
+	_, err = stdin.Write(append(data, '\n'))
+	p.writeMu.Unlock()
+	if err != nil {
+		p.removePending(id)
+		return fmt.Errorf("写入插件进程失败: %w", err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return jsonutil.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		p.removePending(id)
+		return ctx.Err()
+	}
+}
+
+// removePending 移除等待中的请求
+func (p *process) removePending(id int64) {
+	p.mu.Lock()
+	delete(p.pending, id)
+	p.mu.Unlock()
+}
+
+// restart 结束当前进程，由readLoop按退避策略重启
+func (p *process) restart() {
+	p.mu.Lock()
+	cmd := p.cmd
+	p.mu.Unlock()
+	if cmd != nil && cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+}
+
+// resume 允许已停止的进程重新运行并立即启动
+func (p *process) resume() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		p.stopped = false
+		p.retryAt = time.Time{}
+		p.backoff = minRestartBackoff
+	}
+	return p.ensureRunning()
+}
+
+// stop 关闭进程且不再重启
+func (p *process) stop() {
+	p.mu.Lock()
+	p.stopped = true
+	cmd, stdin := p.cmd, p.stdin
+	p.mu.Unlock()
+
+	if cmd == nil {
+		return
+	}
+	// 先关闭stdin让进程自行退出，超时后强制结束
+	stdin.Close()
+	timer := time.AfterFunc(stopGracePeriod, func() {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+	})
+	defer timer.Stop()
+	for i := 0; i < int(stopGracePeriod/(50*time.Millisecond)); i++ {
+		p.mu.Lock()
+		running := p.cmd == cmd
+		p.mu.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// truncate 截断过长的日志内容
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
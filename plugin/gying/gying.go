@@ -478,6 +478,17 @@ func (p *GyingPlugin) RegisterWebRoutes(router *gin.RouterGroup) {
 	fmt.Printf("[Gying] Web路由已注册: /gying/:param\n")
 }
 
+// Metadata 返回插件描述信息
+func (p *GyingPlugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName:   "观影",
+		Description:   "观影网站影视资源，需在 /gying/ 管理页面登录账号后才能搜索",
+		SiteURL:       "https://www.gying.net",
+		Category:      plugin.CategoryFilm,
+		RequiresLogin: true,
+	}
+}
+
 // Search 执行搜索并返回结果
 func (p *GyingPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
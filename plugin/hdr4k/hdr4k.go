@@ -106,6 +106,19 @@ func NewHdr4kAsyncPlugin() *Hdr4kAsyncPlugin {
 	}
 }
 
+// Metadata 返回插件描述信息
+func (p *Hdr4kAsyncPlugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: "4KHDR",
+		Description: "4K高清影视资源论坛",
+		SiteURL:     "https://www.4khdr.cn",
+		Category:    plugin.CategoryFilm,
+		ExtParams: []plugin.PluginExtParam{
+			{Key: "title_en", Type: "string", Description: "英文标题，存在时替代关键词进行搜索"},
+		},
+	}
+}
+
 // Search 执行搜索并返回结果（兼容性方法）
 func (p *Hdr4kAsyncPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
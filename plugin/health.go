@@ -0,0 +1,294 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"pansou/config"
+)
+
+// SearchOutcome 单次插件搜索的结果类型
+type SearchOutcome int
+
+const (
+	OutcomeSuccess SearchOutcome = iota // 返回了结果
+	OutcomeEmpty                        // 正常返回但没有结果
+	OutcomeError                        // 返回错误
+	OutcomeTimeout                      // 响应超时
+)
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"    // 正常
+	CircuitOpen     = "open"      // 熔断中，跳过该插件
+	CircuitHalfOpen = "half_open" // 熔断到期，正在放行探测请求
+)
+
+// 延迟采样窗口大小
+const latencySampleSize = 128
+
+// PluginHealthStatus 插件健康状态快照
+type PluginHealthStatus struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	Successes           int64      `json:"successes"`
+	Empties             int64      `json:"empties"`
+	Errors              int64      `json:"errors"`
+	Timeouts            int64      `json:"timeouts"`
+	Skipped             int64      `json:"skipped"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LatencyP50Ms        int64      `json:"latency_p50_ms"`
+	LatencyP90Ms        int64      `json:"latency_p90_ms"`
+	LatencyP99Ms        int64      `json:"latency_p99_ms"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+// pluginHealth 单个插件的健康统计和熔断状态
+type pluginHealth struct {
+	successes           int64
+	empties             int64
+	errors              int64
+	timeouts            int64
+	skipped             int64
+	consecutiveFailures int
+	state               string
+	openedAt            time.Time
+	probing             bool // 半开状态下是否已有探测请求在进行
+	lastError           string
+	lastErrorAt         time.Time
+	latencies           [latencySampleSize]time.Duration
+	latencyCount        int
+}
+
+// healthTracker 记录所有插件的健康状态
+type healthTracker struct {
+	mu      sync.Mutex
+	plugins map[string]*pluginHealth
+}
+
+// newHealthTracker 创建健康状态记录器
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		plugins: make(map[string]*pluginHealth),
+	}
+}
+
+// get 获取插件的健康记录，不存在时创建（调用方需持有锁）
+func (t *healthTracker) get(name string) *pluginHealth {
+	h, ok := t.plugins[name]
+	if !ok {
+		h = &pluginHealth{state: CircuitClosed}
+		t.plugins[name] = h
+	}
+	return h
+}
+
+// circuitSettings 返回熔断阈值和熔断持续时间
+func circuitSettings() (int, time.Duration) {
+	threshold := 5
+	openDuration := 5 * time.Minute
+	if config.AppConfig != nil {
+		threshold = config.AppConfig.PluginCircuitFailureThreshold
+		openDuration = config.AppConfig.PluginCircuitOpenDuration
+	}
+	return threshold, openDuration
+}
+
+// AllowRequest 判断插件当前是否可以被调用
+// 熔断到期后只放行一个探测请求，其余请求继续跳过，直到探测结果返回
+func (pm *PluginManager) AllowRequest(name string) bool {
+	t := pm.health
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.get(name)
+	switch h.state {
+	case CircuitOpen:
+		_, openDuration := circuitSettings()
+		if time.Since(h.openedAt) < openDuration {
+			h.skipped++
+			return false
+		}
+		h.state = CircuitHalfOpen
+		h.probing = true
+		return true
+	case CircuitHalfOpen:
+		if h.probing {
+			h.skipped++
+			return false
+		}
+		h.probing = true
+		return true
+	}
+	return true
+}
+
+// IsAvailable 判断插件是否可用，不占用探测名额
+func (pm *PluginManager) IsAvailable(name string) bool {
+	t := pm.health
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.plugins[name]
+	if !ok {
+		return true
+	}
+	switch h.state {
+	case CircuitOpen:
+		_, openDuration := circuitSettings()
+		return time.Since(h.openedAt) >= openDuration
+	case CircuitHalfOpen:
+		return !h.probing
+	}
+	return true
+}
+
+// RecordOutcome 记录一次插件搜索的结果和耗时
+func (pm *PluginManager) RecordOutcome(name string, outcome SearchOutcome, latency time.Duration, err error) {
+	t := pm.health
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.get(name)
+	h.latencies[h.latencyCount%latencySampleSize] = latency
+	h.latencyCount++
+
+	switch outcome {
+	case OutcomeSuccess:
+		h.successes++
+	case OutcomeEmpty:
+		h.empties++
+	case OutcomeError:
+		h.errors++
+		if err != nil {
+			h.lastError = err.Error()
+		}
+		h.lastErrorAt = time.Now()
+	case OutcomeTimeout:
+		h.timeouts++
+		h.lastError = "响应超时"
+		h.lastErrorAt = time.Now()
+	}
+
+	// 空结果说明站点可用，与成功一样关闭熔断
+	if outcome == OutcomeSuccess || outcome == OutcomeEmpty {
+		if h.state != CircuitClosed {
+			fmt.Printf("[PluginManager] 插件 %s 探测成功，恢复调用\n", name)
+		}
+		h.consecutiveFailures = 0
+		h.state = CircuitClosed
+		h.probing = false
+		return
+	}
+
+	h.consecutiveFailures++
+	threshold, openDuration := circuitSettings()
+
+	// 探测失败，重新熔断
+	if h.state == CircuitHalfOpen {
+		h.state = CircuitOpen
+		h.openedAt = time.Now()
+		h.probing = false
+		return
+	}
+
+	if threshold > 0 && h.state == CircuitClosed && h.consecutiveFailures >= threshold {
+		h.state = CircuitOpen
+		h.openedAt = time.Now()
+		fmt.Printf("[PluginManager] 插件 %s 连续失败 %d 次，熔断 %v\n", name, h.consecutiveFailures, openDuration)
+	}
+}
+
+// AbandonRequest 放弃一次已放行的调用（如客户端断开），不计入统计
+// 若该调用是半开探测请求，则归还探测名额
+func (pm *PluginManager) AbandonRequest(name string) {
+	t := pm.health
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if h, ok := t.plugins[name]; ok && h.state == CircuitHalfOpen {
+		h.probing = false
+	}
+}
+
+// ResetCircuit 手动关闭插件熔断并清零连续失败次数
+func (pm *PluginManager) ResetCircuit(name string) bool {
+	t := pm.health
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.plugins[name]
+	if !ok {
+		return false
+	}
+	h.state = CircuitClosed
+	h.consecutiveFailures = 0
+	h.probing = false
+	return true
+}
+
+// HealthStatus 返回指定插件的健康状态快照
+func (pm *PluginManager) HealthStatus(names []string) []PluginHealthStatus {
+	t := pm.health
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, openDuration := circuitSettings()
+	statuses := make([]PluginHealthStatus, 0, len(names))
+	for _, name := range names {
+		h := t.get(name)
+		status := PluginHealthStatus{
+			Name:                name,
+			State:               h.state,
+			Successes:           h.successes,
+			Empties:             h.empties,
+			Errors:              h.errors,
+			Timeouts:            h.timeouts,
+			Skipped:             h.skipped,
+			ConsecutiveFailures: h.consecutiveFailures,
+			LastError:           h.lastError,
+		}
+		if !h.lastErrorAt.IsZero() {
+			lastErrorAt := h.lastErrorAt
+			status.LastErrorAt = &lastErrorAt
+		}
+		if h.state != CircuitClosed {
+			openedAt := h.openedAt
+			retryAt := h.openedAt.Add(openDuration)
+			status.OpenedAt = &openedAt
+			status.RetryAt = &retryAt
+		}
+		status.LatencyP50Ms, status.LatencyP90Ms, status.LatencyP99Ms = h.latencyPercentiles()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// latencyPercentiles 计算采样窗口内的P50/P90/P99延迟（毫秒）
+func (h *pluginHealth) latencyPercentiles() (int64, int64, int64) {
+	n := h.latencyCount
+	if n == 0 {
+		return 0, 0, 0
+	}
+	if n > latencySampleSize {
+		n = latencySampleSize
+	}
+
+	samples := make([]time.Duration, n)
+	copy(samples, h.latencies[:n])
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	percentile := func(p int) int64 {
+		idx := (n*p + 99) / 100
+		if idx > 0 {
+			idx--
+		}
+		return samples[idx].Milliseconds()
+	}
+	return percentile(50), percentile(90), percentile(99)
+}
@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+)
+
+// TestMain 使用较短的超时和熔断参数，插件状态文件写入临时目录
+// 后台搜索的协程在测试结束后仍可能读取配置，因此整个包只设置一次，不在单个测试中替换
+func TestMain(m *testing.M) {
+	cachePath, err := os.MkdirTemp("", "pansou-plugin-test")
+	if err != nil {
+		panic(err)
+	}
+	config.AppConfig = &config.Config{
+		CachePath:                     cachePath,
+		AsyncResponseTimeoutDur:       30 * time.Millisecond,
+		PluginTimeout:                 time.Second,
+		AsyncMaxBackgroundWorkers:     4,
+		AsyncMaxBackgroundTasks:       8,
+		PluginCircuitFailureThreshold: 2,
+		PluginCircuitOpenDuration:     50 * time.Millisecond,
+	}
+	code := m.Run()
+	os.RemoveAll(cachePath)
+	os.Exit(code)
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	pm := NewPluginManager()
+	state := func() string { return pm.HealthStatus([]string{"flaky"})[0].State }
+
+	// 空结果说明站点可用，清零连续失败次数
+	pm.RecordOutcome("flaky", OutcomeError, time.Millisecond, errors.New("boom"))
+	pm.RecordOutcome("flaky", OutcomeEmpty, time.Millisecond, nil)
+	pm.RecordOutcome("flaky", OutcomeTimeout, time.Millisecond, nil)
+	if state() != CircuitClosed {
+		t.Fatalf("未连续失败到阈值时应保持关闭: %s", state())
+	}
+
+	// Closed -> Open
+	pm.RecordOutcome("flaky", OutcomeError, time.Millisecond, errors.New("boom"))
+	if state() != CircuitOpen || pm.AllowRequest("flaky") {
+		t.Fatalf("连续失败达到阈值应熔断: %s", state())
+	}
+
+	// Open -> HalfOpen：到期后只放行一个探测请求
+	time.Sleep(60 * time.Millisecond)
+	if !pm.AllowRequest("flaky") || state() != CircuitHalfOpen {
+		t.Fatalf("熔断到期应放行探测请求: %s", state())
+	}
+	if pm.AllowRequest("flaky") {
+		t.Error("探测进行中不应放行其他请求")
+	}
+
+	// HalfOpen -> Open：探测失败重新熔断
+	pm.RecordOutcome("flaky", OutcomeTimeout, time.Millisecond, nil)
+	if state() != CircuitOpen {
+		t.Fatalf("探测失败应重新熔断: %s", state())
+	}
+
+	// HalfOpen -> Closed：探测成功恢复
+	time.Sleep(60 * time.Millisecond)
+	if !pm.AllowRequest("flaky") {
+		t.Fatal("熔断到期应放行探测请求")
+	}
+	pm.RecordOutcome("flaky", OutcomeSuccess, time.Millisecond, nil)
+	if h := pm.HealthStatus([]string{"flaky"})[0]; h.State != CircuitClosed || h.ConsecutiveFailures != 0 || h.Skipped != 2 {
+		t.Errorf("探测成功应恢复: %+v", h)
+	}
+}
+
+func TestNestedAsyncTimeoutReportsBackgroundOutcome(t *testing.T) {
+	pm := NewPluginManager()
+	p := NewBaseAsyncPlugin("nested", 3)
+
+	// 上游无响应，直到后台客户端超时才返回错误
+	hung := func(client *http.Client, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+		time.Sleep(80 * time.Millisecond)
+		return nil, errors.New("upstream timeout")
+	}
+	// 与大多数插件一样，Search内部再通过AsyncSearchWithResult搜索
+	search := func(client *http.Client, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+		result, err := p.AsyncSearchWithResult(keyword, hung, "", ext)
+		return result.Results, err
+	}
+
+	var mu sync.Mutex
+	var reports []error
+	for i := 0; i < 2; i++ {
+		if !pm.AllowRequest("nested") {
+			t.Fatalf("第%d次调用前不应熔断", i+1)
+		}
+		report := NewOutcomeReport(func(results []model.SearchResult, err error) {
+			mu.Lock()
+			reports = append(reports, err)
+			mu.Unlock()
+			outcome := OutcomeEmpty
+			if err != nil {
+				outcome = OutcomeError
+			}
+			pm.RecordOutcome("nested", outcome, 0, err)
+		})
+		ext := WithContext(WithOutcomeReport(context.Background(), report), nil)
+		results, err := p.AsyncSearch(fmt.Sprintf("hung-%d", i), search, "", ext)
+		if err != nil || len(results) != 0 || !report.Deferred() {
+			t.Fatalf("响应超时应返回空结果并转入后台: results=%d err=%v deferred=%v", len(results), err, report.Deferred())
+		}
+	}
+
+	// 外层收到的是内层超时返回的空结果，不应当作空结果报告；后台完成时报告真实的错误
+	time.Sleep(300 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 2 {
+		t.Fatalf("每次调用应只报告一次，实际 %d 次: %v", len(reports), reports)
+	}
+	for _, err := range reports {
+		if err == nil {
+			t.Error("报告的应是后台完成时的错误，而不是超时返回的空结果")
+		}
+	}
+	if state := pm.HealthStatus([]string{"nested"})[0].State; state != CircuitOpen {
+		t.Errorf("上游持续无响应应熔断: %s", state)
+	}
+}
@@ -35,6 +35,19 @@ func NewJikepanAsyncV2Plugin() *JikepanAsyncV2Plugin {
 	}
 }
 
+// Metadata 返回插件描述信息
+func (p *JikepanAsyncV2Plugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: "即刻盘",
+		Description: "即刻盘网盘搜索",
+		SiteURL:     "https://jikepan.xyz",
+		Category:    plugin.CategoryGeneral,
+		ExtParams: []plugin.PluginExtParam{
+			{Key: "is_all", Type: "bool", Description: "全量搜索，耗时更长", Default: false},
+		},
+	}
+}
+
 // Search 执行搜索并返回结果（兼容性方法）
 func (p *JikepanAsyncV2Plugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultPriority 声明式、脚本和外部插件未声明优先级时使用的优先级
+const DefaultPriority = 3
+
+// 插件名只允许小写字母、数字、下划线和连字符，与编译插件保持一致
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// ValidateInfo 校验声明式、脚本和外部插件自行声明的插件名、优先级和分类
+// 返回填充默认值后的优先级
+func ValidateInfo(name string, priority int, category string) (int, error) {
+	if !pluginNamePattern.MatchString(name) {
+		return priority, fmt.Errorf("插件名无效: %q", name)
+	}
+	if priority == 0 {
+		priority = DefaultPriority
+	}
+	if priority < MinPluginPriority || priority > MaxPluginPriority {
+		return priority, fmt.Errorf("优先级必须在%d-%d之间", MinPluginPriority, MaxPluginPriority)
+	}
+	switch category {
+	case "", CategoryGeneral, CategoryFilm, CategoryAnime, CategoryGame, CategoryMagnet, CategoryAdult:
+	default:
+		return priority, fmt.Errorf("未知的插件分类: %s", category)
+	}
+	return priority, nil
+}
+
+// FilePlugin 从文件加载的插件
+type FilePlugin interface {
+	AsyncSearchPlugin
+	File() string // 插件文件路径
+}
+
+// DirLoader 从目录加载插件文件，声明式插件和脚本插件共用
+type DirLoader[P FilePlugin] struct {
+	Tag        string                       // 日志前缀
+	Kind       string                       // 插件种类，用于日志
+	Extensions []string                     // 加载的文件扩展名，小写且包含"."
+	Load       func(file string) (P, error) // 加载单个文件
+}
+
+// LoadDir 按文件名顺序加载目录下扩展名匹配的所有文件
+// 单个文件加载失败或插件名重复不影响其他文件，错误合并返回
+func (l DirLoader[P]) LoadDir(dir string) ([]P, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && l.matches(entry.Name()) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	var plugins []P
+	var errs []string
+	names := make(map[string]string)
+	for _, file := range files {
+		p, err := l.Load(file)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if prev, exists := names[p.Name()]; exists {
+			errs = append(errs, fmt.Sprintf("%s: 插件名 %s 与 %s 重复", file, p.Name(), prev))
+			continue
+		}
+		names[p.Name()] = file
+		plugins = append(plugins, p)
+	}
+
+	if len(errs) > 0 {
+		return plugins, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return plugins, nil
+}
+
+// RegisterDir 加载目录中的插件文件并注册到全局插件表，返回注册的插件数量
+// 目录不存在时直接返回；与已注册插件同名的插件会被跳过，不覆盖编译插件
+func (l DirLoader[P]) RegisterDir(dir string) int {
+	if dir == "" {
+		return 0
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return 0
+	}
+
+	plugins, err := l.LoadDir(dir)
+	if err != nil {
+		fmt.Printf("[%s] 加载%s出错: %v\n", l.Tag, l.Kind, err)
+	}
+
+	count := 0
+	for _, p := range plugins {
+		if _, exists := GetPluginByName(p.Name()); exists {
+			fmt.Printf("[%s] 插件 %s 已存在，跳过 %s\n", l.Tag, p.Name(), p.File())
+			continue
+		}
+		RegisterGlobalPlugin(p)
+		count++
+	}
+
+	if count > 0 {
+		fmt.Printf("[%s] 已从 %s 加载 %d 个%s\n", l.Tag, dir, count, l.Kind)
+	}
+	return count
+}
+
+// matches 判断文件扩展名是否需要加载
+func (l DirLoader[P]) matches(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, want := range l.Extensions {
+		if ext == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pansou/model"
+)
+
+// filePlugin 测试用的文件插件，文件内容为插件名
+type filePlugin struct {
+	*BaseAsyncPlugin
+	file string
+}
+
+func (p *filePlugin) File() string {
+	return p.file
+}
+
+func (p *filePlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	return nil, nil
+}
+
+func loadFilePlugin(file string) (*filePlugin, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(string(data))
+	if _, err := ValidateInfo(name, 0, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &filePlugin{BaseAsyncPlugin: NewBaseAsyncPlugin(name, DefaultPriority), file: file}, nil
+}
+
+func TestDirLoader(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.def":      "loadertest-b",
+		"a.DEF":      "loadertest-a",
+		"c.def":      "loadertest-a", // 与a.DEF重名
+		"d.def":      "Bad Name",
+		"ignore.txt": "loadertest-x",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Mkdir(filepath.Join(dir, "sub.def"), 0755)
+
+	loader := DirLoader[*filePlugin]{Tag: "Test", Kind: "测试插件", Extensions: []string{".def"}, Load: loadFilePlugin}
+	plugins, err := loader.LoadDir(dir)
+	if len(plugins) != 2 || plugins[0].Name() != "loadertest-a" || plugins[1].Name() != "loadertest-b" {
+		t.Fatalf("应按文件名顺序加载扩展名匹配的文件: %+v", plugins)
+	}
+	if err == nil || !strings.Contains(err.Error(), "重复") || !strings.Contains(err.Error(), "插件名无效") {
+		t.Errorf("重名和无效文件的错误应合并返回: %v", err)
+	}
+
+	// 已注册的同名插件不被覆盖
+	existing := &filePlugin{BaseAsyncPlugin: NewBaseAsyncPlugin("loadertest-b", 1)}
+	RegisterGlobalPlugin(existing)
+	if n := loader.RegisterDir(dir); n != 1 {
+		t.Errorf("注册数量 %d，应为1", n)
+	}
+	if p, _ := GetPluginByName("loadertest-b"); p != AsyncSearchPlugin(existing) {
+		t.Error("同名插件不应覆盖已注册的插件")
+	}
+	if _, ok := GetPluginByName("loadertest-a"); !ok {
+		t.Error("插件应注册到全局插件表")
+	}
+
+	if n := loader.RegisterDir(filepath.Join(dir, "missing")); n != 0 {
+		t.Errorf("目录不存在时应返回0: %d", n)
+	}
+}
+
+func TestValidateInfo(t *testing.T) {
+	cases := []struct {
+		name     string
+		priority int
+		category string
+		want     int
+		wantErr  string
+	}{
+		{"demo_site-2", 0, "", DefaultPriority, ""},
+		{"demo", 1, CategoryMagnet, 1, ""},
+		{"Demo", 0, "", 0, "插件名无效"},
+		{"", 0, "", 0, "插件名无效"},
+		{"demo", 5, "", 0, "优先级"},
+		{"demo", 0, "music", 0, "未知的插件分类"},
+	}
+	for _, c := range cases {
+		priority, err := ValidateInfo(c.name, c.priority, c.category)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("ValidateInfo(%q, %d, %q) 错误 = %v，应包含 %q", c.name, c.priority, c.category, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil || priority != c.want {
+			t.Errorf("ValidateInfo(%q, %d, %q) = %d, %v", c.name, c.priority, c.category, priority, err)
+		}
+	}
+}
@@ -70,6 +70,30 @@ func NewNyaaPlugin() *NyaaPlugin {
 	}
 }
 
+// Metadata 返回插件描述信息
+func (p *NyaaPlugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: "Nyaa",
+		Description: "Nyaa动漫BT资源站，返回磁力链接",
+		SiteURL:     SiteURL,
+		CloudTypes:  []string{"magnet"},
+		Category:    plugin.CategoryAnime,
+		ExtParams: []plugin.PluginExtParam{
+			{Key: "title_en", Type: "string", Description: "英文标题，存在时替代关键词进行搜索"},
+		},
+	}
+}
+
+// CacheTTLHint 建议缓存有效期：新番资源按集持续发布，结果变化快，缓存15分钟
+func (p *NyaaPlugin) CacheTTLHint(keyword string, results []model.SearchResult) time.Duration {
+	return 15 * time.Minute
+}
+
+// SearchScriptVariants 字幕组标题繁简混用且站点按字面匹配，需分别搜索繁简写法
+func (p *NyaaPlugin) SearchScriptVariants() bool {
+	return true
+}
+
 // Search 执行搜索并返回结果（兼容性方法）
 func (p *NyaaPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
@@ -93,6 +93,19 @@ func NewPiankuPlugin() *PiankuPlugin {
 	}
 }
 
+// Metadata 返回插件描述信息
+func (p *PiankuPlugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: "片库",
+		Description: "片库影视资源站",
+		SiteURL:     "https://btnull.pro",
+		Category:    plugin.CategoryFilm,
+		ExtParams: []plugin.PluginExtParam{
+			{Key: "title_en", Type: "string", Description: "英文标题，存在时替代关键词进行搜索"},
+		},
+	}
+}
+
 // Search 执行搜索并返回结果（兼容性方法）
 func (p *PiankuPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
@@ -0,0 +1,14 @@
+package pianku
+
+import (
+	"testing"
+
+	"pansou/plugin/plugintest"
+)
+
+func TestSearch(t *testing.T) {
+	plugintest.Run(t, NewPiankuPlugin(), plugintest.Case{
+		Name:    "search",
+		Keyword: "凡人修仙传",
+	})
+}
@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	"pansou/config"
 	"pansou/model"
+	"pansou/util/query"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,9 +32,10 @@ type AsyncSearchPlugin interface {
 	AsyncSearch(keyword string, searchFunc func(*http.Client, string, map[string]interface{}) ([]model.SearchResult, error), mainCacheKey string, ext map[string]interface{}) ([]model.SearchResult, error)
 
 	// SetMainCacheKey 设置主缓存键
+	// 插件实例在并发搜索间共享，服务层改为通过ext的MainCacheKeyExtKey按调用传递，保留此方法以兼容现有插件
 	SetMainCacheKey(key string)
 
-	// SetCurrentKeyword 设置当前搜索关键词（用于日志显示）
+	// SetCurrentKeyword 设置当前搜索关键词（用于日志显示，兼容保留）
 	SetCurrentKeyword(keyword string)
 
 	// Search 兼容性方法（内部调用AsyncSearch）
@@ -43,6 +46,16 @@ type AsyncSearchPlugin interface {
 	SkipServiceFilter() bool
 }
 
+// ContextAsyncSearchPlugin 支持上下文取消的插件接口
+// 未实现此接口的插件可通过AsContextPlugin适配，上下文经ext传递给BaseAsyncPlugin
+type ContextAsyncSearchPlugin interface {
+	AsyncSearchPlugin // 继承搜索插件接口
+
+	// SearchWithContext 带上下文的搜索方法
+	// ctx取消（客户端断开等）时应尽快中止未完成的请求
+	SearchWithContext(ctx context.Context, keyword string, ext map[string]interface{}) ([]model.SearchResult, error)
+}
+
 // PluginWithWebHandler 支持Web路由的插件接口
 // 插件可以选择实现此接口来注册自定义的HTTP路由
 type PluginWithWebHandler interface {
@@ -63,6 +76,60 @@ type InitializablePlugin interface {
 	Initialize() error
 }
 
+// SuspendablePlugin 禁用时可以暂停后台资源的插件接口
+// 外部插件等持有进程或长连接的插件实现此接口，运行时禁用时释放资源，重新启用时恢复
+type SuspendablePlugin interface {
+	AsyncSearchPlugin // 继承搜索插件接口
+
+	// Suspend 插件被禁用时调用，停止后台进程等资源
+	Suspend()
+
+	// Resume 插件被重新启用时调用，恢复Suspend停止的资源
+	Resume() error
+}
+
+// MetadataProvider 支持自我描述的插件接口
+// 插件可以实现此接口，向前端提供展示名称、站点、支持的网盘类型和ext参数等信息
+type MetadataProvider interface {
+	AsyncSearchPlugin // 继承搜索插件接口
+
+	// Metadata 返回插件描述信息
+	// Name、Priority、SkipServiceFilter 以插件自身方法为准，无需填写
+	Metadata() PluginMetadata
+}
+
+// 插件内容分类
+const (
+	CategoryGeneral = "general" // 综合网盘资源
+	CategoryFilm    = "film"    // 影视
+	CategoryAnime   = "anime"   // 动漫
+	CategoryGame    = "game"    // 游戏
+	CategoryMagnet  = "magnet"  // 磁力/BT
+	CategoryAdult   = "adult"   // 成人内容
+)
+
+// PluginMetadata 插件描述信息
+type PluginMetadata struct {
+	Name              string           `json:"name"`                  // 插件名称
+	DisplayName       string           `json:"display_name"`          // 展示名称
+	Description       string           `json:"description,omitempty"` // 简要说明
+	SiteURL           string           `json:"site_url,omitempty"`    // 数据来源站点
+	CloudTypes        []string         `json:"cloud_types,omitempty"` // 可能返回的网盘类型，为空表示不确定
+	Category          string           `json:"category"`              // 内容分类
+	RequiresLogin     bool             `json:"requires_login"`        // 是否需要先登录/配置账号才能搜索
+	ExtParams         []PluginExtParam `json:"ext_params,omitempty"`  // 支持的ext参数
+	SkipServiceFilter bool             `json:"skip_service_filter"`   // 是否跳过Service层关键词过滤
+	Priority          int              `json:"priority"`              // 插件优先级
+}
+
+// PluginExtParam 插件支持的ext参数说明
+type PluginExtParam struct {
+	Key         string      `json:"key"`               // 参数名
+	Type        string      `json:"type"`              // 参数类型：string、int、bool
+	Description string      `json:"description"`       // 参数说明
+	Default     interface{} `json:"default,omitempty"` // 默认值
+}
+
 // SearchRecorder 支持记录搜索关键词的插件接口
 type SearchRecorder interface {
 	AsyncSearchPlugin // 继承搜索插件接口
@@ -85,9 +152,6 @@ var (
 
 // 工作池和统计相关变量
 var (
-	// API响应缓存，键为关键词，值为缓存的响应（仅内存，不持久化）
-	apiResponseCache = sync.Map{}
-
 	// 工作池相关变量
 	backgroundWorkerPool chan struct{}
 	backgroundTasksCount int32 = 0
@@ -107,13 +171,6 @@ var (
 	defaultCacheTTL             = 1 * time.Hour // 恢复但仅用于内存缓存
 	defaultMaxBackgroundWorkers = 20
 	defaultMaxBackgroundTasks   = 100
-
-	// 缓存访问频率记录
-	cacheAccessCount = sync.Map{}
-
-	// 缓存清理相关变量
-	lastCleanupTime = time.Now()
-	cleanupMutex    sync.Mutex
 )
 
 // 全局序列化器引用（由主程序设置）
@@ -122,13 +179,14 @@ var globalCacheSerializer interface {
 	Deserialize([]byte, interface{}) error
 }
 
-// 缓存响应结构（仅内存，不持久化到磁盘）
+// 缓存响应结构（仅内存，不持久化到磁盘），由resultCache按容量淘汰
 type cachedResponse struct {
 	Results     []model.SearchResult `json:"results"`
 	Timestamp   time.Time            `json:"timestamp"`
 	Complete    bool                 `json:"complete"`
 	LastAccess  time.Time            `json:"last_access"`
 	AccessCount int                  `json:"access_count"`
+	TTL         time.Duration        `json:"ttl"` // 按有效期策略计算的有效期，为0时使用插件默认值
 }
 
 // ============================================================
@@ -174,31 +232,87 @@ func GetPluginByName(name string) (AsyncSearchPlugin, bool) {
 	return plugin, exists
 }
 
+// GetPluginMetadata 获取插件描述信息，未实现MetadataProvider的插件返回基础信息
+func GetPluginMetadata(p AsyncSearchPlugin) PluginMetadata {
+	var meta PluginMetadata
+	if provider, ok := p.(MetadataProvider); ok {
+		meta = provider.Metadata()
+	}
+
+	// 以插件自身的方法为准
+	meta.Name = p.Name()
+	meta.Priority = EffectivePriority(p)
+	meta.SkipServiceFilter = p.SkipServiceFilter()
+
+	if meta.DisplayName == "" {
+		meta.DisplayName = meta.Name
+	}
+	if meta.Category == "" {
+		meta.Category = CategoryGeneral
+	}
+
+	return meta
+}
+
 // PluginManager 异步插件管理器
 type PluginManager struct {
-	plugins []AsyncSearchPlugin
+	mu          sync.RWMutex // 保护plugins、onChange和initialized，支持运行时启用/禁用插件
+	plugins     []AsyncSearchPlugin
+	health      *healthTracker              // 插件健康统计和熔断状态
+	onChange    []func([]AsyncSearchPlugin) // 插件集合或优先级变化时的回调
+	state       pluginState                 // 运行时调整（持久化到CACHE_PATH）
+	statePath   string                      // 状态文件路径，为空时不持久化
+	initialized map[string]bool             // 已执行过Initialize的插件，重新启用时不再初始化
 }
 
 // NewPluginManager 创建新的异步插件管理器
 func NewPluginManager() *PluginManager {
 	return &PluginManager{
-		plugins: make([]AsyncSearchPlugin, 0),
+		plugins:     make([]AsyncSearchPlugin, 0),
+		health:      newHealthTracker(),
+		state:       pluginState{Priorities: make(map[string]int)},
+		initialized: make(map[string]bool),
 	}
 }
 
 // RegisterPlugin 注册异步插件
 func (pm *PluginManager) RegisterPlugin(plugin AsyncSearchPlugin) {
 	// 如果插件支持延迟初始化，先执行初始化
-	if initPlugin, ok := plugin.(InitializablePlugin); ok {
-		if err := initPlugin.Initialize(); err != nil {
-			fmt.Printf("[PluginManager] 插件 %s 初始化失败: %v，跳过注册\n", plugin.Name(), err)
-			return
-		}
+	if err := pm.initialize(plugin); err != nil {
+		fmt.Printf("[PluginManager] 插件 %s 初始化失败: %v，跳过注册\n", plugin.Name(), err)
+		return
 	}
 
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 	pm.plugins = append(pm.plugins, plugin)
 }
 
+// initialize 执行插件的延迟初始化，同一插件只初始化一次
+// 初始化可能较慢，在锁外执行
+func (pm *PluginManager) initialize(p AsyncSearchPlugin) error {
+	initPlugin, ok := p.(InitializablePlugin)
+	if !ok {
+		return nil
+	}
+
+	pm.mu.RLock()
+	done := pm.initialized[p.Name()]
+	pm.mu.RUnlock()
+	if done {
+		return nil
+	}
+
+	if err := initPlugin.Initialize(); err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	pm.initialized[p.Name()] = true
+	pm.mu.Unlock()
+	return nil
+}
+
 // RegisterAllGlobalPlugins 注册所有全局异步插件
 func (pm *PluginManager) RegisterAllGlobalPlugins() {
 	allPlugins := GetRegisteredPlugins()
@@ -236,9 +350,14 @@ func (pm *PluginManager) RegisterGlobalPluginsWithFilter(enabledPlugins []string
 	}
 }
 
-// GetPlugins 获取所有注册的异步插件
+// GetPlugins 获取所有注册的异步插件（返回副本，可安全排序和遍历）
 func (pm *PluginManager) GetPlugins() []AsyncSearchPlugin {
-	return pm.plugins
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	plugins := make([]AsyncSearchPlugin, len(pm.plugins))
+	copy(plugins, pm.plugins)
+	return plugins
 }
 
 // ============================================================
@@ -246,36 +365,29 @@ func (pm *PluginManager) GetPlugins() []AsyncSearchPlugin {
 // ============================================================
 
 // FilterResultsByKeyword 根据关键词过滤搜索结果的全局辅助函数
+// 关键词按查询语法解析，支持"短语"、-排除词和OR；标题或内容满足即保留。
+// 插件结果会写入缓存并被不同匹配模式的请求共用，因此这里按模糊匹配的阈值宽松过滤并记录相似度，
+// 严格匹配由Service层按请求的匹配模式再次过滤
 func FilterResultsByKeyword(results []model.SearchResult, keyword string) []model.SearchResult {
-	if keyword == "" {
+	if strings.TrimSpace(keyword) == "" {
 		return results
 	}
 
+	q, err := query.Parse(keyword)
+	if err != nil {
+		return results
+	}
+	if config.AppConfig != nil {
+		q.SetFuzzy(config.AppConfig.FuzzyThreshold)
+	}
+
 	// 预估过滤后会保留80%的结果
 	filteredResults := make([]model.SearchResult, 0, len(results)*8/10)
 
-	// 将关键词转为小写，用于不区分大小写的比较
-	lowerKeyword := strings.ToLower(keyword)
-
-	// 将关键词按空格分割，用于支持多关键词搜索
-	keywords := strings.Fields(lowerKeyword)
-
 	for _, result := range results {
-		// 将标题和内容转为小写
-		lowerTitle := strings.ToLower(result.Title)
-		lowerContent := strings.ToLower(result.Content)
-
-		// 检查每个关键词是否在标题或内容中
-		matched := true
-		for _, kw := range keywords {
-			// 对于所有关键词，检查是否在标题或内容中
-			if !strings.Contains(lowerTitle, kw) && !strings.Contains(lowerContent, kw) {
-				matched = false
-				break
-			}
-		}
-
-		if matched {
+		score := q.ScoreText(result.Title + "\n" + result.Content)
+		if score >= q.Threshold() {
+			result.Score = score
 			filteredResults = append(filteredResults, result)
 		}
 	}
@@ -287,50 +399,6 @@ func FilterResultsByKeyword(results []model.SearchResult, keyword string) []mode
 // 第五部分：异步插件基础设施（初始化、工作池、缓存）
 // ============================================================
 
-// cleanupExpiredApiCache 清理过期API缓存的函数
-func cleanupExpiredApiCache() {
-	cleanupMutex.Lock()
-	defer cleanupMutex.Unlock()
-
-	now := time.Now()
-	// 只有距离上次清理超过30分钟才执行
-	if now.Sub(lastCleanupTime) < 30*time.Minute {
-		return
-	}
-
-	cleanedCount := 0
-	totalCount := 0
-	deletedKeys := make([]string, 0)
-
-	// 清理已过期的缓存（基于实际TTL + 合理的宽限期）
-	apiResponseCache.Range(func(key, value interface{}) bool {
-		totalCount++
-		if cached, ok := value.(cachedResponse); ok {
-			// 使用默认TTL + 30分钟宽限期，避免过于激进的清理
-			expireThreshold := defaultCacheTTL + 30*time.Minute
-			if now.Sub(cached.Timestamp) > expireThreshold {
-				keyStr := key.(string)
-				apiResponseCache.Delete(key)
-				deletedKeys = append(deletedKeys, keyStr)
-				cleanedCount++
-			}
-		}
-		return true
-	})
-
-	// 清理访问计数缓存中对应的项
-	for _, key := range deletedKeys {
-		cacheAccessCount.Delete(key)
-	}
-
-	lastCleanupTime = now
-
-	// 记录清理日志（仅在有清理时输出）
-	if cleanedCount > 0 {
-		fmt.Printf("[Cache] 清理过期缓存: 删除 %d/%d 项，释放内存\n", cleanedCount, totalCount)
-	}
-}
-
 // initAsyncPlugin 初始化异步插件配置
 func initAsyncPlugin() {
 	initLock.Lock()
@@ -402,27 +470,6 @@ func recordAsyncCompletion() {
 	atomic.AddInt64(&asyncCompletions, 1)
 }
 
-// recordCacheAccess 记录缓存访问次数，用于智能缓存策略（仅内存）
-func recordCacheAccess(key string) {
-	// 更新缓存项的访问时间和计数
-	if cached, ok := apiResponseCache.Load(key); ok {
-		cachedItem := cached.(cachedResponse)
-		cachedItem.LastAccess = time.Now()
-		cachedItem.AccessCount++
-		apiResponseCache.Store(key, cachedItem)
-	}
-
-	// 更新全局访问计数
-	if count, ok := cacheAccessCount.Load(key); ok {
-		cacheAccessCount.Store(key, count.(int)+1)
-	} else {
-		cacheAccessCount.Store(key, 1)
-	}
-
-	// 触发定期清理（异步执行，不阻塞当前操作）
-	go cleanupExpiredApiCache()
-}
-
 // ============================================================
 // 第六部分：BaseAsyncPlugin 结构和构造函数
 // ============================================================
@@ -435,11 +482,12 @@ type BaseAsyncPlugin struct {
 	backgroundClient   *http.Client                                                          // 用于长超时的客户端
 	cacheTTL           time.Duration                                                         // 内存缓存有效期
 	mainCacheUpdater   func(string, []model.SearchResult, time.Duration, bool, string) error // 主缓存更新函数（支持IsFinal参数，接收原始数据，最后参数为关键词）
-	MainCacheKey       string                                                                // 主缓存键，导出字段
+	MainCacheKey       string                                                                // 主缓存键，导出字段；ext中的MainCacheKeyExtKey优先
 	currentKeyword     string                                                                // 当前搜索的关键词，用于日志显示
 	finalUpdateTracker map[string]bool                                                       // 追踪已更新的最终结果缓存
 	finalUpdateMutex   sync.RWMutex                                                          // 保护finalUpdateTracker的并发访问
 	skipServiceFilter  bool                                                                  // 是否跳过Service层的关键词过滤
+	synchronous        bool                                                                  // 同步模式：跳过缓存和响应超时，直接执行搜索（用于测试）
 }
 
 // NewBaseAsyncPlugin 创建基础异步插件
@@ -549,6 +597,29 @@ func (p *BaseAsyncPlugin) GetClient() *http.Client {
 	return p.client
 }
 
+// SetHTTPTransport 替换插件HTTP客户端的Transport（用于测试中录制和回放HTTP交互）
+func (p *BaseAsyncPlugin) SetHTTPTransport(transport http.RoundTripper) {
+	p.client.Transport = transport
+	p.backgroundClient.Transport = transport
+}
+
+// SetSynchronous 设置同步模式，开启后搜索不读写缓存、不受响应超时限制
+func (p *BaseAsyncPlugin) SetSynchronous(synchronous bool) {
+	p.synchronous = synchronous
+}
+
+// searchSynchronously 同步模式下直接执行搜索
+func (p *BaseAsyncPlugin) searchSynchronously(
+	keyword string,
+	searchFunc func(*http.Client, string, map[string]interface{}) ([]model.SearchResult, error),
+	ext map[string]interface{},
+) ([]model.SearchResult, error) {
+	if ext == nil {
+		ext = make(map[string]interface{})
+	}
+	return searchFunc(bindClient(p.backgroundClient, ContextFromExt(ext)), keyword, ext)
+}
+
 // ============================================================
 // 第八部分：异步搜索核心逻辑
 // ============================================================
@@ -560,10 +631,15 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 	mainCacheKey string,
 	ext map[string]interface{},
 ) ([]model.SearchResult, error) {
+	if p.synchronous {
+		return p.searchSynchronously(keyword, searchFunc, ext)
+	}
+
 	// 确保ext不为nil
 	if ext == nil {
 		ext = make(map[string]interface{})
 	}
+	mainCacheKey = mainCacheKeyFrom(ext, mainCacheKey)
 
 	now := time.Now()
 
@@ -571,17 +647,16 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 	pluginSpecificCacheKey := fmt.Sprintf("%s:%s", p.name, keyword)
 
 	// 检查缓存
-	if cachedItems, ok := apiResponseCache.Load(pluginSpecificCacheKey); ok {
-		cachedResult := cachedItems.(cachedResponse)
+	if cachedResult, ok := getResultCache().Load(pluginSpecificCacheKey); ok {
+		ttl := p.ttlOf(cachedResult)
 
 		// 缓存完全有效（未过期且完整）
-		if time.Since(cachedResult.Timestamp) < p.cacheTTL && cachedResult.Complete {
+		if time.Since(cachedResult.Timestamp) < ttl && cachedResult.Complete {
 			recordCacheHit()
-			recordCacheAccess(pluginSpecificCacheKey)
 
 			// 如果缓存接近过期（已用时间超过TTL的80%），在后台刷新缓存
-			if time.Since(cachedResult.Timestamp) > (p.cacheTTL * 4 / 5) {
-				go p.refreshCacheInBackground(keyword, pluginSpecificCacheKey, searchFunc, cachedResult, mainCacheKey, ext)
+			if time.Since(cachedResult.Timestamp) > (ttl * 4 / 5) {
+				go p.refreshCacheInBackground(keyword, pluginSpecificCacheKey, searchFunc, cachedResult, mainCacheKey, ext, nil, 0)
 			}
 
 			return cachedResult.Results, nil
@@ -590,12 +665,14 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 		// 缓存已过期但有结果，启动后台刷新，同时返回旧结果
 		if len(cachedResult.Results) > 0 {
 			recordCacheHit()
-			recordCacheAccess(pluginSpecificCacheKey)
 
 			// 标记为部分过期
-			if time.Since(cachedResult.Timestamp) >= p.cacheTTL {
-				// 在后台刷新缓存
-				go p.refreshCacheInBackground(keyword, pluginSpecificCacheKey, searchFunc, cachedResult, mainCacheKey, ext)
+			if time.Since(cachedResult.Timestamp) >= ttl {
+				// 在后台刷新缓存，本次返回的是旧结果，搜索视为转入后台，刷新完成时报告结果
+				report := outcomeReportFrom(ContextFromExt(ext))
+				layer := report.enter()
+				report.deferToBackground(layer)
+				go p.refreshCacheInBackground(keyword, pluginSpecificCacheKey, searchFunc, cachedResult, mainCacheKey, ext, report, layer)
 
 				// 日志记录
 				fmt.Printf("[%s] 缓存已过期，后台刷新中: %s (已过期: %v)\n",
@@ -608,6 +685,13 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 
 	recordCacheMiss()
 
+	// 请求上下文：客户端断开时取消搜索，响应超时后脱离以便后台继续填充缓存
+	parentCtx := ContextFromExt(ext)
+	searchCtx, detach, cancelSearch := newDetachableContext(parentCtx)
+	ext = WithContext(searchCtx, ext)
+	report := outcomeReportFrom(parentCtx)
+	layer := report.enter()
+
 	// 创建通道
 	resultChan := make(chan []model.SearchResult, 1)
 	errorChan := make(chan error, 1)
@@ -615,10 +699,14 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 
 	// 启动后台处理
 	go func() {
+		defer cancelSearch()
+
 		// 尝试获取工作槽
 		if !acquireWorkerSlot() {
 			// 工作池已满，使用快速响应客户端直接处理
-			results, err := searchFunc(p.client, keyword, ext)
+			results, err := searchFunc(bindClient(p.client, searchCtx), keyword, ext)
+			// 已响应超时时由这里报告结果，否则由前台返回
+			report.finish(layer, results, err)
 			if err != nil {
 				select {
 				case errorChan <- err:
@@ -633,35 +721,39 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 			}
 
 			// 缓存结果
-			apiResponseCache.Store(pluginSpecificCacheKey, cachedResponse{
+			ttl := p.resultTTL(keyword, results)
+			getResultCache().Store(pluginSpecificCacheKey, cachedResponse{
 				Results:     results,
 				Timestamp:   now,
 				Complete:    true,
 				LastAccess:  now,
 				AccessCount: 1,
+				TTL:         ttl,
 			})
 
 			// 🔧 工作池满时短超时(默认4秒)内完成，这是完整结果
-			p.updateMainCacheWithFinal(mainCacheKey, results, true)
+			p.updateMainCacheWithFinal(mainCacheKey, keyword, results, ttl, true)
 
 			return
 		}
 		defer releaseWorkerSlot()
 
 		// 执行搜索
-		results, err := searchFunc(p.backgroundClient, keyword, ext)
+		results, err := searchFunc(bindClient(p.backgroundClient, searchCtx), keyword, ext)
 
 		// 检查是否已经响应
 		select {
 		case <-doneChan:
+			// 响应超时后在后台完成，报告最终结果
+			report.finish(layer, results, err)
+
 			// 已经响应，只更新缓存
 			if err == nil {
 				// 检查是否存在旧缓存
 				var accessCount int = 1
 				var lastAccess time.Time = now
 
-				if oldCache, ok := apiResponseCache.Load(pluginSpecificCacheKey); ok {
-					oldCachedResult := oldCache.(cachedResponse)
+				if oldCachedResult, ok := getResultCache().Peek(pluginSpecificCacheKey); ok {
 					accessCount = oldCachedResult.AccessCount
 					lastAccess = oldCachedResult.LastAccess
 
@@ -689,17 +781,19 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 					}
 				}
 
-				apiResponseCache.Store(pluginSpecificCacheKey, cachedResponse{
+				ttl := p.resultTTL(keyword, results)
+				getResultCache().Store(pluginSpecificCacheKey, cachedResponse{
 					Results:     results,
 					Timestamp:   now,
 					Complete:    true,
 					LastAccess:  lastAccess,
 					AccessCount: accessCount,
+					TTL:         ttl,
 				})
 				recordAsyncCompletion()
 
 				// 异步插件后台完成时更新主缓存（标记为最终结果）
-				p.updateMainCacheWithFinal(mainCacheKey, results, true)
+				p.updateMainCacheWithFinal(mainCacheKey, keyword, results, ttl, true)
 
 				// 异步插件本地缓存系统已移除
 			}
@@ -712,8 +806,7 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 				}
 			} else {
 				// 检查是否存在旧缓存用于合并
-				if oldCache, ok := apiResponseCache.Load(pluginSpecificCacheKey); ok {
-					oldCachedResult := oldCache.(cachedResponse)
+				if oldCachedResult, ok := getResultCache().Peek(pluginSpecificCacheKey); ok {
 					if len(oldCachedResult.Results) > 0 {
 						// 创建合并结果集
 						mergedResults := make([]model.SearchResult, 0, len(results)+len(oldCachedResult.Results))
@@ -743,16 +836,18 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 				}
 
 				// 更新缓存
-				apiResponseCache.Store(pluginSpecificCacheKey, cachedResponse{
+				ttl := p.resultTTL(keyword, results)
+				getResultCache().Store(pluginSpecificCacheKey, cachedResponse{
 					Results:     results,
 					Timestamp:   now,
 					Complete:    true,
 					LastAccess:  now,
 					AccessCount: 1,
+					TTL:         ttl,
 				})
 
 				// 🔧 短超时(默认4秒)内正常完成，这是完整的最终结果
-				p.updateMainCacheWithFinal(mainCacheKey, results, true)
+				p.updateMainCacheWithFinal(mainCacheKey, keyword, results, ttl, true)
 
 				// 异步插件本地缓存系统已移除
 			}
@@ -773,8 +868,14 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 	case err := <-errorChan:
 		close(doneChan)
 		return nil, err
+	case <-parentCtx.Done():
+		// 客户端已断开，搜索随上下文一起取消
+		close(doneChan)
+		return nil, parentCtx.Err()
 	case <-time.After(responseTimeout):
 		// 插件响应超时，后台继续处理（优化完成，日志简化）
+		report.deferToBackground(layer)
+		detach()
 
 		// 响应超时，返回空结果，后台继续处理
 		go func() {
@@ -782,11 +883,9 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 		}()
 
 		// 检查是否有部分缓存可用
-		if cachedItems, ok := apiResponseCache.Load(pluginSpecificCacheKey); ok {
-			cachedResult := cachedItems.(cachedResponse)
+		if cachedResult, ok := getResultCache().Peek(pluginSpecificCacheKey); ok {
 			if len(cachedResult.Results) > 0 {
-				// 有部分缓存可用，记录访问并返回
-				recordCacheAccess(pluginSpecificCacheKey)
+				// 有部分缓存可用，直接返回
 				fmt.Printf("[%s] 响应超时，返回部分缓存: %s (项目数: %d)\n",
 					p.name, pluginSpecificCacheKey, len(cachedResult.Results))
 				return cachedResult.Results, nil
@@ -794,7 +893,7 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 		}
 
 		// 创建空的临时缓存，以便后台处理完成后可以更新
-		apiResponseCache.Store(pluginSpecificCacheKey, cachedResponse{
+		getResultCache().Store(pluginSpecificCacheKey, cachedResponse{
 			Results:     []model.SearchResult{},
 			Timestamp:   now,
 			Complete:    false, // 标记为不完整
@@ -803,7 +902,7 @@ func (p *BaseAsyncPlugin) AsyncSearch(
 		})
 
 		// 🔧 修复：4秒超时时也要更新主缓存，标记为部分结果（空结果）
-		p.updateMainCacheWithFinal(mainCacheKey, []model.SearchResult{}, false)
+		p.updateMainCacheWithFinal(mainCacheKey, keyword, []model.SearchResult{}, p.cacheTTL, false)
 
 		// fmt.Printf("[%s] 响应超时，后台继续处理: %s\n", p.name, pluginSpecificCacheKey)
 		return []model.SearchResult{}, nil
@@ -817,10 +916,25 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 	mainCacheKey string,
 	ext map[string]interface{},
 ) (model.PluginSearchResult, error) {
+	if p.synchronous {
+		results, err := p.searchSynchronously(keyword, searchFunc, ext)
+		if err != nil {
+			return model.PluginSearchResult{}, err
+		}
+		return model.PluginSearchResult{
+			Results:   results,
+			IsFinal:   true,
+			Timestamp: time.Now(),
+			Source:    p.name,
+			Message:   "搜索完成",
+		}, nil
+	}
+
 	// 确保ext不为nil
 	if ext == nil {
 		ext = make(map[string]interface{})
 	}
+	mainCacheKey = mainCacheKeyFrom(ext, mainCacheKey)
 
 	now := time.Now()
 
@@ -828,17 +942,16 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 	pluginSpecificCacheKey := fmt.Sprintf("%s:%s", p.name, keyword)
 
 	// 检查缓存
-	if cachedItems, ok := apiResponseCache.Load(pluginSpecificCacheKey); ok {
-		cachedResult := cachedItems.(cachedResponse)
+	if cachedResult, ok := getResultCache().Load(pluginSpecificCacheKey); ok {
+		ttl := p.ttlOf(cachedResult)
 
 		// 缓存完全有效（未过期且完整）
-		if time.Since(cachedResult.Timestamp) < p.cacheTTL && cachedResult.Complete {
+		if time.Since(cachedResult.Timestamp) < ttl && cachedResult.Complete {
 			recordCacheHit()
-			recordCacheAccess(pluginSpecificCacheKey)
 
 			// 如果缓存接近过期（已用时间超过TTL的80%），在后台刷新缓存
-			if time.Since(cachedResult.Timestamp) > (p.cacheTTL * 4 / 5) {
-				go p.refreshCacheInBackground(keyword, pluginSpecificCacheKey, searchFunc, cachedResult, mainCacheKey, ext)
+			if time.Since(cachedResult.Timestamp) > (ttl * 4 / 5) {
+				go p.refreshCacheInBackground(keyword, pluginSpecificCacheKey, searchFunc, cachedResult, mainCacheKey, ext, nil, 0)
 			}
 
 			return model.PluginSearchResult{
@@ -853,12 +966,14 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 		// 缓存已过期但有结果，启动后台刷新，同时返回旧结果
 		if len(cachedResult.Results) > 0 {
 			recordCacheHit()
-			recordCacheAccess(pluginSpecificCacheKey)
 
 			// 标记为部分过期
-			if time.Since(cachedResult.Timestamp) >= p.cacheTTL {
-				// 在后台刷新缓存
-				go p.refreshCacheInBackground(keyword, pluginSpecificCacheKey, searchFunc, cachedResult, mainCacheKey, ext)
+			if time.Since(cachedResult.Timestamp) >= ttl {
+				// 在后台刷新缓存，本次返回的是旧结果，搜索视为转入后台，刷新完成时报告结果
+				report := outcomeReportFrom(ContextFromExt(ext))
+				layer := report.enter()
+				report.deferToBackground(layer)
+				go p.refreshCacheInBackground(keyword, pluginSpecificCacheKey, searchFunc, cachedResult, mainCacheKey, ext, report, layer)
 			}
 
 			return model.PluginSearchResult{
@@ -873,6 +988,13 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 
 	recordCacheMiss()
 
+	// 前台搜索随请求上下文取消，超时后由completeSearchInBackground重新发起独立搜索
+	parentCtx := ContextFromExt(ext)
+	searchCtx, cancelSearch := context.WithCancel(parentCtx)
+	searchExt := WithContext(searchCtx, ext)
+	report := outcomeReportFrom(parentCtx)
+	layer := report.enter()
+
 	// 创建通道
 	resultChan := make(chan []model.SearchResult, 1)
 	errorChan := make(chan error, 1)
@@ -880,6 +1002,7 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 
 	// 启动后台处理
 	go func() {
+		defer cancelSearch()
 		defer func() {
 			select {
 			case <-doneChan:
@@ -891,7 +1014,7 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 		// 尝试获取工作槽
 		if !acquireWorkerSlot() {
 			// 工作池已满，使用快速响应客户端直接处理
-			results, err := searchFunc(p.client, keyword, ext)
+			results, err := searchFunc(bindClient(p.client, searchCtx), keyword, searchExt)
 			if err != nil {
 				select {
 				case errorChan <- err:
@@ -909,7 +1032,7 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 		defer releaseWorkerSlot()
 
 		// 使用长超时客户端进行搜索
-		results, err := searchFunc(p.backgroundClient, keyword, ext)
+		results, err := searchFunc(bindClient(p.backgroundClient, searchCtx), keyword, searchExt)
 		if err != nil {
 			select {
 			case errorChan <- err:
@@ -934,18 +1057,20 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 		// 不直接关闭，让defer处理
 
 		// 缓存结果
-		apiResponseCache.Store(pluginSpecificCacheKey, cachedResponse{
+		ttl := p.resultTTL(keyword, results)
+		getResultCache().Store(pluginSpecificCacheKey, cachedResponse{
 			Results:     results,
 			Timestamp:   now,
 			Complete:    true, // 🔥 及时完成，标记为完整结果
 			LastAccess:  now,
 			AccessCount: 1,
+			TTL:         ttl,
 		})
 
 		// 🔧 恢复主缓存更新：使用统一的GOB序列化
 		// 传递原始数据，由主程序负责序列化
 		if mainCacheKey != "" && p.mainCacheUpdater != nil {
-			err := p.mainCacheUpdater(mainCacheKey, results, p.cacheTTL, true, p.currentKeyword)
+			err := p.mainCacheUpdater(mainCacheKey, results, ttl, true, keyword)
 			if err != nil {
 				fmt.Printf("❌ [%s] 及时完成缓存更新失败: %s | 错误: %v\n", p.name, mainCacheKey, err)
 			}
@@ -963,12 +1088,17 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 		// 不直接关闭，让defer处理
 		return model.PluginSearchResult{}, err
 
+	case <-parentCtx.Done():
+		// 客户端已断开，前台搜索随上下文取消
+		return model.PluginSearchResult{}, parentCtx.Err()
+
 	case <-time.After(responseTimeout):
-		// 🔥 超时处理：返回空结果，后台继续处理
-		go p.completeSearchInBackground(keyword, searchFunc, pluginSpecificCacheKey, mainCacheKey, doneChan, ext)
+		// 🔥 超时处理：返回空结果，后台继续处理，结果在后台完成时报告
+		report.deferToBackground(layer)
+		go p.completeSearchInBackground(keyword, searchFunc, pluginSpecificCacheKey, mainCacheKey, doneChan, ext, report, layer)
 
 		// 存储临时缓存（标记为不完整）
-		apiResponseCache.Store(pluginSpecificCacheKey, cachedResponse{
+		getResultCache().Store(pluginSpecificCacheKey, cachedResponse{
 			Results:     []model.SearchResult{},
 			Timestamp:   now,
 			Complete:    false, // 🔥 标记为不完整
@@ -986,7 +1116,7 @@ func (p *BaseAsyncPlugin) AsyncSearchWithResult(
 	}
 }
 
-// completeSearchInBackground 后台完成搜索
+// completeSearchInBackground 后台完成搜索，完成时通过report报告插件的真实结果
 func (p *BaseAsyncPlugin) completeSearchInBackground(
 	keyword string,
 	searchFunc func(*http.Client, string, map[string]interface{}) ([]model.SearchResult, error),
@@ -994,6 +1124,8 @@ func (p *BaseAsyncPlugin) completeSearchInBackground(
 	mainCacheKey string,
 	doneChan chan struct{},
 	ext map[string]interface{},
+	report *OutcomeReport,
+	layer int,
 ) {
 	defer func() {
 		select {
@@ -1003,26 +1135,32 @@ func (p *BaseAsyncPlugin) completeSearchInBackground(
 		}
 	}()
 
+	// 后台填充缓存不受客户端断开影响
+	ext = WithContext(context.Background(), ext)
+
 	// 执行完整搜索
 	results, err := searchFunc(p.backgroundClient, keyword, ext)
+	report.finish(layer, results, err)
 	if err != nil {
 		return
 	}
 
 	// 更新插件缓存
 	now := time.Now()
-	apiResponseCache.Store(pluginCacheKey, cachedResponse{
+	ttl := p.resultTTL(keyword, results)
+	getResultCache().Store(pluginCacheKey, cachedResponse{
 		Results:     results,
 		Timestamp:   now,
 		Complete:    true, // 🔥 标记为完整结果
 		LastAccess:  now,
 		AccessCount: 1,
+		TTL:         ttl,
 	})
 
 	// 🔧 恢复主缓存更新：使用统一的GOB序列化
 	// 传递原始数据，由主程序负责序列化
 	if mainCacheKey != "" && p.mainCacheUpdater != nil {
-		err := p.mainCacheUpdater(mainCacheKey, results, p.cacheTTL, true, p.currentKeyword)
+		err := p.mainCacheUpdater(mainCacheKey, results, ttl, true, keyword)
 		if err != nil {
 			fmt.Printf("❌ [%s] 后台完成缓存更新失败: %s | 错误: %v\n", p.name, mainCacheKey, err)
 		}
@@ -1030,6 +1168,7 @@ func (p *BaseAsyncPlugin) completeSearchInBackground(
 }
 
 // refreshCacheInBackground 在后台刷新缓存
+// 返回过期缓存的搜索传入report，刷新完成时报告结果；缓存即将过期的提前刷新report为nil
 func (p *BaseAsyncPlugin) refreshCacheInBackground(
 	keyword string,
 	cacheKey string,
@@ -1037,6 +1176,8 @@ func (p *BaseAsyncPlugin) refreshCacheInBackground(
 	oldCache cachedResponse,
 	originalCacheKey string,
 	ext map[string]interface{},
+	report *OutcomeReport,
+	layer int,
 ) {
 	// 确保ext不为nil
 	if ext == nil {
@@ -1045,8 +1186,13 @@ func (p *BaseAsyncPlugin) refreshCacheInBackground(
 
 	// 注意：这里的cacheKey已经是插件特定的了，因为是从AsyncSearch传入的
 
+	// 后台刷新不受客户端断开影响
+	ext = WithContext(context.Background(), ext)
+
 	// 检查是否有足够的工作槽
 	if !acquireWorkerSlot() {
+		// 未能刷新，按已返回的旧结果报告
+		report.finish(layer, oldCache.Results, nil)
 		return
 	}
 	defer releaseWorkerSlot()
@@ -1056,6 +1202,7 @@ func (p *BaseAsyncPlugin) refreshCacheInBackground(
 
 	// 执行搜索
 	results, err := searchFunc(p.backgroundClient, keyword, ext)
+	report.finish(layer, results, err)
 	if err != nil || len(results) == 0 {
 		return
 	}
@@ -1078,16 +1225,18 @@ func (p *BaseAsyncPlugin) refreshCacheInBackground(
 	}
 
 	// 更新缓存
-	apiResponseCache.Store(cacheKey, cachedResponse{
+	ttl := p.resultTTL(keyword, mergedResults)
+	getResultCache().Store(cacheKey, cachedResponse{
 		Results:     mergedResults,
 		Timestamp:   time.Now(),
 		Complete:    true,
 		LastAccess:  oldCache.LastAccess,
 		AccessCount: oldCache.AccessCount,
+		TTL:         ttl,
 	})
 
 	// 🔥 异步插件后台刷新完成时更新主缓存（标记为最终结果）
-	p.updateMainCacheWithFinal(originalCacheKey, mergedResults, true)
+	p.updateMainCacheWithFinal(originalCacheKey, keyword, mergedResults, ttl, true)
 
 	// 记录刷新时间
 	refreshTime := time.Since(refreshStart)
@@ -1102,12 +1251,12 @@ func (p *BaseAsyncPlugin) refreshCacheInBackground(
 // ============================================================
 
 // updateMainCache 更新主缓存系统（兼容性方法，默认IsFinal=true）
-func (p *BaseAsyncPlugin) updateMainCache(cacheKey string, results []model.SearchResult) {
-	p.updateMainCacheWithFinal(cacheKey, results, true)
+func (p *BaseAsyncPlugin) updateMainCache(cacheKey string, keyword string, results []model.SearchResult) {
+	p.updateMainCacheWithFinal(cacheKey, keyword, results, p.resultTTL(keyword, results), true)
 }
 
-// updateMainCacheWithFinal 更新主缓存系统，支持IsFinal参数
-func (p *BaseAsyncPlugin) updateMainCacheWithFinal(cacheKey string, results []model.SearchResult, isFinal bool) {
+// updateMainCacheWithFinal 更新主缓存系统，支持IsFinal参数，ttl为按有效期策略计算的有效期
+func (p *BaseAsyncPlugin) updateMainCacheWithFinal(cacheKey string, keyword string, results []model.SearchResult, ttl time.Duration, isFinal bool) {
 	// 如果主缓存更新函数为空或缓存键为空，直接返回
 	if p.mainCacheUpdater == nil || cacheKey == "" {
 		return
@@ -1137,13 +1286,26 @@ func (p *BaseAsyncPlugin) updateMainCacheWithFinal(cacheKey string, results []mo
 	// 🔧 恢复异步插件缓存更新，使用修复后的统一序列化
 	// 传递原始数据，由主程序负责GOB序列化
 	if p.mainCacheUpdater != nil {
-		err := p.mainCacheUpdater(cacheKey, results, p.cacheTTL, isFinal, p.currentKeyword)
+		err := p.mainCacheUpdater(cacheKey, results, ttl, isFinal, keyword)
 		if err != nil {
 			fmt.Printf("❌ [%s] 主缓存更新失败: %s | 错误: %v\n", p.name, cacheKey, err)
 		}
 	}
 }
 
+// resultTTL 按有效期策略计算本次结果的缓存有效期
+func (p *BaseAsyncPlugin) resultTTL(keyword string, results []model.SearchResult) time.Duration {
+	return CacheTTL(p.name, keyword, results)
+}
+
+// ttlOf 返回缓存条目的有效期，写入时未计算有效期的条目使用插件默认值
+func (p *BaseAsyncPlugin) ttlOf(c cachedResponse) time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return p.cacheTTL
+}
+
 // hasUpdatedFinalCache 检查是否已经更新过指定的最终结果缓存
 func (p *BaseAsyncPlugin) hasUpdatedFinalCache(updateKey string) bool {
 	p.finalUpdateMutex.RLock()
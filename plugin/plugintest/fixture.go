@@ -0,0 +1,280 @@
+package plugintest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// Fixture 录制的一组HTTP交互
+type Fixture struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Exchange 一次HTTP请求及其响应
+type Exchange struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest 录制的请求
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse 录制的响应，非UTF-8内容以base64保存
+type RecordedResponse struct {
+	Status     int               `json:"status"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	BodyBase64 string            `json:"body_base64,omitempty"`
+}
+
+// LoadFixture 读取fixture文件
+func LoadFixture(path string) (*Fixture, error) {
+	var fixture Fixture
+	if err := readJSON(path, &fixture); err != nil {
+		return nil, err
+	}
+	return &fixture, nil
+}
+
+// readJSON 读取并解析JSON文件
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("解析%s失败: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Save 写入fixture文件
+func (f *Fixture) Save(path string) error {
+	return writeJSON(path, f)
+}
+
+// writeJSON 写入格式化的JSON文件，不转义HTML字符，便于阅读和比较
+func writeJSON(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// requestKey 请求匹配键，查询参数按名称排序
+func requestKey(method, rawURL, body string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		u.RawQuery = u.Query().Encode()
+		rawURL = u.String()
+	}
+	return method + " " + rawURL + "\n" + body
+}
+
+// readRequestBody 读取请求体并恢复，便于后续继续发送
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+// Recorder 转发请求到真实网络并录制交互
+type Recorder struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder 创建录制器，base为空时使用http.DefaultTransport
+func NewRecorder(base http.RoundTripper) *Recorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Recorder{base: base}
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	recorded := RecordedResponse{
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string),
+	}
+	for key := range resp.Header {
+		recorded.Headers[key] = resp.Header.Get(key)
+	}
+	if utf8.Valid(data) {
+		recorded.Body = string(data)
+	} else {
+		recorded.BodyBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+
+	r.mu.Lock()
+	r.fixture.Exchanges = append(r.fixture.Exchanges, Exchange{
+		Request:  RecordedRequest{Method: req.Method, URL: req.URL.String(), Body: body},
+		Response: recorded,
+	})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// ServeHTTP 作为HTTP代理转发并录制
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	serveProxy(w, req, r)
+}
+
+// Fixture 返回已录制的交互
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fixture := Fixture{Exchanges: append([]Exchange(nil), r.fixture.Exchanges...)}
+	return &fixture
+}
+
+// Replayer 按请求方法、URL和请求体回放录制的响应，不访问网络
+type Replayer struct {
+	mu        sync.Mutex
+	exchanges map[string][]Exchange
+	misses    []string
+}
+
+// NewReplayer 根据fixture创建回放器
+// 同一请求录制了多次时按录制顺序依次返回，用完后重复返回最后一次
+func NewReplayer(fixture *Fixture) *Replayer {
+	r := &Replayer{exchanges: make(map[string][]Exchange)}
+	for _, exchange := range fixture.Exchanges {
+		key := requestKey(exchange.Request.Method, exchange.Request.URL, exchange.Request.Body)
+		r.exchanges[key] = append(r.exchanges[key], exchange)
+	}
+	return r
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := requestKey(req.Method, req.URL.String(), body)
+
+	r.mu.Lock()
+	queue := r.exchanges[key]
+	if len(queue) == 0 {
+		r.misses = append(r.misses, req.Method+" "+req.URL.String())
+		r.mu.Unlock()
+		return nil, fmt.Errorf("fixture中没有匹配的请求: %s %s", req.Method, req.URL)
+	}
+	exchange := queue[0]
+	if len(queue) > 1 {
+		r.exchanges[key] = queue[1:]
+	}
+	r.mu.Unlock()
+
+	data := []byte(exchange.Response.Body)
+	if exchange.Response.BodyBase64 != "" {
+		if data, err = base64.StdEncoding.DecodeString(exchange.Response.BodyBase64); err != nil {
+			return nil, err
+		}
+	}
+
+	header := make(http.Header)
+	for key, value := range exchange.Response.Headers {
+		header.Set(key, value)
+	}
+	// Transport自动解压时会去掉Content-Encoding，保留该头说明录制的是原始压缩内容
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Response.Status, http.StatusText(exchange.Response.Status)),
+		StatusCode:    exchange.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// ServeHTTP 作为HTTP代理回放，供自行发请求、无法替换Transport的插件（如外部进程插件）通过HTTP_PROXY使用
+func (r *Replayer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	serveProxy(w, req, r)
+}
+
+// Misses 返回未能匹配的请求
+func (r *Replayer) Misses() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.misses...)
+}
+
+// serveProxy 将代理请求交给transport处理并写回响应
+// 只支持http://地址：HTTPS请求经代理时使用CONNECT隧道，无法看到请求内容
+func serveProxy(w http.ResponseWriter, req *http.Request, transport http.RoundTripper) {
+	if req.Method == http.MethodConnect || !req.URL.IsAbs() {
+		http.Error(w, "只支持代理http://请求", http.StatusBadRequest)
+		return
+	}
+
+	out := req.Clone(req.Context())
+	out.RequestURI = ""
+	out.Header.Del("Proxy-Connection")
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
@@ -0,0 +1,293 @@
+// Package plugintest 提供插件的fixture回放测试工具
+//
+// 测试时插件的HTTP请求由录制好的fixture文件回放，解析结果与golden文件比较，
+// 站点改版后重新录制即可发现解析失效。基于BaseAsyncPlugin、且使用searchFunc
+// 传入的http.Client发请求的插件无需额外改动。
+package plugintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"pansou/model"
+	"pansou/plugin"
+)
+
+// 控制录制和更新的环境变量
+const (
+	RecordEnv = "PLUGINTEST_RECORD" // 为1时访问真实站点并重新录制fixture和golden
+	UpdateEnv = "PLUGINTEST_UPDATE" // 为1时用回放结果覆盖golden文件
+)
+
+// NowPlaceholder golden文件中表示"插件使用了当前时间"的占位符
+const NowPlaceholder = "<now>"
+
+// TestDataDir fixture和golden文件所在目录，相对于测试所在的包
+var TestDataDir = "testdata"
+
+// Harness 插件需要支持的测试钩子，嵌入BaseAsyncPlugin即自动实现
+type Harness interface {
+	plugin.AsyncSearchPlugin
+	SetHTTPTransport(transport http.RoundTripper)
+	SetSynchronous(synchronous bool)
+}
+
+// Case 一个测试用例，Name对应testdata下的文件名
+type Case struct {
+	Name    string
+	Keyword string
+	Ext     map[string]interface{}
+}
+
+// fixturePath 返回用例的fixture文件路径
+func (c Case) fixturePath() string {
+	return filepath.Join(TestDataDir, c.Name+".fixture.json")
+}
+
+// goldenPath 返回用例的golden文件路径
+func (c Case) goldenPath() string {
+	return filepath.Join(TestDataDir, c.Name+".golden.json")
+}
+
+// GoldenResult golden文件中的一条结果
+type GoldenResult struct {
+	UniqueID string       `json:"unique_id"`
+	Title    string       `json:"title"`
+	Datetime string       `json:"datetime"` // 本地时间；插件使用当前时间时为"<now>"
+	Tags     []string     `json:"tags,omitempty"`
+	Links    []GoldenLink `json:"links"`
+}
+
+// GoldenLink golden文件中的一个链接
+type GoldenLink struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	Password  string `json:"password"`
+	WorkTitle string `json:"work_title,omitempty"`
+}
+
+// Run 回放fixture执行搜索，并将结果与golden文件比较
+func Run(t testing.TB, p plugin.AsyncSearchPlugin, c Case) []model.SearchResult {
+	t.Helper()
+
+	start := time.Now()
+	results := Search(t, p, c)
+	checkGolden(t, c, ToGolden(results, start, time.Now()))
+	return results
+}
+
+// checkGolden 将结果与golden文件比较，录制或更新时覆盖golden文件
+func checkGolden(t testing.TB, c Case, got []GoldenResult) {
+	t.Helper()
+
+	if envEnabled(RecordEnv) || envEnabled(UpdateEnv) {
+		if err := writeJSON(c.goldenPath(), got); err != nil {
+			t.Fatalf("写入golden文件失败: %v", err)
+		}
+		t.Logf("已更新 %s（%d条结果）", c.goldenPath(), len(got))
+		return
+	}
+
+	var want []GoldenResult
+	if err := readJSON(c.goldenPath(), &want); err != nil {
+		t.Fatalf("读取golden文件失败: %v（设置 %s=1 生成）", err, UpdateEnv)
+	}
+	compareGolden(t, want, got)
+}
+
+// RunProxied 以HTTP代理的方式回放fixture，测试自行发HTTP请求的插件（如外部进程插件），并将结果与golden文件比较
+// start在设置HTTP_PROXY后调用，插件启动的进程继承该环境变量；只能回放http://地址
+func RunProxied(t testing.TB, start func() plugin.AsyncSearchPlugin, c Case) []model.SearchResult {
+	t.Helper()
+
+	var recorder *Recorder
+	var replayer *Replayer
+	var handler http.Handler
+	if envEnabled(RecordEnv) {
+		// 录制时直接访问站点，不能再经过HTTP_PROXY指向的代理自身
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.Proxy = nil
+		recorder = NewRecorder(base)
+		handler = recorder
+	} else {
+		fixture, err := LoadFixture(c.fixturePath())
+		if err != nil {
+			t.Fatalf("读取fixture失败: %v（设置 %s=1 从真实站点录制）", err, RecordEnv)
+		}
+		replayer = NewReplayer(fixture)
+		handler = replayer
+	}
+
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+	for _, name := range []string{"HTTP_PROXY", "http_proxy"} {
+		t.Setenv(name, proxy.URL)
+	}
+	for _, name := range []string{"NO_PROXY", "no_proxy"} {
+		t.Setenv(name, "")
+	}
+
+	p := start()
+	if h, ok := p.(Harness); ok {
+		h.SetSynchronous(true)
+	}
+
+	begin := time.Now()
+	results, err := p.Search(c.Keyword, c.Ext)
+	if err != nil {
+		t.Fatalf("搜索失败: %v", err)
+	}
+	if recorder != nil {
+		if err := recorder.Fixture().Save(c.fixturePath()); err != nil {
+			t.Fatalf("写入fixture失败: %v", err)
+		}
+		t.Logf("已录制 %s（%d次请求）", c.fixturePath(), len(recorder.Fixture().Exchanges))
+	} else {
+		for _, miss := range replayer.Misses() {
+			t.Errorf("fixture中没有匹配的请求: %s", miss)
+		}
+	}
+
+	checkGolden(t, c, ToGolden(results, begin, time.Now()))
+	return results
+}
+
+// Search 使用fixture回放（或录制）HTTP交互执行搜索，返回插件解析的结果
+func Search(t testing.TB, p plugin.AsyncSearchPlugin, c Case) []model.SearchResult {
+	t.Helper()
+
+	h, ok := p.(Harness)
+	if !ok {
+		t.Fatalf("插件 %s 未实现SetHTTPTransport/SetSynchronous，请嵌入BaseAsyncPlugin", p.Name())
+	}
+	h.SetSynchronous(true)
+
+	if envEnabled(RecordEnv) {
+		recorder := NewRecorder(nil)
+		h.SetHTTPTransport(recorder)
+		results, err := p.Search(c.Keyword, c.Ext)
+		if err != nil {
+			t.Fatalf("录制时搜索失败: %v", err)
+		}
+		if err := recorder.Fixture().Save(c.fixturePath()); err != nil {
+			t.Fatalf("写入fixture失败: %v", err)
+		}
+		t.Logf("已录制 %s（%d次请求）", c.fixturePath(), len(recorder.Fixture().Exchanges))
+		return results
+	}
+
+	fixture, err := LoadFixture(c.fixturePath())
+	if err != nil {
+		t.Fatalf("读取fixture失败: %v（设置 %s=1 从真实站点录制）", err, RecordEnv)
+	}
+	replayer := NewReplayer(fixture)
+	h.SetHTTPTransport(replayer)
+
+	results, err := p.Search(c.Keyword, c.Ext)
+	if err != nil {
+		t.Fatalf("搜索失败: %v", err)
+	}
+	for _, miss := range replayer.Misses() {
+		t.Errorf("fixture中没有匹配的请求: %s", miss)
+	}
+	return results
+}
+
+// ToGolden 将搜索结果转换为golden格式，按UniqueID排序
+// 时间落在[start, end]内的结果视为插件使用了当前时间，记为占位符
+func ToGolden(results []model.SearchResult, start, end time.Time) []GoldenResult {
+	golden := make([]GoldenResult, 0, len(results))
+	for _, r := range results {
+		g := GoldenResult{
+			UniqueID: r.UniqueID,
+			Title:    r.Title,
+			Datetime: formatDatetime(r.Datetime, start, end),
+			Tags:     r.Tags,
+			Links:    make([]GoldenLink, 0, len(r.Links)),
+		}
+		for _, l := range r.Links {
+			g.Links = append(g.Links, GoldenLink{
+				Type:      l.Type,
+				URL:       l.URL,
+				Password:  l.Password,
+				WorkTitle: l.WorkTitle,
+			})
+		}
+		golden = append(golden, g)
+	}
+
+	// 部分插件并发请求详情页，结果顺序不固定
+	sort.SliceStable(golden, func(i, j int) bool {
+		if golden[i].UniqueID != golden[j].UniqueID {
+			return golden[i].UniqueID < golden[j].UniqueID
+		}
+		return golden[i].Title < golden[j].Title
+	})
+	return golden
+}
+
+// formatDatetime 格式化时间，与时区无关
+func formatDatetime(t, start, end time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if !t.Before(start.Add(-time.Second)) && !t.After(end.Add(time.Second)) {
+		return NowPlaceholder
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// compareGolden 逐条比较结果，输出可读的差异
+func compareGolden(t testing.TB, want, got []GoldenResult) {
+	t.Helper()
+
+	if len(want) != len(got) {
+		t.Errorf("结果数量不一致: want %d, got %d", len(want), len(got))
+	}
+
+	n := len(want)
+	if len(got) < n {
+		n = len(got)
+	}
+	for i := 0; i < n; i++ {
+		w, g := want[i], got[i]
+		prefix := "结果[" + w.UniqueID + "]"
+		if w.UniqueID != g.UniqueID {
+			t.Errorf("%s unique_id: want %q, got %q", prefix, w.UniqueID, g.UniqueID)
+			continue
+		}
+		if w.Title != g.Title {
+			t.Errorf("%s title: want %q, got %q", prefix, w.Title, g.Title)
+		}
+		if w.Datetime != g.Datetime {
+			t.Errorf("%s datetime: want %q, got %q", prefix, w.Datetime, g.Datetime)
+		}
+		if !reflect.DeepEqual(normalizeTags(w.Tags), normalizeTags(g.Tags)) {
+			t.Errorf("%s tags: want %v, got %v", prefix, w.Tags, g.Tags)
+		}
+		if !reflect.DeepEqual(w.Links, g.Links) {
+			t.Errorf("%s links:\nwant %+v\n got %+v", prefix, w.Links, g.Links)
+		}
+	}
+}
+
+// normalizeTags 将空标签列表统一为nil
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// envEnabled 判断环境变量是否开启
+func envEnabled(name string) bool {
+	value := strings.ToLower(os.Getenv(name))
+	return value == "1" || value == "true"
+}
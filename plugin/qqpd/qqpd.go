@@ -561,6 +561,17 @@ func (p *QQPDPlugin) RegisterWebRoutes(router *gin.RouterGroup) {
 	fmt.Printf("[QQPD] Web路由已注册: /qqpd/:param\n")
 }
 
+// Metadata 返回插件描述信息
+func (p *QQPDPlugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName:   "QQ频道",
+		Description:   "搜索QQ频道中的资源分享，需在 /qqpd/ 管理页面扫码登录后才能搜索",
+		SiteURL:       "https://pd.qq.com",
+		Category:      plugin.CategoryGeneral,
+		RequiresLogin: true,
+	}
+}
+
 // Search 执行搜索并返回结果（兼容性方法）
 func (p *QQPDPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
@@ -0,0 +1,448 @@
+package plugin
+
+import (
+	"container/list"
+	"hash/fnv"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+)
+
+// 插件结果缓存默认配置
+const (
+	defaultResultCacheMaxItems  = 10000
+	defaultResultCacheMaxSizeMB = 64
+	resultCacheGracePeriod      = 30 * time.Minute // 超过TTL后继续保留的时间，期间返回旧结果并后台刷新
+	sketchCounterMax            = 15               // 频率计数上限，计数达到上限后不再增长
+	sketchResetFactor           = 10               // 累计增长次数达到宽度的该倍数时计数减半
+)
+
+// ResultCacheStats 插件结果缓存统计
+type ResultCacheStats struct {
+	Items        int     `json:"items"`
+	SizeBytes    int64   `json:"size_bytes"`
+	MaxItems     int     `json:"max_items"`
+	MaxSizeBytes int64   `json:"max_size_bytes"`
+	Hits         int64   `json:"hits"`
+	Misses       int64   `json:"misses"`
+	HitRate      float64 `json:"hit_rate"`
+	Evictions    int64   `json:"evictions"`   // 因容量不足被淘汰的条目数
+	Rejections   int64   `json:"rejections"`  // 访问频率低于淘汰候选、未被接纳的写入数
+	Expirations  int64   `json:"expirations"` // 超过保留时间被删除的条目数
+}
+
+// resultCache 插件级搜索结果缓存
+// 按键哈希分片，每个分片独立维护LRU链表和访问频率（TinyLFU），
+// 分片已满时只有访问频率高于LRU尾部条目的新键才会被接纳，
+// 避免大量一次性关键词把热门关键词挤出缓存
+type resultCache struct {
+	shards        []*resultCacheShard
+	shardMask     uint64
+	maxItems      int
+	maxSize       int64
+	itemsPerShard int
+	sizePerShard  int64
+	retention     time.Duration
+
+	hits        int64
+	misses      int64
+	evictions   int64
+	rejections  int64
+	expirations int64
+}
+
+// resultCacheShard 单个分片
+type resultCacheShard struct {
+	mu     sync.Mutex
+	items  map[string]*list.Element
+	lru    *list.List // 头部为最近使用
+	size   int64
+	sketch *frequencySketch
+}
+
+// resultCacheEntry 缓存条目
+type resultCacheEntry struct {
+	key   string
+	hash  uint64
+	value cachedResponse
+	size  int64
+}
+
+var (
+	globalResultCache     *resultCache
+	globalResultCacheOnce sync.Once
+)
+
+// getResultCache 返回全局插件结果缓存，首次使用时按配置创建
+func getResultCache() *resultCache {
+	globalResultCacheOnce.Do(func() {
+		maxItems := defaultResultCacheMaxItems
+		maxSizeMB := defaultResultCacheMaxSizeMB
+		cacheTTL := defaultCacheTTL
+		if config.AppConfig != nil {
+			maxItems = config.AppConfig.PluginCacheMaxItems
+			maxSizeMB = config.AppConfig.PluginCacheMaxSizeMB
+			cacheTTL = time.Duration(config.AppConfig.AsyncCacheTTLHours) * time.Hour
+		}
+		globalResultCache = newResultCache(maxItems, int64(maxSizeMB)*1024*1024, cacheTTL+resultCacheGracePeriod)
+	})
+	return globalResultCache
+}
+
+// GetResultCacheStats 返回插件结果缓存的统计信息
+func GetResultCacheStats() ResultCacheStats {
+	return getResultCache().Stats()
+}
+
+// PurgeResultCache 删除指定插件和关键词的插件结果缓存，参数为空表示不限，返回删除数量
+func PurgeResultCache(pluginName, keyword string) int {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	return getResultCache().removeIf(func(key string) bool {
+		// 插件结果缓存键格式为"插件名:关键词"
+		name, kw, ok := strings.Cut(key, ":")
+		if !ok {
+			return false
+		}
+		if pluginName != "" && !strings.EqualFold(name, pluginName) {
+			return false
+		}
+		return keyword == "" || strings.ToLower(strings.TrimSpace(kw)) == keyword
+	})
+}
+
+// newResultCache 创建插件结果缓存
+func newResultCache(maxItems int, maxSize int64, retention time.Duration) *resultCache {
+	// 分片数量与ShardedMemoryCache一致：CPU核心数的2倍，限制在4-64之间
+	shardCount := runtime.NumCPU() * 2
+	if shardCount < 4 {
+		shardCount = 4
+	}
+	if shardCount > 64 {
+		shardCount = 64
+	}
+	shardCount = roundUpPowerOfTwo(shardCount)
+
+	itemsPerShard := maxItems / shardCount
+	if itemsPerShard < 1 {
+		itemsPerShard = 1
+	}
+	sizePerShard := maxSize / int64(shardCount)
+
+	c := &resultCache{
+		shards:        make([]*resultCacheShard, shardCount),
+		shardMask:     uint64(shardCount - 1),
+		maxItems:      maxItems,
+		maxSize:       maxSize,
+		itemsPerShard: itemsPerShard,
+		sizePerShard:  sizePerShard,
+		retention:     retention,
+	}
+	for i := range c.shards {
+		c.shards[i] = &resultCacheShard{
+			items:  make(map[string]*list.Element),
+			lru:    list.New(),
+			sketch: newFrequencySketch(itemsPerShard),
+		}
+	}
+	return c
+}
+
+// hashKey 计算键的哈希，低位用于选择分片，整体用于频率统计
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// getShard 获取键所在的分片
+func (c *resultCache) getShard(hash uint64) *resultCacheShard {
+	return c.shards[hash&c.shardMask]
+}
+
+// Load 读取缓存并记录一次访问（更新LRU位置、访问频率和访问计数）
+func (c *resultCache) Load(key string) (cachedResponse, bool) {
+	hash := hashKey(key)
+	shard := c.getShard(hash)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.sketch.increment(hash)
+
+	elem, ok := shard.items[key]
+	if !ok {
+		atomic.AddInt64(&c.misses, 1)
+		return cachedResponse{}, false
+	}
+
+	entry := elem.Value.(*resultCacheEntry)
+	if c.expired(entry) {
+		shard.remove(elem)
+		atomic.AddInt64(&c.expirations, 1)
+		atomic.AddInt64(&c.misses, 1)
+		return cachedResponse{}, false
+	}
+
+	entry.value.LastAccess = time.Now()
+	entry.value.AccessCount++
+	shard.lru.MoveToFront(elem)
+	atomic.AddInt64(&c.hits, 1)
+	return entry.value, true
+}
+
+// Peek 读取缓存但不记录访问，用于同一次搜索中合并旧结果等内部读取
+func (c *resultCache) Peek(key string) (cachedResponse, bool) {
+	hash := hashKey(key)
+	shard := c.getShard(hash)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	elem, ok := shard.items[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	entry := elem.Value.(*resultCacheEntry)
+	if c.expired(entry) {
+		return cachedResponse{}, false
+	}
+	return entry.value, true
+}
+
+// Store 写入缓存，分片已满时由TinyLFU决定是否接纳新键
+func (c *resultCache) Store(key string, value cachedResponse) {
+	hash := hashKey(key)
+	shard := c.getShard(hash)
+	size := estimateResponseSize(key, value)
+
+	// 单个条目超过分片容量时不缓存
+	if size > c.sizePerShard {
+		atomic.AddInt64(&c.rejections, 1)
+		return
+	}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	// 已存在的键直接更新
+	if elem, ok := shard.items[key]; ok {
+		entry := elem.Value.(*resultCacheEntry)
+		shard.size += size - entry.size
+		entry.value = value
+		entry.size = size
+		shard.lru.MoveToFront(elem)
+		c.evictOverflow(shard, elem)
+		return
+	}
+
+	// 新键：空间不足时与LRU尾部条目比较访问频率，频率不高于尾部条目则不接纳
+	if c.full(shard, size) {
+		if victim := shard.lru.Back(); victim != nil {
+			entry := victim.Value.(*resultCacheEntry)
+			if !c.expired(entry) && shard.sketch.estimate(hash) <= shard.sketch.estimate(entry.hash) {
+				atomic.AddInt64(&c.rejections, 1)
+				return
+			}
+		}
+	}
+
+	elem := shard.lru.PushFront(&resultCacheEntry{key: key, hash: hash, value: value, size: size})
+	shard.items[key] = elem
+	shard.size += size
+	c.evictOverflow(shard, elem)
+}
+
+// full 判断分片写入指定大小的条目后是否超出容量
+func (c *resultCache) full(shard *resultCacheShard, size int64) bool {
+	return len(shard.items) >= c.itemsPerShard || shard.size+size > c.sizePerShard
+}
+
+// evictOverflow 从LRU尾部淘汰条目直到分片不超出容量，keep为刚写入的条目
+func (c *resultCache) evictOverflow(shard *resultCacheShard, keep *list.Element) {
+	for len(shard.items) > c.itemsPerShard || shard.size > c.sizePerShard {
+		victim := shard.lru.Back()
+		if victim == nil || victim == keep {
+			return
+		}
+		if c.expired(victim.Value.(*resultCacheEntry)) {
+			atomic.AddInt64(&c.expirations, 1)
+		} else {
+			atomic.AddInt64(&c.evictions, 1)
+		}
+		shard.remove(victim)
+	}
+}
+
+// expired 判断条目是否超过保留时间，有单独有效期的条目按有效期加宽限期保留
+func (c *resultCache) expired(entry *resultCacheEntry) bool {
+	retention := c.retention
+	if entry.value.TTL > 0 {
+		retention = entry.value.TTL + resultCacheGracePeriod
+	}
+	return time.Since(entry.value.Timestamp) > retention
+}
+
+// remove 删除条目（调用方需持有分片锁）
+func (s *resultCacheShard) remove(elem *list.Element) {
+	entry := elem.Value.(*resultCacheEntry)
+	s.lru.Remove(elem)
+	delete(s.items, entry.key)
+	s.size -= entry.size
+}
+
+// removeIf 删除键满足条件的条目，返回删除数量
+func (c *resultCache) removeIf(match func(key string) bool) int {
+	removed := 0
+	for _, shard := range c.shards {
+		shard.mu.Lock()
+		for key, elem := range shard.items {
+			if match(key) {
+				shard.remove(elem)
+				removed++
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return removed
+}
+
+// Stats 返回缓存统计信息
+func (c *resultCache) Stats() ResultCacheStats {
+	stats := ResultCacheStats{
+		MaxItems:     c.maxItems,
+		MaxSizeBytes: c.maxSize,
+		Hits:         atomic.LoadInt64(&c.hits),
+		Misses:       atomic.LoadInt64(&c.misses),
+		Evictions:    atomic.LoadInt64(&c.evictions),
+		Rejections:   atomic.LoadInt64(&c.rejections),
+		Expirations:  atomic.LoadInt64(&c.expirations),
+	}
+	for _, shard := range c.shards {
+		shard.mu.Lock()
+		stats.Items += len(shard.items)
+		stats.SizeBytes += shard.size
+		shard.mu.Unlock()
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// estimateResponseSize 估算缓存条目占用的内存（字节）
+func estimateResponseSize(key string, value cachedResponse) int64 {
+	size := int64(len(key)) + 128
+	for _, r := range value.Results {
+		size += 256 + int64(len(r.MessageID)+len(r.UniqueID)+len(r.Channel)+len(r.Title)+len(r.Content))
+		for _, tag := range r.Tags {
+			size += int64(len(tag)) + 16
+		}
+		for _, image := range r.Images {
+			size += int64(len(image)) + 16
+		}
+		for _, link := range r.Links {
+			size += 64 + linkSize(link)
+		}
+	}
+	return size
+}
+
+// linkSize 估算链接中字符串占用的内存
+func linkSize(link model.Link) int64 {
+	return int64(len(link.Type) + len(link.URL) + len(link.Password) + len(link.WorkTitle))
+}
+
+// roundUpPowerOfTwo 向上取整到2的幂
+func roundUpPowerOfTwo(n int) int {
+	power := 1
+	for power < n {
+		power <<= 1
+	}
+	return power
+}
+
+// ============================================================
+// TinyLFU频率统计
+// ============================================================
+
+// frequencySketch Count-Min Sketch，用4行计数器近似统计键的访问频率
+// 计数累计增长到一定次数后全部减半，使旧的热点逐渐失效
+type frequencySketch struct {
+	counters  []uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// newFrequencySketch 根据分片容量创建频率统计
+func newFrequencySketch(capacity int) *frequencySketch {
+	width := roundUpPowerOfTwo(capacity * 2)
+	if width < 64 {
+		width = 64
+	}
+	return &frequencySketch{
+		counters: make([]uint8, 4*width),
+		mask:     uint64(width - 1),
+		resetAt:  width * sketchResetFactor,
+	}
+}
+
+// index 返回第row行中键对应的计数器位置（双重哈希）
+// 同一分片内键哈希的低位相同（分片按hash&shardMask选择），先重新混合再取位，避免计数器集中在少数位置
+func (s *frequencySketch) index(hash uint64, row int) uint64 {
+	hash = mixHash(hash)
+	h := hash + uint64(row)*((hash>>32)|1)
+	return uint64(row)*(s.mask+1) + (h & s.mask)
+}
+
+// mixHash 重新混合哈希的各个位（splitmix64的终结步骤）
+func mixHash(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// increment 记录一次访问
+func (s *frequencySketch) increment(hash uint64) {
+	added := false
+	for row := 0; row < 4; row++ {
+		i := s.index(hash, row)
+		if s.counters[i] < sketchCounterMax {
+			s.counters[i]++
+			added = true
+		}
+	}
+	if added {
+		s.additions++
+		if s.additions >= s.resetAt {
+			s.reset()
+		}
+	}
+}
+
+// estimate 返回键的估计访问频率
+func (s *frequencySketch) estimate(hash uint64) uint8 {
+	min := uint8(sketchCounterMax)
+	for row := 0; row < 4; row++ {
+		if v := s.counters[s.index(hash, row)]; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// reset 所有计数减半
+func (s *frequencySketch) reset() {
+	for i := range s.counters {
+		s.counters[i] >>= 1
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,26 @@
+package plugin
+
+import "testing"
+
+func TestFrequencySketchSpreadsShardKeys(t *testing.T) {
+	s := newFrequencySketch(32)
+
+	// 同一分片的键哈希低8位相同，计数器位置仍应分散
+	for row := 0; row < 4; row++ {
+		seen := make(map[uint64]bool)
+		for i := uint64(0); i < 64; i++ {
+			seen[s.index(i<<8|5, row)] = true
+		}
+		if len(seen) < 32 {
+			t.Errorf("第%d行64个键只落在 %d 个计数器上", row, len(seen))
+		}
+	}
+
+	hot, cold := uint64(1<<8|5), uint64(2<<8|5)
+	for i := 0; i < 5; i++ {
+		s.increment(hot)
+	}
+	if s.estimate(hot) != 5 || s.estimate(cold) != 0 {
+		t.Errorf("估计频率 hot=%d cold=%d，应为5和0", s.estimate(hot), s.estimate(cold))
+	}
+}
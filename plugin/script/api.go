@@ -0,0 +1,250 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/robertkrimen/otto"
+
+	"pansou/util"
+)
+
+// 脚本HTTP请求的默认User-Agent
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36"
+
+// installStaticAPI 注入与请求无关的API：html、util、log、console
+// 注意回调中必须使用call.Otto，脚本运行在模板的副本中
+func installStaticAPI(vm *otto.Otto, file string) {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+
+	logFunc := func(call otto.FunctionCall) otto.Value {
+		args := make([]string, 0, len(call.ArgumentList))
+		for _, arg := range call.ArgumentList {
+			args = append(args, arg.String())
+		}
+		fmt.Printf("[script:%s] %s\n", name, strings.Join(args, " "))
+		return otto.UndefinedValue()
+	}
+	vm.Set("log", logFunc)
+	console, _ := vm.Object(`({})`)
+	console.Set("log", logFunc)
+	vm.Set("console", console)
+
+	htmlAPI, _ := vm.Object(`({})`)
+	htmlAPI.Set("parse", func(call otto.FunctionCall) otto.Value {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(call.Argument(0).String()))
+		if err != nil {
+			panic(call.Otto.MakeCustomError("HTMLError", err.Error()))
+		}
+		return newSelection(call.Otto, doc.Selection)
+	})
+	vm.Set("html", htmlAPI)
+
+	utilAPI, _ := vm.Object(`({})`)
+	utilAPI.Set("extractNetDiskLinks", func(call otto.FunctionCall) otto.Value {
+		text := call.Argument(0).String()
+		links := util.ExtractNetDiskLinks(text)
+		// ExtractNetDiskLinks不含磁力和ed2k链接
+		links = append(links, util.AllPanLinksPattern.FindAllString(text, -1)...)
+		return newArray(call.Otto, dedupe(links))
+	})
+	utilAPI.Set("extractPassword", func(call otto.FunctionCall) otto.Value {
+		return toValue(call.Otto, util.ExtractPassword(call.Argument(0).String(), call.Argument(1).String()))
+	})
+	utilAPI.Set("linkType", func(call otto.FunctionCall) otto.Value {
+		return toValue(call.Otto, linkTypeOf(call.Argument(0).String()))
+	})
+	vm.Set("util", utilAPI)
+}
+
+// newHTTPAPI 创建绑定插件HTTP客户端的http对象
+// 请求受ctx控制，超过单次搜索的请求数上限后抛出异常
+func newHTTPAPI(ctx context.Context, vm *otto.Otto, client *http.Client) *otto.Object {
+	var requests int32
+
+	do := func(call otto.FunctionCall, method, target string, body io.Reader, options otto.Value) otto.Value {
+		if atomic.AddInt32(&requests, 1) > maxRequestsPerSearch {
+			panic(call.Otto.MakeCustomError("HTTPError", fmt.Sprintf("请求数超过上限%d", maxRequestsPerSearch)))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, target, body)
+		if err != nil {
+			panic(call.Otto.MakeCustomError("HTTPError", err.Error()))
+		}
+		req.Header.Set("User-Agent", defaultUserAgent)
+		if method == "POST" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if options.IsObject() {
+			if headers, _ := options.Object().Get("headers"); headers.IsObject() {
+				for _, key := range headers.Object().Keys() {
+					value, _ := headers.Object().Get(key)
+					req.Header.Set(key, value.String())
+				}
+			}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			panic(call.Otto.MakeCustomError("HTTPError", err.Error()))
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+		if err != nil {
+			panic(call.Otto.MakeCustomError("HTTPError", err.Error()))
+		}
+
+		headers := make(map[string]string, len(resp.Header))
+		for key := range resp.Header {
+			headers[strings.ToLower(key)] = resp.Header.Get(key)
+		}
+		result, _ := call.Otto.Object(`({})`)
+		result.Set("status", resp.StatusCode)
+		result.Set("url", resp.Request.URL.String())
+		result.Set("body", string(data))
+		headerValue, _ := toJSValue(call.Otto, headers)
+		result.Set("headers", headerValue)
+		return result.Value()
+	}
+
+	httpAPI, _ := vm.Object(`({})`)
+	// http.get(url, options)
+	httpAPI.Set("get", func(call otto.FunctionCall) otto.Value {
+		return do(call, "GET", call.Argument(0).String(), nil, call.Argument(1))
+	})
+	// http.post(url, body, options)，body为对象时按表单编码
+	httpAPI.Set("post", func(call otto.FunctionCall) otto.Value {
+		var body string
+		if arg := call.Argument(1); arg.IsObject() {
+			form := url.Values{}
+			for _, key := range arg.Object().Keys() {
+				value, _ := arg.Object().Get(key)
+				form.Set(key, value.String())
+			}
+			body = form.Encode()
+		} else if arg.IsDefined() {
+			body = arg.String()
+		}
+		return do(call, "POST", call.Argument(0).String(), strings.NewReader(body), call.Argument(2))
+	})
+	return httpAPI
+}
+
+// newSelection 将goquery选择集包装为脚本对象
+func newSelection(vm *otto.Otto, sel *goquery.Selection) otto.Value {
+	obj, _ := vm.Object(`({})`)
+	obj.Set("length", sel.Length())
+
+	obj.Set("find", func(call otto.FunctionCall) otto.Value {
+		return newSelection(call.Otto, sel.Find(call.Argument(0).String()))
+	})
+	obj.Set("first", func(call otto.FunctionCall) otto.Value {
+		return newSelection(call.Otto, sel.First())
+	})
+	obj.Set("last", func(call otto.FunctionCall) otto.Value {
+		return newSelection(call.Otto, sel.Last())
+	})
+	obj.Set("eq", func(call otto.FunctionCall) otto.Value {
+		i, _ := call.Argument(0).ToInteger()
+		return newSelection(call.Otto, sel.Eq(int(i)))
+	})
+	obj.Set("parent", func(call otto.FunctionCall) otto.Value {
+		return newSelection(call.Otto, sel.Parent())
+	})
+	obj.Set("children", func(call otto.FunctionCall) otto.Value {
+		return newSelection(call.Otto, sel.Children())
+	})
+	obj.Set("text", func(call otto.FunctionCall) otto.Value {
+		return toValue(call.Otto, strings.TrimSpace(sel.Text()))
+	})
+	obj.Set("html", func(call otto.FunctionCall) otto.Value {
+		html, _ := sel.Html()
+		return toValue(call.Otto, html)
+	})
+	obj.Set("attr", func(call otto.FunctionCall) otto.Value {
+		value, _ := sel.Attr(call.Argument(0).String())
+		return toValue(call.Otto, strings.TrimSpace(value))
+	})
+	// each(fn(i, item))，回调返回false时停止遍历
+	obj.Set("each", func(call otto.FunctionCall) otto.Value {
+		fn := call.Argument(0)
+		if !fn.IsFunction() {
+			return otto.UndefinedValue()
+		}
+		sel.EachWithBreak(func(i int, item *goquery.Selection) bool {
+			ret, err := fn.Call(otto.UndefinedValue(), i, newSelection(call.Otto, item))
+			if err != nil {
+				panic(err)
+			}
+			return !(ret.IsBoolean() && !mustBool(ret))
+		})
+		return otto.UndefinedValue()
+	})
+	// map(fn(i, item))，返回回调结果组成的数组
+	obj.Set("map", func(call otto.FunctionCall) otto.Value {
+		fn := call.Argument(0)
+		array, _ := call.Otto.Object(`([])`)
+		if fn.IsFunction() {
+			sel.Each(func(i int, item *goquery.Selection) {
+				ret, err := fn.Call(otto.UndefinedValue(), i, newSelection(call.Otto, item))
+				if err != nil {
+					panic(err)
+				}
+				array.Call("push", ret)
+			})
+		}
+		return array.Value()
+	})
+
+	return obj.Value()
+}
+
+// toValue 将Go值转换为脚本值，转换失败时返回undefined
+func toValue(vm *otto.Otto, v interface{}) otto.Value {
+	value, err := vm.ToValue(v)
+	if err != nil {
+		return otto.UndefinedValue()
+	}
+	return value
+}
+
+// newArray 将字符串切片转换为脚本数组
+func newArray(vm *otto.Otto, values []string) otto.Value {
+	array, _ := vm.Object(`([])`)
+	for _, v := range values {
+		array.Call("push", v)
+	}
+	return array.Value()
+}
+
+// mustBool 读取布尔值
+func mustBool(v otto.Value) bool {
+	b, _ := v.ToBoolean()
+	return b
+}
+
+// linkTypeOf 根据URL识别链接类型
+func linkTypeOf(linkURL string) string {
+	return util.GetLinkType(linkURL)
+}
+
+// dedupe 去除重复字符串并保持顺序
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,25 @@
+package script
+
+import (
+	"pansou/plugin"
+)
+
+// loader 脚本插件的目录加载器
+var loader = plugin.DirLoader[*Plugin]{
+	Tag:        "Script",
+	Kind:       "脚本插件",
+	Extensions: []string{".js"},
+	Load:       LoadFile,
+}
+
+// LoadDir 加载目录下所有.js脚本
+// 单个脚本加载失败不影响其他脚本，错误合并返回
+func LoadDir(dir string) ([]*Plugin, error) {
+	return loader.LoadDir(dir)
+}
+
+// RegisterDir 加载目录中的脚本并注册到全局插件表，返回注册的插件数量
+// 目录不存在时直接返回；与已注册插件同名的脚本会被跳过
+func RegisterDir(dir string) int {
+	return loader.RegisterDir(dir)
+}
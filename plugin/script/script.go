@@ -0,0 +1,330 @@
+package script
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robertkrimen/otto"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+	jsonutil "pansou/util/json"
+)
+
+// 脚本限制
+const (
+	defaultScriptTimeout = 20 * time.Second // 未配置时单次搜索的执行时间上限
+	loadTimeout          = 5 * time.Second  // 加载脚本（执行顶层代码）的时间上限
+	maxStackDepth        = 200              // 调用栈深度上限，防止无限递归耗尽Go栈
+	maxRequestsPerSearch = 30               // 单次搜索最多发出的HTTP请求数
+	maxResponseBodySize  = 5 << 20          // 单个HTTP响应体大小上限
+)
+
+// errHalt 脚本超时时由Interrupt触发的panic值，也是runWithTimeout超时时返回的错误
+var errHalt = errors.New("脚本执行超时")
+
+// scriptInfo 脚本中plugin对象声明的插件信息
+type scriptInfo struct {
+	Name              string `json:"name"`
+	DisplayName       string `json:"displayName"`
+	Description       string `json:"description"`
+	SiteURL           string `json:"siteURL"`
+	Category          string `json:"category"`
+	Priority          int    `json:"priority"`
+	SkipServiceFilter bool   `json:"skipServiceFilter"`
+}
+
+// scriptResult 脚本search函数返回的单条结果
+type scriptResult struct {
+	ID       interface{}  `json:"id"`
+	Title    string       `json:"title"`
+	Content  string       `json:"content"`
+	Datetime interface{}  `json:"datetime"` // 时间字符串或毫秒时间戳
+	Tags     []string     `json:"tags"`
+	Images   []string     `json:"images"`
+	Links    []scriptLink `json:"links"`
+}
+
+// scriptLink 脚本返回的链接
+type scriptLink struct {
+	Type      string `json:"type"` // 为空时根据URL识别
+	URL       string `json:"url"`
+	Password  string `json:"password"`
+	WorkTitle string `json:"work_title"`
+}
+
+// Plugin 由JavaScript脚本实现的搜索插件
+type Plugin struct {
+	*plugin.BaseAsyncPlugin
+	info scriptInfo
+	file string
+
+	// 已执行顶层代码的虚拟机模板，每次搜索复制一份独立运行
+	mu       sync.Mutex
+	template *otto.Otto
+}
+
+// LoadFile 加载脚本文件并创建插件
+func LoadFile(file string) (*Plugin, error) {
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := otto.New()
+	vm.SetStackDepthLimit(maxStackDepth)
+	installStaticAPI(vm, file)
+
+	if err := runWithTimeout(vm, loadTimeout, func() error {
+		_, err := vm.Run(string(source))
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("%s: 脚本执行失败: %w", file, err)
+	}
+
+	info, err := readInfo(vm)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if search, _ := vm.Get("search"); !search.IsFunction() {
+		return nil, fmt.Errorf("%s: 脚本缺少search函数", file)
+	}
+
+	return &Plugin{
+		BaseAsyncPlugin: plugin.NewBaseAsyncPluginWithFilter(info.Name, info.Priority, info.SkipServiceFilter),
+		info:            info,
+		file:            file,
+		template:        vm,
+	}, nil
+}
+
+// readInfo 读取并校验脚本声明的plugin对象
+func readInfo(vm *otto.Otto) (scriptInfo, error) {
+	var info scriptInfo
+
+	value, err := vm.Get("plugin")
+	if err != nil || !value.IsObject() {
+		return info, fmt.Errorf("脚本缺少plugin对象")
+	}
+	if err := exportJSON(vm, value, &info); err != nil {
+		return info, fmt.Errorf("解析plugin对象失败: %w", err)
+	}
+
+	info.Priority, err = plugin.ValidateInfo(info.Name, info.Priority, info.Category)
+	return info, err
+}
+
+// File 返回脚本文件路径
+func (p *Plugin) File() string {
+	return p.file
+}
+
+// Metadata 返回插件描述信息
+func (p *Plugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: p.info.DisplayName,
+		Description: p.info.Description,
+		SiteURL:     p.info.SiteURL,
+		Category:    p.info.Category,
+	}
+}
+
+// Search 执行搜索并返回结果（兼容性方法）
+func (p *Plugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	result, err := p.SearchWithResult(keyword, ext)
+	if err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// SearchWithResult 执行搜索并返回包含IsFinal标记的结果
+func (p *Plugin) SearchWithResult(keyword string, ext map[string]interface{}) (model.PluginSearchResult, error) {
+	return p.AsyncSearchWithResult(keyword, p.searchImpl, p.MainCacheKey, ext)
+}
+
+// searchImpl 在独立的虚拟机副本中调用脚本的search函数
+func (p *Plugin) searchImpl(client *http.Client, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	p.mu.Lock()
+	vm := p.template.Copy()
+	p.mu.Unlock()
+
+	timeout := scriptTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	vm.Set("http", newHTTPAPI(ctx, vm, client))
+
+	var results []scriptResult
+	err := runWithTimeout(vm, timeout, func() error {
+		extValue, err := toJSValue(vm, plugin.PublicExt(ext))
+		if err != nil {
+			return err
+		}
+		value, err := vm.Call("search", nil, keyword, extValue)
+		if err != nil {
+			return err
+		}
+		if value.IsUndefined() || value.IsNull() {
+			return nil
+		}
+		if value.Class() != "Array" {
+			return fmt.Errorf("search函数必须返回数组")
+		}
+		return exportJSON(vm, value, &results)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", p.Name(), err)
+	}
+
+	return p.convertResults(results), nil
+}
+
+// convertResults 将脚本结果转换为标准搜索结果，丢弃没有链接的结果
+func (p *Plugin) convertResults(results []scriptResult) []model.SearchResult {
+	converted := make([]model.SearchResult, 0, len(results))
+	for _, r := range results {
+		links := make([]model.Link, 0, len(r.Links))
+		for _, l := range r.Links {
+			if l.URL == "" {
+				continue
+			}
+			linkType := l.Type
+			if linkType == "" {
+				linkType = linkTypeOf(l.URL)
+			}
+			links = append(links, model.Link{
+				Type:      linkType,
+				URL:       l.URL,
+				Password:  l.Password,
+				WorkTitle: l.WorkTitle,
+			})
+		}
+		if r.Title == "" || len(links) == 0 {
+			continue
+		}
+
+		id := ""
+		if r.ID != nil {
+			id = fmt.Sprint(r.ID)
+		}
+		if id == "" {
+			id = fmt.Sprintf("%x", md5.Sum([]byte(links[0].URL)))[:12]
+		}
+
+		converted = append(converted, model.SearchResult{
+			UniqueID: fmt.Sprintf("%s-%s", p.Name(), id),
+			Title:    r.Title,
+			Content:  r.Content,
+			Datetime: parseDatetime(r.Datetime),
+			Tags:     r.Tags,
+			Images:   r.Images,
+			Links:    links,
+			Channel:  "", // 插件搜索结果必须为空字符串
+		})
+	}
+	return converted
+}
+
+// runWithTimeout 在时间限制内执行脚本
+// 超时后通过Interrupt持续打断执行，直到脚本真正退出。中断的panic被脚本中的try/catch
+// 捕获时，otto会把它转换为普通的脚本异常再抛出，因此以是否已超时判断结果，而不是依赖panic值
+func runWithTimeout(vm *otto.Otto, timeout time.Duration, fn func() error) (err error) {
+	vm.Interrupt = make(chan func(), 1)
+
+	// 0: 执行中，1: 已完成，2: 已超时
+	var state int32
+	var halt func()
+	halt = func() {
+		// 重新排队，保证被catch捕获后下一条语句再次中断
+		select {
+		case vm.Interrupt <- halt:
+		default:
+		}
+		panic(errHalt)
+	}
+	timer := time.AfterFunc(timeout, func() {
+		if !atomic.CompareAndSwapInt32(&state, 0, 2) {
+			return
+		}
+		select {
+		case vm.Interrupt <- halt:
+		default:
+		}
+	})
+
+	defer func() {
+		timer.Stop()
+		caught := recover()
+		if !atomic.CompareAndSwapInt32(&state, 0, 1) {
+			// 已超时：无论脚本以何种错误退出，都报告超时
+			err = errHalt
+			return
+		}
+		if caught != nil {
+			panic(caught)
+		}
+	}()
+
+	return fn()
+}
+
+// scriptTimeout 返回单次搜索的脚本执行时间上限
+func scriptTimeout() time.Duration {
+	if config.AppConfig != nil && config.AppConfig.PluginScriptTimeout > 0 {
+		return config.AppConfig.PluginScriptTimeout
+	}
+	return defaultScriptTimeout
+}
+
+// toJSValue 通过JSON将Go值转换为脚本中的普通对象
+func toJSValue(vm *otto.Otto, v interface{}) (otto.Value, error) {
+	data, err := jsonutil.MarshalString(v)
+	if err != nil {
+		return otto.UndefinedValue(), err
+	}
+	return vm.Call("JSON.parse", nil, data)
+}
+
+// exportJSON 通过JSON将脚本中的值转换为Go结构
+func exportJSON(vm *otto.Otto, value otto.Value, v interface{}) error {
+	data, err := vm.Call("JSON.stringify", nil, value)
+	if err != nil {
+		return err
+	}
+	return jsonutil.UnmarshalString(data.String(), v)
+}
+
+// parseDatetime 解析脚本返回的时间，无法解析时使用当前时间
+func parseDatetime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case json.Number:
+		// exportJSON解码数字时保留为json.Number
+		if ms, err := t.Int64(); err == nil && ms > 0 {
+			return time.UnixMilli(ms)
+		}
+	case float64:
+		if t > 0 {
+			return time.UnixMilli(int64(t))
+		}
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+			if parsed, err := time.ParseInLocation(layout, t, time.Local); err == nil {
+				return parsed
+			}
+		}
+		if ms, err := strconv.ParseInt(t, 10, 64); err == nil && ms > 0 {
+			return time.UnixMilli(ms)
+		}
+	}
+	return time.Now()
+}
@@ -0,0 +1,106 @@
+package script
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robertkrimen/otto"
+
+	"pansou/plugin/plugintest"
+)
+
+func TestSearch(t *testing.T) {
+	p, err := LoadFile("testdata/demo.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugintest.Run(t, p, plugintest.Case{
+		Name:    "search",
+		Keyword: "凡人修仙传",
+	})
+}
+
+func TestLoadFileValidation(t *testing.T) {
+	const search = "function search(keyword, ext) { return []; }\n"
+	cases := map[string]struct {
+		source  string
+		wantErr string
+	}{
+		"缺少plugin": {search, "缺少plugin对象"},
+		"插件名":      {`var plugin = {name: "Demo JS"};` + search, "插件名无效"},
+		"分类":       {`var plugin = {name: "demo", category: "music"};` + search, "未知的插件分类"},
+		"优先级":      {`var plugin = {name: "demo", priority: 0.5};` + search, "解析plugin对象失败"},
+		"缺少search": {`var plugin = {name: "demo"};`, "缺少search函数"},
+		"顶层代码出错":   {`var plugin = {name: "demo"}; undefinedFunc();` + search, "脚本执行失败"},
+		"优先级超出范围":  {`var plugin = {name: "demo", priority: 7};` + search, "优先级"},
+	}
+	dir := t.TempDir()
+	for name, c := range cases {
+		file := filepath.Join(dir, "case.js")
+		if err := os.WriteFile(file, []byte(c.source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFile(file); err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s: 错误 = %v，应包含 %q", name, err, c.wantErr)
+		}
+	}
+
+	file := filepath.Join(dir, "ok.js")
+	os.WriteFile(file, []byte(`var plugin = {name: "demo"};`+search), 0644)
+	p, err := LoadFile(file)
+	if err != nil || p.Priority() != 3 {
+		t.Errorf("未声明优先级时应使用默认值: %v", err)
+	}
+}
+
+func TestParseDatetime(t *testing.T) {
+	want := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range []interface{}{json.Number("1717200000000"), float64(1717200000000), "1717200000000", "2024-06-01T08:00:00+08:00"} {
+		if got := parseDatetime(v); !got.Equal(want) {
+			t.Errorf("parseDatetime(%#v) = %v", v, got)
+		}
+	}
+	if got := parseDatetime(nil); time.Since(got) > time.Minute {
+		t.Errorf("无法解析时应使用当前时间: %v", got)
+	}
+}
+
+func TestRunWithTimeoutHaltsCaughtInterrupt(t *testing.T) {
+	for _, source := range []string{
+		`while (true) {}`,
+		`while (true) { try { while (true) {} } catch (e) {} }`,
+		`while (true) { try { try { while (true) {} } catch (e) {} } catch (e) {} }`,
+		`function g() { try { while (true) {} } finally { return 1; } } while (true) { g(); }`,
+	} {
+		vm := otto.New()
+		done := make(chan error, 1)
+		go func() {
+			done <- runWithTimeout(vm, 50*time.Millisecond, func() error {
+				_, err := vm.Run(source)
+				return err
+			})
+		}()
+		select {
+		case err := <-done:
+			if err != errHalt {
+				t.Errorf("%s: 错误 = %v，应为超时", source, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: 超时后脚本仍在执行", source)
+		}
+	}
+
+	// 未超时的脚本正常返回自己的错误
+	vm := otto.New()
+	err := runWithTimeout(vm, time.Second, func() error {
+		_, err := vm.Run(`throw new Error("boom")`)
+		return err
+	})
+	if err == nil || err == errHalt || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("未超时时应返回脚本错误: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+var plugin = {
+  name: "demo_js",
+  displayName: "示例接口",
+  siteURL: "https://api.example.com",
+  category: "general"
+};
+
+function search(keyword, ext) {
+  var resp = http.get("https://api.example.com/search?q=" + encodeURIComponent(keyword) + "&page=1", {
+    headers: { Accept: "application/json" }
+  });
+  if (resp.status !== 200) {
+    throw new Error("请求返回状态码: " + resp.status);
+  }
+
+  var data = JSON.parse(resp.body);
+  return data.list.map(function (item) {
+    return {
+      id: item.id,
+      title: item.name,
+      datetime: item.time,
+      tags: item.tags,
+      links: [{ url: item.share, password: item.code }]
+    };
+  });
+}
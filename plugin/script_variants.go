@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"sync"
+
+	"pansou/model"
+	"pansou/util/textnorm"
+)
+
+// ScriptVariantSearcher 需要分别用简体和繁体关键词搜索的插件接口
+// 站点只按字面匹配标题时（如字幕组常用繁体发布），单一写法的关键词会漏掉另一种写法的资源
+type ScriptVariantSearcher interface {
+	AsyncSearchPlugin // 继承搜索插件接口
+
+	// SearchScriptVariants 返回true时，关键词的简体和繁体写法分别搜索并合并结果
+	SearchScriptVariants() bool
+}
+
+// SearchVariants 按插件需要用关键词的各个繁简写法调用search，结果按UniqueID去重合并
+// 插件未实现ScriptVariantSearcher或关键词没有繁简差异时只搜索一次；
+// 各写法并发搜索，耗时与单次搜索相当，合并时按写法顺序排列；部分写法失败时返回其余写法的结果
+func SearchVariants(p AsyncSearchPlugin, keyword string, search func(keyword string) ([]model.SearchResult, error)) ([]model.SearchResult, error) {
+	variants := []string{keyword}
+	if vs, ok := p.(ScriptVariantSearcher); ok && vs.SearchScriptVariants() {
+		variants = textnorm.Variants(keyword)
+	}
+	if len(variants) == 1 {
+		return search(keyword)
+	}
+
+	results := make([][]model.SearchResult, len(variants))
+	errs := make([]error, len(variants))
+	var wg sync.WaitGroup
+	for i, variant := range variants {
+		wg.Add(1)
+		go func(i int, variant string) {
+			defer wg.Done()
+			results[i], errs[i] = search(variant)
+		}(i, variant)
+	}
+	wg.Wait()
+
+	var merged []model.SearchResult
+	var lastErr error
+	succeeded := false
+	seen := make(map[string]bool)
+	for i := range variants {
+		if errs[i] != nil {
+			lastErr = errs[i]
+			continue
+		}
+		succeeded = true
+		for _, result := range results[i] {
+			if !seen[result.UniqueID] {
+				seen[result.UniqueID] = true
+				merged = append(merged, result)
+			}
+		}
+	}
+	if !succeeded {
+		return nil, lastErr
+	}
+	return merged, nil
+}
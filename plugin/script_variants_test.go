@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"pansou/model"
+)
+
+// variantPlugin 需要分别搜索繁简写法的测试插件
+type variantPlugin struct {
+	*BaseAsyncPlugin
+}
+
+func (p *variantPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	return nil, nil
+}
+
+func (p *variantPlugin) SearchScriptVariants() bool { return true }
+
+func TestSearchVariantsConcurrent(t *testing.T) {
+	p := &variantPlugin{BaseAsyncPlugin: NewBaseAsyncPlugin("variants", 3)}
+
+	// 每个写法都要等另一个写法开始后才返回，顺序执行时会一直等待
+	var mu sync.Mutex
+	var started []string
+	allStarted := make(chan struct{})
+	search := func(keyword string) ([]model.SearchResult, error) {
+		mu.Lock()
+		started = append(started, keyword)
+		if len(started) == 2 {
+			close(allStarted)
+		}
+		mu.Unlock()
+		select {
+		case <-allStarted:
+		case <-time.After(time.Second):
+			return nil, errors.New("写法未并发搜索")
+		}
+		if keyword == "凡人修仙传" {
+			return []model.SearchResult{{UniqueID: "shared"}, {UniqueID: "simplified"}}, nil
+		}
+		return []model.SearchResult{{UniqueID: "traditional"}, {UniqueID: "shared"}}, nil
+	}
+
+	results, err := SearchVariants(p, "凡人修仙传", search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.UniqueID)
+	}
+	if len(ids) != 3 || ids[0] != "shared" || ids[1] != "simplified" || ids[2] != "traditional" {
+		t.Errorf("合并结果应去重并按写法顺序排列: %v", ids)
+	}
+
+	// 部分写法失败时返回其余写法的结果，全部失败时返回错误
+	results, err = SearchVariants(p, "凡人修仙传", func(keyword string) ([]model.SearchResult, error) {
+		if keyword == "凡人修仙传" {
+			return nil, errors.New("boom")
+		}
+		return []model.SearchResult{{UniqueID: "traditional"}}, nil
+	})
+	if err != nil || len(results) != 1 {
+		t.Errorf("部分写法失败: results=%d err=%v", len(results), err)
+	}
+	if _, err := SearchVariants(p, "凡人修仙传", func(string) ([]model.SearchResult, error) { return nil, errors.New("boom") }); err == nil {
+		t.Error("全部写法失败时应返回错误")
+	}
+}
@@ -88,6 +88,20 @@ func init() {
 	plugin.RegisterGlobalPlugin(p)
 }
 
+// Metadata 返回插件描述信息
+func (p *SDSOPlugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: "SDSO",
+		Description: "按网盘类型分别搜索分享链接",
+		SiteURL:     "https://sdso.top",
+		CloudTypes:  []string{"baidu", "quark", "xunlei", "aliyun"},
+		Category:    plugin.CategoryGeneral,
+		ExtParams: []plugin.PluginExtParam{
+			{Key: "pages_per_type", Type: "int", Description: fmt.Sprintf("每种网盘类型搜索的页数，最大%d", MaxAllowedPagesPerType), Default: DefaultPagesPerType},
+		},
+	}
+}
+
 // Search 执行搜索并返回结果（兼容性方法）
 func (p *SDSOPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
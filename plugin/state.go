@@ -0,0 +1,265 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"pansou/config"
+)
+
+// 插件状态持久化文件名（位于CACHE_PATH下）
+const pluginStateFileName = "plugin_state.json"
+
+// 插件优先级的有效范围
+const (
+	MinPluginPriority = 1
+	MaxPluginPriority = 4
+)
+
+// 运行时优先级覆盖，键为插件名，值为优先级
+var priorityOverrides sync.Map
+
+// EffectivePriority 返回插件的实际优先级（运行时覆盖优先于插件自身的设置）
+func EffectivePriority(p AsyncSearchPlugin) int {
+	if priority, ok := priorityOverrides.Load(p.Name()); ok {
+		return priority.(int)
+	}
+	return p.Priority()
+}
+
+// pluginState 运行时插件调整，相对于ENABLED_PLUGINS的增量
+type pluginState struct {
+	Enabled    []string       `json:"enabled"`    // 运行时启用的插件
+	Disabled   []string       `json:"disabled"`   // 运行时禁用的插件
+	Priorities map[string]int `json:"priorities"` // 运行时调整的优先级
+}
+
+// OnChange 注册插件集合或优先级变化时的回调，回调参数为变化后的插件列表
+func (pm *PluginManager) OnChange(callback func([]AsyncSearchPlugin)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.onChange = append(pm.onChange, callback)
+}
+
+// notifyChange 调用所有变化回调（不持有锁）
+func (pm *PluginManager) notifyChange() {
+	pm.mu.RLock()
+	callbacks := make([]func([]AsyncSearchPlugin), len(pm.onChange))
+	copy(callbacks, pm.onChange)
+	pm.mu.RUnlock()
+
+	plugins := pm.GetPlugins()
+	for _, callback := range callbacks {
+		callback(plugins)
+	}
+}
+
+// IsEnabled 判断插件当前是否已启用
+func (pm *PluginManager) IsEnabled(name string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.indexOf(name) >= 0
+}
+
+// indexOf 返回插件在列表中的位置，不存在返回-1（调用方需持有锁）
+func (pm *PluginManager) indexOf(name string) int {
+	for i, p := range pm.plugins {
+		if p.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// EnablePlugin 在运行时启用已注册的插件
+func (pm *PluginManager) EnablePlugin(name string) error {
+	p, exists := GetPluginByName(name)
+	if !exists {
+		return fmt.Errorf("插件未注册: %s", name)
+	}
+	if pm.IsEnabled(name) {
+		return nil
+	}
+
+	// 首次启用时初始化，之后重新启用只恢复被禁用时暂停的资源
+	if err := pm.initialize(p); err != nil {
+		return fmt.Errorf("插件 %s 初始化失败: %w", name, err)
+	}
+	if suspendable, ok := p.(SuspendablePlugin); ok {
+		if err := suspendable.Resume(); err != nil {
+			return fmt.Errorf("插件 %s 恢复失败: %w", name, err)
+		}
+	}
+
+	pm.mu.Lock()
+	if pm.indexOf(name) < 0 {
+		pm.plugins = append(pm.plugins, p)
+	}
+	pm.state.Enabled = addName(pm.state.Enabled, name)
+	pm.state.Disabled = removeName(pm.state.Disabled, name)
+	err := pm.saveState()
+	pm.mu.Unlock()
+
+	fmt.Printf("[PluginManager] 插件 %s 已启用\n", name)
+	pm.notifyChange()
+	return err
+}
+
+// DisablePlugin 在运行时禁用插件
+func (pm *PluginManager) DisablePlugin(name string) error {
+	p, exists := GetPluginByName(name)
+	if !exists {
+		return fmt.Errorf("插件未注册: %s", name)
+	}
+
+	pm.mu.Lock()
+	if i := pm.indexOf(name); i >= 0 {
+		plugins := make([]AsyncSearchPlugin, 0, len(pm.plugins)-1)
+		plugins = append(plugins, pm.plugins[:i]...)
+		pm.plugins = append(plugins, pm.plugins[i+1:]...)
+	}
+	pm.state.Disabled = addName(pm.state.Disabled, name)
+	pm.state.Enabled = removeName(pm.state.Enabled, name)
+	err := pm.saveState()
+	pm.mu.Unlock()
+
+	// 已从插件集合中移除，新的搜索不会再使用该插件，停止其后台资源
+	if suspendable, ok := p.(SuspendablePlugin); ok {
+		suspendable.Suspend()
+	}
+
+	fmt.Printf("[PluginManager] 插件 %s 已禁用\n", name)
+	pm.notifyChange()
+	return err
+}
+
+// SetPluginPriority 在运行时调整插件优先级，priority为0表示恢复插件默认优先级
+func (pm *PluginManager) SetPluginPriority(name string, priority int) error {
+	if _, exists := GetPluginByName(name); !exists {
+		return fmt.Errorf("插件未注册: %s", name)
+	}
+	if priority != 0 && (priority < MinPluginPriority || priority > MaxPluginPriority) {
+		return fmt.Errorf("优先级必须在%d-%d之间", MinPluginPriority, MaxPluginPriority)
+	}
+
+	pm.mu.Lock()
+	if priority == 0 {
+		priorityOverrides.Delete(name)
+		delete(pm.state.Priorities, name)
+	} else {
+		priorityOverrides.Store(name, priority)
+		pm.state.Priorities[name] = priority
+	}
+	err := pm.saveState()
+	pm.mu.Unlock()
+
+	pm.notifyChange()
+	return err
+}
+
+// LoadState 加载持久化的插件状态，并应用到ENABLED_PLUGINS确定的插件集合上
+// 应在注册插件之后、创建搜索服务之前调用
+func (pm *PluginManager) LoadState() error {
+	if config.AppConfig == nil {
+		return nil
+	}
+	path := filepath.Join(config.AppConfig.CachePath, pluginStateFileName)
+
+	pm.mu.Lock()
+	pm.statePath = path
+	pm.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var state pluginState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("解析插件状态文件失败: %w", err)
+	}
+	if state.Priorities == nil {
+		state.Priorities = make(map[string]int)
+	}
+
+	for name, priority := range state.Priorities {
+		if priority >= MinPluginPriority && priority <= MaxPluginPriority {
+			priorityOverrides.Store(name, priority)
+		}
+	}
+
+	for _, name := range state.Enabled {
+		p, exists := GetPluginByName(name)
+		if !exists || pm.IsEnabled(name) {
+			continue
+		}
+		pm.RegisterPlugin(p)
+	}
+
+	pm.mu.Lock()
+	for _, name := range state.Disabled {
+		if i := pm.indexOf(name); i >= 0 {
+			pm.plugins = append(pm.plugins[:i], pm.plugins[i+1:]...)
+		}
+	}
+	pm.state = state
+	pm.mu.Unlock()
+
+	if len(state.Enabled)+len(state.Disabled)+len(state.Priorities) > 0 {
+		fmt.Printf("[PluginManager] 已加载插件运行时状态: 启用 %v, 禁用 %v, 优先级调整 %d 个\n",
+			state.Enabled, state.Disabled, len(state.Priorities))
+	}
+	return nil
+}
+
+// saveState 将插件状态写入文件（调用方需持有pm.mu）
+func (pm *PluginManager) saveState() error {
+	if pm.statePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(pm.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(pm.statePath), 0755); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := pm.statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, pm.statePath)
+}
+
+// addName 向有序名称列表中添加名称（去重）
+func addName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	names = append(names, name)
+	sort.Strings(names)
+	return names
+}
+
+// removeName 从名称列表中移除名称
+func removeName(names []string, name string) []string {
+	result := names[:0]
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+	return result
+}
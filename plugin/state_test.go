@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"testing"
+
+	"pansou/model"
+)
+
+// initPlugin 记录Initialize调用次数的测试插件
+type initPlugin struct {
+	*BaseAsyncPlugin
+	inits int
+}
+
+func (p *initPlugin) Initialize() error {
+	p.inits++
+	return nil
+}
+
+func (p *initPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	return nil, nil
+}
+
+func TestPluginStatePersistsAcrossRestart(t *testing.T) {
+	a := &initPlugin{BaseAsyncPlugin: NewBaseAsyncPlugin("statetest-a", DefaultPriority)}
+	b := &initPlugin{BaseAsyncPlugin: NewBaseAsyncPlugin("statetest-b", DefaultPriority)}
+	RegisterGlobalPlugin(a)
+	RegisterGlobalPlugin(b)
+	t.Cleanup(func() { priorityOverrides.Delete(b.Name()) })
+
+	// 按ENABLED_PLUGINS注册a，运行时启用b、禁用a并调整b的优先级
+	pm := NewPluginManager()
+	pm.RegisterPlugin(a)
+	if err := pm.LoadState(); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []func() error{
+		func() error { return pm.EnablePlugin(b.Name()) },
+		func() error { return pm.DisablePlugin(a.Name()) },
+		func() error { return pm.SetPluginPriority(b.Name(), 1) },
+		func() error { return pm.DisablePlugin(b.Name()) },
+		func() error { return pm.EnablePlugin(b.Name()) },
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.inits != 1 {
+		t.Errorf("重新启用不应再次初始化，Initialize调用 %d 次", b.inits)
+	}
+
+	// 重启后在ENABLED_PLUGINS的基础上重新应用保存的状态
+	priorityOverrides.Delete(b.Name())
+	restarted := NewPluginManager()
+	restarted.RegisterPlugin(a)
+	if err := restarted.LoadState(); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.IsEnabled(a.Name()) || !restarted.IsEnabled(b.Name()) {
+		t.Errorf("重启后启用状态错误: a=%v b=%v", restarted.IsEnabled(a.Name()), restarted.IsEnabled(b.Name()))
+	}
+	if priority := EffectivePriority(b); priority != 1 {
+		t.Errorf("重启后优先级应恢复为1，实际 %d", priority)
+	}
+}
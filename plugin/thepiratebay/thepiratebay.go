@@ -128,6 +128,25 @@ func startCacheCleaner() {
 	}
 }
 
+// Metadata 返回插件描述信息
+func (p *ThePirateBayPlugin) Metadata() plugin.PluginMetadata {
+	return plugin.PluginMetadata{
+		DisplayName: "The Pirate Bay",
+		Description: "海盗湾BT搜索，返回磁力链接",
+		SiteURL:     "https://thpibay.xyz",
+		CloudTypes:  []string{"magnet"},
+		Category:    plugin.CategoryMagnet,
+		ExtParams: []plugin.PluginExtParam{
+			{Key: "title_en", Type: "string", Description: "英文标题，存在时替代关键词进行搜索"},
+		},
+	}
+}
+
+// CacheTTLHint 建议缓存有效期：做种信息和新种子更新频繁，缓存30分钟
+func (p *ThePirateBayPlugin) CacheTTLHint(keyword string, results []model.SearchResult) time.Duration {
+	return 30 * time.Minute
+}
+
 // Search 执行搜索并返回结果（兼容性方法）
 func (p *ThePirateBayPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
 	result, err := p.SearchWithResult(keyword, ext)
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"sync"
+
+	"pansou/config"
+	"pansou/util/cache"
+)
+
+// 无结果缓存：TG和插件缓存都跳过空结果，错别字、随机字符串等无结果的关键词每次都会请求所有上游，
+// 这里按来源缓存键（即单个频道或插件）记录最近无结果的搜索，有效期内不再请求该来源，refresh=true时忽略
+var (
+	negativeResults     *cache.NegativeCache
+	negativeResultsOnce sync.Once
+)
+
+// getNegativeCache 返回无结果缓存，缓存或无结果缓存未启用时返回nil
+func getNegativeCache() *cache.NegativeCache {
+	negativeResultsOnce.Do(func() {
+		if config.AppConfig != nil && config.AppConfig.CacheEnabled && config.AppConfig.NegativeCacheTTL > 0 {
+			negativeResults = cache.NewNegativeCache(config.AppConfig.NegativeCacheTTL, config.AppConfig.NegativeCacheMaxItems)
+		}
+	})
+	return negativeResults
+}
+
+// knownEmpty 判断缓存键最近是否搜索无结果
+func knownEmpty(cacheKey string) bool {
+	nc := getNegativeCache()
+	return nc != nil && nc.Contains(cacheKey)
+}
+
+// recordSearchOutcome 根据搜索结果更新无结果缓存
+// succeeded为成功返回的频道或插件数，全部失败（超时、熔断、上游故障）时不记录，避免把故障当作无结果
+func recordSearchOutcome(cacheKey string, resultCount, succeeded int) {
+	nc := getNegativeCache()
+	if nc == nil {
+		return
+	}
+	switch {
+	case resultCount > 0:
+		nc.Remove(cacheKey)
+	case succeeded > 0:
+		nc.Add(cacheKey)
+	}
+}
+
+// forgetEmpty 删除缓存键的无结果记录，异步插件在后台得到结果时调用
+func forgetEmpty(cacheKey string) {
+	if nc := getNegativeCache(); nc != nil {
+		nc.Remove(cacheKey)
+	}
+}
+
+// GetNegativeCacheStats 返回无结果缓存统计，未启用时第二个返回值为false
+func GetNegativeCacheStats() (cache.NegativeCacheStats, bool) {
+	nc := getNegativeCache()
+	if nc == nil {
+		return cache.NegativeCacheStats{}, false
+	}
+	return nc.Stats(), true
+}
+
+// ClearNegativeCache 清空无结果缓存
+func ClearNegativeCache() {
+	if nc := getNegativeCache(); nc != nil {
+		nc.Clear()
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"pansou/config"
+	"pansou/model"
+	"pansou/util/alias"
+	"pansou/util/query"
+)
+
+// aliasExpansion 按别名词典扩展查询的结果
+type aliasExpansion struct {
+	original []string          // 用户输入的关键词，用于学习别名
+	aliases  []string          // 追加搜索的别名
+	titleEn  map[string]string // 上游关键词对应作品的英文名
+}
+
+// expandAliases 为查询中属于词典作品的关键词追加别名分支，别名分支与原关键词的结果一起过滤和合并
+func expandAliases(q *query.Query) aliasExpansion {
+	exp := aliasExpansion{original: q.Keywords(), titleEn: make(map[string]string)}
+	if config.AppConfig == nil || config.AppConfig.AliasMaxExpansions <= 0 {
+		return exp
+	}
+
+	dict := alias.Default()
+	for _, kw := range exp.original {
+		others, titleEn := dict.Expand(kw, config.AppConfig.AliasMaxExpansions)
+		if titleEn != "" {
+			exp.titleEn[kw] = titleEn
+		}
+		for _, name := range others {
+			if q.AddAlias(name) {
+				exp.aliases = append(exp.aliases, name)
+				if titleEn != "" {
+					exp.titleEn[name] = titleEn
+				}
+			}
+		}
+	}
+	return exp
+}
+
+// ext 返回关键词分支使用的扩展参数副本，请求未指定title_en时填入作品的英文名
+func (e aliasExpansion) ext(ext map[string]interface{}, keyword string) map[string]interface{} {
+	copied := copyExt(ext)
+	if titleEn := e.titleEn[keyword]; titleEn != "" {
+		if v, ok := copied["title_en"].(string); !ok || v == "" {
+			copied["title_en"] = titleEn
+		}
+	}
+	return copied
+}
+
+// learn 从搜索结果标题中学习用户输入关键词的英文别名
+func (e aliasExpansion) learn(results []model.SearchResult) {
+	if config.AppConfig == nil || !config.AppConfig.AliasLearnEnabled || len(results) == 0 {
+		return
+	}
+	titles := make([]string, len(results))
+	for i, result := range results {
+		titles[i] = result.Title
+	}
+	for _, kw := range e.original {
+		alias.Default().Learn(kw, titles)
+	}
+}
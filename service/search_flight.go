@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"pansou/model"
+)
+
+// 合并相同缓存键的并发搜索：热门关键词短时间内被大量请求时，
+// 只有第一个请求真正向频道和插件发起搜索，其余请求等待并共享结果
+var (
+	tgFlights     = newFlightGroup()
+	pluginFlights = newFlightGroup()
+)
+
+// FlightStats 搜索合并统计
+type FlightStats struct {
+	Searches  int64   `json:"searches"`   // 缓存未命中、需要实际搜索的请求数
+	Shared    int64   `json:"shared"`     // 复用了进行中搜索的请求数
+	InFlight  int     `json:"in_flight"`  // 当前进行中的搜索数
+	DedupRate float64 `json:"dedup_rate"` // Shared / Searches
+}
+
+// GetFlightStats 返回TG和插件搜索的合并统计
+func GetFlightStats() map[string]FlightStats {
+	return map[string]FlightStats{
+		"tg":     tgFlights.Stats(),
+		"plugin": pluginFlights.Stats(),
+	}
+}
+
+// flightGroup 按键合并进行中的搜索
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+
+	searches int64
+	shared   int64
+}
+
+// flightCall 一次进行中的搜索
+// 搜索使用独立的上下文，只有所有等待的请求都取消后才会取消，
+// 避免第一个请求断开导致其他请求一起失败
+type flightCall struct {
+	done    chan struct{}
+	results []model.SearchResult
+	pending bool // 来源仍在后台搜索，results不完整
+	err     error
+	refs    int
+	cancel  context.CancelFunc
+	abandon context.CancelFunc // 取消搜索的请求上下文，表示等待的请求都已断开
+}
+
+// newFlightGroup 创建搜索合并组
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// flightKey 返回合并使用的键，强制刷新的搜索不与普通搜索合并
+func flightKey(cacheKey string, forceRefresh bool) string {
+	if forceRefresh {
+		return "refresh:" + cacheKey
+	}
+	return cacheKey
+}
+
+// Do 执行搜索，已有相同键的搜索进行中时等待其结果
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) ([]model.SearchResult, bool, error)) ([]model.SearchResult, bool, error) {
+	atomic.AddInt64(&g.searches, 1)
+
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if ok {
+		call.refs++
+		atomic.AddInt64(&g.shared, 1)
+	} else {
+		// 搜索被多个请求共享，不继承首个请求上下文的取消和值，
+		// 只携带代表所有等待请求的请求上下文，用于区分客户端断开和批量超时
+		reqCtx, abandon := context.WithCancel(context.Background())
+		flightCtx, cancel := context.WithCancel(context.WithValue(context.Background(), requestCtxKey{}, reqCtx))
+		call = &flightCall{done: make(chan struct{}), refs: 1, cancel: cancel, abandon: abandon}
+		g.calls[key] = call
+		go g.run(key, call, flightCtx, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		// 结果被多个请求共享，返回副本避免调用方追加时互相覆盖
+		results := make([]model.SearchResult, len(call.results))
+		copy(results, call.results)
+		return results, call.pending, call.err
+	case <-ctx.Done():
+		g.leave(key, call, requestCanceled(ctx))
+		return nil, false, ctx.Err()
+	}
+}
+
+// run 执行搜索并唤醒所有等待者
+func (g *flightGroup) run(key string, call *flightCall, ctx context.Context, fn func(ctx context.Context) ([]model.SearchResult, bool, error)) {
+	defer call.abandon()
+	defer call.cancel()
+
+	call.results, call.pending, call.err = fn(ctx)
+
+	g.mu.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	close(call.done)
+}
+
+// leave 等待者取消，最后一个等待者离开时取消搜索
+// disconnected表示该等待者的请求已断开，最后一个等待者因断开离开时同时取消搜索的请求上下文
+func (g *flightGroup) leave(key string, call *flightCall, disconnected bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	call.refs--
+	if call.refs > 0 {
+		return
+	}
+	// 之后的相同请求重新发起搜索，而不是等待已取消的搜索
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	if disconnected {
+		call.abandon()
+	}
+	call.cancel()
+}
+
+// Stats 返回合并统计
+func (g *flightGroup) Stats() FlightStats {
+	g.mu.Lock()
+	inFlight := len(g.calls)
+	g.mu.Unlock()
+
+	stats := FlightStats{
+		Searches: atomic.LoadInt64(&g.searches),
+		Shared:   atomic.LoadInt64(&g.shared),
+		InFlight: inFlight,
+	}
+	if stats.Searches > 0 {
+		stats.DedupRate = float64(stats.Shared) / float64(stats.Searches)
+	}
+	return stats
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pansou/model"
+)
+
+// blockingFlight 返回一个阻塞到release关闭的搜索函数，started在搜索开始时关闭
+func blockingFlight(calls *int32, started, release chan struct{}, searchCtx *context.Context) func(ctx context.Context) ([]model.SearchResult, bool, error) {
+	return func(ctx context.Context) ([]model.SearchResult, bool, error) {
+		atomic.AddInt32(calls, 1)
+		*searchCtx = ctx
+		close(started)
+		select {
+		case <-release:
+			return []model.SearchResult{{UniqueID: "r1"}}, false, nil
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+func TestFlightGroupCoalesces(t *testing.T) {
+	g := newFlightGroup()
+	var calls int32
+	var searchCtx context.Context
+	started, release := make(chan struct{}), make(chan struct{})
+	fn := blockingFlight(&calls, started, release, &searchCtx)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, _, err := g.Do(context.Background(), "k", fn)
+			if err == nil && len(results) != 1 {
+				t.Errorf("结果数 %d", len(results))
+			}
+			errs <- err
+		}()
+	}
+	<-started
+	// 等待其余请求加入
+	deadline := time.Now().Add(time.Second)
+	for g.Stats().Searches < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Do: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("相同键的并发搜索应只执行一次，实际 %d 次", calls)
+	}
+	if stats := g.Stats(); stats.Shared != 4 || stats.InFlight != 0 {
+		t.Errorf("统计错误: %+v", stats)
+	}
+}
+
+// flightCtxKey 测试用的请求上下文值
+type flightCtxKey struct{}
+
+func TestFlightGroupLeaderCancels(t *testing.T) {
+	g := newFlightGroup()
+	var calls int32
+	var searchCtx context.Context
+	started, release := make(chan struct{}), make(chan struct{})
+	fn := blockingFlight(&calls, started, release, &searchCtx)
+
+	leaderCtx, cancelLeader := context.WithCancel(context.WithValue(context.Background(), flightCtxKey{}, "leader"))
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do(withRequestContext(leaderCtx), "k", fn)
+		leaderErr <- err
+	}()
+	<-started
+
+	waiterDone := make(chan error, 1)
+	go func() {
+		results, _, err := g.Do(context.Background(), "k", fn)
+		if err == nil && len(results) != 1 {
+			err = context.Canceled
+		}
+		waiterDone <- err
+	}()
+	for g.Stats().Shared < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 首个请求断开，搜索继续为其他请求执行
+	cancelLeader()
+	if err := <-leaderErr; err != context.Canceled {
+		t.Errorf("首个请求应返回取消错误: %v", err)
+	}
+	if searchCtx.Err() != nil {
+		t.Fatal("首个请求断开不应取消共享的搜索")
+	}
+	// 共享的搜索不携带首个请求的值，也不继承其请求上下文
+	if searchCtx.Value(flightCtxKey{}) != nil || requestCanceled(searchCtx) {
+		t.Error("搜索上下文不应保留首个请求上下文中的值")
+	}
+
+	close(release)
+	if err := <-waiterDone; err != nil {
+		t.Errorf("其他请求应得到结果: %v", err)
+	}
+}
+
+func TestFlightGroupAllWaitersCancel(t *testing.T) {
+	g := newFlightGroup()
+	var calls int32
+	var searchCtx context.Context
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	fn := blockingFlight(&calls, started, release, &searchCtx)
+
+	// 一个请求批量超时离开，搜索仍为剩下的请求执行
+	timeoutCtx, timeout := context.WithCancel(withRequestContext(context.Background()))
+	reqCtx, disconnect := context.WithCancel(context.Background())
+	done := make(chan struct{}, 2)
+	go func() { g.Do(timeoutCtx, "k", fn); done <- struct{}{} }()
+	<-started
+	go func() { g.Do(withRequestContext(reqCtx), "k", fn); done <- struct{}{} }()
+	for g.Stats().Shared < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	timeout()
+	<-done
+	if searchCtx.Err() != nil {
+		t.Fatal("仍有请求等待时不应取消搜索")
+	}
+
+	// 最后一个请求断开，搜索取消，并可识别为客户端断开而不是超时
+	disconnect()
+	<-done
+	if searchCtx.Err() == nil || !requestCanceled(searchCtx) {
+		t.Errorf("所有请求断开后应取消搜索并标记为断开: err=%v canceled=%v", searchCtx.Err(), requestCanceled(searchCtx))
+	}
+	if g.Stats().InFlight != 0 {
+		t.Error("取消的搜索应从进行中列表移除")
+	}
+}
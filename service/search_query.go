@@ -0,0 +1,151 @@
+package service
+
+import (
+	"strings"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+	"pansou/util/mediainfo"
+	"pansou/util/query"
+)
+
+// applyQueryScope 按查询中的source:、plugin:、channel:限定缩小搜索范围，不请求结果会被全部过滤掉的来源
+// plugin:和channel:指定的插件和频道替换请求参数中的列表
+func applyQueryScope(q *query.Query, sourceType string, channels, plugins []string) (string, []string, []string) {
+	if sourceType == "all" {
+		switch {
+		case len(q.Sources.Include) == 1:
+			sourceType = q.Sources.Include[0]
+		case len(q.Sources.Exclude) == 1 && q.Sources.Exclude[0] == query.SourceTG:
+			sourceType = query.SourcePlugin
+		case len(q.Sources.Exclude) == 1 && q.Sources.Exclude[0] == query.SourcePlugin:
+			sourceType = query.SourceTG
+		case len(q.Plugins.Include) > 0 && len(q.Channels.Include) == 0:
+			sourceType = query.SourcePlugin
+		case len(q.Channels.Include) > 0 && len(q.Plugins.Include) == 0:
+			sourceType = query.SourceTG
+		}
+	}
+
+	if len(q.Channels.Include) > 0 {
+		channels = q.Channels.Include
+	}
+	if len(q.Plugins.Include) > 0 {
+		plugins = q.Plugins.Include
+	}
+	return sourceType, channels, plugins
+}
+
+// resultOrigin 返回结果的来源类型和频道名或插件名
+func resultOrigin(result model.SearchResult) (string, string) {
+	source := getResultSource(result)
+	kind, name, _ := strings.Cut(source, ":")
+	return kind, name
+}
+
+// skipsServiceFilter 检查结果所属插件是否要求跳过Service层的关键词过滤
+func skipsServiceFilter(result model.SearchResult) bool {
+	kind, name := resultOrigin(result)
+	if kind != query.SourcePlugin {
+		return false
+	}
+	if p, exists := plugin.GetPluginByName(name); exists {
+		return p.SkipServiceFilter()
+	}
+	return false
+}
+
+// pluginFiltered 返回结果是否已由插件自行过滤，无需Service层再按关键词过滤
+// 插件过滤时按模糊匹配保留并记录相似度，严格匹配的请求丢弃其中未完全匹配的结果
+func pluginFiltered(q *query.Query, result model.SearchResult) bool {
+	if !skipsServiceFilter(result) {
+		return false
+	}
+	return result.Score == 0 || result.Score >= q.Threshold()
+}
+
+// applyMatchMode 按请求的匹配模式设置查询，mode为空时使用配置的默认模式
+func applyMatchMode(q *query.Query, mode string) {
+	if mode == "" && config.AppConfig != nil {
+		mode = config.AppConfig.MatchMode
+	}
+	if mode == query.MatchFuzzy && config.AppConfig != nil {
+		q.SetFuzzy(config.AppConfig.FuzzyThreshold)
+	}
+}
+
+// linkDocument 构造参与匹配的链接文档，text为链接对应的标题等文本
+func linkDocument(result model.SearchResult, link model.Link, text string) query.Document {
+	kind, name := resultOrigin(result)
+	datetime := result.Datetime
+	if !link.Datetime.IsZero() {
+		datetime = link.Datetime
+	}
+	return query.Document{Text: text, Type: link.Type, Source: kind, Name: name, Datetime: datetime}
+}
+
+// scoreLink 返回链接的匹配分数和是否保留，title为链接对应的作品标题
+// skipText时只要求满足字段限定，分数仍按标题计算，用于排序
+func scoreLink(q *query.Query, result model.SearchResult, link model.Link, title string, skipText bool) (float64, bool) {
+	doc := linkDocument(result, link, title)
+	if !q.MatchFields(doc) {
+		return 0, false
+	}
+	score := q.ScoreText(title)
+	return score, skipText || score >= q.Threshold()
+}
+
+// filterResultsByQuery 过滤Results中的结果，只保留满足查询的链接，没有链接满足的结果被移除
+// 标题和消息内容任一满足关键词即可，与上游搜索的匹配范围一致；保留的结果记录匹配分数和标题中的媒体属性
+func filterResultsByQuery(results []model.SearchResult, q *query.Query) []model.SearchResult {
+	filtered := make([]model.SearchResult, 0, len(results))
+	for _, result := range results {
+		text := result.Title + "\n" + result.Content
+		score := q.ScoreText(text)
+		if score < q.Threshold() && !pluginFiltered(q, result) {
+			continue
+		}
+
+		links := make([]model.Link, 0, len(result.Links))
+		for _, link := range result.Links {
+			if q.MatchFields(linkDocument(result, link, text)) {
+				links = append(links, link)
+			}
+		}
+		if len(links) > 0 {
+			result.Links = links
+			result.Score = score
+			// 媒体属性只从标题解析：正文常介绍多个资源，解析出的属性无法对应到具体链接
+			result.Media = mediainfo.Parse(result.Title)
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// mergeSourceStatuses 合并多个关键词分支中同一来源的状态，结果数累加，有结果的状态优先
+func mergeSourceStatuses(statuses []model.SourceStatus, more []model.SourceStatus) []model.SourceStatus {
+	index := make(map[string]int, len(statuses))
+	for i, st := range statuses {
+		index[st.Source] = i
+	}
+	for _, st := range more {
+		i, exists := index[st.Source]
+		if !exists {
+			index[st.Source] = len(statuses)
+			statuses = append(statuses, st)
+			continue
+		}
+		merged := &statuses[i]
+		if merged.Count == 0 && st.Count > 0 {
+			merged.Status = st.Status
+		}
+		merged.Count += st.Count
+		// 保留较早的获取时间，反映其中最旧的数据
+		if st.UpdatedAt != nil && (merged.UpdatedAt == nil || st.UpdatedAt.Before(*merged.UpdatedAt)) {
+			merged.UpdatedAt = st.UpdatedAt
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+	"pansou/util/query"
+)
+
+func TestQueryFiltersMergedAndResults(t *testing.T) {
+	now := time.Now()
+	results := []model.SearchResult{
+		{
+			UniqueID: "tgsearchers3-1", Channel: "tgsearchers3", Datetime: now,
+			Title: "速度与激情7 4K", Content: "速度与激情7 4K",
+			Links: []model.Link{
+				{Type: "quark", URL: "https://pan.quark.cn/s/a"},
+				{Type: "baidu", URL: "https://pan.baidu.com/s/b"},
+			},
+		},
+		{
+			UniqueID: "labi-2", Datetime: now,
+			Title: "速度与激情7 枪版",
+			Links: []model.Link{{Type: "quark", URL: "https://pan.quark.cn/s/c"}},
+		},
+	}
+
+	q, err := query.Parse("速度与激情 -枪版 type:quark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := mergeResultsByType(results, q, nil)
+	if len(merged) != 1 || len(merged["quark"]) != 1 || merged["quark"][0].URL != "https://pan.quark.cn/s/a" {
+		t.Errorf("merged_by_type过滤错误: %+v", merged)
+	}
+	if media := merged["quark"][0].Media; media == nil || media.Resolution != "2160p" {
+		t.Errorf("合并链接应带有标题中的媒体属性: %+v", media)
+	}
+
+	filtered := filterResultsByQuery(results, q)
+	if len(filtered) != 1 || len(filtered[0].Links) != 1 || filtered[0].Links[0].Type != "quark" {
+		t.Errorf("results过滤错误: %+v", filtered)
+	}
+
+	q, _ = query.Parse("速度与激情 source:plugin")
+	if merged := mergeResultsByType(results, q, nil); len(merged["quark"]) != 1 || merged["quark"][0].Source != "plugin:labi" {
+		t.Errorf("来源过滤错误: %+v", merged)
+	}
+}
+
+func TestFuzzyMatchScores(t *testing.T) {
+	now := time.Now()
+	results := []model.SearchResult{
+		{
+			UniqueID: "tgsearchers3-1", Channel: "tgsearchers3", Datetime: now,
+			Title: "星际穿梭 1080p", Content: "星际穿梭 1080p",
+			Links: []model.Link{{Type: "quark", URL: "https://pan.quark.cn/s/a"}},
+		},
+		{
+			UniqueID: "tgsearchers3-2", Channel: "tgsearchers3", Datetime: now,
+			Title: "星际穿越 4K", Content: "星际穿越 4K",
+			Links: []model.Link{{Type: "quark", URL: "https://pan.quark.cn/s/b"}},
+		},
+	}
+
+	q, _ := query.Parse("星际穿越")
+	if merged := mergeResultsByType(results, q, nil); len(merged["quark"]) != 1 || merged["quark"][0].Score != 1 {
+		t.Errorf("严格匹配应只保留完整包含关键词的链接: %+v", merged)
+	}
+
+	q.SetFuzzy(0.75)
+	merged := mergeResultsByType(results, q, nil)
+	if len(merged["quark"]) != 2 {
+		t.Fatalf("模糊匹配应保留错一个字的标题: %+v", merged)
+	}
+	if merged["quark"][0].URL != "https://pan.quark.cn/s/b" || merged["quark"][1].Score != 0.75 {
+		t.Errorf("模糊匹配应按分数排序: %+v", merged)
+	}
+
+	filtered := filterResultsByQuery(results, q)
+	if len(filtered) != 2 || filtered[0].Score != 0.75 || filtered[1].Score != 1 {
+		t.Errorf("results应记录匹配分数: %+v", filtered)
+	}
+}
+
+func TestApplyQueryScope(t *testing.T) {
+	q, _ := query.Parse("凡人 plugin:labi,hdr4k")
+	sourceType, channels, plugins := applyQueryScope(q, "all", []string{"tgsearchers3"}, nil)
+	if sourceType != "plugin" || len(channels) != 1 || len(plugins) != 2 {
+		t.Errorf("plugin:限定: src=%s channels=%v plugins=%v", sourceType, channels, plugins)
+	}
+
+	q, _ = query.Parse("凡人 -source:plugin channel:yunpanx")
+	sourceType, channels, _ = applyQueryScope(q, "all", []string{"tgsearchers3"}, nil)
+	if sourceType != "tg" || len(channels) != 1 || channels[0] != "yunpanx" {
+		t.Errorf("channel:限定: src=%s channels=%v", sourceType, channels)
+	}
+}
+
+func TestPluginFilterKeepsFuzzyCandidates(t *testing.T) {
+	saved := config.AppConfig
+	defer func() { config.AppConfig = saved }()
+	config.AppConfig = &config.Config{MatchMode: query.MatchStrict, FuzzyThreshold: 0.75}
+
+	results := []model.SearchResult{
+		{UniqueID: "labi-1", Title: "星际穿梭 1080p", Links: []model.Link{{Type: "quark", URL: "https://pan.quark.cn/s/a"}}},
+		{UniqueID: "labi-2", Title: "星际穿越 4K", Links: []model.Link{{Type: "quark", URL: "https://pan.quark.cn/s/b"}}},
+		{UniqueID: "labi-3", Title: "流浪地球", Links: []model.Link{{Type: "quark", URL: "https://pan.quark.cn/s/c"}}},
+	}
+
+	// 插件层的过滤结果会被缓存，必须保留模糊匹配能用到的结果
+	cached := plugin.FilterResultsByKeyword(results, "星际穿越")
+	if len(cached) != 2 {
+		t.Fatalf("插件过滤应保留相似标题: %+v", cached)
+	}
+
+	q, _ := query.Parse("星际穿越")
+	applyMatchMode(q, "")
+	if filtered := filterResultsByQuery(cached, q); len(filtered) != 1 {
+		t.Errorf("严格匹配应由Service层过滤: %+v", filtered)
+	}
+	applyMatchMode(q, query.MatchFuzzy)
+	if filtered := filterResultsByQuery(cached, q); len(filtered) != 2 {
+		t.Errorf("模糊匹配应能使用插件缓存的结果: %+v", filtered)
+	}
+}
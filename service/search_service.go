@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -17,7 +19,8 @@ import (
 	"pansou/plugin"
 	"pansou/util"
 	"pansou/util/cache"
-	"pansou/util/pool"
+	"pansou/util/mediainfo"
+	"pansou/util/query"
 )
 
 // normalizeUrl 标准化URL，将URL编码的中文部分解码为中文，用于去重
@@ -217,6 +220,18 @@ func NewSearchService(pluginManager *plugin.PluginManager) *SearchService {
 	// 将主缓存注入到异步插件中
 	injectMainCacheToAsyncPlugins(pluginManager, enhancedTwoLevelCache)
 
+	// 插件集合在运行时变化时，为新插件注入主缓存并刷新优先级
+	// 缓存按单个插件保存，插件集合变化不影响缓存键
+	if pluginManager != nil {
+		pluginManager.OnChange(func(plugins []plugin.AsyncSearchPlugin) {
+			injectMainCacheToAsyncPlugins(pluginManager, enhancedTwoLevelCache)
+			pluginLevelCache.Range(func(key, _ interface{}) bool {
+				pluginLevelCache.Delete(key)
+				return true
+			})
+		})
+	}
+
 	// 确保缓存写入管理器设置了主缓存更新函数
 	if globalCacheWriteManager != nil && enhancedTwoLevelCache != nil {
 		globalCacheWriteManager.SetMainCacheUpdater(func(key string, data []byte, ttl time.Duration) error {
@@ -244,16 +259,23 @@ func injectMainCacheToAsyncPlugins(pluginManager *plugin.PluginManager, mainCach
 
 	// 创建缓存更新函数（支持IsFinal参数）- 接收原始数据并与现有缓存合并
 	cacheUpdater := func(key string, newResults []model.SearchResult, ttl time.Duration, isFinal bool, keyword string, pluginName string) error {
+		// 通知正在等待该缓存键的流式搜索（包括后台迟到的结果）；
+		// 搜索是否完成由fetchPlugin在转入后台的搜索完成时另行通知
+		publishPluginUpdate(key, pluginName, newResults, false)
+
 		// 优化：如果新结果为空，跳过缓存更新（避免无效操作）
 		if len(newResults) == 0 {
 			return nil
 		}
 
-		// 获取现有缓存数据进行合并
+		// 后台得到了结果，之前记录的无结果不再成立
+		forgetEmpty(key)
+
+		// 缓存键只对应这一个插件，最终结果直接替换；中间结果与现有缓存合并，避免覆盖已有的完整结果
 		var finalResults []model.SearchResult
-		if existingData, hit, err := mainCache.Get(key); err == nil && hit {
+		if existingData, hit, err := mainCache.Get(key); err == nil && hit && !isFinal {
 			var existingResults []model.SearchResult
-			if err := mainCache.GetSerializer().Deserialize(existingData, &existingResults); err == nil {
+			if err := mainCache.Decode(key, existingData, &existingResults); err == nil {
 				// 合并新旧结果，去重保留最完整的数据
 				finalResults = mergeSearchResults(existingResults, newResults)
 				if config.AppConfig != nil && config.AppConfig.AsyncLogEnabled {
@@ -275,14 +297,14 @@ func injectMainCacheToAsyncPlugins(pluginManager *plugin.PluginManager, mainCach
 				}
 			}
 		} else {
-			// 无现有缓存，直接使用新结果
+			// 最终结果或无现有缓存，直接使用新结果
 			finalResults = newResults
 			if config.AppConfig != nil && config.AppConfig.AsyncLogEnabled {
 				displayKey := key[:8] + "..."
 				if keyword != "" {
-					fmt.Printf("[异步插件 %s] 初始缓存创建: %s(关键词:%s) | 结果数: %d\n", pluginName, displayKey, keyword, len(newResults))
+					fmt.Printf("[异步插件 %s] 缓存写入: %s(关键词:%s) | 结果数: %d\n", pluginName, displayKey, keyword, len(newResults))
 				} else {
-					fmt.Printf("[异步插件 %s] 初始缓存创建: %s | 结果数: %d\n", pluginName, key, len(newResults))
+					fmt.Printf("[异步插件 %s] 缓存写入: %s | 结果数: %d\n", pluginName, key, len(newResults))
 				}
 			}
 		}
@@ -350,115 +372,99 @@ func injectMainCacheToAsyncPlugins(pluginManager *plugin.PluginManager, mainCach
 }
 
 // Search 执行搜索
-func (s *SearchService) Search(keyword string, channels []string, concurrency int, forceRefresh bool, resultType string, sourceType string, plugins []string, cloudTypes []string, ext map[string]interface{}) (model.SearchResponse, error) {
+func (s *SearchService) Search(keyword string, channels []string, concurrency int, forceRefresh bool, resultType string, sourceType string, plugins []string, cloudTypes []string, ext map[string]interface{}, matchMode string) (model.SearchResponse, error) {
+	return s.SearchWithContext(context.Background(), keyword, channels, concurrency, forceRefresh, resultType, sourceType, plugins, cloudTypes, ext, matchMode)
+}
+
+// SearchWithContext 执行搜索，ctx取消（如客户端断开）时中止尚未完成的频道和插件请求
+// matchMode为strict或fuzzy，为空时使用配置的默认匹配模式
+func (s *SearchService) SearchWithContext(ctx context.Context, keyword string, channels []string, concurrency int, forceRefresh bool, resultType string, sourceType string, plugins []string, cloudTypes []string, ext map[string]interface{}, matchMode string) (model.SearchResponse, error) {
 	// 确保ext不为nil
 	if ext == nil {
 		ext = make(map[string]interface{})
 	}
 
+	// 解析查询语法，上游只收到普通关键词
+	q, err := query.Parse(keyword)
+	if err != nil {
+		return model.SearchResponse{}, err
+	}
+	applyMatchMode(q, matchMode)
+
 	// 参数预处理
 	// 源类型标准化
 	if sourceType == "" {
 		sourceType = "all"
 	}
+	sourceType, channels, plugins = applyQueryScope(q, sourceType, channels, plugins)
 
-	// 插件参数规范化处理
-	if sourceType == "tg" {
-		// 对于只搜索Telegram的请求，忽略插件参数
-		plugins = nil
-	} else if sourceType == "all" || sourceType == "plugin" {
-		// 检查是否为空列表或只包含空字符串
-		if len(plugins) == 0 {
-			plugins = nil
-		} else {
-			// 检查是否有非空元素
-			hasNonEmpty := false
-			for _, p := range plugins {
-				if p != "" {
-					hasNonEmpty = true
-					break
-				}
-			}
+	// 属于别名词典中作品的关键词同时搜索作品的其他名称
+	aliases := expandAliases(q)
 
-			// 如果全是空字符串，视为未指定
-			if !hasNonEmpty {
-				plugins = nil
-			} else {
-				// 检查是否包含所有插件
-				allPlugins := s.pluginManager.GetPlugins()
-				allPluginNames := make([]string, 0, len(allPlugins))
-				for _, p := range allPlugins {
-					allPluginNames = append(allPluginNames, strings.ToLower(p.Name()))
-				}
-
-				// 创建请求的插件名称集合（忽略空字符串）
-				requestedPlugins := make([]string, 0, len(plugins))
-				for _, p := range plugins {
-					if p != "" {
-						requestedPlugins = append(requestedPlugins, strings.ToLower(p))
-					}
-				}
-
-				// 如果请求的插件数量与所有插件数量相同，检查是否包含所有插件
-				if len(requestedPlugins) == len(allPluginNames) {
-					// 创建映射以便快速查找
-					pluginMap := make(map[string]bool)
-					for _, p := range requestedPlugins {
-						pluginMap[p] = true
-					}
-
-					// 检查是否包含所有插件
-					allIncluded := true
-					for _, name := range allPluginNames {
-						if !pluginMap[name] {
-							allIncluded = false
-							break
-						}
-					}
+	// 插件参数规范化处理
+	plugins = s.normalizePlugins(sourceType, plugins)
 
-					// 如果包含所有插件，统一设为nil
-					if allIncluded {
-						plugins = nil
-					}
-				}
-			}
-		}
-	}
+	// 记录搜索条件，供缓存预热统计热度
+	recordPopularSearch(q.Keywords(), sourceType, channels, plugins)
 
 	// 如果未指定并发数，使用配置中的默认值
 	if concurrency <= 0 {
 		concurrency = config.AppConfig.DefaultConcurrency
 	}
 
-	// 并行获取TG搜索和插件搜索结果
+	// 并行获取TG搜索和插件搜索结果，每个OR分支的关键词分别搜索
 	var tgResults []model.SearchResult
 	var pluginResults []model.SearchResult
+	var tgSources, pluginSources []model.SourceStatus
 
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	var tgErr, pluginErr error
 
-	// 如果需要搜索TG
-	if sourceType == "all" || sourceType == "tg" {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			tgResults, tgErr = s.searchTG(keyword, channels, forceRefresh)
-		}()
-	}
-	// 如果需要搜索插件（且插件功能已启用）
-	if (sourceType == "all" || sourceType == "plugin") && config.AppConfig.AsyncPluginEnabled {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			// 对于插件搜索，我们总是希望获取最新的缓存数据
-			// 因此，即使forceRefresh=false，我们也需要确保获取到最新的缓存
-			pluginResults, pluginErr = s.searchPlugins(keyword, plugins, forceRefresh, concurrency, ext)
-		}()
+	for _, kw := range q.Keywords() {
+		kw := kw
+		// 如果需要搜索TG
+		if sourceType == "all" || sourceType == "tg" {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results, sources, err := s.searchTG(ctx, kw, channels, forceRefresh)
+				mu.Lock()
+				defer mu.Unlock()
+				tgResults = mergeSearchResults(tgResults, results)
+				tgSources = mergeSourceStatuses(tgSources, sources)
+				if err != nil {
+					tgErr = err
+				}
+			}()
+		}
+		// 如果需要搜索插件（且插件功能已启用）
+		if (sourceType == "all" || sourceType == "plugin") && config.AppConfig.AsyncPluginEnabled {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// 对于插件搜索，我们总是希望获取最新的缓存数据
+				// 因此，即使forceRefresh=false，我们也需要确保获取到最新的缓存
+				results, sources, err := s.searchPlugins(ctx, kw, plugins, forceRefresh, concurrency, aliases.ext(ext, kw))
+				mu.Lock()
+				defer mu.Unlock()
+				pluginResults = mergeSearchResults(pluginResults, results)
+				pluginSources = mergeSourceStatuses(pluginSources, sources)
+				if err != nil {
+					pluginErr = err
+				}
+			}()
+		}
 	}
 
 	// 等待所有搜索完成
 	wg.Wait()
 
+	// 客户端已断开，结果不再需要
+	if err := ctx.Err(); err != nil {
+		return model.SearchResponse{}, err
+	}
+
 	// 检查错误
 	if tgErr != nil {
 		return model.SearchResponse{}, tgErr
@@ -475,7 +481,7 @@ func (s *SearchService) Search(keyword string, channels []string, concurrency in
 
 	// 过滤结果，只保留有时间的结果或包含优先关键词的结果或高等级插件结果到Results中
 	filteredForResults := make([]model.SearchResult, 0, len(allResults))
-	for _, result := range allResults {
+	for _, result := range filterResultsByQuery(allResults, q) {
 		source := getResultSource(result)
 		pluginLevel := getPluginLevelBySource(source)
 
@@ -486,7 +492,7 @@ func (s *SearchService) Search(keyword string, channels []string, concurrency in
 	}
 
 	// 合并链接按网盘类型分组（使用所有过滤后的结果）
-	mergedLinks := mergeResultsByType(allResults, keyword, cloudTypes)
+	mergedLinks := mergeResultsByType(allResults, q, cloudTypes)
 
 	// 构建响应
 	var total int
@@ -505,14 +511,19 @@ func (s *SearchService) Search(keyword string, channels []string, concurrency in
 		Total:        total,
 		Results:      filteredForResults, // 使用进一步过滤的结果
 		MergedByType: mergedLinks,
+		Sources:      append(tgSources, pluginSources...),
+		Aliases:      aliases.aliases,
 	}
 
-	// 记录搜索热词（如果有结果）
-	if response.Total > 0 && keyword != "" {
+	// 记录搜索热词（如果有结果），热词为发送给上游的关键词
+	if response.Total > 0 {
+		go aliases.learn(allResults)
 		go func() {
 			for _, p := range s.pluginManager.GetPlugins() {
 				if recorder, ok := p.(plugin.SearchRecorder); ok {
-					recorder.RecordSearch(keyword, allResults)
+					for _, kw := range q.Keywords() {
+						recorder.RecordSearch(kw, allResults)
+					}
 				}
 			}
 		}()
@@ -522,6 +533,73 @@ func (s *SearchService) Search(keyword string, channels []string, concurrency in
 	return filterResponseByType(response, resultType), nil
 }
 
+// normalizePlugins 规范化插件参数：仅搜索TG时忽略插件，未指定或包含全部插件时统一为nil
+func (s *SearchService) normalizePlugins(sourceType string, plugins []string) []string {
+	if sourceType == "tg" {
+		// 对于只搜索Telegram的请求，忽略插件参数
+		plugins = nil
+	} else if sourceType == "all" || sourceType == "plugin" {
+		// 检查是否为空列表或只包含空字符串
+		if len(plugins) == 0 {
+			plugins = nil
+		} else {
+			// 检查是否有非空元素
+			hasNonEmpty := false
+			for _, p := range plugins {
+				if p != "" {
+					hasNonEmpty = true
+					break
+				}
+			}
+
+			// 如果全是空字符串，视为未指定
+			if !hasNonEmpty {
+				plugins = nil
+			} else {
+				// 检查是否包含所有插件
+				allPlugins := s.pluginManager.GetPlugins()
+				allPluginNames := make([]string, 0, len(allPlugins))
+				for _, p := range allPlugins {
+					allPluginNames = append(allPluginNames, strings.ToLower(p.Name()))
+				}
+
+				// 创建请求的插件名称集合（忽略空字符串）
+				requestedPlugins := make([]string, 0, len(plugins))
+				for _, p := range plugins {
+					if p != "" {
+						requestedPlugins = append(requestedPlugins, strings.ToLower(p))
+					}
+				}
+
+				// 如果请求的插件数量与所有插件数量相同，检查是否包含所有插件
+				if len(requestedPlugins) == len(allPluginNames) {
+					// 创建映射以便快速查找
+					pluginMap := make(map[string]bool)
+					for _, p := range requestedPlugins {
+						pluginMap[p] = true
+					}
+
+					// 检查是否包含所有插件
+					allIncluded := true
+					for _, name := range allPluginNames {
+						if !pluginMap[name] {
+							allIncluded = false
+							break
+						}
+					}
+
+					// 如果包含所有插件，统一设为nil
+					if allIncluded {
+						plugins = nil
+					}
+				}
+			}
+		}
+	}
+
+	return plugins
+}
+
 // filterResponseByType 根据结果类型过滤响应
 func filterResponseByType(response model.SearchResponse, resultType string) model.SearchResponse {
 	switch resultType {
@@ -531,6 +609,8 @@ func filterResponseByType(response model.SearchResponse, resultType string) mode
 			Total:        response.Total,
 			MergedByType: response.MergedByType,
 			Results:      nil,
+			Sources:      response.Sources,
+			Aliases:      response.Aliases,
 		}
 	case "all":
 		return response
@@ -539,6 +619,8 @@ func filterResponseByType(response model.SearchResponse, resultType string) mode
 		return model.SearchResponse{
 			Total:   response.Total,
 			Results: response.Results,
+			Sources: response.Sources,
+			Aliases: response.Aliases,
 		}
 	default:
 		// // 默认返回全部
@@ -547,6 +629,8 @@ func filterResponseByType(response model.SearchResponse, resultType string) mode
 			Total:        response.Total,
 			MergedByType: response.MergedByType,
 			Results:      nil,
+			Sources:      response.Sources,
+			Aliases:      response.Aliases,
 		}
 	}
 }
@@ -597,7 +681,7 @@ func getKeywordPriority(title string) int {
 }
 
 // 搜索单个频道
-func (s *SearchService) searchChannel(keyword string, channel string) ([]model.SearchResult, error) {
+func (s *SearchService) searchChannel(ctx context.Context, keyword string, channel string) ([]model.SearchResult, error) {
 	// 构建搜索URL
 	url := util.BuildSearchURL(channel, keyword, "")
 
@@ -605,7 +689,7 @@ func (s *SearchService) searchChannel(keyword string, channel string) ([]model.S
 	client := util.GetHTTPClient()
 
 	// 创建一个带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 4*time.Second)
 	defer cancel()
 
 	// 创建请求
@@ -993,19 +1077,19 @@ func isEmpty(line string) bool {
 	return strings.TrimSpace(line) == ""
 }
 
-// 将搜索结果按网盘类型分组
-func mergeResultsByType(results []model.SearchResult, keyword string, cloudTypes []string) model.MergedLinks {
+// 将搜索结果按网盘类型分组，只保留满足查询的链接
+func mergeResultsByType(results []model.SearchResult, q *query.Query, cloudTypes []string) model.MergedLinks {
 	// 创建合并结果的映射
 	mergedLinks := make(model.MergedLinks, 12) // 预分配容量，假设有12种不同的网盘类型
 
 	// 用于去重的映射，键为URL
 	uniqueLinks := make(map[string]model.MergedLink)
 
-	// 将关键词转为小写，用于不区分大小写的匹配
-	lowerKeyword := strings.ToLower(keyword)
-
 	// 遍历所有搜索结果
 	for _, result := range results {
+		// 检查插件是否需要跳过Service层过滤
+		skipKeywordFilter := pluginFiltered(q, result)
+
 		// 提取消息中的链接-标题对应关系
 		linkTitleMap := extractLinkTitlePairs(result.Content)
 
@@ -1086,25 +1170,10 @@ func mergeResultsByType(results []model.SearchResult, keyword string, cloudTypes
 				}
 			}
 
-			// 检查插件是否需要跳过Service层过滤
-			var skipKeywordFilter bool = false
-			if result.UniqueID != "" && strings.Contains(result.UniqueID, "-") {
-				parts := strings.SplitN(result.UniqueID, "-", 2)
-				if len(parts) >= 1 {
-					pluginName := parts[0]
-					// 通过插件注册表动态获取过滤设置
-					if pluginInstance, exists := plugin.GetPluginByName(pluginName); exists {
-						skipKeywordFilter = pluginInstance.SkipServiceFilter()
-					}
-				}
-			}
-
-			// 关键词过滤：现在我们有了准确的链接-标题对应关系，只需检查每个链接的具体标题
-			if !skipKeywordFilter && keyword != "" {
-				// 只检查链接的具体标题，无论是TG来源还是插件来源
-				if !strings.Contains(strings.ToLower(title), lowerKeyword) {
-					continue
-				}
+			// 查询过滤：现在我们有了准确的链接-标题对应关系，关键词只检查每个链接的具体标题
+			score, ok := scoreLink(q, result, link, title, skipKeywordFilter)
+			if !ok {
+				continue
 			}
 
 			// 确定数据来源
@@ -1139,6 +1208,8 @@ func mergeResultsByType(results []model.SearchResult, keyword string, cloudTypes
 				Datetime: linkDatetime,
 				Source:   source,        // 添加数据来源字段
 				Images:   result.Images, // 添加TG消息中的图片链接
+				Score:    score,
+				Media:    mediainfo.Parse(title),
 			}
 
 			// 检查是否已存在相同URL的链接
@@ -1191,6 +1262,15 @@ func mergeResultsByType(results []model.SearchResult, keyword string, cloudTypes
 		mergedLinks[linkType] = append(mergedLinks[linkType], mergedLink)
 	}
 
+	// 模糊匹配时相似度高的链接排在前面，分数相同的保持原有顺序
+	if q.Fuzzy() {
+		for _, links := range mergedLinks {
+			sort.SliceStable(links, func(i, j int) bool {
+				return links[i].Score > links[j].Score
+			})
+		}
+	}
+
 	// 如果指定了cloudTypes，则过滤结果
 	if len(cloudTypes) > 0 {
 		// 创建过滤后的结果映射
@@ -1215,123 +1295,129 @@ func mergeResultsByType(results []model.SearchResult, keyword string, cloudTypes
 	return mergedLinks
 }
 
-// searchTG 搜索TG频道
-func (s *SearchService) searchTG(keyword string, channels []string, forceRefresh bool) ([]model.SearchResult, error) {
-	// 生成缓存键
-	cacheKey := cache.GenerateTGCacheKey(keyword, channels)
-
-	// 如果未启用强制刷新，尝试从缓存获取结果
-	if !forceRefresh && cacheInitialized && config.AppConfig.CacheEnabled {
-		var data []byte
-		var hit bool
-		var err error
+// searchTG 搜索TG频道，每个频道的结果单独缓存
+func (s *SearchService) searchTG(ctx context.Context, keyword string, channels []string, forceRefresh bool) ([]model.SearchResult, []model.SourceStatus, error) {
+	return s.searchSources(ctx, cache.KeySourceTG, channels, keyword, forceRefresh, len(channels), tgFlights,
+		func(ctx context.Context, channel string, _ string) ([]model.SearchResult, bool, error) {
+			results, err := s.searchChannel(ctx, keyword, channel)
+			return results, false, err
+		})
+}
 
-		// 使用增强版缓存
-		if enhancedTwoLevelCache != nil {
-			data, hit, err = enhancedTwoLevelCache.Get(cacheKey)
-
-			if err == nil && hit {
-				var results []model.SearchResult
-				if err := enhancedTwoLevelCache.GetSerializer().Deserialize(data, &results); err == nil {
-					// 直接返回缓存数据，不检查新鲜度
-					return results, nil
-				}
-			}
-		}
+// searchPlugins 搜索插件，每个插件的结果单独缓存
+func (s *SearchService) searchPlugins(ctx context.Context, keyword string, plugins []string, forceRefresh bool, concurrency int, ext map[string]interface{}) ([]model.SearchResult, []model.SourceStatus, error) {
+	// 确保ext不为nil
+	if ext == nil {
+		ext = make(map[string]interface{})
 	}
 
-	// 缓存未命中或强制刷新，执行实际搜索
-	var results []model.SearchResult
-
-	// 使用工作池并行搜索多个频道
-	tasks := make([]pool.Task, 0, len(channels))
+	// 关键：将forceRefresh同步到插件ext["refresh"]
+	if forceRefresh {
+		ext["refresh"] = true
+	}
 
-	for _, channel := range channels {
-		ch := channel // 创建副本，避免闭包问题
-		tasks = append(tasks, func() interface{} {
-			results, err := s.searchChannel(keyword, ch)
-			if err != nil {
-				return nil
-			}
-			return results
-		})
+	availablePlugins := make(map[string]plugin.AsyncSearchPlugin)
+	names := make([]string, 0)
+	for _, p := range s.selectPlugins(plugins) {
+		availablePlugins[p.Name()] = p
+		names = append(names, p.Name())
 	}
 
-	// 执行搜索任务并获取结果
-	taskResults := pool.ExecuteBatchWithTimeout(tasks, len(channels), config.AppConfig.PluginTimeout)
+	results, statuses, err := s.searchSources(ctx, cache.KeySourcePlugin, names, keyword, forceRefresh, concurrency, pluginFlights,
+		func(ctx context.Context, name string, key string) ([]model.SearchResult, bool, error) {
+			return s.fetchPlugin(ctx, availablePlugins[name], keyword, ext, key)
+		})
 
-	// 合并所有频道的结果
-	for _, result := range taskResults {
-		if result != nil {
-			channelResults := result.([]model.SearchResult)
-			results = append(results, channelResults...)
+	cachedCount := 0
+	for _, status := range statuses {
+		if status.Status == model.SourceStatusCached {
+			cachedCount++
 		}
 	}
-
-	// 异步缓存结果
-	if cacheInitialized && config.AppConfig.CacheEnabled {
-		go func(res []model.SearchResult) {
-			ttl := time.Duration(config.AppConfig.CacheTTLMinutes) * time.Minute
-
-			// 使用增强版缓存
-			if enhancedTwoLevelCache != nil {
-				data, err := enhancedTwoLevelCache.GetSerializer().Serialize(res)
-				if err != nil {
-					return
-				}
-				enhancedTwoLevelCache.Set(cacheKey, data, ttl)
-			}
-		}(results)
+	if cachedCount > 0 && cachedCount == len(statuses) {
+		fmt.Printf("✅ [%s] 命中缓存 结果数: %d\n", keyword, len(results))
 	}
-
-	return results, nil
+	return results, statuses, err
 }
 
-// searchPlugins 搜索插件
-func (s *SearchService) searchPlugins(keyword string, plugins []string, forceRefresh bool, concurrency int, ext map[string]interface{}) ([]model.SearchResult, error) {
-	// 确保ext不为nil
-	if ext == nil {
-		ext = make(map[string]interface{})
-	}
+// fetchPlugin 调用单个插件搜索，只保留有链接的结果；插件响应超时转入后台时返回pending
+// 插件以该来源的缓存键作为主缓存键，前台超时后在后台完成时只更新自己的缓存项；
+// 插件实例被并发搜索共享，缓存键随ext按调用传入，不写入插件的共享字段
+func (s *SearchService) fetchPlugin(ctx context.Context, asyncPlugin plugin.AsyncSearchPlugin, keyword string, ext map[string]interface{}, cacheKey string) ([]model.SearchResult, bool, error) {
+	// 熔断中的插件直接跳过
+	if !s.pluginManager.AllowRequest(asyncPlugin.Name()) {
+		return nil, false, fmt.Errorf("插件 %s 已熔断", asyncPlugin.Name())
+	}
+
+	// 响应超时后转入后台的搜索在后台完成时才计入健康统计
+	start := time.Now()
+	name := asyncPlugin.Name()
+	report := plugin.NewOutcomeReport(func(results []model.SearchResult, err error) {
+		s.recordPluginOutcome(context.Background(), name, results, err, time.Since(start))
+		// 无论有无结果、是否失败都通知等待中的流式搜索，结果写入缓存时另有通知
+		publishPluginUpdate(cacheKey, name, withLinks(results), true)
+	})
 
-	// 关键：将forceRefresh同步到插件ext["refresh"]
-	if forceRefresh {
-		ext["refresh"] = true
+	// 调用异步插件的AsyncSearch方法，上下文经ext传入，响应超时后由插件自行脱离以完成后台缓存
+	results, err := asyncPlugin.AsyncSearch(keyword, func(client *http.Client, kw string, extParams map[string]interface{}) ([]model.SearchResult, error) {
+		// 使用插件的Search方法作为搜索函数，需要时并发搜索关键词的繁简写法，各写法使用独立的ext副本
+		return plugin.SearchVariants(asyncPlugin, kw, func(variant string) ([]model.SearchResult, error) {
+			return plugin.AsContextPlugin(asyncPlugin).SearchWithContext(plugin.ContextFromExt(extParams), variant, copyExt(extParams))
+		})
+	}, cacheKey, plugin.WithMainCacheKey(cacheKey, plugin.WithContext(plugin.WithOutcomeReport(ctx, report), ext)))
+	if !report.Deferred() {
+		s.recordPluginOutcome(ctx, name, results, err, time.Since(start))
 	}
 
-	// 生成缓存键
-	cacheKey := cache.GeneratePluginCacheKey(keyword, plugins)
+	if err != nil {
+		return nil, false, err
+	}
 
-	// 如果未启用强制刷新，尝试从缓存获取结果
-	if !forceRefresh && cacheInitialized && config.AppConfig.CacheEnabled {
-		var data []byte
-		var hit bool
-		var err error
+	return withLinks(results), report.Deferred(), nil
+}
 
-		// 使用增强版缓存
-		if enhancedTwoLevelCache != nil {
-
-			// 使用Get方法，它会检查磁盘缓存是否有更新
-			// 如果磁盘缓存比内存缓存更新，会自动更新内存缓存并返回最新数据
-			data, hit, err = enhancedTwoLevelCache.Get(cacheKey)
-
-			if err == nil && hit {
-				var results []model.SearchResult
-				if err := enhancedTwoLevelCache.GetSerializer().Deserialize(data, &results); err == nil {
-					// 返回缓存数据
-					fmt.Printf("✅ [%s] 命中缓存 结果数: %d\n", keyword, len(results))
-					return results, nil
-				} else {
-					displayKey := cacheKey[:8] + "..."
-					fmt.Printf("[主服务] 缓存反序列化失败: %s(关键词:%s) | 错误: %v\n", displayKey, keyword, err)
-				}
-			}
+// withLinks 只保留有链接的结果
+func withLinks(results []model.SearchResult) []model.SearchResult {
+	filtered := make([]model.SearchResult, 0, len(results))
+	for _, result := range results {
+		if len(result.Links) > 0 {
+			filtered = append(filtered, result)
 		}
 	}
+	return filtered
+}
+
+// recordPluginOutcome 记录插件本次调用的结果，用于健康统计和熔断
+// 客户端断开导致的取消不代表插件异常，不计入；批量搜索超时和上游请求超时计为超时
+func (s *SearchService) recordPluginOutcome(ctx context.Context, name string, results []model.SearchResult, err error, latency time.Duration) {
+	switch {
+	case requestCanceled(ctx):
+		s.pluginManager.AbandonRequest(name)
+	case ctx.Err() != nil, isTimeout(err):
+		s.pluginManager.RecordOutcome(name, plugin.OutcomeTimeout, latency, nil)
+	case err != nil:
+		s.pluginManager.RecordOutcome(name, plugin.OutcomeError, latency, err)
+	case len(results) > 0:
+		s.pluginManager.RecordOutcome(name, plugin.OutcomeSuccess, latency, nil)
+	default:
+		s.pluginManager.RecordOutcome(name, plugin.OutcomeEmpty, latency, nil)
+	}
+}
 
-	// 缓存未命中或强制刷新，执行实际搜索
+// isTimeout 判断错误是否为请求超时（上游无响应时由HTTP客户端或上下文超时产生）
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
 
-	// 获取所有可用插件
+// selectPlugins 根据请求的插件列表筛选可用插件，未指定时返回全部插件
+func (s *SearchService) selectPlugins(plugins []string) []plugin.AsyncSearchPlugin {
 	var availablePlugins []plugin.AsyncSearchPlugin
 	if s.pluginManager != nil {
 		allPlugins := s.pluginManager.GetPlugins()
@@ -1369,76 +1455,7 @@ func (s *SearchService) searchPlugins(keyword string, plugins []string, forceRef
 		}
 	}
 
-	// 控制并发数
-	if concurrency <= 0 {
-		// 使用配置中的默认值
-		concurrency = config.AppConfig.DefaultConcurrency
-	}
-
-	// 使用工作池执行并行搜索
-	tasks := make([]pool.Task, 0, len(availablePlugins))
-	for _, p := range availablePlugins {
-		plugin := p // 创建副本，避免闭包问题
-		tasks = append(tasks, func() interface{} {
-			// 设置主缓存键和当前关键词
-			plugin.SetMainCacheKey(cacheKey)
-			plugin.SetCurrentKeyword(keyword)
-
-			// 调用异步插件的AsyncSearch方法
-			results, err := plugin.AsyncSearch(keyword, func(client *http.Client, kw string, extParams map[string]interface{}) ([]model.SearchResult, error) {
-				// 使用插件的Search方法作为搜索函数
-				return plugin.Search(kw, extParams)
-			}, cacheKey, ext)
-
-			if err != nil {
-				return nil
-			}
-			return results
-		})
-	}
-
-	// 执行搜索任务并获取结果
-	results := pool.ExecuteBatchWithTimeout(tasks, concurrency, config.AppConfig.PluginTimeout)
-
-	// 合并所有插件的结果，过滤掉无链接的结果
-	var allResults []model.SearchResult
-	for _, result := range results {
-		if result != nil {
-			pluginResults := result.([]model.SearchResult)
-			// 只添加有链接的结果到最终结果中
-			for _, pluginResult := range pluginResults {
-				if len(pluginResult.Links) > 0 {
-					allResults = append(allResults, pluginResult)
-				}
-			}
-		}
-	}
-
-	// 恢复主程序缓存更新：确保最终合并结果被正确缓存
-	if cacheInitialized && config.AppConfig.CacheEnabled {
-		go func(res []model.SearchResult, kw string, key string) {
-			ttl := time.Duration(config.AppConfig.CacheTTLMinutes) * time.Minute
-
-			// 使用增强版缓存，确保与异步插件使用相同的序列化器
-			if enhancedTwoLevelCache != nil {
-				data, err := enhancedTwoLevelCache.GetSerializer().Serialize(res)
-				if err != nil {
-					fmt.Printf("[主程序] 缓存序列化失败: %s | 错误: %v\n", key, err)
-					return
-				}
-
-				// 主程序最后更新，覆盖可能有问题的异步插件缓存
-				// 使用同步方式确保数据写入磁盘
-				enhancedTwoLevelCache.SetBothLevels(key, data, ttl)
-				if config.AppConfig != nil && config.AppConfig.AsyncLogEnabled {
-					fmt.Printf("[主程序] 缓存更新完成: %s | 结果数: %d",
-						key, len(res))
-				}
-			}
-		}(allResults, keyword, cacheKey)
-	}
-
-	return allResults, nil
+	return availablePlugins
 }
 
 // GetPluginManager 获取插件管理器
@@ -1511,7 +1528,7 @@ func getPluginLevelBySource(source string) int {
 func getPluginPriorityByName(pluginName string) int {
 	// 从插件管理器动态获取真实的优先级 (O(1)哈希查找)
 	if pluginInstance, exists := plugin.GetPluginByName(pluginName); exists {
-		return pluginInstance.Priority()
+		return plugin.EffectivePriority(pluginInstance)
 	}
 	return 3 // 默认等级
 }
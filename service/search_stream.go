@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/util/cache"
+	"pansou/util/query"
+)
+
+// 流式搜索事件类型
+const (
+	StreamEventDelta = "delta" // 增量结果
+	StreamEventFinal = "final" // 搜索结束
+)
+
+// pluginUpdate 插件写入主缓存或转入后台的搜索完成时的通知
+type pluginUpdate struct {
+	PluginName string
+	Results    []model.SearchResult
+	Completed  bool // 转入后台的搜索已完成（包括无结果和失败），流式搜索不再等待该分支
+}
+
+// 主缓存更新订阅表，键为插件缓存键
+var (
+	pluginUpdateSubscribers      = make(map[string]map[chan pluginUpdate]struct{})
+	pluginUpdateSubscribersMutex sync.RWMutex
+)
+
+// subscribePluginUpdates 订阅指定缓存键的插件结果更新，返回取消订阅函数
+func subscribePluginUpdates(key string) (chan pluginUpdate, func()) {
+	ch := make(chan pluginUpdate, 16)
+
+	pluginUpdateSubscribersMutex.Lock()
+	if pluginUpdateSubscribers[key] == nil {
+		pluginUpdateSubscribers[key] = make(map[chan pluginUpdate]struct{})
+	}
+	pluginUpdateSubscribers[key][ch] = struct{}{}
+	pluginUpdateSubscribersMutex.Unlock()
+
+	unsubscribe := func() {
+		pluginUpdateSubscribersMutex.Lock()
+		defer pluginUpdateSubscribersMutex.Unlock()
+		if subs, ok := pluginUpdateSubscribers[key]; ok {
+			delete(subs, ch)
+			if len(subs) == 0 {
+				delete(pluginUpdateSubscribers, key)
+			}
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// publishPluginUpdate 向订阅者广播插件结果更新（非阻塞，订阅者处理不过来时丢弃）
+func publishPluginUpdate(key string, pluginName string, results []model.SearchResult, completed bool) {
+	pluginUpdateSubscribersMutex.RLock()
+	defer pluginUpdateSubscribersMutex.RUnlock()
+
+	for ch := range pluginUpdateSubscribers[key] {
+		select {
+		case ch <- pluginUpdate{PluginName: pluginName, Results: results, Completed: completed}:
+		default:
+		}
+	}
+}
+
+// streamSourceResult 单个来源的前台搜索结果
+type streamSourceResult struct {
+	Source  string
+	Results []model.SearchResult
+	Pending int // 转入后台、结果未返回完整的关键词分支数
+}
+
+// SearchStream 流式搜索：每个TG频道和插件完成后推送增量的merged_by_type结果，
+// 前台超时后仍在后台运行的插件完成时也会继续推送，最后发送final事件
+func (s *SearchService) SearchStream(ctx context.Context, keyword string, channels []string, concurrency int, forceRefresh bool, sourceType string, plugins []string, cloudTypes []string, ext map[string]interface{}, matchMode string, emit func(model.SearchStreamEvent) error) error {
+	// 确保ext不为nil
+	if ext == nil {
+		ext = make(map[string]interface{})
+	}
+
+	// 解析查询语法，上游只收到普通关键词
+	q, err := query.Parse(keyword)
+	if err != nil {
+		return err
+	}
+	applyMatchMode(q, matchMode)
+
+	// 参数预处理
+	if sourceType == "" {
+		sourceType = "all"
+	}
+	sourceType, channels, plugins = applyQueryScope(q, sourceType, channels, plugins)
+	aliases := expandAliases(q)
+	keywords := q.Keywords()
+	plugins = s.normalizePlugins(sourceType, plugins)
+	recordPopularSearch(keywords, sourceType, channels, plugins)
+	if concurrency <= 0 {
+		concurrency = config.AppConfig.DefaultConcurrency
+	}
+
+	start := time.Now()
+	resultChan := make(chan streamSourceResult, 16)
+	updateChan := make(chan pluginUpdate, 64)
+	pending := make(map[string]int) // 来源尚未得到最终结果的关键词分支数
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	// 每个TG频道作为独立来源
+	if sourceType == "all" || sourceType == "tg" {
+		for _, channel := range channels {
+			ch := channel
+			source := sourceName(cache.KeySourceTG, ch)
+			pending[source] = len(keywords)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				var results []model.SearchResult
+				for _, kw := range keywords {
+					r, _, _ := s.searchTG(ctx, kw, []string{ch}, forceRefresh)
+					results = append(results, r...)
+				}
+				select {
+				case resultChan <- streamSourceResult{Source: source, Results: results}:
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}
+
+	// 每个插件作为独立来源，并订阅其后台完成时的缓存更新
+	if (sourceType == "all" || sourceType == "plugin") && config.AppConfig.AsyncPluginEnabled {
+		for _, p := range s.selectPlugins(plugins) {
+			name := p.Name()
+			// 熔断中的插件不会返回结果，无需等待
+			if !s.pluginManager.IsAvailable(name) {
+				continue
+			}
+			source := sourceName(cache.KeySourcePlugin, name)
+			pending[source] = len(keywords)
+
+			for _, kw := range keywords {
+				updates, unsubscribe := subscribePluginUpdates(cache.GenerateSourceCacheKey(cache.KeySourcePlugin, name, kw))
+				defer unsubscribe()
+				go func() {
+					for {
+						select {
+						case u := <-updates:
+							select {
+							case updateChan <- u:
+							case <-ctx.Done():
+								return
+							}
+						case <-ctx.Done():
+							return
+						}
+					}
+				}()
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				// 每个插件单独调用，结果与普通搜索共享该插件的缓存项
+				var results []model.SearchResult
+				deferred := 0
+				for _, kw := range keywords {
+					r, statuses, _ := s.searchPlugins(ctx, kw, []string{name}, forceRefresh, 1, aliases.ext(ext, kw))
+					results = append(results, r...)
+					if len(statuses) > 0 && statuses[0].Status == model.SourceStatusPending {
+						deferred++
+					}
+				}
+				select {
+				case resultChan <- streamSourceResult{Source: source, Results: results, Pending: deferred}:
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	sentLinks := make(map[string]bool)
+	total := 0
+
+	// pushDelta 计算并推送尚未发送过的链接
+	pushDelta := func(source string, results []model.SearchResult, late bool) error {
+		merged := mergeResultsByType(results, q, cloudTypes)
+		delta := make(model.MergedLinks)
+		count := 0
+		for linkType, links := range merged {
+			for _, link := range links {
+				if sentLinks[link.URL] {
+					continue
+				}
+				sentLinks[link.URL] = true
+				delta[linkType] = append(delta[linkType], link)
+				count++
+			}
+		}
+		if count == 0 {
+			return nil
+		}
+		total += count
+		return emit(model.SearchStreamEvent{
+			Event:        StreamEventDelta,
+			Source:       source,
+			Late:         late,
+			Total:        total,
+			MergedByType: delta,
+		})
+	}
+
+	// 后台迟到结果最多等待到插件超时时间，届时仍未完成的来源在final事件中列出
+	deadline := time.NewTimer(config.AppConfig.PluginTimeout)
+	defer deadline.Stop()
+
+	foregroundDone := false
+	var unfinished []string
+	for {
+		// 前台全部返回且没有仍在后台处理的来源时结束
+		if foregroundDone && len(pending) == 0 {
+			break
+		}
+
+		select {
+		case r, ok := <-resultChan:
+			if !ok {
+				foregroundDone = true
+				resultChan = nil
+				continue
+			}
+			// 已完成的分支不再等待，转入后台的分支等待其完成通知；
+			// 完成通知可能先于前台结果到达，因此按分支数递减而不是直接赋值
+			if pending[r.Source] -= len(keywords) - r.Pending; pending[r.Source] <= 0 {
+				delete(pending, r.Source)
+			}
+			if err := pushDelta(r.Source, r.Results, false); err != nil {
+				return err
+			}
+
+		case u := <-updateChan:
+			source := sourceName(cache.KeySourcePlugin, u.PluginName)
+			if u.Completed {
+				if pending[source]--; pending[source] <= 0 {
+					delete(pending, source)
+				}
+			}
+			if err := pushDelta(source, u.Results, foregroundDone); err != nil {
+				return err
+			}
+
+		case <-deadline.C:
+			for source := range pending {
+				unfinished = append(unfinished, source)
+			}
+			sort.Strings(unfinished)
+			pending = map[string]int{}
+			foregroundDone = true
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return emit(model.SearchStreamEvent{
+		Event:   StreamEventFinal,
+		Total:   total,
+		Elapsed: time.Since(start).Milliseconds(),
+		Pending: unfinished,
+	})
+}
+
+// copyExt 复制扩展参数，避免并发的插件调用共享同一个map
+func copyExt(ext map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(ext))
+	for k, v := range ext {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+)
+
+// delayedPlugin 与大多数插件一样在Search中调用AsyncSearchWithResult，上游延迟delay后返回
+type delayedPlugin struct {
+	*plugin.BaseAsyncPlugin
+	delay time.Duration
+	links int
+}
+
+func (p *delayedPlugin) Search(keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	result, err := p.AsyncSearchWithResult(keyword, p.searchImpl, p.MainCacheKey, ext)
+	return result.Results, err
+}
+
+func (p *delayedPlugin) searchImpl(client *http.Client, keyword string, ext map[string]interface{}) ([]model.SearchResult, error) {
+	time.Sleep(p.delay)
+	var results []model.SearchResult
+	for i := 0; i < p.links; i++ {
+		id := p.Name() + "-" + string(rune('a'+i))
+		results = append(results, model.SearchResult{
+			UniqueID: id,
+			Title:    keyword + " " + id,
+			Links:    []model.Link{{Type: "quark", URL: "https://pan.quark.cn/s/" + id}},
+		})
+	}
+	return results, nil
+}
+
+func TestSearchStreamWaitsForDeferredPlugins(t *testing.T) {
+	setupSourceCache(t)
+
+	pm := plugin.NewPluginManager()
+	pm.RegisterPlugin(&delayedPlugin{BaseAsyncPlugin: plugin.NewBaseAsyncPlugin("streamfast", 3), links: 1})
+	pm.RegisterPlugin(&delayedPlugin{BaseAsyncPlugin: plugin.NewBaseAsyncPlugin("streamlate", 3), delay: 200 * time.Millisecond, links: 2})
+	// 转入后台后没有结果，完成时也应通知流式搜索，而不是等到插件超时
+	pm.RegisterPlugin(&delayedPlugin{BaseAsyncPlugin: plugin.NewBaseAsyncPlugin("streamnothing", 3), delay: 200 * time.Millisecond})
+	s := NewSearchService(pm)
+
+	var events []model.SearchStreamEvent
+	start := time.Now()
+	err := s.SearchStream(context.Background(), "流式测试", nil, 4, false, "plugin",
+		[]string{"streamfast", "streamlate", "streamnothing"}, nil, nil, "",
+		func(e model.SearchStreamEvent) error {
+			events = append(events, e)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("SearchStream: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= config.AppConfig.PluginTimeout {
+		t.Errorf("所有插件在后台完成后应立即结束，实际等待 %v", elapsed)
+	}
+
+	final := events[len(events)-1]
+	if final.Event != StreamEventFinal || len(final.Pending) != 0 || final.Total != 3 {
+		t.Fatalf("final事件应包含全部3个链接且没有未完成的来源: %+v", final)
+	}
+	lateDelta := false
+	for _, e := range events[:len(events)-1] {
+		if e.Event == StreamEventDelta && e.Source == "plugin:streamlate" {
+			lateDelta = true
+		}
+	}
+	if !lateDelta {
+		t.Error("后台完成的结果应作为增量推送")
+	}
+}
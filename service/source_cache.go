@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+	"pansou/util/cache"
+	"pansou/util/pool"
+	"pansou/util/ttlpolicy"
+)
+
+// 分来源缓存：每个TG频道、每个插件的结果按（来源, 标准化关键词）单独缓存。
+// 搜索时逐个读取请求的频道或插件的缓存，只对未命中的来源发起请求，再组装成响应，
+// 因此plugins=a,b与plugins=a共享插件a的缓存，插件的后台更新也只改写它自己的条目
+
+// sourceFetchFunc 对单个来源执行实际搜索，key为该来源的缓存键
+// pending表示来源未在响应超时内完成、仍在后台搜索，返回的结果不完整，由后台完成时写入缓存
+type sourceFetchFunc func(ctx context.Context, name string, key string) (results []model.SearchResult, pending bool, err error)
+
+// sourceFetchResult 单个来源的实际搜索结果
+type sourceFetchResult struct {
+	index     int
+	results   []model.SearchResult
+	pending   bool
+	err       error
+	fetchedAt time.Time
+}
+
+// sourceName 返回来源标识，如 tg:频道名、plugin:插件名，与流式搜索事件中的来源一致
+func sourceName(kind, name string) string {
+	return kind + ":" + name
+}
+
+// searchSources 按来源读取缓存并搜索未命中的来源，返回组装后的结果和各来源状态
+// 相同来源和关键词的并发搜索通过flights合并，单个来源失败只影响它自己的状态
+func (s *SearchService) searchSources(ctx context.Context, kind string, names []string, keyword string, forceRefresh bool, concurrency int, flights *flightGroup, fetch sourceFetchFunc) ([]model.SearchResult, []model.SourceStatus, error) {
+	ctx = withRequestContext(ctx)
+	statuses := make([]model.SourceStatus, len(names))
+	keys := make([]string, len(names))
+	var results []model.SearchResult
+	var missing []int
+
+	for i, name := range names {
+		keys[i] = cache.GenerateSourceCacheKey(kind, name, keyword)
+		statuses[i] = model.SourceStatus{Source: sourceName(kind, name), Status: model.SourceStatusFailed}
+
+		if !forceRefresh {
+			if cached, updatedAt, ok := loadSourceCache(keys[i]); ok {
+				statuses[i].Status = model.SourceStatusCached
+				statuses[i].Count = len(cached)
+				statuses[i].UpdatedAt = &updatedAt
+				results = append(results, cached...)
+				continue
+			}
+			// 最近搜索无结果，不再请求
+			if knownEmpty(keys[i]) {
+				statuses[i].Status = model.SourceStatusEmpty
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+
+	if len(missing) == 0 {
+		return results, statuses, nil
+	}
+
+	if concurrency <= 0 {
+		concurrency = config.AppConfig.DefaultConcurrency
+	}
+
+	tasks := make([]pool.ContextTask, 0, len(missing))
+	for _, index := range missing {
+		i := index // 创建副本，避免闭包问题
+		tasks = append(tasks, func(taskCtx context.Context) interface{} {
+			fetched, pending, err := flights.Do(taskCtx, flightKey(keys[i], forceRefresh), func(flightCtx context.Context) ([]model.SearchResult, bool, error) {
+				return fetchSource(flightCtx, kind, names[i], keyword, keys[i], fetch)
+			})
+			return sourceFetchResult{index: i, results: fetched, pending: pending, err: err, fetchedAt: time.Now()}
+		})
+	}
+
+	// 超时未返回的来源保持failed状态
+	for _, item := range pool.ExecuteBatchWithContext(ctx, tasks, concurrency, config.AppConfig.PluginTimeout) {
+		r, ok := item.(sourceFetchResult)
+		if !ok || r.err != nil {
+			continue
+		}
+		statuses[r.index].Count = len(r.results)
+		if r.pending {
+			// 仍在后台搜索，返回的只是部分结果
+			statuses[r.index].Status = model.SourceStatusPending
+		} else {
+			statuses[r.index].Status = model.SourceStatusFresh
+			statuses[r.index].UpdatedAt = &r.fetchedAt
+		}
+		results = append(results, r.results...)
+	}
+
+	// 客户端已断开，结果不完整
+	if err := ctx.Err(); err != nil {
+		return results, statuses, err
+	}
+	return results, statuses, nil
+}
+
+// fetchSource 搜索单个来源，成功时更新无结果缓存并写入分来源缓存；来源仍在后台搜索时pending为true
+func fetchSource(ctx context.Context, kind, name, keyword, key string, fetch sourceFetchFunc) ([]model.SearchResult, bool, error) {
+	start := time.Now()
+	results, pending, err := fetch(ctx, name, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 被取消或仍在后台搜索的结果不完整，不写入缓存，也不记为无结果
+	if ctx.Err() != nil || pending {
+		return results, pending, nil
+	}
+
+	if cacheInitialized {
+		recordSearchOutcome(key, len(results), 1)
+	}
+	if len(results) > 0 {
+		go storeSourceCache(key, results, sourceTTL(kind, name, keyword, results), start)
+	}
+	return results, false, nil
+}
+
+// loadSourceCache 读取单个来源的缓存结果及其写入时间
+func loadSourceCache(key string) ([]model.SearchResult, time.Time, bool) {
+	if !cacheInitialized || !config.AppConfig.CacheEnabled || enhancedTwoLevelCache == nil {
+		return nil, time.Time{}, false
+	}
+
+	data, lastModified, hit, err := enhancedTwoLevelCache.GetWithTimestamp(key)
+	if err != nil || !hit {
+		return nil, time.Time{}, false
+	}
+
+	var results []model.SearchResult
+	if err := enhancedTwoLevelCache.Decode(key, data, &results); err != nil {
+		fmt.Printf("[主服务] 缓存反序列化失败: %s... | 错误: %v\n", key[:8], err)
+		return nil, time.Time{}, false
+	}
+	return results, lastModified, true
+}
+
+// sourceTTL 按有效期策略计算单个来源结果的缓存有效期
+func sourceTTL(kind, name, keyword string, results []model.SearchResult) time.Duration {
+	if kind == cache.KeySourcePlugin {
+		return plugin.CacheTTL(name, keyword, results)
+	}
+	return ttlpolicy.Default().TTL(ttlpolicy.Source{Kind: ttlpolicy.KindTG, Name: name}, keyword, results, 0)
+}
+
+// storeSourceCache 写入单个来源的结果
+// 异步插件可能在前台返回后由后台更新写入更完整的结果，缓存在fetchStart之后已被更新时不覆盖
+func storeSourceCache(key string, results []model.SearchResult, ttl time.Duration, fetchStart time.Time) {
+	if !cacheInitialized || !config.AppConfig.CacheEnabled || enhancedTwoLevelCache == nil {
+		return
+	}
+
+	if _, lastModified, hit, err := enhancedTwoLevelCache.GetWithTimestamp(key); err == nil && hit && lastModified.After(fetchStart) {
+		return
+	}
+
+	data, err := enhancedTwoLevelCache.GetSerializer().Serialize(results)
+	if err != nil {
+		fmt.Printf("[主程序] 缓存序列化失败: %s | 错误: %v\n", key, err)
+		return
+	}
+
+	if err := enhancedTwoLevelCache.SetBothLevels(key, data, ttl); err != nil {
+		fmt.Printf("[主程序] 缓存写入失败: %s | 错误: %v\n", key, err)
+		return
+	}
+	if config.AppConfig.AsyncLogEnabled {
+		fmt.Printf("[主程序] 缓存更新完成: %s | 结果数: %d\n", key, len(results))
+	}
+}
+
+// requestCtxKey 上下文中保存发起搜索的请求上下文的键
+// 来源任务的上下文在批量超时和客户端断开时都会取消，需要对照请求上下文区分两者
+type requestCtxKey struct{}
+
+// withRequestContext 在上下文中记录请求上下文，已记录时保持不变
+func withRequestContext(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestCtxKey{}).(context.Context); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestCtxKey{}, ctx)
+}
+
+// requestCanceled 检查发起搜索的请求是否已取消（如客户端断开）
+func requestCanceled(ctx context.Context) bool {
+	req, ok := ctx.Value(requestCtxKey{}).(context.Context)
+	return ok && req.Err() != nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+	"pansou/plugin"
+	"pansou/util/cache"
+)
+
+// TestMain 使用临时目录初始化配置，缩短插件响应超时以便测试转入后台的搜索
+// 插件的后台搜索在测试结束后仍可能读取配置，因此整个包只初始化一次，不在单个测试中替换
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "pansou-service-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("CACHE_PATH", dir)
+	config.Init()
+	config.AppConfig.AsyncPluginEnabled = true
+	config.AppConfig.AsyncResponseTimeoutDur = 50 * time.Millisecond
+	config.AppConfig.PluginTimeout = 3 * time.Second
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// setupSourceCache 初始化主缓存
+func setupSourceCache(t *testing.T) *SearchService {
+	t.Helper()
+	s := NewSearchService(nil)
+	if !cacheInitialized {
+		t.Fatal("主缓存初始化失败")
+	}
+	return s
+}
+
+func TestSearchSourcesSharesCacheAcrossSubsets(t *testing.T) {
+	s := setupSourceCache(t)
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	fetch := func(ctx context.Context, name string, key string) ([]model.SearchResult, bool, error) {
+		mu.Lock()
+		calls[name]++
+		mu.Unlock()
+		switch name {
+		case "broken":
+			return nil, false, errors.New("upstream down")
+		case "nothing":
+			return nil, false, nil
+		case "slow":
+			// 响应超时，仍在后台搜索
+			return nil, true, nil
+		}
+		return []model.SearchResult{{UniqueID: name + "-1", Title: name, Links: []model.Link{{URL: "https://pan.quark.cn/s/" + name}}}}, false, nil
+	}
+	search := func(names ...string) ([]model.SearchResult, map[string]model.SourceStatus) {
+		results, statuses, err := s.searchSources(context.Background(), cache.KeySourcePlugin, names, "subset", false, 4, newFlightGroup(), fetch)
+		if err != nil {
+			t.Fatalf("searchSources: %v", err)
+		}
+		byName := make(map[string]model.SourceStatus)
+		for _, status := range statuses {
+			byName[status.Source] = status
+		}
+		return results, byName
+	}
+
+	results, statuses := search("a", "b", "broken", "nothing", "slow")
+	if len(results) != 2 {
+		t.Fatalf("结果数 %d，应为2", len(results))
+	}
+	for source, want := range map[string]string{
+		"plugin:a":       model.SourceStatusFresh,
+		"plugin:b":       model.SourceStatusFresh,
+		"plugin:broken":  model.SourceStatusFailed,
+		"plugin:nothing": model.SourceStatusFresh,
+		"plugin:slow":    model.SourceStatusPending,
+	} {
+		if got := statuses[source].Status; got != want {
+			t.Errorf("%s 状态 %s，应为 %s", source, got, want)
+		}
+	}
+	if statuses["plugin:slow"].UpdatedAt != nil {
+		t.Error("仍在后台搜索的来源不应返回获取时间")
+	}
+
+	// 缓存在后台写入
+	key := cache.GenerateSourceCacheKey(cache.KeySourcePlugin, "a", "subset")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, _, ok := loadSourceCache(key); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("来源缓存未写入")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 子集直接命中各来源的缓存，无结果的来源也不再请求
+	results, statuses = search("a", "nothing")
+	if len(results) != 1 || statuses["plugin:a"].Status != model.SourceStatusCached || statuses["plugin:a"].UpdatedAt == nil {
+		t.Errorf("plugin:a 应命中缓存: %+v", statuses["plugin:a"])
+	}
+	if statuses["plugin:nothing"].Status != model.SourceStatusEmpty {
+		t.Errorf("plugin:nothing 状态 %s，应为 empty", statuses["plugin:nothing"].Status)
+	}
+	if calls["a"] != 1 || calls["nothing"] != 1 {
+		t.Errorf("请求次数 a=%d nothing=%d，都应为1", calls["a"], calls["nothing"])
+	}
+
+	// 失败和仍在后台搜索的来源不记为无结果，下次仍会请求
+	search("broken", "slow")
+	if calls["broken"] != 2 || calls["slow"] != 2 {
+		t.Errorf("请求次数 broken=%d slow=%d，都应为2", calls["broken"], calls["slow"])
+	}
+}
+
+func TestRecordPluginOutcomeSeparatesDisconnectFromTimeout(t *testing.T) {
+	s := &SearchService{pluginManager: plugin.NewPluginManager()}
+	health := func() plugin.PluginHealthStatus {
+		return s.pluginManager.HealthStatus([]string{"slow"})[0]
+	}
+
+	// 客户端断开：请求上下文已取消，不计入统计
+	reqCtx, disconnect := context.WithCancel(context.Background())
+	ctx, cancelTask := context.WithCancel(withRequestContext(reqCtx))
+	disconnect()
+	cancelTask()
+	s.recordPluginOutcome(ctx, "slow", nil, ctx.Err(), time.Second)
+	if h := health(); h.Timeouts+h.Errors+h.Empties != 0 {
+		t.Errorf("客户端断开不应计入: %+v", h)
+	}
+
+	// 批量超时：只有任务上下文取消，计为超时
+	ctx, cancelTask = context.WithCancel(withRequestContext(context.Background()))
+	cancelTask()
+	s.recordPluginOutcome(ctx, "slow", nil, ctx.Err(), time.Second)
+	if h := health(); h.Timeouts != 1 {
+		t.Errorf("批量超时应计为超时: %+v", h)
+	}
+
+	// 响应超时后在后台完成：按后台结果计入
+	s.recordPluginOutcome(context.Background(), "slow", []model.SearchResult{{UniqueID: "slow-1"}}, nil, 10*time.Second)
+	if h := health(); h.Successes != 1 || h.ConsecutiveFailures != 0 {
+		t.Errorf("后台完成应计为成功: %+v", h)
+	}
+}
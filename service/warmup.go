@@ -0,0 +1,509 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pansou/config"
+	"pansou/util/cache"
+)
+
+// 缓存预热：在配置的低峰时段定期以refresh=true重新执行热门搜索，
+// 赶在缓存过期前刷新主缓存，高峰期用户直接命中缓存而不必等待上游
+
+// popularityHalfLife 搜索热度的半衰期，一天前的搜索只计一半热度
+const popularityHalfLife = 24 * time.Hour
+
+// popularityMinScore 热度衰减到该值以下的条件不再跟踪（约4天未被搜索）
+const popularityMinScore = 0.05
+
+// WarmupTarget 一次预热搜索的条件，预热时只刷新其中缓存即将过期的频道和插件
+type WarmupTarget struct {
+	Keyword    string   `json:"keyword"`
+	SourceType string   `json:"source_type"`
+	Channels   []string `json:"channels,omitempty"`
+	Plugins    []string `json:"plugins,omitempty"`
+	Seed       bool     `json:"seed,omitempty"`  // 是否为配置的种子关键词
+	Score      float64  `json:"score,omitempty"` // 衰减后的搜索热度
+}
+
+// id 返回搜索条件的唯一标识
+func (t WarmupTarget) id() string {
+	return strings.ToLower(strings.TrimSpace(t.Keyword)) + "|" + t.SourceType + "|" +
+		strings.Join(t.Channels, ",") + "|" + strings.Join(t.Plugins, ",")
+}
+
+// popularSearch 热度表中的一条记录
+type popularSearch struct {
+	target   WarmupTarget
+	score    float64   // lastSeen时刻的热度
+	lastSeen time.Time // 最近一次搜索时间
+}
+
+// scoreAt 计算指定时刻衰减后的热度
+func (p *popularSearch) scoreAt(now time.Time) float64 {
+	age := now.Sub(p.lastSeen)
+	if age <= 0 {
+		return p.score
+	}
+	return p.score * math.Pow(0.5, float64(age)/float64(popularityHalfLife))
+}
+
+// searchPopularity 按搜索条件统计的热度表，热度随时间指数衰减
+type searchPopularity struct {
+	mu      sync.Mutex
+	entries map[string]*popularSearch
+	limit   int
+}
+
+func newSearchPopularity(limit int) *searchPopularity {
+	return &searchPopularity{
+		entries: make(map[string]*popularSearch),
+		limit:   limit,
+	}
+}
+
+// record 记录一次搜索，超过上限时淘汰热度最低的一半
+func (p *searchPopularity) record(target WarmupTarget, now time.Time) {
+	id := target.id()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry, ok := p.entries[id]; ok {
+		entry.score = entry.scoreAt(now) + 1
+		entry.lastSeen = now
+		return
+	}
+	if len(p.entries) >= p.limit {
+		p.pruneLocked(now, p.limit/2)
+	}
+	p.entries[id] = &popularSearch{target: target, score: 1, lastSeen: now}
+}
+
+// prune 删除热度过低的记录
+func (p *searchPopularity) prune(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pruneLocked(now, p.limit)
+}
+
+// pruneLocked 删除热度过低的记录，并只保留热度最高的keep条（调用方需持有锁）
+func (p *searchPopularity) pruneLocked(now time.Time, keep int) {
+	for id, entry := range p.entries {
+		if entry.scoreAt(now) < popularityMinScore {
+			delete(p.entries, id)
+		}
+	}
+	if len(p.entries) <= keep {
+		return
+	}
+	for _, target := range p.rankLocked(now)[keep:] {
+		delete(p.entries, target.id())
+	}
+}
+
+// top 返回热度最高的n个搜索条件
+func (p *searchPopularity) top(n int, now time.Time) []WarmupTarget {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ranked := p.rankLocked(now)
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// rankLocked 按热度从高到低排序（调用方需持有锁）
+func (p *searchPopularity) rankLocked(now time.Time) []WarmupTarget {
+	ranked := make([]WarmupTarget, 0, len(p.entries))
+	for _, entry := range p.entries {
+		target := entry.target
+		target.Score = entry.scoreAt(now)
+		ranked = append(ranked, target)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Keyword < ranked[j].Keyword
+	})
+	return ranked
+}
+
+// size 返回跟踪的搜索条件数
+func (p *searchPopularity) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// quietWindow 每天允许预热的时段，结束时间早于开始时间表示跨越午夜
+type quietWindow struct {
+	start, end int // 距当天零点的分钟数
+	always     bool
+}
+
+// parseQuietWindow 解析"HH:MM-HH:MM"格式的时段，为空表示不限时段
+func parseQuietWindow(value string) (quietWindow, error) {
+	if value == "" {
+		return quietWindow{always: true}, nil
+	}
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return quietWindow{}, fmt.Errorf("时段格式应为HH:MM-HH:MM: %s", value)
+	}
+	var bounds [2]int
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return quietWindow{}, fmt.Errorf("时段格式应为HH:MM-HH:MM: %s", value)
+		}
+		bounds[i] = t.Hour()*60 + t.Minute()
+	}
+	if bounds[0] == bounds[1] {
+		return quietWindow{always: true}, nil
+	}
+	return quietWindow{start: bounds[0], end: bounds[1]}, nil
+}
+
+// contains 判断指定时刻（本地时间）是否在时段内
+func (w quietWindow) contains(now time.Time) bool {
+	if w.always {
+		return true
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// WarmupStatus 缓存预热状态
+type WarmupStatus struct {
+	Enabled         bool           `json:"enabled"`
+	Running         bool           `json:"running"`
+	Window          string         `json:"window,omitempty"`
+	InWindow        bool           `json:"in_window"`
+	IntervalSeconds int            `json:"interval_seconds"`
+	TopN            int            `json:"top_n"`
+	Tracked         int            `json:"tracked"` // 跟踪热度的搜索条件数
+	Runs            int64          `json:"runs"`
+	LastRunAt       *time.Time     `json:"last_run_at,omitempty"`
+	LastDurationMs  int64          `json:"last_duration_ms"`
+	LastWarmed      int            `json:"last_warmed"`  // 上一轮刷新的搜索条件数
+	LastFresh       int            `json:"last_fresh"`   // 上一轮缓存仍在有效期内而跳过的数量
+	LastFailed      int            `json:"last_failed"`  // 上一轮刷新失败的数量
+	LastStopped     bool           `json:"last_stopped"` // 上一轮是否因离开时段或服务关闭而提前结束
+	Candidates      []WarmupTarget `json:"candidates"`   // 下一轮的预热候选
+}
+
+// WarmupScheduler 缓存预热调度器
+type WarmupScheduler struct {
+	service    *SearchService
+	interval   time.Duration
+	stagger    time.Duration
+	topN       int
+	seeds      []string
+	window     quietWindow
+	popularity *searchPopularity
+
+	running int32
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu     sync.Mutex
+	status WarmupStatus
+}
+
+var warmupScheduler *WarmupScheduler
+
+// StartWarmup 按配置启动缓存预热调度器，未启用预热或缓存时返回nil
+func StartWarmup(s *SearchService) *WarmupScheduler {
+	cfg := config.AppConfig
+	if !cfg.WarmupEnabled || !cfg.CacheEnabled || enhancedTwoLevelCache == nil {
+		return nil
+	}
+	window, err := parseQuietWindow(cfg.WarmupWindow)
+	if err != nil {
+		fmt.Printf("[Warmup] WARMUP_WINDOW无效: %v，缓存预热未启动\n", err)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	limit := cfg.WarmupTopN * 20
+	if limit < 1000 {
+		limit = 1000
+	}
+	w := &WarmupScheduler{
+		service:    s,
+		interval:   cfg.WarmupInterval,
+		stagger:    cfg.WarmupStagger,
+		topN:       cfg.WarmupTopN,
+		seeds:      cfg.WarmupKeywords,
+		window:     window,
+		popularity: newSearchPopularity(limit),
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	warmupScheduler = w
+	go w.loop()
+
+	fmt.Printf("[Warmup] 缓存预热已启动: 每%v预热%d个热门搜索和%d个种子关键词，时段: %s\n",
+		w.interval, w.topN, len(w.seeds), w.windowText())
+	return w
+}
+
+// GetWarmupStatus 返回缓存预热状态，未启用时Enabled为false
+func GetWarmupStatus() WarmupStatus {
+	w := warmupScheduler
+	if w == nil {
+		return WarmupStatus{Candidates: []WarmupTarget{}}
+	}
+	now := time.Now()
+
+	w.mu.Lock()
+	status := w.status
+	w.mu.Unlock()
+
+	status.Enabled = true
+	status.Running = atomic.LoadInt32(&w.running) == 1
+	status.Window = w.windowText()
+	status.InWindow = w.window.contains(now)
+	status.IntervalSeconds = int(w.interval / time.Second)
+	status.TopN = w.topN
+	status.Tracked = w.popularity.size()
+	status.Candidates = w.candidates(now)
+	return status
+}
+
+// TriggerWarmup 立即在后台执行一轮预热，忽略时段限制
+// 预热未启用时返回错误，已有一轮在执行时返回false
+func TriggerWarmup() (bool, error) {
+	w := warmupScheduler
+	if w == nil {
+		return false, fmt.Errorf("缓存预热未启用")
+	}
+	if !atomic.CompareAndSwapInt32(&w.running, 0, 1) {
+		return false, nil
+	}
+	go w.run(true)
+	return true, nil
+}
+
+// recordPopularSearch 记录用户搜索的条件，作为预热候选
+// keywords为查询语法解析后发送给上游的关键词，每个OR分支单独记录，
+// 与搜索时使用的缓存键一致
+func recordPopularSearch(keywords []string, sourceType string, channels, plugins []string) {
+	w := warmupScheduler
+	if w == nil {
+		return
+	}
+	now := time.Now()
+	for _, keyword := range keywords {
+		if strings.TrimSpace(keyword) == "" {
+			continue
+		}
+		target := WarmupTarget{Keyword: strings.TrimSpace(keyword), SourceType: sourceType}
+		if sourceType == "all" || sourceType == "tg" {
+			target.Channels = append([]string(nil), channels...)
+		}
+		target.Plugins = append([]string(nil), plugins...)
+		w.popularity.record(target, now)
+	}
+}
+
+// Stop 停止调度器，正在执行的预热搜索会被取消
+func (w *WarmupScheduler) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+// loop 每个周期检查一次是否处于预热时段
+func (w *WarmupScheduler) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.popularity.prune(now)
+			if !w.window.contains(now) {
+				continue
+			}
+			if atomic.CompareAndSwapInt32(&w.running, 0, 1) {
+				w.run(false)
+			}
+		}
+	}
+}
+
+// run 执行一轮预热，调用方需已将running置为1
+// 候选逐个执行，相邻两次间隔stagger，每个上游在此期间最多收到一次预热请求
+func (w *WarmupScheduler) run(force bool) {
+	defer atomic.StoreInt32(&w.running, 0)
+
+	start := time.Now()
+	targets := w.candidates(start)
+	warmed, fresh, failed := 0, 0, 0
+	stopped := false
+
+	for _, target := range targets {
+		if w.ctx.Err() != nil || (!force && !w.window.contains(time.Now())) {
+			stopped = true
+			break
+		}
+		channels, plugins := w.staleSources(target)
+		if len(channels) == 0 && len(plugins) == 0 {
+			fresh++
+			continue
+		}
+		if warmed+failed > 0 && !w.sleep(w.stagger) {
+			stopped = true
+			break
+		}
+		if err := w.warm(target.Keyword, channels, plugins); err != nil {
+			failed++
+			fmt.Printf("[Warmup] 预热 %s 失败: %v\n", target.Keyword, err)
+			continue
+		}
+		warmed++
+	}
+
+	duration := time.Since(start)
+	w.mu.Lock()
+	w.status.Runs++
+	w.status.LastRunAt = &start
+	w.status.LastDurationMs = duration.Milliseconds()
+	w.status.LastWarmed = warmed
+	w.status.LastFresh = fresh
+	w.status.LastFailed = failed
+	w.status.LastStopped = stopped
+	w.mu.Unlock()
+
+	if warmed+failed > 0 || stopped {
+		fmt.Printf("[Warmup] 本轮预热完成: 刷新%d 跳过%d 失败%d 耗时%v\n", warmed, fresh, failed, duration.Round(time.Millisecond))
+	}
+}
+
+// sleep 等待指定时间，调度器停止时返回false
+func (w *WarmupScheduler) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return w.ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// candidates 返回本轮的预热候选：种子关键词在前，其后为热度最高的搜索条件
+func (w *WarmupScheduler) candidates(now time.Time) []WarmupTarget {
+	seen := make(map[string]bool)
+	targets := make([]WarmupTarget, 0, len(w.seeds)+w.topN)
+	for _, keyword := range w.seeds {
+		// 种子关键词使用与不带参数的搜索请求相同的条件
+		target := WarmupTarget{
+			Keyword:    keyword,
+			SourceType: "all",
+			Channels:   config.AppConfig.DefaultChannels,
+			Seed:       true,
+		}
+		if !seen[target.id()] {
+			seen[target.id()] = true
+			targets = append(targets, target)
+		}
+	}
+	for _, target := range w.popularity.top(w.topN+len(targets), now) {
+		if len(targets) >= len(w.seeds)+w.topN {
+			break
+		}
+		if !seen[target.id()] {
+			seen[target.id()] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// searchesTG 判断搜索条件是否包含TG搜索
+func (t WarmupTarget) searchesTG() bool {
+	return (t.SourceType == "all" || t.SourceType == "tg") && len(t.Channels) > 0
+}
+
+// searchesPlugins 判断搜索条件是否包含插件搜索
+func (t WarmupTarget) searchesPlugins() bool {
+	return (t.SourceType == "all" || t.SourceType == "plugin") && config.AppConfig.AsyncPluginEnabled
+}
+
+// staleSources 返回缓存会在下一轮预热前过期的频道和插件
+func (w *WarmupScheduler) staleSources(target WarmupTarget) (channels []string, plugins []string) {
+	deadline := time.Now().Add(w.interval)
+	stale := func(kind, name string) bool {
+		expiry, ok := enhancedTwoLevelCache.GetExpiry(cache.GenerateSourceCacheKey(kind, name, target.Keyword))
+		return !ok || expiry.Before(deadline)
+	}
+
+	if target.searchesTG() {
+		for _, channel := range target.Channels {
+			if stale(cache.KeySourceTG, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	if target.searchesPlugins() {
+		for _, p := range w.service.selectPlugins(target.Plugins) {
+			if stale(cache.KeySourcePlugin, p.Name()) {
+				plugins = append(plugins, p.Name())
+			}
+		}
+	}
+	return channels, plugins
+}
+
+// warm 以refresh=true重新搜索缓存即将过期的频道和插件，结果由正常的搜索流程写入缓存
+// 并发数不超过后台工作者上限，避免预热挤占异步插件的后台更新
+func (w *WarmupScheduler) warm(keyword string, channels, plugins []string) error {
+	concurrency := config.AppConfig.DefaultConcurrency
+	if workers := config.AppConfig.AsyncMaxBackgroundWorkers; workers > 0 && concurrency > workers {
+		concurrency = workers
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	if len(channels) > 0 {
+		if _, _, err := w.service.searchTG(w.ctx, keyword, channels, true); err != nil {
+			return err
+		}
+	}
+	if len(plugins) > 0 {
+		if _, _, err := w.service.searchPlugins(w.ctx, keyword, plugins, true, concurrency, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// windowText 返回时段的文字描述
+func (w *WarmupScheduler) windowText() string {
+	if w.window.always {
+		return "不限"
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.window.start/60, w.window.start%60, w.window.end/60, w.window.end%60)
+}
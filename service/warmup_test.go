@@ -0,0 +1,98 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"pansou/util/query"
+)
+
+func TestQuietWindow(t *testing.T) {
+	at := func(clock string) time.Time {
+		v, _ := time.Parse("15:04", clock)
+		return time.Date(2024, 1, 1, v.Hour(), v.Minute(), 0, 0, time.Local)
+	}
+
+	cases := []struct {
+		window string
+		clock  string
+		want   bool
+	}{
+		{"", "12:00", true},
+		{"02:00-06:00", "02:00", true},
+		{"02:00-06:00", "05:59", true},
+		{"02:00-06:00", "06:00", false},
+		{"23:00-05:00", "23:30", true},
+		{"23:00-05:00", "04:00", true},
+		{"23:00-05:00", "12:00", false},
+	}
+	for _, c := range cases {
+		w, err := parseQuietWindow(c.window)
+		if err != nil {
+			t.Fatalf("parseQuietWindow(%q): %v", c.window, err)
+		}
+		if got := w.contains(at(c.clock)); got != c.want {
+			t.Errorf("%q contains %s = %v, want %v", c.window, c.clock, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"02:00", "2-6", "25:00-06:00"} {
+		if _, err := parseQuietWindow(bad); err == nil {
+			t.Errorf("parseQuietWindow(%q) 应返回错误", bad)
+		}
+	}
+}
+
+func TestSearchPopularityRanking(t *testing.T) {
+	p := newSearchPopularity(4)
+	now := time.Now()
+	target := func(keyword string) WarmupTarget {
+		return WarmupTarget{Keyword: keyword, SourceType: "all", Channels: []string{"tgsearchers4"}}
+	}
+
+	// 两天前搜索三次的热度低于刚刚搜索一次的两倍
+	for i := 0; i < 3; i++ {
+		p.record(target("old"), now.Add(-2*popularityHalfLife))
+	}
+	p.record(target("new"), now)
+	p.record(target("new"), now)
+	p.record(target("once"), now)
+
+	top := p.top(2, now)
+	if len(top) != 2 || top[0].Keyword != "new" || top[1].Keyword != "once" {
+		t.Fatalf("排序错误: %+v", top)
+	}
+
+	// 超过上限时淘汰热度最低的条件
+	p.record(target("a"), now)
+	p.record(target("b"), now)
+	if n := p.size(); n > 4 {
+		t.Errorf("跟踪条件数 %d 超过上限", n)
+	}
+	for _, entry := range p.top(10, now) {
+		if entry.Keyword == "old" {
+			t.Error("热度最低的条件未被淘汰")
+		}
+	}
+}
+
+func TestRecordPopularSearchUsesParsedKeywords(t *testing.T) {
+	saved := warmupScheduler
+	defer func() { warmupScheduler = saved }()
+	warmupScheduler = &WarmupScheduler{popularity: newSearchPopularity(10)}
+
+	q, err := query.Parse(`速度与激情 OR "fast furious" -预告 source:plugin`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordPopularSearch(q.Keywords(), "plugin", nil, []string{"labi"})
+
+	// 记录的是发送给上游的关键词，而不是原始查询语法
+	got := map[string]bool{}
+	for _, target := range warmupScheduler.popularity.top(10, time.Now()) {
+		got[target.Keyword] = true
+	}
+	if len(got) != 2 || !got["速度与激情"] || !got["fast furious"] {
+		t.Errorf("记录的关键词 = %v", got)
+	}
+}
@@ -0,0 +1,402 @@
+package alias
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pansou/config"
+	"pansou/util/textnorm"
+)
+
+// 作品别名词典：同一部作品的中文名、英文名和缩写互为别名，搜索其中任一名称时同时搜索其他名称，
+// 并把英文名作为 title_en 交给支持的插件。词典保存在JSON文件中，可以手工编辑、通过管理接口修改，
+// 也可以从搜索结果中学习：多数结果标题在中文名后附带同一个英文名时，该英文名记为学习到的别名
+
+// 默认词典文件名（位于CACHE_PATH下）
+const defaultFileName = "title_aliases.json"
+
+// maxLearned 每部作品最多保留的学习别名数
+const maxLearned = 5
+
+// 词典修改错误
+var (
+	ErrConflict   = errors.New("别名已属于其他作品")
+	ErrEmptyTitle = errors.New("作品名称不能为空")
+)
+
+// Entry 一部作品的名称
+type Entry struct {
+	Title     string    `json:"title"`              // 规范名称，一般为中文名
+	TitleEn   string    `json:"title_en,omitempty"` // 英文名，为空时取第一个英文别名
+	Aliases   []string  `json:"aliases,omitempty"`  // 手工维护的别名
+	Learned   []string  `json:"learned,omitempty"`  // 从搜索结果学习到的别名
+	Rejected  []string  `json:"rejected,omitempty"` // 被删除的学习别名，不再学习
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Names 返回作品的所有名称，规范名称在前，随后是英文名、手工别名和学习别名
+func (e Entry) Names() []string {
+	names := []string{e.Title}
+	for _, name := range append(append([]string{e.TitleEn}, e.Aliases...), e.Learned...) {
+		if name != "" && !containsFolded(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// English 返回作品的英文名
+func (e Entry) English() string {
+	if e.TitleEn != "" {
+		return e.TitleEn
+	}
+	for _, name := range append(append([]string{}, e.Aliases...), e.Learned...) {
+		if isLatin(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Dictionary 别名词典
+type Dictionary struct {
+	mu      sync.RWMutex
+	path    string            // 持久化文件路径，为空时只保存在内存
+	entries map[string]*Entry // 键为归一化的规范名称
+	index   map[string]string // 归一化的名称到规范名称键
+}
+
+// New 创建别名词典，path为空时不持久化
+func New(path string) *Dictionary {
+	return &Dictionary{
+		path:    path,
+		entries: make(map[string]*Entry),
+		index:   make(map[string]string),
+	}
+}
+
+var (
+	defaultDict     *Dictionary
+	defaultDictOnce sync.Once
+)
+
+// Default 返回按应用配置加载的全局词典
+func Default() *Dictionary {
+	defaultDictOnce.Do(func() {
+		path := ""
+		if config.AppConfig != nil {
+			path = config.AppConfig.AliasDictPath
+			if path == "" {
+				path = filepath.Join(config.AppConfig.CachePath, defaultFileName)
+			}
+		}
+		defaultDict = New(path)
+		if err := defaultDict.Load(); err != nil {
+			fmt.Printf("[Alias] 加载别名词典失败: %v\n", err)
+		} else if n := defaultDict.Len(); n > 0 {
+			fmt.Printf("[Alias] 已加载别名词典: %d 部作品\n", n)
+		}
+	})
+	return defaultDict
+}
+
+// Load 从文件加载词典，文件不存在时为空词典
+func (d *Dictionary) Load() error {
+	if d.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("解析别名词典失败: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = make(map[string]*Entry, len(entries))
+	d.index = make(map[string]string)
+	for i := range entries {
+		if entries[i].Title == "" {
+			continue
+		}
+		// 手工编辑的文件中重复的名称只保留先出现的
+		if d.conflictLocked(entries[i]) != "" {
+			fmt.Printf("[Alias] 跳过与其他作品重名的条目: %s\n", entries[i].Title)
+			continue
+		}
+		d.putLocked(&entries[i])
+	}
+	return nil
+}
+
+// Len 返回作品数
+func (d *Dictionary) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.entries)
+}
+
+// Lookup 按任一名称查找作品
+func (d *Dictionary) Lookup(name string) (Entry, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	key, ok := d.index[textnorm.Fold(name)]
+	if !ok {
+		return Entry{}, false
+	}
+	return d.entries[key].clone(), true
+}
+
+// Expand 返回关键词所属作品的其他名称（最多max个）和英文名，关键词不在词典中时返回空
+func (d *Dictionary) Expand(keyword string, max int) ([]string, string) {
+	entry, ok := d.Lookup(keyword)
+	if !ok {
+		return nil, ""
+	}
+	folded := textnorm.Fold(keyword)
+	var others []string
+	for _, name := range entry.Names() {
+		if len(others) >= max {
+			break
+		}
+		if textnorm.Fold(name) != folded {
+			others = append(others, name)
+		}
+	}
+	return others, entry.English()
+}
+
+// List 返回名称包含filter的作品，按规范名称排序，filter为空时返回全部
+func (d *Dictionary) List(filter string) []Entry {
+	filter = textnorm.Fold(filter)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(d.entries))
+	for _, entry := range d.entries {
+		if filter == "" || entry.matches(filter) {
+			entries = append(entries, entry.clone())
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Title < entries[j].Title
+	})
+	return entries
+}
+
+// Put 新增或替换作品的规范名称、英文名和手工别名，已学习的别名保留
+// 按规范名称或任一名称找到已有作品时更新该作品；名称属于其他作品时返回ErrConflict
+func (d *Dictionary) Put(entry Entry) (Entry, error) {
+	entry.Title = strings.TrimSpace(entry.Title)
+	if entry.Title == "" {
+		return Entry{}, ErrEmptyTitle
+	}
+	entry.TitleEn = strings.TrimSpace(entry.TitleEn)
+	entry.Aliases = cleanNames(entry.Aliases)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	existing, ok := d.entries[d.index[textnorm.Fold(entry.Title)]]
+	entry.Learned, entry.Rejected = nil, nil
+	if ok {
+		entry.Learned = removeFolded(existing.Learned, entry.Aliases)
+		entry.Rejected = existing.Rejected
+		d.removeLocked(textnorm.Fold(existing.Title))
+	}
+	if conflict := d.conflictLocked(entry); conflict != "" {
+		if ok {
+			d.putLocked(existing)
+		}
+		return Entry{}, fmt.Errorf("%w: %s", ErrConflict, conflict)
+	}
+
+	entry.UpdatedAt = time.Now()
+	d.putLocked(&entry)
+	return entry.clone(), d.saveLocked()
+}
+
+// Delete 按任一名称删除作品，作品不存在时返回false
+func (d *Dictionary) Delete(title string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key, ok := d.index[textnorm.Fold(title)]
+	if !ok {
+		return false, nil
+	}
+	d.removeLocked(key)
+	return true, d.saveLocked()
+}
+
+// RemoveAlias 删除作品的一个别名，删除的学习别名记入Rejected以免再次学习
+// 作品或别名不存在时返回false，规范名称不能删除
+func (d *Dictionary) RemoveAlias(title, name string) (Entry, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key, ok := d.index[textnorm.Fold(title)]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	entry := d.entries[key].clone()
+	folded := textnorm.Fold(name)
+	if folded == key {
+		return Entry{}, false, nil
+	}
+
+	switch {
+	case containsFolded(entry.Learned, name):
+		entry.Learned = removeFolded(entry.Learned, []string{name})
+		entry.Rejected = append(entry.Rejected, name)
+	case containsFolded(entry.Aliases, name):
+		entry.Aliases = removeFolded(entry.Aliases, []string{name})
+	case textnorm.Fold(entry.TitleEn) == folded:
+		entry.TitleEn = ""
+	default:
+		return Entry{}, false, nil
+	}
+
+	entry.UpdatedAt = time.Now()
+	d.removeLocked(key)
+	d.putLocked(&entry)
+	return entry.clone(), true, d.saveLocked()
+}
+
+// putLocked 添加作品并建立名称索引（调用方需持有写锁）
+func (d *Dictionary) putLocked(entry *Entry) {
+	key := textnorm.Fold(entry.Title)
+	d.entries[key] = entry
+	for _, name := range entry.Names() {
+		d.index[textnorm.Fold(name)] = key
+	}
+}
+
+// removeLocked 删除作品及其名称索引（调用方需持有写锁）
+func (d *Dictionary) removeLocked(key string) {
+	entry, ok := d.entries[key]
+	if !ok {
+		return
+	}
+	for _, name := range entry.Names() {
+		delete(d.index, textnorm.Fold(name))
+	}
+	delete(d.entries, key)
+}
+
+// conflictLocked 返回entry中已属于词典中作品的名称，没有冲突时返回空（调用方需持有锁）
+func (d *Dictionary) conflictLocked(entry Entry) string {
+	for _, name := range entry.Names() {
+		if _, ok := d.index[textnorm.Fold(name)]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// saveLocked 将词典写入文件（调用方需持有锁）
+func (d *Dictionary) saveLocked() error {
+	if d.path == "" {
+		return nil
+	}
+
+	entries := make([]*Entry, 0, len(d.entries))
+	for _, entry := range d.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Title < entries[j].Title
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := d.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, d.path)
+}
+
+// matches 检查作品的任一名称是否包含归一化后的filter
+func (e *Entry) matches(filter string) bool {
+	for _, name := range e.Names() {
+		if strings.Contains(textnorm.Fold(name), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// clone 复制作品，避免调用方修改词典中的切片
+func (e *Entry) clone() Entry {
+	c := *e
+	c.Aliases = append([]string(nil), e.Aliases...)
+	c.Learned = append([]string(nil), e.Learned...)
+	c.Rejected = append([]string(nil), e.Rejected...)
+	return c
+}
+
+// cleanNames 去掉空白和归一化后重复的名称
+func cleanNames(names []string) []string {
+	var cleaned []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" && !containsFolded(cleaned, name) {
+			cleaned = append(cleaned, name)
+		}
+	}
+	return cleaned
+}
+
+// containsFolded 检查names中是否有与name归一化后相同的名称
+func containsFolded(names []string, name string) bool {
+	folded := textnorm.Fold(name)
+	for _, n := range names {
+		if textnorm.Fold(n) == folded {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFolded 移除names中与remove任一名称归一化后相同的名称
+func removeFolded(names []string, remove []string) []string {
+	var kept []string
+	for _, name := range names {
+		if !containsFolded(remove, name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// isLatin 检查名称是否只由ASCII字符组成且包含字母
+func isLatin(name string) bool {
+	hasLetter := false
+	for _, r := range name {
+		if r > 0x7f {
+			return false
+		}
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			hasLetter = true
+		}
+	}
+	return hasLetter
+}
@@ -0,0 +1,75 @@
+package alias
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPutLookupExpand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	d := New(path)
+
+	if _, err := d.Put(Entry{Title: "复仇者联盟4", TitleEn: "Avengers Endgame", Aliases: []string{"复联4", "終局之戰", "复联4"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Put(Entry{Title: "银翼杀手2049", Aliases: []string{"复联4"}}); !errors.Is(err, ErrConflict) {
+		t.Errorf("重复的别名应返回ErrConflict: %v", err)
+	}
+	if _, err := d.Put(Entry{Title: " "}); !errors.Is(err, ErrEmptyTitle) {
+		t.Errorf("空名称应返回ErrEmptyTitle: %v", err)
+	}
+
+	// 任一名称都能找到作品，繁简体不影响
+	others, titleEn := d.Expand("终局之战", 2)
+	if want := []string{"复仇者联盟4", "Avengers Endgame"}; !reflect.DeepEqual(others, want) || titleEn != "Avengers Endgame" {
+		t.Errorf("Expand() = %q %q", others, titleEn)
+	}
+	if others, _ := d.Expand("速度与激情", 2); others != nil {
+		t.Errorf("不在词典中的关键词不应扩展: %q", others)
+	}
+
+	// 重新加载文件后内容不变
+	reloaded := New(path)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := reloaded.Lookup("AVENGERS ENDGAME"); !ok || entry.Title != "复仇者联盟4" || len(entry.Aliases) != 2 {
+		t.Errorf("重新加载后查找错误: %+v %v", entry, ok)
+	}
+}
+
+func TestLearn(t *testing.T) {
+	d := New("")
+	titles := []string{
+		"复仇者联盟4：终局之战 Avengers.Endgame.2019.2160p.BluRay",
+		"【4K】复仇者联盟4 Avengers Endgame (2019) 国英双语",
+		"复仇者联盟4 终局之战 Avengers: Endgame 1080p",
+		"复仇者联盟4 Marvel Studios 2019",
+		"复仇者联盟4 国语中字",
+	}
+	if got := d.Learn("复仇者联盟4", titles[:2]); got != "" {
+		t.Errorf("标题不足时不应学习: %q", got)
+	}
+	if got := d.Learn("复仇者联盟4", titles); got != "avengers endgame" {
+		t.Fatalf("Learn() = %q", got)
+	}
+	entry, _ := d.Lookup("avengers endgame")
+	if entry.Title != "复仇者联盟4" || entry.English() != "avengers endgame" {
+		t.Errorf("学习的别名应成为英文名: %+v", entry)
+	}
+
+	// 删除的学习别名不再学习
+	if _, ok, _ := d.RemoveAlias("复仇者联盟4", "Avengers Endgame"); !ok {
+		t.Fatal("删除学习别名失败")
+	}
+	if got := d.Learn("复仇者联盟4", titles); got != "" {
+		t.Errorf("删除过的别名不应再次学习: %q", got)
+	}
+
+	// 英文关键词不学习
+	if got := d.Learn("Avengers", titles); got != "" {
+		t.Errorf("英文关键词不应学习: %q", got)
+	}
+}
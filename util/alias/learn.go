@@ -0,0 +1,159 @@
+package alias
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"pansou/util/textnorm"
+)
+
+// 从中文关键词的搜索结果中学习英文名：资源标题常写作"中文名 [中文副标题] English Name 2019 2160p ..."，
+// 取中文名之后的英文片段并去掉年份、分辨率、编码等发布信息，足够多的标题给出同一个英文名时记为学习别名
+
+// 学习阈值
+const (
+	minLearnVotes  = 3   // 至少有这么多条标题给出同一个英文名
+	minLearnShare  = 0.5 // 且不少于给出英文名的标题的一半
+	maxSkipTokens  = 3   // 中文名之后最多跳过的中文副标题、集数等片段数
+	maxLearnedWord = 6   // 英文名最多的单词数
+)
+
+// releaseWords 标题中英文名之后的常见发布信息和网址片段
+var releaseWords = map[string]bool{
+	"uhd": true, "hdr": true, "hdr10": true, "dv": true, "sdr": true, "web": true, "dl": true, "webrip": true,
+	"bluray": true, "blu": true, "ray": true, "bdrip": true, "remux": true, "hevc": true, "avc": true,
+	"aac": true, "ac3": true, "dts": true, "atmos": true, "truehd": true, "ddp": true, "mp4": true, "mkv": true,
+	"complete": true, "repack": true, "proper": true, "extended": true, "hd": true, "fhd": true, "tc": true,
+	"ts": true, "cam": true, "chs": true, "cht": true, "eng": true, "mandarin": true, "cantonese": true,
+	"www": true, "com": true, "net": true, "org": true,
+}
+
+// releaseTokenRegex 年份、分辨率、季集、编码等发布信息
+var releaseTokenRegex = regexp.MustCompile(`^((19|20)\d{2}|\d{3,4}[pi]|\d+k|s\d{1,3}(e\d{1,4})?|e[p]?\d{1,4}|x26[45]|h26[45]|\d+bit|\d+(\.\d+)?(gb|mb|fps))$`)
+
+// Learn 从中文关键词的结果标题中学习英文别名，返回新学习到的别名，没有学到时返回空
+// 已属于其他作品、被删除过或作品学习别名已满时不学习
+func (d *Dictionary) Learn(keyword string, titles []string) string {
+	folded := textnorm.Fold(keyword)
+	if !hasHan(folded) || strings.ContainsFunc(folded, isASCIILetter) {
+		return ""
+	}
+
+	votes := make(map[string]int)
+	voted := 0
+	for _, title := range titles {
+		ft := textnorm.Fold(title)
+		idx := strings.Index(ft, folded)
+		if idx < 0 {
+			continue
+		}
+		if name := englishName(ft[idx+len(folded):]); name != "" {
+			votes[name]++
+			voted++
+		}
+	}
+
+	best, count := "", 0
+	for name, n := range votes {
+		if n > count || (n == count && name < best) {
+			best, count = name, n
+		}
+	}
+	if count < minLearnVotes || float64(count) < minLearnShare*float64(voted) {
+		return ""
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, taken := d.index[textnorm.Fold(best)]; taken {
+		return ""
+	}
+	entry := &Entry{Title: strings.TrimSpace(keyword)}
+	if key, ok := d.index[folded]; ok {
+		existing := d.entries[key]
+		if containsFolded(existing.Rejected, best) || len(existing.Learned) >= maxLearned {
+			return ""
+		}
+		clone := existing.clone()
+		entry = &clone
+		d.removeLocked(key)
+	}
+	entry.Learned = append(entry.Learned, best)
+	entry.UpdatedAt = time.Now()
+	d.putLocked(entry)
+	if err := d.saveLocked(); err != nil {
+		fmt.Printf("[Alias] 保存别名词典失败: %v\n", err)
+	}
+	fmt.Printf("[Alias] 学习到别名: %s -> %s (%d/%d条标题)\n", entry.Title, best, count, voted)
+	return best
+}
+
+// englishName 从中文名之后的归一化文本中提取英文名，跳过开头的中文副标题和集数
+func englishName(rest string) string {
+	tokens := strings.Fields(rest)
+	i := 0
+	for i < len(tokens) && i < maxSkipTokens && !startsName(tokens[i]) {
+		i++
+	}
+
+	var words []string
+	for ; i < len(tokens); i++ {
+		token := tokens[i]
+		if (!isLatin(token) && !isDigits(token)) || releaseWords[token] || releaseTokenRegex.MatchString(token) {
+			break
+		}
+		words = append(words, token)
+	}
+	// 末尾的数字通常是集数而不是续集编号
+	for len(words) > 0 && isDigits(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 || len(words) > maxLearnedWord || !hasLongWord(words) {
+		return ""
+	}
+	return strings.Join(words, " ")
+}
+
+// startsName 检查片段能否作为英文名的开头：只由ASCII字符组成、含字母且不是发布信息
+func startsName(token string) bool {
+	return isLatin(token) && !releaseWords[token] && !releaseTokenRegex.MatchString(token)
+}
+
+// hasLongWord 检查是否至少有一个三个字母以上的单词，排除只有缩写或字母编号的片段
+func hasLongWord(words []string) bool {
+	for _, word := range words {
+		letters := 0
+		for _, r := range word {
+			if isASCIILetter(r) {
+				letters++
+			}
+		}
+		if letters >= 3 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHan 检查文本是否包含汉字
+func hasHan(s string) bool {
+	return strings.ContainsFunc(s, func(r rune) bool { return unicode.Is(unicode.Han, r) })
+}
+
+// isASCIILetter 检查是否为ASCII字母
+func isASCIILetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// isDigits 检查片段是否只由数字组成
+func isDigits(token string) bool {
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return token != ""
+}
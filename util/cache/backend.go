@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CacheBackend EnhancedTwoLevelCache的二级存储
+// ShardedDiskCache（本地磁盘）和RedisCache（远程共享）均实现该接口
+type CacheBackend interface {
+	Set(key string, data []byte, ttl time.Duration) error
+	Get(key string) ([]byte, bool, error)
+	Delete(key string) error
+	Clear() error
+	GetLastModified(key string) (time.Time, bool)
+}
+
+// timestampedBackend 可在一次读取中同时返回最后修改时间的存储
+type timestampedBackend interface {
+	GetWithTimestamp(key string) ([]byte, time.Time, bool, error)
+}
+
+// getWithTimestamp 读取数据和最后修改时间，存储支持时只访问一次
+func getWithTimestamp(backend CacheBackend, key string) ([]byte, time.Time, bool, error) {
+	if tb, ok := backend.(timestampedBackend); ok {
+		return tb.GetWithTimestamp(key)
+	}
+	data, hit, err := backend.Get(key)
+	if err != nil || !hit {
+		return nil, time.Time{}, false, err
+	}
+	lastModified, _ := backend.GetLastModified(key)
+	return data, lastModified, true, nil
+}
+
+// expiryBackend 可查询过期时间的存储
+type expiryBackend interface {
+	GetExpiry(key string) (time.Time, bool)
+}
+
+// remainingTTL 返回缓存项的剩余有效期，存储不支持查询或已过期时返回fallback
+func remainingTTL(backend CacheBackend, key string, fallback time.Duration) time.Duration {
+	if eb, ok := backend.(expiryBackend); ok {
+		if expiry, found := eb.GetExpiry(key); found {
+			if remaining := time.Until(expiry); remaining > 0 {
+				return remaining
+			}
+		}
+	}
+	return fallback
+}
+
+// finalFlagBackend 可记录数据是否为完整结果的存储
+type finalFlagBackend interface {
+	SetWithFinal(key string, data []byte, ttl time.Duration, final bool) error
+}
+
+// setWithFinal 写入数据，存储支持时同时记录是否为完整结果
+func setWithFinal(backend CacheBackend, key string, data []byte, ttl time.Duration, final bool) error {
+	if fb, ok := backend.(finalFlagBackend); ok {
+		return fb.SetWithFinal(key, data, ttl, final)
+	}
+	return backend.Set(key, data, ttl)
+}
+
+// remoteRetryInterval 远程缓存出错后改用本地缓存的时间，到期后再次尝试远程缓存
+const remoteRetryInterval = 10 * time.Second
+
+// FallbackBackend 优先使用远程缓存，远程缓存不可用时读写本地缓存
+type FallbackBackend struct {
+	remote CacheBackend
+	local  CacheBackend
+	name   string // 日志中的远程缓存名称
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// NewFallbackBackend 创建带本地回退的远程缓存
+func NewFallbackBackend(name string, remote, local CacheBackend) *FallbackBackend {
+	return &FallbackBackend{remote: remote, local: local, name: name}
+}
+
+// remoteAvailable 远程缓存是否可用（不在出错后的等待期内）
+func (b *FallbackBackend) remoteAvailable() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().After(b.downUntil)
+}
+
+// remoteUnreachable 判断远程缓存的错误是否为连接或传输错误
+// Redis对单条命令的错误回复和无法解码的单个缓存值只影响对应的键，不切换到本地缓存
+func remoteUnreachable(err error) bool {
+	var reply respError
+	return !errors.As(err, &reply) && !errors.Is(err, errCorruptRedisValue)
+}
+
+// markRemoteDown 记录远程缓存出错，等待期内改用本地缓存
+func (b *FallbackBackend) markRemoteDown(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().After(b.downUntil) {
+		fmt.Printf("[Cache] %s 不可用: %v，%v 内使用本地磁盘缓存\n", b.name, err, remoteRetryInterval)
+	}
+	b.downUntil = time.Now().Add(remoteRetryInterval)
+}
+
+// Set 设置缓存，远程写入失败时写入本地
+func (b *FallbackBackend) Set(key string, data []byte, ttl time.Duration) error {
+	return b.SetWithFinal(key, data, ttl, true)
+}
+
+// SetWithFinal 设置缓存，写入本地时记录数据是否为完整结果
+func (b *FallbackBackend) SetWithFinal(key string, data []byte, ttl time.Duration, final bool) error {
+	if b.remoteAvailable() {
+		err := setWithFinal(b.remote, key, data, ttl, final)
+		if err == nil {
+			return nil
+		}
+		if remoteUnreachable(err) {
+			b.markRemoteDown(err)
+		}
+	}
+	return setWithFinal(b.local, key, data, ttl, final)
+}
+
+// Get 获取缓存
+func (b *FallbackBackend) Get(key string) ([]byte, bool, error) {
+	data, _, hit, err := b.GetWithTimestamp(key)
+	return data, hit, err
+}
+
+// GetWithTimestamp 获取缓存及其最后修改时间
+// 远程未命中时也检查本地，以便读到远程不可用期间写入本地的数据
+func (b *FallbackBackend) GetWithTimestamp(key string) ([]byte, time.Time, bool, error) {
+	if b.remoteAvailable() {
+		data, lastModified, hit, err := getWithTimestamp(b.remote, key)
+		if err == nil && hit {
+			return data, lastModified, true, nil
+		}
+		if err != nil {
+			if !remoteUnreachable(err) {
+				// 只有这个键的数据有问题，按未命中处理
+				fmt.Printf("[Cache] %s 读取 %s 失败: %v\n", b.name, key, err)
+			} else {
+				b.markRemoteDown(err)
+			}
+		}
+	}
+	return getWithTimestamp(b.local, key)
+}
+
+// GetLastModified 获取缓存项的最后修改时间
+func (b *FallbackBackend) GetLastModified(key string) (time.Time, bool) {
+	_, lastModified, hit, err := b.GetWithTimestamp(key)
+	if err != nil || !hit {
+		return time.Time{}, false
+	}
+	return lastModified, true
+}
+
+// Delete 同时删除远程和本地缓存
+func (b *FallbackBackend) Delete(key string) error {
+	if b.remoteAvailable() {
+		if err := b.remote.Delete(key); err != nil && remoteUnreachable(err) {
+			b.markRemoteDown(err)
+		}
+	}
+	return b.local.Delete(key)
+}
+
+// Clear 同时清空远程和本地缓存
+func (b *FallbackBackend) Clear() error {
+	var remoteErr error
+	if b.remoteAvailable() {
+		if remoteErr = b.remote.Clear(); remoteErr != nil && remoteUnreachable(remoteErr) {
+			b.markRemoteDown(remoteErr)
+		}
+	}
+	if err := b.local.Clear(); err != nil {
+		return err
+	}
+	return remoteErr
+}
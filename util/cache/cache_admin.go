@@ -0,0 +1,333 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"pansou/util/json"
+)
+
+// 缓存快照格式
+const (
+	snapshotVersion      = 1
+	snapshotManifestName = "snapshot.json"
+	snapshotEntryDir     = "entries/"
+	snapshotMaxMetaSize  = 1 << 20 // 单个元数据文件的大小上限
+)
+
+// 缓存项所在层级
+const (
+	TierMemory = "memory"
+	TierDisk   = "disk"
+)
+
+// CacheEntry 缓存项概要，供缓存管理接口展示
+type CacheEntry struct {
+	Key          string    `json:"key"`
+	Keyword      string    `json:"keyword,omitempty"`
+	Source       string    `json:"source,omitempty"`
+	Targets      []string  `json:"targets,omitempty"`
+	Tiers        []string  `json:"tiers"`
+	Size         int       `json:"size"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	LastModified time.Time `json:"last_modified"`
+	Final        bool      `json:"final"` // false表示仅在内存中、尚未写入磁盘的中间结果
+}
+
+// CacheFilter 缓存项筛选条件，各条件同时满足才匹配，全部为空时匹配所有缓存项
+type CacheFilter struct {
+	Keyword string // 关键词（标准化后完全匹配）
+	Source  string // tg 或 plugin
+	Plugin  string // 包含该插件结果的插件搜索缓存（含未指定插件、搜索全部插件的缓存）
+	Channel string // 包含该频道结果的TG搜索缓存（含未指定频道的缓存）
+}
+
+// IsEmpty 是否未设置任何条件
+func (f CacheFilter) IsEmpty() bool {
+	return f.Keyword == "" && f.Source == "" && f.Plugin == "" && f.Channel == ""
+}
+
+// Match 判断缓存项是否满足条件，没有搜索条件信息的缓存项只在条件为空时匹配
+func (f CacheFilter) Match(entry CacheEntry) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	if entry.Source == "" {
+		return false
+	}
+	info := KeyInfo{Keyword: entry.Keyword, Source: entry.Source, Targets: entry.Targets}
+	if f.Keyword != "" && info.Keyword != normalizeKeyword(f.Keyword) {
+		return false
+	}
+	if f.Source != "" && info.Source != f.Source {
+		return false
+	}
+	if f.Plugin != "" && (info.Source != KeySourcePlugin || !info.hasTarget(f.Plugin)) {
+		return false
+	}
+	if f.Channel != "" && (info.Source != KeySourceTG || !info.hasTarget(f.Channel)) {
+		return false
+	}
+	return true
+}
+
+// ListEntries 列出内存和本地磁盘中满足条件的缓存项，按最后修改时间倒序
+// 配置Redis时只包含本实例写入过本地的缓存项
+func (c *EnhancedTwoLevelCache) ListEntries(filter CacheFilter) []CacheEntry {
+	entries := make(map[string]*CacheEntry)
+
+	for key, item := range c.memory.GetAllItems() {
+		entry := &CacheEntry{
+			Key:          key,
+			Tiers:        []string{TierMemory},
+			Size:         len(item.Data),
+			ExpiresAt:    time.Now().Add(item.TTL),
+			LastModified: item.LastModified,
+			Final:        item.Final,
+		}
+		applyKeyInfo(entry, item.Info)
+		entries[key] = entry
+	}
+
+	for _, meta := range c.local.entries() {
+		entry, inMemory := entries[meta.Key]
+		if !inMemory {
+			entry = &CacheEntry{
+				Key:          meta.Key,
+				Size:         meta.Size,
+				ExpiresAt:    meta.Expiry,
+				LastModified: meta.LastModified,
+				Final:        !meta.Partial,
+			}
+			applyKeyInfo(entry, meta.Info)
+			entries[meta.Key] = entry
+		} else if entry.Source == "" {
+			applyKeyInfo(entry, meta.Info)
+		}
+		entry.Tiers = append(entry.Tiers, TierDisk)
+	}
+
+	result := make([]CacheEntry, 0, len(entries))
+	for _, entry := range entries {
+		if filter.Match(*entry) {
+			result = append(result, *entry)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastModified.After(result[j].LastModified)
+	})
+	return result
+}
+
+// applyKeyInfo 填充缓存项的搜索条件
+func applyKeyInfo(entry *CacheEntry, info *KeyInfo) {
+	if info == nil {
+		return
+	}
+	entry.Keyword = info.Keyword
+	entry.Source = info.Source
+	entry.Targets = info.Targets
+}
+
+// Purge 删除满足条件的缓存项（内存和二级缓存），返回删除数量
+func (c *EnhancedTwoLevelCache) Purge(filter CacheFilter) (int, error) {
+	var lastErr error
+	entries := c.ListEntries(filter)
+	for _, entry := range entries {
+		if err := c.Delete(entry.Key); err != nil {
+			lastErr = err
+		}
+	}
+	return len(entries), lastErr
+}
+
+// snapshotManifest 快照描述文件
+type snapshotManifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Entries   int       `json:"entries"`
+}
+
+// ExportSnapshot 将内存缓存写入本地磁盘后，把本地磁盘缓存导出为tar.gz快照，返回导出的缓存项数量
+// 快照按缓存项保存，不依赖分片数量，可导入到CPU核数不同的实例
+func (c *EnhancedTwoLevelCache) ExportSnapshot(w io.Writer) (int, error) {
+	if err := c.flushMemoryToLocal(); err != nil {
+		fmt.Printf("[Cache] 导出快照前同步内存缓存失败: %v\n", err)
+	}
+
+	metas := c.local.entries()
+	sort.Slice(metas, func(i, j int) bool { return metas[i].Key < metas[j].Key })
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	manifest, err := json.Marshal(snapshotManifest{Version: snapshotVersion, CreatedAt: now, Entries: len(metas)})
+	if err != nil {
+		return 0, err
+	}
+	if err := writeTarFile(tw, snapshotManifestName, manifest, now); err != nil {
+		return 0, err
+	}
+
+	exported := 0
+	for _, meta := range metas {
+		// 读取期间被淘汰或已过期的缓存项直接跳过
+		data, current, ok := c.local.readEntry(meta.Key)
+		if !ok {
+			continue
+		}
+		metaData, err := json.Marshal(current)
+		if err != nil {
+			return exported, err
+		}
+		name := snapshotEntryDir + c.local.getShard(meta.Key).getFilename(meta.Key)
+		if err := writeTarFile(tw, name+".meta", metaData, current.LastModified); err != nil {
+			return exported, err
+		}
+		if err := writeTarFile(tw, name+".data", data, current.LastModified); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+
+	if err := tw.Close(); err != nil {
+		return exported, err
+	}
+	return exported, gz.Close()
+}
+
+// flushMemoryToLocal 与FlushMemoryToDisk相同，但写入本地磁盘并保留最后修改时间和完整标记
+// 配置Redis时FlushMemoryToDisk写入的是Redis，导出快照需要内存数据落到本地磁盘
+func (c *EnhancedTwoLevelCache) flushMemoryToLocal() error {
+	var lastErr error
+	now := time.Now()
+	for key, item := range c.memory.GetAllItems() {
+		meta := diskCacheMetadata{
+			Key:          key,
+			Expiry:       now.Add(item.TTL),
+			LastModified: item.LastModified,
+			Partial:      !item.Final,
+			Info:         item.Info,
+		}
+		data := item.Data
+		if packed, err := transcode(data, c.compression); err == nil {
+			data = packed
+		}
+		if err := c.local.restore(meta, data); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// writeTarFile 向tar写入一个文件
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// ImportSnapshot 从ExportSnapshot生成的快照导入缓存项到本地磁盘，保留原有的过期时间
+// 已过期的缓存项被跳过；内存中的同名缓存项被删除，下次读取时从磁盘加载导入的数据
+func (c *EnhancedTwoLevelCache) ImportSnapshot(r io.Reader) (imported, skipped int, err error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, 0, fmt.Errorf("快照不是有效的gzip文件: %v", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var pending *diskCacheMetadata // 等待对应数据文件的元数据
+	var pendingName string
+	now := time.Now()
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, skipped, fmt.Errorf("读取快照失败: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := header.Name
+		switch {
+		case name == snapshotManifestName:
+			var manifest snapshotManifest
+			if err := decodeTarJSON(tr, &manifest); err != nil {
+				return imported, skipped, fmt.Errorf("快照描述文件格式错误: %v", err)
+			}
+			if manifest.Version != snapshotVersion {
+				return imported, skipped, fmt.Errorf("不支持的快照版本: %d", manifest.Version)
+			}
+
+		case strings.HasSuffix(name, ".meta"):
+			var meta diskCacheMetadata
+			if err := decodeTarJSON(tr, &meta); err != nil || meta.Key == "" {
+				return imported, skipped, fmt.Errorf("缓存项元数据格式错误: %s", name)
+			}
+			pending, pendingName = &meta, strings.TrimSuffix(name, ".meta")
+
+		case strings.HasSuffix(name, ".data"):
+			if pending == nil || strings.TrimSuffix(name, ".data") != pendingName {
+				return imported, skipped, fmt.Errorf("缓存项缺少元数据: %s", path.Base(name))
+			}
+			meta := *pending
+			pending = nil
+			if now.After(meta.Expiry) {
+				skipped++
+				continue
+			}
+			// 单条缓存数据不会超过段文件的记录上限，更大的数据文件视为损坏或恶意快照，不读入内存
+			if header.Size > maxSegmentSize {
+				return imported, skipped, fmt.Errorf("缓存项过大: %s（%d字节，上限%d字节）", path.Base(name), header.Size, maxSegmentSize)
+			}
+			data, err := io.ReadAll(io.LimitReader(tr, maxSegmentSize+1))
+			if err != nil {
+				return imported, skipped, fmt.Errorf("读取缓存项失败: %v", err)
+			}
+			if len(data) > maxSegmentSize {
+				return imported, skipped, fmt.Errorf("缓存项过大: %s（上限%d字节）", path.Base(name), maxSegmentSize)
+			}
+			if err := c.local.restore(meta, data); err != nil {
+				return imported, skipped, err
+			}
+			c.memory.Delete(meta.Key)
+			imported++
+		}
+	}
+
+	if pending != nil {
+		return imported, skipped, errors.New("快照不完整: 最后一个缓存项缺少数据文件")
+	}
+	return imported, skipped, nil
+}
+
+// decodeTarJSON 解析tar中的JSON文件
+func decodeTarJSON(r io.Reader, v interface{}) error {
+	data, err := io.ReadAll(io.LimitReader(r, snapshotMaxMetaSize))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTwoLevelCache 创建使用临时目录的两级缓存，不依赖全局配置
+func newTestTwoLevelCache(t *testing.T) *EnhancedTwoLevelCache {
+	t.Helper()
+	disk, err := NewShardedDiskCache(t.TempDir(), 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memory := NewShardedMemoryCache(100, 10)
+	memory.SetDiskCacheReference(disk)
+	return &EnhancedTwoLevelCache{memory: memory, disk: disk, local: disk, serializer: NewGobSerializer()}
+}
+
+func TestCacheListAndPurge(t *testing.T) {
+	c := newTestTwoLevelCache(t)
+
+	tgKey := GenerateSourceCacheKey(KeySourceTG, "yunpanx", "凡人修仙传")
+	piankuKey := GenerateSourceCacheKey(KeySourcePlugin, "pianku", "凡人修仙传")
+	labiKey := GenerateSourceCacheKey(KeySourcePlugin, "labi", "Matrix")
+
+	c.SetBothLevels(tgKey, []byte("tg"), time.Hour)
+	c.SetBothLevels(piankuKey, []byte("plugins"), time.Hour)
+	c.SetMemoryOnly(labiKey, []byte("labi"), time.Hour)
+
+	entries := c.ListEntries(CacheFilter{Keyword: " matrix "})
+	if len(entries) != 1 || entries[0].Key != labiKey {
+		t.Fatalf("按关键词筛选结果错误: %+v", entries)
+	}
+	if entries[0].Final || len(entries[0].Tiers) != 1 || entries[0].Tiers[0] != TierMemory {
+		t.Errorf("仅在内存中的中间结果状态错误: %+v", entries[0])
+	}
+
+	if got := c.ListEntries(CacheFilter{Plugin: "pianku"}); len(got) != 1 || got[0].Key != piankuKey {
+		t.Errorf("按插件筛选结果错误: %+v", got)
+	}
+	if got := c.ListEntries(CacheFilter{Channel: "yunpanx"}); len(got) != 1 || got[0].Key != tgKey {
+		t.Errorf("按频道筛选结果错误: %+v", got)
+	}
+
+	removed, err := c.Purge(CacheFilter{Keyword: "凡人修仙传"})
+	if err != nil || removed != 2 {
+		t.Fatalf("Purge removed=%d err=%v", removed, err)
+	}
+	if _, hit, _ := c.Get(tgKey); hit {
+		t.Error("已删除的缓存仍可读取")
+	}
+	if got := c.ListEntries(CacheFilter{}); len(got) != 1 || got[0].Key != labiKey {
+		t.Errorf("删除后剩余缓存错误: %+v", got)
+	}
+}
+
+func TestCacheSnapshotRoundTrip(t *testing.T) {
+	src := newTestTwoLevelCache(t)
+	key := GenerateSourceCacheKey(KeySourcePlugin, "labi", "snapshot")
+	data := bytes.Repeat([]byte("x"), 4096)
+	src.SetMemoryOnly(key, data, time.Hour)
+	src.SetBothLevels("unknown-key", []byte("y"), time.Hour)
+
+	var buf bytes.Buffer
+	exported, err := src.ExportSnapshot(&buf)
+	if err != nil || exported != 2 {
+		t.Fatalf("ExportSnapshot exported=%d err=%v", exported, err)
+	}
+
+	dst := newTestTwoLevelCache(t)
+	imported, skipped, err := dst.ImportSnapshot(&buf)
+	if err != nil || imported != 2 || skipped != 0 {
+		t.Fatalf("ImportSnapshot imported=%d skipped=%d err=%v", imported, skipped, err)
+	}
+
+	got, hit, err := dst.disk.Get(key)
+	if err != nil || !hit || !bytes.Equal(got, data) {
+		t.Fatalf("导入后读取 hit=%v err=%v", hit, err)
+	}
+	entries := dst.ListEntries(CacheFilter{Keyword: "snapshot"})
+	if len(entries) != 1 || entries[0].Source != KeySourcePlugin {
+		t.Errorf("导入后缓存键信息丢失: %+v", entries)
+	}
+
+	if _, _, err := dst.ImportSnapshot(bytes.NewReader([]byte("not a snapshot"))); err == nil {
+		t.Error("无效快照应返回错误")
+	}
+}
+
+func TestImportSnapshotRejectsOversizedEntry(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	manifest, _ := json.Marshal(snapshotManifest{Version: snapshotVersion, CreatedAt: now, Entries: 1})
+	meta, _ := json.Marshal(diskCacheMetadata{Key: "huge", Expiry: now.Add(time.Hour), LastModified: now})
+	writeTarFile(tw, snapshotManifestName, manifest, now)
+	writeTarFile(tw, snapshotEntryDir+"huge.meta", meta, now)
+	// 只写入声明超出上限的数据文件头，导入时不应尝试读取数据
+	tw.WriteHeader(&tar.Header{Name: snapshotEntryDir + "huge.data", Mode: 0644, Size: maxSegmentSize + 1, ModTime: now, Typeflag: tar.TypeReg})
+	gz.Close()
+
+	c := newTestTwoLevelCache(t)
+	imported, _, err := c.ImportSnapshot(&buf)
+	if err == nil || !strings.Contains(err.Error(), "缓存项过大") || imported != 0 {
+		t.Errorf("超出段文件记录上限的缓存项应被拒绝: imported=%d err=%v", imported, err)
+	}
+}
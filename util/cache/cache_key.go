@@ -46,32 +46,17 @@ func init() {
 	precomputedHashes.Store("all_channels", allChannelsHash)
 }
 
-// GenerateTGCacheKey 为TG搜索生成缓存键
-func GenerateTGCacheKey(keyword string, channels []string) string {
-	// 关键词标准化
-	normalizedKeyword := strings.ToLower(strings.TrimSpace(keyword))
-
-	// 获取频道列表哈希
-	channelsHash := getChannelsHash(channels)
-
-	// 生成TG搜索特定的缓存键
-	keyStr := fmt.Sprintf("tg:%s:%s", normalizedKeyword, channelsHash)
+// GenerateSourceCacheKey 为单个来源（TG频道或插件）的搜索结果生成缓存键
+// source为KeySourceTG或KeySourcePlugin，name为频道名或插件名
+// 不同频道、插件组合的搜索共享各来源的缓存项
+func GenerateSourceCacheKey(source, name, keyword string) string {
+	keyStr := fmt.Sprintf("source:%s:%s:%s", source, name, normalizeKeyword(keyword))
 	hash := md5.Sum([]byte(keyStr))
-	return hex.EncodeToString(hash[:])
-}
-
-// GeneratePluginCacheKey 为插件搜索生成缓存键
-func GeneratePluginCacheKey(keyword string, plugins []string) string {
-	// 关键词标准化
-	normalizedKeyword := strings.ToLower(strings.TrimSpace(keyword))
+	key := hex.EncodeToString(hash[:])
 
-	// 获取插件列表哈希
-	pluginsHash := getPluginsHash(plugins)
-
-	// 生成插件搜索特定的缓存键
-	keyStr := fmt.Sprintf("plugin:%s:%s", normalizedKeyword, pluginsHash)
-	hash := md5.Sum([]byte(keyStr))
-	return hex.EncodeToString(hash[:])
+	// 记录键对应的搜索条件，供缓存管理接口使用
+	keyInfos.record(key, newKeyInfo(source, keyword, []string{name}))
+	return key
 }
 
 // GenerateCacheKey 根据所有影响搜索结果的参数生成缓存键
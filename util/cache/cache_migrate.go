@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"pansou/model"
+)
+
+// MigrationReport 缓存迁移结果
+type MigrationReport struct {
+	Scanned   int `json:"scanned"`   // 检查的缓存项数
+	Current   int `json:"current"`   // 已是当前格式的缓存项数
+	Rewritten int `json:"rewritten"` // 按当前格式重写的缓存项数
+	Dropped   int `json:"dropped"`   // 无法解码而删除的缓存项数
+	Newer     int `json:"newer"`     // 由更高版本写入而保留的缓存项数
+}
+
+// Decode 反序列化key对应的缓存数据
+// 旧格式的数据解码成功后按当前格式重写，保留过期时间和最后修改时间；
+// 无法解码的数据从内存和本地磁盘删除，Redis中的数据可能由其他版本的实例写入，不删除；
+// 更高版本写入的数据只按未命中处理
+func (c *EnhancedTwoLevelCache) Decode(key string, data []byte, v interface{}) error {
+	serializer := c.GetSerializer()
+	vs, ok := serializer.(*VersionedSerializer)
+	if !ok {
+		return serializer.Deserialize(data, v)
+	}
+
+	stale, err := vs.Decode(data, v)
+	if err != nil {
+		if errors.Is(err, ErrIncompatibleCache) && !errors.Is(err, ErrNewerCache) {
+			fmt.Printf("[Cache] 删除不兼容的本地缓存项: %s | %v\n", key, err)
+			c.memory.Delete(key)
+			c.local.Delete(key)
+		}
+		return err
+	}
+	if stale {
+		if upgraded, err := vs.Serialize(v); err == nil {
+			c.rewrite(key, data, upgraded)
+		}
+	}
+	return nil
+}
+
+// rewrite 把内存和本地磁盘中仍为old的缓存项替换为data，old和data都是未压缩的数据
+// 配置Redis时Redis中的旧格式数据不重写，由下次写入替换，避免滚动升级期间旧版本实例读不出新格式
+func (c *EnhancedTwoLevelCache) rewrite(key string, old, data []byte) {
+	c.memory.replaceData(key, old, data)
+
+	packed, err := transcode(data, c.compression)
+	if err != nil {
+		return
+	}
+	c.local.getShard(key).rewrite(key, packed, func(current []byte) bool {
+		raw, err := transcode(current, CompressionNone)
+		return err == nil && bytes.Equal(raw, old)
+	})
+}
+
+// MigrateLocal 检查本地磁盘中的所有缓存项，旧格式或压缩方式与配置不同的按当前格式重写，无法解码的删除，
+// 更高版本写入的保留
+// dryRun为true时只统计不修改
+func (c *EnhancedTwoLevelCache) MigrateLocal(dryRun bool) (MigrationReport, error) {
+	var report MigrationReport
+	vs, ok := c.GetSerializer().(*VersionedSerializer)
+	if !ok {
+		return report, errors.New("当前序列化器不支持格式迁移")
+	}
+
+	for _, meta := range c.local.entries() {
+		data, _, ok := c.local.readEntry(meta.Key)
+		if !ok {
+			continue
+		}
+		report.Scanned++
+
+		var results []model.SearchResult
+		h, legacy, err := vs.decode(data, &results)
+		stale := legacy || !vs.isCurrent(h, c.compression)
+		switch {
+		case errors.Is(err, ErrNewerCache):
+			report.Newer++
+		case err != nil:
+			report.Dropped++
+			if !dryRun {
+				c.memory.Delete(meta.Key)
+				if err := c.local.Delete(meta.Key); err != nil {
+					return report, err
+				}
+			}
+		case stale:
+			report.Rewritten++
+			if !dryRun {
+				upgraded, err := vs.Serialize(results)
+				if err != nil {
+					return report, err
+				}
+				if upgraded, err = transcode(upgraded, c.compression); err != nil {
+					return report, err
+				}
+				c.memory.Delete(meta.Key)
+				c.local.getShard(meta.Key).rewrite(meta.Key, upgraded, func(current []byte) bool {
+					return bytes.Equal(current, data)
+				})
+			}
+		default:
+			report.Current++
+		}
+	}
+	return report, nil
+}
+
+// SamplePayloads 从本地磁盘缓存中取最多limit个缓存项的未压缩数据，用于训练zstd字典
+func (c *EnhancedTwoLevelCache) SamplePayloads(limit int) [][]byte {
+	var samples [][]byte
+	for _, meta := range c.local.entries() {
+		if len(samples) >= limit {
+			break
+		}
+		data, _, ok := c.local.readEntry(meta.Key)
+		if !ok {
+			continue
+		}
+		if payload, ok := payloadOf(data); ok && len(payload) > 0 {
+			samples = append(samples, payload)
+		}
+	}
+	return samples
+}
+
+// replaceData 缓存项数据仍为old时替换为data，保留过期时间、最后修改时间和完整标记
+func (c *ShardedMemoryCache) replaceData(key string, old, data []byte) bool {
+	shard := c.getShard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	item, exists := shard.items[key]
+	if !exists || time.Now().After(item.expiry) || !bytes.Equal(item.data, old) {
+		return false
+	}
+	atomic.AddInt64(&shard.currSize, int64(len(data)-item.size))
+	item.data = data
+	item.size = len(data)
+	return true
+}
+
+// rewrite 当前数据满足match时替换为data，保留元数据
+func (c *DiskCache) rewrite(key string, data []byte, match func(current []byte) bool) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	meta, exists := c.metadata[key]
+	if !exists || time.Now().After(meta.Expiry) {
+		return false
+	}
+	current, err := c.readRecord(meta)
+	if err != nil || !match(current) {
+		return false
+	}
+
+	updated := *meta
+	updated.Size = len(data)
+	return c.writeEntry(&updated, data) == nil
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"time"
+)
+
+// compressedBackend 写入二级存储前压缩、读取后解压的包装
+// 内存缓存保存未压缩的数据以保证读取速度，只有磁盘和Redis中的数据是压缩的；
+// 压缩方式记录在信封头部，不带信封的数据原样读写
+type compressedBackend struct {
+	backend     CacheBackend
+	compression byte
+}
+
+// newCompressedBackend 创建压缩包装
+func newCompressedBackend(backend CacheBackend, compression byte) *compressedBackend {
+	return &compressedBackend{backend: backend, compression: compression}
+}
+
+// pack 压缩写入的数据，压缩失败时写入原始数据
+func (b *compressedBackend) pack(data []byte) []byte {
+	if packed, err := transcode(data, b.compression); err == nil {
+		return packed
+	}
+	return data
+}
+
+// unpack 解压读取的数据，解压失败时返回原始数据，由反序列化时按不兼容数据处理
+func (b *compressedBackend) unpack(data []byte) []byte {
+	if raw, err := transcode(data, CompressionNone); err == nil {
+		return raw
+	}
+	return data
+}
+
+// Set 压缩后写入
+func (b *compressedBackend) Set(key string, data []byte, ttl time.Duration) error {
+	return b.backend.Set(key, b.pack(data), ttl)
+}
+
+// SetWithFinal 压缩后写入，同时记录是否为完整结果
+func (b *compressedBackend) SetWithFinal(key string, data []byte, ttl time.Duration, final bool) error {
+	return setWithFinal(b.backend, key, b.pack(data), ttl, final)
+}
+
+// Get 读取并解压
+func (b *compressedBackend) Get(key string) ([]byte, bool, error) {
+	data, hit, err := b.backend.Get(key)
+	if err != nil || !hit {
+		return nil, hit, err
+	}
+	return b.unpack(data), true, nil
+}
+
+// GetWithTimestamp 读取并解压，同时返回最后修改时间
+func (b *compressedBackend) GetWithTimestamp(key string) ([]byte, time.Time, bool, error) {
+	data, lastModified, hit, err := getWithTimestamp(b.backend, key)
+	if err != nil || !hit {
+		return nil, time.Time{}, hit, err
+	}
+	return b.unpack(data), lastModified, true, nil
+}
+
+// GetExpiry 获取过期时间，被包装的存储不支持时返回false
+func (b *compressedBackend) GetExpiry(key string) (time.Time, bool) {
+	if eb, ok := b.backend.(expiryBackend); ok {
+		return eb.GetExpiry(key)
+	}
+	return time.Time{}, false
+}
+
+// Delete 删除缓存
+func (b *compressedBackend) Delete(key string) error {
+	return b.backend.Delete(key)
+}
+
+// Clear 清空缓存
+func (b *compressedBackend) Clear() error {
+	return b.backend.Clear()
+}
+
+// GetLastModified 获取最后修改时间
+func (b *compressedBackend) GetLastModified(key string) (time.Time, bool) {
+	return b.backend.GetLastModified(key)
+}
@@ -274,7 +274,7 @@ type DelayedBatchWriteManager struct {
 	mainCacheUpdater func(string, []byte, time.Duration) error
 
 	// 序列化器
-	serializer *GobSerializer
+	serializer *VersionedSerializer
 
 	// 初始化标志
 	initialized int32
@@ -334,7 +334,7 @@ func NewDelayedBatchWriteManager() (*DelayedBatchWriteManager, error) {
 		stats: &WriteManagerStats{
 			WindowStart: time.Now(),
 		},
-		serializer: NewGobSerializer(),
+		serializer: NewVersionedSerializer(),
 	}
 
 	return manager, nil
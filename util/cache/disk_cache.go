@@ -3,10 +3,12 @@ package cache
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,16 +21,36 @@ type diskCacheMetadata struct {
 	Expiry       time.Time `json:"expiry"`
 	LastUsed     time.Time `json:"last_used"`
 	Size         int       `json:"size"`
-	LastModified time.Time `json:"last_modified"` // 添加最后修改时间字段
+	LastModified time.Time `json:"last_modified"`     // 添加最后修改时间字段
+	Partial      bool      `json:"partial,omitempty"` // 是否为未完成的中间结果（旧版元数据没有该字段，视为完整结果）
+	Info         *KeyInfo  `json:"info,omitempty"`    // 缓存键对应的搜索条件
+
+	segment    uint32 // 记录所在分段
+	offset     int64  // 记录在分段中的偏移
+	recordSize int64  // 记录总长度
 }
 
+// compactionDeadRatio 分段中失效记录超过该比例时压缩
+const compactionDeadRatio = 0.5
+
+// errDiskCacheClosed 磁盘缓存已关闭
+var errDiskCacheClosed = errors.New("磁盘缓存已关闭")
+
 // DiskCache 磁盘缓存
+// 数据以追加写的分段日志保存（格式见disk_segment.go），内存中的metadata作为索引，
+// 启动时从分段索引文件恢复，不再为每个缓存项单独保存文件
 type DiskCache struct {
 	path      string
 	maxSizeMB int
 	metadata  map[string]*diskCacheMetadata
 	mutex     sync.RWMutex
 	currSize  int64
+
+	segments       map[uint32]*diskSegment
+	active         *diskSegment // 当前追加写入的分段，关闭后为nil
+	segmentMaxSize int64
+	stop           chan struct{}
+	stopOnce       sync.Once
 }
 
 // NewDiskCache 创建新的磁盘缓存
@@ -38,14 +60,29 @@ func NewDiskCache(path string, maxSizeMB int) (*DiskCache, error) {
 		return nil, err
 	}
 
+	// 分段大小为缓存容量的1/4，限制在1MB-64MB之间
+	segmentMaxSize := int64(maxSizeMB) * 1024 * 1024 / 4
+	if segmentMaxSize < minSegmentSize {
+		segmentMaxSize = minSegmentSize
+	}
+	if segmentMaxSize > maxSegmentSize {
+		segmentMaxSize = maxSegmentSize
+	}
+
 	cache := &DiskCache{
-		path:      path,
-		maxSizeMB: maxSizeMB,
-		metadata:  make(map[string]*diskCacheMetadata),
+		path:           path,
+		maxSizeMB:      maxSizeMB,
+		metadata:       make(map[string]*diskCacheMetadata),
+		segments:       make(map[uint32]*diskSegment),
+		segmentMaxSize: segmentMaxSize,
+		stop:           make(chan struct{}),
 	}
 
-	// 加载现有缓存元数据
-	cache.loadMetadata()
+	// 加载现有分段并创建新的活动分段
+	if err := cache.open(); err != nil {
+		cache.closeFiles()
+		return nil, err
+	}
 
 	// 启动周期性清理
 	go cache.startCleanupTask()
@@ -53,99 +90,268 @@ func NewDiskCache(path string, maxSizeMB int) (*DiskCache, error) {
 	return cache, nil
 }
 
-// 加载元数据
-func (c *DiskCache) loadMetadata() {
+// open 加载已有分段，迁移旧版的单文件缓存，并创建新的活动分段
+// 上次运行的活动分段在这里被封存，因此所有已有分段都是只读的
+func (c *DiskCache) open() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	// 遍历缓存目录
-	files, err := ioutil.ReadDir(c.path)
+	ids, err := listSegments(c.path)
 	if err != nil {
-		return
+		return err
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
+	now := time.Now().UnixNano()
+	for _, id := range ids {
+		if err := c.loadSegment(id, now); err != nil {
+			return err
 		}
+	}
 
-		// 跳过元数据文件
-		if file.Name() == "metadata.json" {
-			continue
-		}
+	nextID := uint32(1)
+	if len(ids) > 0 {
+		nextID = ids[len(ids)-1] + 1
+	}
+	if err := c.createActiveSegment(nextID); err != nil {
+		return err
+	}
 
-		// 读取元数据
-		metadataFile := filepath.Join(c.path, file.Name()+".meta")
-		data, err := ioutil.ReadFile(metadataFile)
-		if err != nil {
-			continue
+	return c.migrateLegacyFiles()
+}
+
+// loadSegment 加载一个已封存的分段：优先读取索引文件，索引缺失或损坏时扫描分段并重建索引
+func (c *DiskCache) loadSegment(id uint32, now int64) error {
+	logPath := filepath.Join(c.path, segmentFileName(id, segmentLogSuffix))
+	hintPath := filepath.Join(c.path, segmentFileName(id, segmentHintSuffix))
+
+	file, err := os.OpenFile(logPath, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	seg := &diskSegment{id: id, file: file, size: info.Size()}
+
+	entries, err := readHintFile(hintPath)
+	if err == nil && !hintsWithin(entries, seg.size) {
+		err = errCorruptRecord
+	}
+	if err != nil {
+		if entries, seg.size, err = scanSegment(file); err != nil {
+			file.Close()
+			return err
 		}
+		if err := writeHintFile(hintPath, entries); err != nil {
+			fmt.Printf("[Cache] 写入分段索引失败: %s -> %v\n", hintPath, err)
+		}
+	}
 
-		var meta diskCacheMetadata
-		if err := json.Unmarshal(data, &meta); err != nil {
-			continue
+	c.segments[id] = seg
+	for i := range entries {
+		c.applyEntry(seg, &entries[i], now)
+	}
+	return nil
+}
+
+// hintsWithin 检查索引项是否都在分段文件范围内
+func hintsWithin(entries []hintEntry, size int64) bool {
+	for i := range entries {
+		if entries[i].offset+entries[i].size > size {
+			return false
 		}
+	}
+	return true
+}
 
-		// 更新总大小
-		c.currSize += int64(meta.Size)
+// applyEntry 按写入顺序把记录应用到索引：后写入的记录覆盖先前的记录，删除记录和已过期的记录使键失效
+func (c *DiskCache) applyEntry(seg *diskSegment, e *hintEntry, now int64) {
+	c.unindex(e.key)
+	if e.op != recordPut || e.expiry <= now {
+		return
+	}
+	lastModified := time.Unix(0, e.lastModified)
+	c.metadata[e.key] = &diskCacheMetadata{
+		Key:          e.key,
+		Expiry:       time.Unix(0, e.expiry),
+		LastUsed:     lastModified,
+		Size:         e.dataSize,
+		LastModified: lastModified,
+		Partial:      e.partial,
+		Info:         e.info,
+		segment:      seg.id,
+		offset:       e.offset,
+		recordSize:   e.size,
+	}
+	seg.live += e.size
+	c.currSize += int64(e.dataSize)
+}
 
-		// 存储元数据
-		c.metadata[meta.Key] = &meta
+// unindex 从索引中移除键，不写入删除记录（调用方需持有写锁）
+func (c *DiskCache) unindex(key string) (*diskCacheMetadata, bool) {
+	meta, exists := c.metadata[key]
+	if !exists {
+		return nil, false
+	}
+	if seg := c.segments[meta.segment]; seg != nil {
+		seg.live -= meta.recordSize
 	}
+	c.currSize -= int64(meta.Size)
+	delete(c.metadata, key)
+	return meta, true
 }
 
-// 保存元数据
-func (c *DiskCache) saveMetadata(key string, meta *diskCacheMetadata) error {
-	metadataFile := filepath.Join(c.path, c.getFilename(key)+".meta")
-	data, err := json.Marshal(meta)
+// createActiveSegment 创建新的活动分段（调用方需持有写锁）
+func (c *DiskCache) createActiveSegment(id uint32) error {
+	path := filepath.Join(c.path, segmentFileName(id, segmentLogSuffix))
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(metadataFile, data, 0644)
+	seg := &diskSegment{id: id, file: file}
+	c.segments[id] = seg
+	c.active = seg
+	return nil
 }
 
-// 获取文件名
-func (c *DiskCache) getFilename(key string) string {
-	hash := md5.Sum([]byte(key))
-	return hex.EncodeToString(hash[:])
+// sealActive 封存活动分段：同步到磁盘并写入分段索引（调用方需持有写锁）
+func (c *DiskCache) sealActive() error {
+	seg := c.active
+	if seg == nil {
+		return nil
+	}
+	if err := seg.file.Sync(); err != nil {
+		return err
+	}
+	hintPath := filepath.Join(c.path, segmentFileName(seg.id, segmentHintSuffix))
+	if err := writeHintFile(hintPath, seg.hints); err != nil {
+		// 缺少索引时下次启动会扫描分段文件，不影响正确性
+		fmt.Printf("[Cache] 写入分段索引失败: %s -> %v\n", hintPath, err)
+	}
+	seg.hints = nil
+	return nil
 }
 
-// Set 设置缓存
-func (c *DiskCache) Set(key string, data []byte, ttl time.Duration) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	// 如果已存在，先减去旧项的大小
-	if meta, exists := c.metadata[key]; exists {
-		c.currSize -= int64(meta.Size)
-		// 删除旧文件
-		filename := c.getFilename(key)
-		os.Remove(filepath.Join(c.path, filename))
-		os.Remove(filepath.Join(c.path, filename+".meta"))
+// appendRecord 向活动分段追加一条记录，分段写满时先封存并切换到新分段（调用方需持有写锁）
+func (c *DiskCache) appendRecord(rec *logRecord) (*diskSegment, int64, int64, error) {
+	if c.active == nil {
+		return nil, 0, 0, errDiskCacheClosed
+	}
+	buf, err := encodeRecord(rec)
+	if err != nil {
+		return nil, 0, 0, err
 	}
 
-	// 检查空间
-	maxSize := int64(c.maxSizeMB) * 1024 * 1024
-	if c.currSize+int64(len(data)) > maxSize {
-		// 清理空间
-		c.evictLRU(int64(len(data)))
+	if c.active.size > 0 && c.active.size+int64(len(buf)) > c.segmentMaxSize {
+		if err := c.sealActive(); err != nil {
+			return nil, 0, 0, err
+		}
+		if err := c.createActiveSegment(c.active.id + 1); err != nil {
+			c.active = nil
+			return nil, 0, 0, err
+		}
 	}
 
-	// 获取文件名
-	filename := c.getFilename(key)
-	filePath := filepath.Join(c.path, filename)
+	seg := c.active
+	offset := seg.size
+	if _, err := seg.file.WriteAt(buf, offset); err != nil {
+		// 截断写了一半的记录，保持分段末尾完整
+		seg.file.Truncate(offset)
+		return nil, 0, 0, err
+	}
+	seg.size += int64(len(buf))
+	seg.hints = append(seg.hints, hintEntry{
+		op:           rec.op,
+		partial:      rec.partial,
+		key:          rec.key,
+		info:         rec.info,
+		expiry:       rec.expiry,
+		lastModified: rec.lastModified,
+		dataSize:     len(rec.data),
+		offset:       offset,
+		size:         int64(len(buf)),
+	})
+	return seg, offset, int64(len(buf)), nil
+}
 
-	// 确保目录存在（防止外部删除缓存目录）
-	if err := os.MkdirAll(c.path, 0755); err != nil {
-		return fmt.Errorf("创建缓存目录失败: %v", err)
+// readRecord 读取并校验缓存项对应的记录（调用方需持有读锁）
+func (c *DiskCache) readRecord(meta *diskCacheMetadata) ([]byte, error) {
+	seg := c.segments[meta.segment]
+	if seg == nil {
+		return nil, fmt.Errorf("磁盘缓存分段 %d 不存在", meta.segment)
+	}
+	buf := make([]byte, meta.recordSize)
+	if _, err := seg.file.ReadAt(buf, meta.offset); err != nil {
+		return nil, err
+	}
+	rec, err := decodeRecord(buf)
+	if err != nil {
+		return nil, err
 	}
+	if rec.key != meta.Key {
+		return nil, errCorruptRecord
+	}
+	return rec.data, nil
+}
 
-	// 写入文件
-	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+// migrateLegacyFiles 把旧版每项一个数据文件和.meta文件的缓存写入分段日志后删除（调用方需持有写锁）
+func (c *DiskCache) migrateLegacyFiles() error {
+	files, err := os.ReadDir(c.path)
+	if err != nil {
 		return err
 	}
 
-	// 创建元数据
+	migrated := 0
+	now := time.Now()
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".meta") {
+			continue
+		}
+		metaPath := filepath.Join(c.path, name)
+		dataPath := strings.TrimSuffix(metaPath, ".meta")
+
+		var meta diskCacheMetadata
+		raw, err := os.ReadFile(metaPath)
+		if err == nil {
+			err = json.Unmarshal(raw, &meta)
+		}
+		var data []byte
+		if err == nil && meta.Key != "" && now.Before(meta.Expiry) {
+			if data, err = os.ReadFile(dataPath); err == nil {
+				err = c.writeEntry(&meta, data)
+				migrated++
+			}
+		}
+		if err != nil {
+			fmt.Printf("[Cache] 迁移旧版磁盘缓存失败: %s -> %v\n", name, err)
+		}
+		os.Remove(dataPath)
+		os.Remove(metaPath)
+	}
+	os.Remove(filepath.Join(c.path, "metadata.json"))
+
+	if migrated > 0 {
+		fmt.Printf("[Cache] 已将 %d 个旧版磁盘缓存项迁移到分段日志: %s\n", migrated, c.path)
+	}
+	return nil
+}
+
+// 获取文件名（缓存键的MD5，用于快照中的条目名和旧版缓存文件）
+func (c *DiskCache) getFilename(key string) string {
+	hash := md5.Sum([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// Set 设置缓存
+func (c *DiskCache) Set(key string, data []byte, ttl time.Duration) error {
+	return c.SetWithFinal(key, data, ttl, true)
+}
+
+// SetWithFinal 设置缓存，并标记数据是否为完整结果
+func (c *DiskCache) SetWithFinal(key string, data []byte, ttl time.Duration, final bool) error {
 	now := time.Now()
 	meta := &diskCacheMetadata{
 		Key:          key,
@@ -153,55 +359,98 @@ func (c *DiskCache) Set(key string, data []byte, ttl time.Duration) error {
 		LastUsed:     now,
 		LastModified: now, // 设置最后修改时间
 		Size:         len(data),
+		Partial:      !final,
+		Info:         keyInfos.lookup(key),
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.writeEntry(meta, data)
+}
+
+// writeEntry 追加写入缓存项并更新索引（调用方需持有写锁）
+func (c *DiskCache) writeEntry(meta *diskCacheMetadata, data []byte) error {
+	key := meta.Key
+
+	// 登记表中已没有键信息时沿用旧项的信息
+	var oldSize int64
+	if old, exists := c.metadata[key]; exists {
+		oldSize = int64(old.Size)
+		if meta.Info == nil {
+			meta.Info = old.Info
+		}
+	}
+
+	// 检查空间（旧项的空间会被新项替换）
+	maxSize := int64(c.maxSizeMB) * 1024 * 1024
+	if c.currSize-oldSize+int64(len(data)) > maxSize {
+		// 清理空间
+		c.evictLRU(int64(len(data)))
 	}
 
-	// 保存元数据
-	if err := c.saveMetadata(key, meta); err != nil {
-		// 如果元数据保存失败，删除数据文件
-		os.Remove(filePath)
+	seg, offset, size, err := c.appendRecord(&logRecord{
+		op:           recordPut,
+		partial:      meta.Partial,
+		key:          key,
+		info:         meta.Info,
+		expiry:       meta.Expiry.UnixNano(),
+		lastModified: meta.LastModified.UnixNano(),
+		data:         data,
+	})
+	if err != nil {
 		return err
 	}
 
-	// 更新内存中的元数据
+	// 新记录覆盖旧记录
+	c.unindex(key)
+	meta.Size = len(data)
+	meta.segment, meta.offset, meta.recordSize = seg.id, offset, size
 	c.metadata[key] = meta
+	seg.live += size
 	c.currSize += int64(len(data))
 
 	return nil
 }
 
+// deleteEntry 写入删除记录并从索引移除，避免重启后旧记录重新生效（调用方需持有写锁）
+func (c *DiskCache) deleteEntry(key string) error {
+	if _, exists := c.metadata[key]; !exists {
+		return nil
+	}
+	if _, _, _, err := c.appendRecord(&logRecord{op: recordDelete, key: key}); err != nil {
+		return err
+	}
+	c.unindex(key)
+	return nil
+}
+
 // Get 获取缓存
 func (c *DiskCache) Get(key string) ([]byte, bool, error) {
 	c.mutex.RLock()
 	meta, exists := c.metadata[key]
-	c.mutex.RUnlock()
-
 	if !exists {
+		c.mutex.RUnlock()
 		return nil, false, nil
 	}
 
 	// 检查是否过期
 	if time.Now().After(meta.Expiry) {
+		c.mutex.RUnlock()
 		c.Delete(key)
 		return nil, false, nil
 	}
 
-	// 获取文件路径
-	filePath := filepath.Join(c.path, c.getFilename(key))
-
-	// 读取文件
-	data, err := ioutil.ReadFile(filePath)
+	data, err := c.readRecord(meta)
+	c.mutex.RUnlock()
 	if err != nil {
-		// 如果文件不存在，删除元数据
-		if os.IsNotExist(err) {
-			c.Delete(key)
-		}
+		// 记录损坏，删除该缓存项
+		c.Delete(key)
 		return nil, false, err
 	}
 
-	// 更新最后使用时间
+	// 更新最后使用时间（只保存在内存中，用于LRU淘汰）
 	c.mutex.Lock()
 	meta.LastUsed = time.Now()
-	c.saveMetadata(key, meta)
 	c.mutex.Unlock()
 
 	return data, true, nil
@@ -211,22 +460,7 @@ func (c *DiskCache) Get(key string) ([]byte, bool, error) {
 func (c *DiskCache) Delete(key string) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-
-	meta, exists := c.metadata[key]
-	if !exists {
-		return nil
-	}
-
-	// 删除文件
-	filename := c.getFilename(key)
-	os.Remove(filepath.Join(c.path, filename))
-	os.Remove(filepath.Join(c.path, filename+".meta"))
-
-	// 更新元数据
-	c.currSize -= int64(meta.Size)
-	delete(c.metadata, key)
-
-	return nil
+	return c.deleteEntry(key)
 }
 
 // Has 检查缓存是否存在
@@ -250,6 +484,7 @@ func (c *DiskCache) Has(key string) bool {
 }
 
 // 清理过期项
+// 过期记录在重启加载时会被忽略，因此只需从索引移除，无需写入删除记录
 func (c *DiskCache) cleanExpired() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -257,69 +492,140 @@ func (c *DiskCache) cleanExpired() {
 	now := time.Now()
 	for key, meta := range c.metadata {
 		if now.After(meta.Expiry) {
-			// 删除文件
-			filename := c.getFilename(key)
-			err := os.Remove(filepath.Join(c.path, filename))
-			if err == nil || os.IsNotExist(err) {
-				os.Remove(filepath.Join(c.path, filename+".meta"))
-				c.currSize -= int64(meta.Size)
-				delete(c.metadata, key)
-			}
+			c.unindex(key)
 		}
 	}
 }
 
-// 驱逐策略 - LRU
+// 驱逐策略 - LRU（调用方需持有写锁）
 func (c *DiskCache) evictLRU(requiredSpace int64) {
 	// 按最后使用时间排序
-	type cacheItem struct {
-		key      string
-		lastUsed time.Time
-		size     int
+	items := make([]*diskCacheMetadata, 0, len(c.metadata))
+	for _, v := range c.metadata {
+		items = append(items, v)
 	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].LastUsed.Before(items[j].LastUsed)
+	})
 
-	items := make([]cacheItem, 0, len(c.metadata))
-	for k, v := range c.metadata {
-		items = append(items, cacheItem{
-			key:      k,
-			lastUsed: v.LastUsed,
-			size:     v.Size,
-		})
+	// 从最久未使用开始删除，直到有足够空间
+	maxSize := int64(c.maxSizeMB) * 1024 * 1024
+	for _, item := range items {
+		if c.currSize+requiredSpace <= maxSize {
+			break
+		}
+		if err := c.deleteEntry(item.Key); err != nil {
+			return
+		}
 	}
+}
 
-	// 按最后使用时间排序
-	// 使用冒泡排序保持简单
-	for i := 0; i < len(items); i++ {
-		for j := 0; j < len(items)-i-1; j++ {
-			if items[j].lastUsed.After(items[j+1].lastUsed) {
-				items[j], items[j+1] = items[j+1], items[j]
+// compact 压缩失效记录较多或过小的已封存分段：把仍有效的记录复制到活动分段后删除原分段
+// 每次只持锁处理一个分段，复制完成后才删除原分段，中途崩溃时重复的记录以后写入的为准
+func (c *DiskCache) compact() {
+	for {
+		c.mutex.Lock()
+		seg := c.pickCompactionSegment()
+		if seg == nil {
+			c.mutex.Unlock()
+			return
+		}
+		err := c.compactSegment(seg)
+		c.mutex.Unlock()
+		if err != nil {
+			fmt.Printf("[Cache] 压缩磁盘缓存分段失败: %s/%s -> %v\n", c.path, segmentFileName(seg.id, segmentLogSuffix), err)
+			return
+		}
+	}
+}
+
+// pickCompactionSegment 选择需要压缩的分段（调用方需持有写锁）
+func (c *DiskCache) pickCompactionSegment() *diskSegment {
+	if c.active == nil {
+		return nil
+	}
+	var picked *diskSegment
+	for _, seg := range c.segments {
+		if seg == c.active {
+			continue
+		}
+		dead := seg.size - seg.live
+		if float64(dead) >= float64(seg.size)*compactionDeadRatio || seg.size < c.segmentMaxSize/8 {
+			if picked == nil || seg.id < picked.id {
+				picked = seg
 			}
 		}
 	}
+	return picked
+}
 
-	// 从最久未使用开始删除，直到有足够空间
-	maxSize := int64(c.maxSizeMB) * 1024 * 1024
-	for _, item := range items {
-		if c.currSize+requiredSpace <= maxSize {
+// compactSegment 压缩一个分段（调用方需持有写锁）
+func (c *DiskCache) compactSegment(seg *diskSegment) error {
+	hintPath := filepath.Join(c.path, segmentFileName(seg.id, segmentHintSuffix))
+	entries, err := readHintFile(hintPath)
+	if err != nil {
+		if entries, _, err = scanSegment(seg.file); err != nil {
+			return err
+		}
+	}
+
+	// 存在更早的分段时，删除记录需要保留，否则更早分段中的旧数据会在重启后重新生效
+	hasOlder := false
+	for id := range c.segments {
+		if id < seg.id {
+			hasOlder = true
 			break
 		}
+	}
 
-		// 删除文件
-		filename := c.getFilename(item.key)
-		err := os.Remove(filepath.Join(c.path, filename))
-		if err == nil || os.IsNotExist(err) {
-			os.Remove(filepath.Join(c.path, filename+".meta"))
-			c.currSize -= int64(item.size)
-			delete(c.metadata, item.key)
+	for i := range entries {
+		e := &entries[i]
+		switch e.op {
+		case recordPut:
+			meta, ok := c.metadata[e.key]
+			if !ok || meta.segment != seg.id || meta.offset != e.offset {
+				continue
+			}
+			data, err := c.readRecord(meta)
+			if err != nil {
+				c.unindex(e.key)
+				continue
+			}
+			if err := c.writeEntry(meta, data); err != nil {
+				return err
+			}
+		case recordDelete:
+			if _, ok := c.metadata[e.key]; ok || !hasOlder {
+				continue
+			}
+			if _, _, _, err := c.appendRecord(&logRecord{op: recordDelete, key: e.key}); err != nil {
+				return err
+			}
 		}
 	}
+
+	// 复制的记录先落盘，再删除原分段
+	if err := c.active.file.Sync(); err != nil {
+		return err
+	}
+	seg.file.Close()
+	delete(c.segments, seg.id)
+	os.Remove(hintPath)
+	return os.Remove(filepath.Join(c.path, segmentFileName(seg.id, segmentLogSuffix)))
 }
 
 // 启动定期清理任务
 func (c *DiskCache) startCleanupTask() {
 	ticker := time.NewTicker(10 * time.Minute)
-	for range ticker.C {
-		c.cleanExpired()
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.cleanExpired()
+			c.compact()
+		case <-c.stop:
+			return
+		}
 	}
 }
 
@@ -328,8 +634,14 @@ func (c *DiskCache) Clear() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if c.active == nil {
+		return errDiskCacheClosed
+	}
+	nextID := c.active.id + 1
+	c.closeFiles()
+
 	// 删除所有缓存文件
-	files, err := ioutil.ReadDir(c.path)
+	files, err := os.ReadDir(c.path)
 	if err != nil {
 		return err
 	}
@@ -344,9 +656,30 @@ func (c *DiskCache) Clear() error {
 
 	// 重置元数据
 	c.metadata = make(map[string]*diskCacheMetadata)
+	c.segments = make(map[uint32]*diskSegment)
 	c.currSize = 0
 
-	return nil
+	return c.createActiveSegment(nextID)
+}
+
+// Close 封存活动分段并关闭文件，下次启动直接读取分段索引
+func (c *DiskCache) Close() error {
+	c.stopOnce.Do(func() { close(c.stop) })
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.sealActive()
+	c.closeFiles()
+	return err
+}
+
+// closeFiles 关闭所有分段文件（调用方需持有写锁）
+func (c *DiskCache) closeFiles() {
+	for _, seg := range c.segments {
+		seg.file.Close()
+	}
+	c.active = nil
 }
 
 // GetLastModified 获取缓存项的最后修改时间
@@ -361,3 +694,57 @@ func (c *DiskCache) GetLastModified(key string) (time.Time, bool) {
 
 	return meta.LastModified, true
 }
+
+// GetExpiry 获取缓存项的过期时间
+func (c *DiskCache) GetExpiry(key string) (time.Time, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	meta, exists := c.metadata[key]
+	if !exists || time.Now().After(meta.Expiry) {
+		return time.Time{}, false
+	}
+	return meta.Expiry, true
+}
+
+// entries 返回未过期缓存项的元数据副本
+func (c *DiskCache) entries() []diskCacheMetadata {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	now := time.Now()
+	result := make([]diskCacheMetadata, 0, len(c.metadata))
+	for _, meta := range c.metadata {
+		if now.After(meta.Expiry) {
+			continue
+		}
+		result = append(result, *meta)
+	}
+	return result
+}
+
+// readEntry 读取缓存数据和元数据，不更新最后使用时间
+func (c *DiskCache) readEntry(key string) ([]byte, diskCacheMetadata, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	meta, exists := c.metadata[key]
+	if !exists || time.Now().After(meta.Expiry) {
+		return nil, diskCacheMetadata{}, false
+	}
+	data, err := c.readRecord(meta)
+	if err != nil {
+		return nil, diskCacheMetadata{}, false
+	}
+	return data, *meta, true
+}
+
+// restore 按给定元数据写入缓存项，保留原有的过期时间和最后修改时间
+func (c *DiskCache) restore(meta diskCacheMetadata, data []byte) error {
+	meta.LastUsed = time.Now()
+	meta.Size = len(data)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.writeEntry(&meta, data)
+}
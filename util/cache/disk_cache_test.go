@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pansou/util/json"
+)
+
+// openTestDiskCache 打开磁盘缓存，测试结束时关闭
+func openTestDiskCache(t *testing.T, dir string, maxSizeMB int) *DiskCache {
+	t.Helper()
+	c, err := NewDiskCache(dir, maxSizeMB)
+	if err != nil {
+		t.Fatalf("打开磁盘缓存失败: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// segmentCount 返回目录中的分段数量
+func segmentCount(t *testing.T, dir string) int {
+	t.Helper()
+	ids, err := listSegments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(ids)
+}
+
+func TestDiskCacheReopen(t *testing.T) {
+	dir := t.TempDir()
+	c := openTestDiskCache(t, dir, 10)
+
+	key := GenerateSourceCacheKey(KeySourcePlugin, "labi", "reopen")
+	if err := c.Set(key, []byte("v1"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetWithFinal(key, []byte("v2"), time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("deleted", []byte("x"), time.Hour)
+	c.Delete("deleted")
+	c.Set("expired", []byte("x"), time.Millisecond)
+	lastModified, _ := c.GetLastModified(key)
+	time.Sleep(5 * time.Millisecond)
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := os.ReadDir(dir)
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name(), segmentPrefix) {
+			t.Errorf("分段日志之外的文件: %s", f.Name())
+		}
+	}
+
+	reopened := openTestDiskCache(t, dir, 10)
+	data, hit, err := reopened.Get(key)
+	if err != nil || !hit || string(data) != "v2" {
+		t.Fatalf("重启后读取 data=%q hit=%v err=%v", data, hit, err)
+	}
+	meta := reopened.metadata[key]
+	if !meta.Partial || meta.Info == nil || meta.Info.Keyword != "reopen" || !meta.LastModified.Equal(lastModified) {
+		t.Errorf("重启后元数据不一致: %+v", meta)
+	}
+	for _, k := range []string{"deleted", "expired"} {
+		if _, hit, _ := reopened.Get(k); hit {
+			t.Errorf("重启后 %s 不应存在", k)
+		}
+	}
+}
+
+func TestDiskCacheTruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i)), time.Hour)
+	}
+
+	// 模拟崩溃：不封存活动分段，并在末尾留下写了一半的记录
+	active := c.active.file.Name()
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.closeFiles()
+	half, _ := encodeRecord(&logRecord{op: recordPut, key: "torn", data: []byte("lost"), expiry: time.Now().Add(time.Hour).UnixNano()})
+	f, err := os.OpenFile(active, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(half[:len(half)-2])
+	f.Close()
+
+	reopened := openTestDiskCache(t, dir, 10)
+	for i := 0; i < 10; i++ {
+		data, hit, _ := reopened.Get(fmt.Sprintf("key%d", i))
+		if !hit || string(data) != fmt.Sprintf("value%d", i) {
+			t.Errorf("key%d 丢失: %q", i, data)
+		}
+	}
+	if _, hit, _ := reopened.Get("torn"); hit {
+		t.Error("不完整的记录不应生效")
+	}
+	if _, err := os.Stat(strings.TrimSuffix(active, segmentLogSuffix) + segmentHintSuffix); err != nil {
+		t.Errorf("扫描后应重建分段索引: %v", err)
+	}
+}
+
+func TestDiskCacheCompaction(t *testing.T) {
+	dir := t.TempDir()
+	c := openTestDiskCache(t, dir, 4) // 分段大小1MB
+
+	value := bytes.Repeat([]byte("x"), 100*1024)
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 8; i++ {
+			if err := c.Set(fmt.Sprintf("key%d", i), value, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	c.Delete("key0")
+	before := segmentCount(t, dir)
+
+	c.compact()
+
+	after := segmentCount(t, dir)
+	if after >= before {
+		t.Errorf("压缩后分段数 %d，压缩前 %d", after, before)
+	}
+	c.Close()
+
+	reopened := openTestDiskCache(t, dir, 4)
+	if _, hit, _ := reopened.Get("key0"); hit {
+		t.Error("已删除的键在压缩后重新出现")
+	}
+	for i := 1; i < 8; i++ {
+		if data, hit, _ := reopened.Get(fmt.Sprintf("key%d", i)); !hit || !bytes.Equal(data, value) {
+			t.Errorf("key%d 在压缩后丢失", i)
+		}
+	}
+}
+
+func TestDiskCacheMigratesLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	legacy := &DiskCache{}
+	now := time.Now()
+	for i, expiry := range []time.Time{now.Add(time.Hour), now.Add(-time.Hour)} {
+		key := fmt.Sprintf("legacy%d", i)
+		name := filepath.Join(dir, legacy.getFilename(key))
+		meta, _ := json.Marshal(diskCacheMetadata{Key: key, Expiry: expiry, LastModified: now, Size: 4})
+		os.WriteFile(name, []byte("data"), 0644)
+		os.WriteFile(name+".meta", meta, 0644)
+	}
+
+	c := openTestDiskCache(t, dir, 10)
+	if data, hit, _ := c.Get("legacy0"); !hit || string(data) != "data" {
+		t.Errorf("旧版缓存未迁移: %q", data)
+	}
+	if _, hit, _ := c.Get("legacy1"); hit {
+		t.Error("已过期的旧版缓存不应迁移")
+	}
+	files, _ := os.ReadDir(dir)
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name(), segmentPrefix) {
+			t.Errorf("旧版文件未删除: %s", f.Name())
+		}
+	}
+}
+
+func TestEncodeRecordRejectsOversizedData(t *testing.T) {
+	rec := &logRecord{op: recordPut, key: "huge", data: make([]byte, maxSegmentSize+1)}
+	if _, err := encodeRecord(rec); err == nil {
+		t.Fatal("超过分段上限的数据应在写入时拒绝")
+	}
+
+	// 上限以内的记录可以正常读回
+	rec.data = rec.data[:maxSegmentSize]
+	buf, err := encodeRecord(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeRecord(buf); err != nil {
+		t.Errorf("上限以内的记录解码失败: %v", err)
+	}
+}
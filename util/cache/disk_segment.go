@@ -0,0 +1,363 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 磁盘缓存以追加写的分段日志保存，每个分片一个目录：
+//
+//	seg-00000001.log   分段文件，依次追加写入和删除记录
+//	seg-00000001.hint  分段封存时写入的索引，只包含记录位置和元数据，启动时代替扫描分段文件
+//
+// 记录格式（小端序），CRC32覆盖CRC之后的全部字节，启动时据此截断崩溃时写了一半的记录：
+//
+//	crc(4) | 类型(1) | 标志(1) | 键长度(2) | 键信息长度(4) | 数据长度(4) | 过期时间(8) | 最后修改时间(8) | 键 | 键信息 | 数据
+//
+// 索引项格式与记录头相同，数据部分换成记录在分段中的偏移(8)，不包含数据
+const (
+	segmentPrefix     = "seg-"
+	segmentLogSuffix  = ".log"
+	segmentHintSuffix = ".hint"
+
+	recordHeaderSize = 32
+	hintHeaderSize   = recordHeaderSize + 8
+
+	recordPut    byte = 1
+	recordDelete byte = 2
+
+	recordFlagPartial byte = 1 << 0
+
+	maxRecordInfoSize = 1 << 20 // 键信息的长度上限，超过视为损坏
+	minSegmentSize    = 1 << 20
+	maxSegmentSize    = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errCorruptRecord 记录校验失败
+var errCorruptRecord = errors.New("磁盘缓存记录损坏")
+
+// logRecord 分段日志中的一条记录
+type logRecord struct {
+	op           byte
+	partial      bool
+	key          string
+	info         *KeyInfo
+	expiry       int64 // UnixNano
+	lastModified int64 // UnixNano
+	data         []byte
+}
+
+// hintEntry 分段中一条记录的位置和元数据
+type hintEntry struct {
+	op           byte
+	partial      bool
+	key          string
+	info         *KeyInfo
+	expiry       int64
+	lastModified int64
+	dataSize     int
+	offset       int64
+	size         int64 // 记录总长度
+}
+
+// diskSegment 一个分段文件
+type diskSegment struct {
+	id   uint32
+	file *os.File
+	size int64 // 文件长度
+	live int64 // 仍被索引引用的记录字节数
+	// hints 活动分段已写入的记录，封存时写入索引文件
+	hints []hintEntry
+}
+
+// segmentFileName 分段文件名
+func segmentFileName(id uint32, suffix string) string {
+	return fmt.Sprintf("%s%08d%s", segmentPrefix, id, suffix)
+}
+
+// parseSegmentID 从分段文件名解析编号
+func parseSegmentID(name string) (uint32, bool) {
+	if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentLogSuffix) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentLogSuffix), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+// listSegments 返回目录中按编号排序的分段
+func listSegments(dir string) ([]uint32, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint32
+	for _, f := range files {
+		if id, ok := parseSegmentID(f.Name()); ok && !f.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// encodeKeyInfo 以\x00分隔编码键信息：来源、关键词、频道或插件
+func encodeKeyInfo(info *KeyInfo) []byte {
+	if info == nil {
+		return nil
+	}
+	parts := append([]string{info.Source, info.Keyword}, info.Targets...)
+	return []byte(strings.Join(parts, "\x00"))
+}
+
+// decodeKeyInfo 解码键信息
+func decodeKeyInfo(b []byte) *KeyInfo {
+	if len(b) == 0 {
+		return nil
+	}
+	parts := strings.Split(string(b), "\x00")
+	if len(parts) < 2 {
+		return nil
+	}
+	info := &KeyInfo{Source: parts[0], Keyword: parts[1]}
+	if len(parts) > 2 {
+		info.Targets = parts[2:]
+	}
+	return info
+}
+
+// putHeader 写入记录头或索引项头中CRC之后的公共字段
+func putHeader(buf []byte, op byte, partial bool, keyLen, infoLen, dataLen int, expiry, lastModified int64) {
+	var flags byte
+	if partial {
+		flags |= recordFlagPartial
+	}
+	buf[4] = op
+	buf[5] = flags
+	binary.LittleEndian.PutUint16(buf[6:8], uint16(keyLen))
+	binary.LittleEndian.PutUint32(buf[8:12], uint32(infoLen))
+	binary.LittleEndian.PutUint32(buf[12:16], uint32(dataLen))
+	binary.LittleEndian.PutUint64(buf[16:24], uint64(expiry))
+	binary.LittleEndian.PutUint64(buf[24:32], uint64(lastModified))
+}
+
+// recordHeader 解析后的记录头
+type recordHeader struct {
+	op           byte
+	partial      bool
+	keyLen       int
+	infoLen      int
+	dataLen      int
+	expiry       int64
+	lastModified int64
+}
+
+// parseHeader 解析记录头或索引项头
+func parseHeader(buf []byte) (recordHeader, error) {
+	h := recordHeader{
+		op:           buf[4],
+		partial:      buf[5]&recordFlagPartial != 0,
+		keyLen:       int(binary.LittleEndian.Uint16(buf[6:8])),
+		infoLen:      int(binary.LittleEndian.Uint32(buf[8:12])),
+		dataLen:      int(binary.LittleEndian.Uint32(buf[12:16])),
+		expiry:       int64(binary.LittleEndian.Uint64(buf[16:24])),
+		lastModified: int64(binary.LittleEndian.Uint64(buf[24:32])),
+	}
+	if (h.op != recordPut && h.op != recordDelete) || h.keyLen == 0 || h.infoLen > maxRecordInfoSize || h.dataLen > maxSegmentSize {
+		return h, errCorruptRecord
+	}
+	return h, nil
+}
+
+// encodeRecord 编码一条记录
+func encodeRecord(rec *logRecord) ([]byte, error) {
+	if len(rec.key) == 0 || len(rec.key) > 0xFFFF {
+		return nil, fmt.Errorf("缓存键长度无效: %d", len(rec.key))
+	}
+	info := encodeKeyInfo(rec.info)
+	// 与parseHeader的校验一致，超出限制的记录写入后读取时会被当作损坏
+	if len(info) > maxRecordInfoSize {
+		return nil, fmt.Errorf("缓存键信息过大: %d字节", len(info))
+	}
+	if len(rec.data) > maxSegmentSize {
+		return nil, fmt.Errorf("缓存数据过大: %d字节，上限%d字节", len(rec.data), maxSegmentSize)
+	}
+	buf := make([]byte, recordHeaderSize+len(rec.key)+len(info)+len(rec.data))
+	putHeader(buf, rec.op, rec.partial, len(rec.key), len(info), len(rec.data), rec.expiry, rec.lastModified)
+	n := copy(buf[recordHeaderSize:], rec.key)
+	n += copy(buf[recordHeaderSize+n:], info)
+	copy(buf[recordHeaderSize+n:], rec.data)
+	binary.LittleEndian.PutUint32(buf[0:4], crc32.Checksum(buf[4:], crcTable))
+	return buf, nil
+}
+
+// decodeRecord 解码并校验一条完整记录
+func decodeRecord(buf []byte) (*logRecord, error) {
+	if len(buf) < recordHeaderSize {
+		return nil, errCorruptRecord
+	}
+	h, err := parseHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) != recordHeaderSize+h.keyLen+h.infoLen+h.dataLen {
+		return nil, errCorruptRecord
+	}
+	if binary.LittleEndian.Uint32(buf[0:4]) != crc32.Checksum(buf[4:], crcTable) {
+		return nil, errCorruptRecord
+	}
+	body := buf[recordHeaderSize:]
+	return &logRecord{
+		op:           h.op,
+		partial:      h.partial,
+		key:          string(body[:h.keyLen]),
+		info:         decodeKeyInfo(body[h.keyLen : h.keyLen+h.infoLen]),
+		expiry:       h.expiry,
+		lastModified: h.lastModified,
+		data:         body[h.keyLen+h.infoLen:],
+	}, nil
+}
+
+// scanSegment 顺序读取分段中的全部记录，遇到不完整或校验失败的记录时截断文件
+// 分段只会在末尾出现半条记录（写入过程中崩溃），截断后其余记录仍然有效
+func scanSegment(file *os.File) ([]hintEntry, int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	r := bufio.NewReaderSize(file, 256*1024)
+	var entries []hintEntry
+	var offset int64
+	header := make([]byte, recordHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err != io.EOF {
+				err = truncateSegment(file, offset)
+			} else {
+				err = nil
+			}
+			return entries, offset, err
+		}
+		h, err := parseHeader(header)
+		if err != nil {
+			return entries, offset, truncateSegment(file, offset)
+		}
+		size := recordHeaderSize + h.keyLen + h.infoLen + h.dataLen
+		buf := make([]byte, size)
+		copy(buf, header)
+		if _, err := io.ReadFull(r, buf[recordHeaderSize:]); err != nil {
+			return entries, offset, truncateSegment(file, offset)
+		}
+		rec, err := decodeRecord(buf)
+		if err != nil {
+			return entries, offset, truncateSegment(file, offset)
+		}
+		entries = append(entries, hintEntry{
+			op:           rec.op,
+			partial:      rec.partial,
+			key:          rec.key,
+			info:         rec.info,
+			expiry:       rec.expiry,
+			lastModified: rec.lastModified,
+			dataSize:     len(rec.data),
+			offset:       offset,
+			size:         int64(size),
+		})
+		offset += int64(size)
+	}
+}
+
+// truncateSegment 截断分段末尾的损坏记录
+func truncateSegment(file *os.File, size int64) error {
+	fmt.Printf("[Cache] 磁盘缓存分段 %s 末尾记录不完整，截断至 %d 字节\n", filepath.Base(file.Name()), size)
+	return file.Truncate(size)
+}
+
+// writeHintFile 写入分段索引，先写临时文件再重命名，避免留下不完整的索引
+func writeHintFile(path string, entries []hintEntry) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for i := range entries {
+		if _, err := w.Write(encodeHint(&entries[i])); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := w.Flush(); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// encodeHint 编码一条索引项
+func encodeHint(e *hintEntry) []byte {
+	info := encodeKeyInfo(e.info)
+	buf := make([]byte, hintHeaderSize+len(e.key)+len(info))
+	putHeader(buf, e.op, e.partial, len(e.key), len(info), e.dataSize, e.expiry, e.lastModified)
+	binary.LittleEndian.PutUint64(buf[recordHeaderSize:hintHeaderSize], uint64(e.offset))
+	n := copy(buf[hintHeaderSize:], e.key)
+	copy(buf[hintHeaderSize+n:], info)
+	binary.LittleEndian.PutUint32(buf[0:4], crc32.Checksum(buf[4:], crcTable))
+	return buf
+}
+
+// readHintFile 读取分段索引，任何一项校验失败都返回错误，由调用方改为扫描分段文件
+func readHintFile(path string) ([]hintEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []hintEntry
+	for len(data) > 0 {
+		if len(data) < hintHeaderSize {
+			return nil, errCorruptRecord
+		}
+		h, err := parseHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		size := hintHeaderSize + h.keyLen + h.infoLen
+		if len(data) < size || binary.LittleEndian.Uint32(data[0:4]) != crc32.Checksum(data[4:size], crcTable) {
+			return nil, errCorruptRecord
+		}
+		body := data[hintHeaderSize:size]
+		entries = append(entries, hintEntry{
+			op:           h.op,
+			partial:      h.partial,
+			key:          string(body[:h.keyLen]),
+			info:         decodeKeyInfo(body[h.keyLen:]),
+			expiry:       h.expiry,
+			lastModified: h.lastModified,
+			dataSize:     h.dataLen,
+			offset:       int64(binary.LittleEndian.Uint64(data[recordHeaderSize:hintHeaderSize])),
+			size:         int64(recordHeaderSize + h.keyLen + h.infoLen + h.dataLen),
+		})
+		data = data[size:]
+	}
+	return entries, nil
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -10,10 +11,12 @@ import (
 
 // EnhancedTwoLevelCache 改进的两级缓存
 type EnhancedTwoLevelCache struct {
-	memory     *ShardedMemoryCache
-	disk       *ShardedDiskCache
-	mutex      sync.RWMutex
-	serializer Serializer
+	memory      *ShardedMemoryCache
+	disk        CacheBackend      // 二级存储：本地磁盘，或配置REDIS_URL时为带磁盘回退的Redis
+	local       *ShardedDiskCache // 本地磁盘缓存，用于缓存管理和快照导入导出
+	mutex       sync.RWMutex
+	serializer  Serializer
+	compression byte // 二级存储中数据的压缩方式，内存中的数据不压缩
 }
 
 // NewEnhancedTwoLevelCache 创建新的改进两级缓存
@@ -31,16 +34,49 @@ func NewEnhancedTwoLevelCache() (*EnhancedTwoLevelCache, error) {
 		return nil, err
 	}
 
-	// 创建序列化器
-	serializer := NewGobSerializer()
+	// 配置了Redis时使用Redis作为多实例共享的二级缓存，本地磁盘作为回退
+	var backend CacheBackend = diskCache
+	if config.AppConfig.CacheRedisURL != "" {
+		redisCache, err := NewRedisCache(config.AppConfig.CacheRedisURL, config.AppConfig.CacheRedisPoolSize, config.AppConfig.CacheRedisKeyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		if err := redisCache.Ping(); err != nil {
+			fmt.Printf("[Cache] Redis %s 暂不可用: %v，将在恢复后自动使用\n", redisCache.Addr(), err)
+		}
+		backend = NewFallbackBackend("Redis "+redisCache.Addr(), redisCache, diskCache)
+	}
+
+	// 创建序列化器，写入的数据带格式信封，结构变化后旧数据可以迁移
+	serializer := NewVersionedSerializer()
+
+	// 二级存储中的数据按配置压缩
+	compression, err := ParseCompression(config.AppConfig.CacheCompression)
+	if err != nil {
+		return nil, err
+	}
+	if path := config.AppConfig.CacheZstdDictPath; path != "" {
+		dictionary, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取zstd字典失败: %v", err)
+		}
+		if err := SetZstdDictionary(dictionary); err != nil {
+			return nil, err
+		}
+	}
+	if compression != CompressionNone {
+		backend = newCompressedBackend(backend, compression)
+	}
 
-	// 设置内存缓存的磁盘缓存引用，用于LRU淘汰时的备份
-	memCache.SetDiskCacheReference(diskCache)
+	// 设置内存缓存的二级缓存引用，用于LRU淘汰时的备份
+	memCache.SetDiskCacheReference(backend)
 
 	return &EnhancedTwoLevelCache{
-		memory:     memCache,
-		disk:       diskCache,
-		serializer: serializer,
+		memory:      memCache,
+		disk:        backend,
+		local:       diskCache,
+		serializer:  serializer,
+		compression: compression,
 	}, nil
 }
 
@@ -65,8 +101,8 @@ func (c *EnhancedTwoLevelCache) Set(key string, data []byte, ttl time.Duration)
 func (c *EnhancedTwoLevelCache) SetMemoryOnly(key string, data []byte, ttl time.Duration) error {
 	now := time.Now()
 
-	// 只更新内存缓存，不触发磁盘写入
-	c.memory.SetWithTimestamp(key, data, ttl, now)
+	// 只更新内存缓存，不触发磁盘写入，标记为等待写入磁盘的中间结果
+	c.memory.setItem(key, data, ttl, now, false)
 
 	return nil
 }
@@ -93,24 +129,38 @@ func (c *EnhancedTwoLevelCache) SetWithFinalFlag(key string, data []byte, ttl ti
 
 // Get 获取缓存
 func (c *EnhancedTwoLevelCache) Get(key string) ([]byte, bool, error) {
+	data, _, hit, err := c.GetWithTimestamp(key)
+	return data, hit, err
+}
+
+// GetWithTimestamp 获取缓存及其最后修改时间
+func (c *EnhancedTwoLevelCache) GetWithTimestamp(key string) ([]byte, time.Time, bool, error) {
 
 	// 检查内存缓存
-	data, _, memHit := c.memory.GetWithTimestamp(key)
+	data, lastModified, memHit := c.memory.GetWithTimestamp(key)
 	if memHit {
-		return data, true, nil
+		return data, lastModified, true, nil
 	}
 
 	// 尝试从磁盘读取数据
-	diskData, diskHit, diskErr := c.disk.Get(key)
+	diskData, diskLastModified, diskHit, diskErr := getWithTimestamp(c.disk, key)
 	if diskErr == nil && diskHit {
-		// 磁盘缓存命中，更新内存缓存
-		diskLastModified, _ := c.disk.GetLastModified(key)
-		ttl := time.Duration(config.AppConfig.CacheTTLMinutes) * time.Minute
+		// 磁盘缓存命中，更新内存缓存，内存副本沿用磁盘上按有效期策略写入的剩余有效期
+		ttl := remainingTTL(c.disk, key, time.Duration(config.AppConfig.CacheTTLMinutes)*time.Minute)
 		c.memory.SetWithTimestamp(key, diskData, ttl, diskLastModified)
-		return diskData, true, nil
+		return diskData, diskLastModified, true, nil
 	}
 
-	return nil, false, nil
+	return nil, time.Time{}, false, nil
+}
+
+// GetExpiry 获取缓存项的过期时间，依次检查内存和本地磁盘
+// 只存在于Redis中的缓存项无法在不读取数据的情况下得知过期时间，返回false
+func (c *EnhancedTwoLevelCache) GetExpiry(key string) (time.Time, bool) {
+	if expiry, ok := c.memory.GetExpiry(key); ok {
+		return expiry, true
+	}
+	return c.local.GetExpiry(key)
 }
 
 // Delete 删除缓存
@@ -154,7 +204,7 @@ func (c *EnhancedTwoLevelCache) FlushMemoryToDisk() error {
 
 	for key, item := range allItems {
 		// 同步写入到磁盘缓存
-		if err := c.disk.Set(key, item.Data, item.TTL); err != nil {
+		if err := setWithFinal(c.disk, key, item.Data, item.TTL, item.Final); err != nil {
 			fmt.Printf("[内存同步] 同步失败: %s -> %v\n", key, err)
 			lastErr = err
 			continue
@@ -163,3 +213,8 @@ func (c *EnhancedTwoLevelCache) FlushMemoryToDisk() error {
 
 	return lastErr
 }
+
+// Close 关闭本地磁盘缓存，应在FlushMemoryToDisk之后调用
+func (c *EnhancedTwoLevelCache) Close() error {
+	return c.local.Close()
+}
@@ -0,0 +1,352 @@
+package cache
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"sync"
+
+	"pansou/model"
+	"pansou/util/codec"
+)
+
+// 缓存数据信封：写入缓存的数据带有固定长度的头部，记录格式版本、序列化器、压缩方式和结果结构指纹，
+// 结构变化后旧数据仍能识别出来并按需迁移，而不是反序列化失败后当作未命中
+//
+// 格式：magic(4) | 格式版本(1) | 序列化器(1) | 压缩方式(1) | 结构指纹(4, 大端) | 数据
+// magic以0字节开头，gob数据的第一个字节是消息长度，不会为0，因此可以与旧版本直接写入的gob数据区分
+
+const (
+	envelopeMagic      = "\x00PSC"
+	envelopeVersion    = 1
+	envelopeHeaderSize = len(envelopeMagic) + 7
+)
+
+// 序列化器标识
+const (
+	SerializerGob  byte = 1
+	SerializerJSON byte = 2
+)
+
+// 压缩方式标识
+const (
+	CompressionNone byte = 0
+	CompressionGzip byte = 1
+	CompressionZstd byte = 2
+)
+
+// 压缩方式对应的编解码器，zstd可通过SetZstdDictionary换成使用字典的编解码器
+var (
+	compressionMutex  sync.RWMutex
+	compressionCodecs = make(map[byte]codec.Codec)
+)
+
+func init() {
+	for id, name := range map[byte]string{CompressionGzip: codec.Gzip, CompressionZstd: codec.Zstd} {
+		c, _ := codec.Get(name)
+		compressionCodecs[id] = c
+	}
+}
+
+// ParseCompression 把压缩方式名称（none、gzip、zstd）转换为标识
+func ParseCompression(name string) (byte, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "none":
+		return CompressionNone, nil
+	case codec.Gzip:
+		return CompressionGzip, nil
+	case codec.Zstd:
+		return CompressionZstd, nil
+	default:
+		return CompressionNone, fmt.Errorf("不支持的缓存压缩方式: %s", name)
+	}
+}
+
+// SetZstdDictionary 设置zstd压缩使用的字典，应在读写缓存之前调用
+// 更换字典后，用旧字典压缩的缓存项无法解压，读取时按不兼容数据删除
+func SetZstdDictionary(dictionary []byte) error {
+	c, err := codec.NewZstd(dictionary)
+	if err != nil {
+		return err
+	}
+	compressionMutex.Lock()
+	compressionCodecs[CompressionZstd] = c
+	compressionMutex.Unlock()
+	return nil
+}
+
+// compressionCodec 返回压缩方式对应的编解码器
+func compressionCodec(compression byte) (codec.Codec, error) {
+	compressionMutex.RLock()
+	defer compressionMutex.RUnlock()
+	c, ok := compressionCodecs[compression]
+	if !ok {
+		return nil, fmt.Errorf("未知的压缩方式: %d", compression)
+	}
+	return c, nil
+}
+
+// ErrIncompatibleCache 缓存数据无法按当前版本解码
+var ErrIncompatibleCache = errors.New("缓存数据格式不兼容")
+
+// ErrNewerCache 缓存数据由更高版本写入，当前版本无法解码，但可能仍被其他实例使用，不应删除
+var ErrNewerCache = fmt.Errorf("%w: 由更高版本写入", ErrIncompatibleCache)
+
+// envelopeHeader 信封头部
+type envelopeHeader struct {
+	version     byte
+	serializer  byte
+	compression byte
+	schema      uint32
+}
+
+// parseEnvelope 解析信封，数据不带信封（旧版本写入）时返回false
+func parseEnvelope(data []byte) (envelopeHeader, []byte, bool, error) {
+	if !bytes.HasPrefix(data, []byte(envelopeMagic)) {
+		return envelopeHeader{}, nil, false, nil
+	}
+	if len(data) < envelopeHeaderSize {
+		return envelopeHeader{}, nil, true, fmt.Errorf("%w: 头部不完整", ErrIncompatibleCache)
+	}
+	rest := data[len(envelopeMagic):]
+	h := envelopeHeader{
+		version:     rest[0],
+		serializer:  rest[1],
+		compression: rest[2],
+		schema:      binary.BigEndian.Uint32(rest[3:7]),
+	}
+	return h, data[envelopeHeaderSize:], true, nil
+}
+
+// VersionedSerializer 带信封的序列化器
+// 写入时使用指定的序列化器和压缩方式；读取时按信封头部选择解码方式，也能读取旧版本直接写入的gob数据
+type VersionedSerializer struct {
+	serializers map[byte]Serializer
+	serializer  byte
+	compression byte
+	schema      uint32
+}
+
+// NewVersionedSerializer 创建使用gob、不压缩的带信封序列化器
+func NewVersionedSerializer() *VersionedSerializer {
+	return &VersionedSerializer{
+		serializers: map[byte]Serializer{
+			SerializerGob:  NewGobSerializer(),
+			SerializerJSON: NewJSONSerializer(),
+		},
+		serializer:  SerializerGob,
+		compression: CompressionNone,
+		schema:      SchemaHash(),
+	}
+}
+
+// SetCompression 设置写入时使用的压缩方式
+func (s *VersionedSerializer) SetCompression(compression byte) error {
+	if _, err := compress(compression, nil); err != nil {
+		return err
+	}
+	s.compression = compression
+	return nil
+}
+
+// Serialize 序列化数据并加上信封
+func (s *VersionedSerializer) Serialize(v interface{}) ([]byte, error) {
+	payload, err := s.serializers[s.serializer].Serialize(v)
+	if err != nil {
+		return nil, err
+	}
+	payload, err = compress(s.compression, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(payload))
+	copy(data, envelopeMagic)
+	rest := data[len(envelopeMagic):]
+	rest[0] = envelopeVersion
+	rest[1] = s.serializer
+	rest[2] = s.compression
+	binary.BigEndian.PutUint32(rest[3:7], s.schema)
+	return append(data, payload...), nil
+}
+
+// Deserialize 反序列化数据
+func (s *VersionedSerializer) Deserialize(data []byte, v interface{}) error {
+	_, err := s.Decode(data, v)
+	return err
+}
+
+// Decode 反序列化数据，stale表示数据不是按当前格式写入的，应重写
+// 结构指纹不同时仍尝试解码（gob和JSON都能容忍增删字段），解码失败返回ErrIncompatibleCache
+func (s *VersionedSerializer) Decode(data []byte, v interface{}) (stale bool, err error) {
+	h, legacy, err := s.decode(data, v)
+	if err != nil {
+		return false, err
+	}
+	return legacy || !s.isCurrent(h, s.compression), nil
+}
+
+// decode 反序列化数据并返回信封头部，legacy表示数据是旧版本直接写入的gob数据
+func (s *VersionedSerializer) decode(data []byte, v interface{}) (envelopeHeader, bool, error) {
+	h, payload, ok, err := parseEnvelope(data)
+	if err != nil {
+		return h, false, err
+	}
+	if !ok {
+		if err := s.serializers[SerializerGob].Deserialize(data, v); err != nil {
+			return h, true, fmt.Errorf("%w: %v", ErrIncompatibleCache, err)
+		}
+		return h, true, nil
+	}
+
+	if h.version > envelopeVersion {
+		return h, false, fmt.Errorf("%w: 格式版本%d高于当前支持的版本%d", ErrNewerCache, h.version, envelopeVersion)
+	}
+	inner, ok := s.serializers[h.serializer]
+	if !ok {
+		return h, false, fmt.Errorf("%w: 未知的序列化器%d", ErrIncompatibleCache, h.serializer)
+	}
+	payload, err = decompress(h.compression, payload)
+	if err != nil {
+		return h, false, fmt.Errorf("%w: %v", ErrIncompatibleCache, err)
+	}
+	if err := inner.Deserialize(payload, v); err != nil {
+		return h, false, fmt.Errorf("%w: %v", ErrIncompatibleCache, err)
+	}
+	return h, false, nil
+}
+
+// isCurrent 判断信封是否与当前格式一致，compression为所在存储层使用的压缩方式
+func (s *VersionedSerializer) isCurrent(h envelopeHeader, compression byte) bool {
+	return h.version == envelopeVersion && h.serializer == s.serializer &&
+		h.compression == compression && h.schema == s.schema
+}
+
+// transcode 把信封数据改为指定的压缩方式，不带信封或已是该压缩方式的数据原样返回
+func transcode(data []byte, compression byte) ([]byte, error) {
+	h, payload, ok, err := parseEnvelope(data)
+	if err != nil || !ok || h.compression == compression {
+		return data, err
+	}
+	raw, err := decompress(h.compression, payload)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := compress(compression, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(packed))
+	copy(out, data[:envelopeHeaderSize])
+	out[len(envelopeMagic)+2] = compression
+	return append(out, packed...), nil
+}
+
+// payloadOf 返回信封中未压缩的数据，用于训练压缩字典
+func payloadOf(data []byte) ([]byte, bool) {
+	h, payload, ok, err := parseEnvelope(data)
+	if err != nil || !ok {
+		return nil, false
+	}
+	raw, err := decompress(h.compression, payload)
+	return raw, err == nil
+}
+
+// compress 按压缩方式压缩数据
+func compress(compression byte, data []byte) ([]byte, error) {
+	if compression == CompressionNone {
+		return data, nil
+	}
+	c, err := compressionCodec(compression)
+	if err != nil {
+		return nil, err
+	}
+	return c.Compress(data)
+}
+
+// decompress 按压缩方式解压数据
+func decompress(compression byte, data []byte) ([]byte, error) {
+	if compression == CompressionNone {
+		return data, nil
+	}
+	c, err := compressionCodec(compression)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decompress(data)
+}
+
+var (
+	schemaHash     uint32
+	schemaHashOnce sync.Once
+)
+
+// SchemaHash 返回缓存结果结构（model.SearchResult及其包含的类型）的指纹
+// 字段名、类型或JSON标签变化时指纹随之变化
+func SchemaHash() uint32 {
+	schemaHashOnce.Do(func() {
+		var b strings.Builder
+		describeType(&b, reflect.TypeOf(model.SearchResult{}), make(map[reflect.Type]bool))
+		h := fnv.New32a()
+		h.Write([]byte(b.String()))
+		schemaHash = h.Sum32()
+	})
+	return schemaHash
+}
+
+var (
+	gobEncoderType    = reflect.TypeOf((*gob.GobEncoder)(nil)).Elem()
+	binaryMarshalType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+)
+
+// describeType 生成类型结构的文本描述
+func describeType(b *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	// 自定义编码的类型（如time.Time）只记录类型名
+	if t.Implements(gobEncoderType) || t.Implements(binaryMarshalType) {
+		b.WriteString(t.String())
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		b.WriteString("*")
+		describeType(b, t.Elem(), seen)
+	case reflect.Slice:
+		b.WriteString("[]")
+		describeType(b, t.Elem(), seen)
+	case reflect.Array:
+		fmt.Fprintf(b, "[%d]", t.Len())
+		describeType(b, t.Elem(), seen)
+	case reflect.Map:
+		b.WriteString("map[")
+		describeType(b, t.Key(), seen)
+		b.WriteString("]")
+		describeType(b, t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			b.WriteString(t.String())
+			return
+		}
+		seen[t] = true
+		b.WriteString("struct{")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			fmt.Fprintf(b, "%s %q ", f.Name, f.Tag.Get("json"))
+			describeType(b, f.Type, seen)
+			b.WriteString(";")
+		}
+		b.WriteString("}")
+	default:
+		b.WriteString(t.Kind().String())
+	}
+}
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"pansou/model"
+)
+
+func testResults() []model.SearchResult {
+	return []model.SearchResult{{
+		UniqueID: "tg-1",
+		Title:    "凡人修仙传",
+		Links:    []model.Link{{Type: "quark", URL: "https://pan.quark.cn/s/abc"}},
+	}}
+}
+
+func TestVersionedSerializerRoundTrip(t *testing.T) {
+	s := NewVersionedSerializer()
+	for _, compression := range []byte{CompressionNone, CompressionGzip} {
+		if err := s.SetCompression(compression); err != nil {
+			t.Fatal(err)
+		}
+		data, err := s.Serialize(testResults())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []model.SearchResult
+		stale, err := s.Decode(data, &got)
+		if err != nil || stale {
+			t.Fatalf("压缩方式%d: stale=%v err=%v", compression, stale, err)
+		}
+		if len(got) != 1 || got[0].Links[0].URL != "https://pan.quark.cn/s/abc" {
+			t.Fatalf("压缩方式%d: 解码结果错误 %+v", compression, got)
+		}
+	}
+
+	// 压缩方式改变后，旧压缩方式写入的数据需要重写
+	var got []model.SearchResult
+	s.SetCompression(CompressionNone)
+	gz := NewVersionedSerializer()
+	gz.SetCompression(CompressionGzip)
+	data, _ := gz.Serialize(testResults())
+	if stale, err := s.Decode(data, &got); err != nil || !stale {
+		t.Errorf("gzip数据应可解码并标记为需要重写: stale=%v err=%v", stale, err)
+	}
+}
+
+func TestVersionedSerializerLegacyAndIncompatible(t *testing.T) {
+	s := NewVersionedSerializer()
+
+	// 旧版本直接写入的gob数据
+	legacy, err := NewGobSerializer().Serialize(testResults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []model.SearchResult
+	if stale, err := s.Decode(legacy, &got); err != nil || !stale || len(got) != 1 {
+		t.Fatalf("旧格式数据: stale=%v err=%v results=%d", stale, err, len(got))
+	}
+
+	// 结构指纹不同但仍可解码
+	data, _ := s.Serialize(testResults())
+	data[len(envelopeMagic)+3] ^= 0xff
+	if stale, err := s.Decode(data, &got); err != nil || !stale {
+		t.Errorf("结构指纹不同: stale=%v err=%v", stale, err)
+	}
+
+	// 更高的格式版本
+	data, _ = s.Serialize(testResults())
+	data[len(envelopeMagic)] = envelopeVersion + 1
+	if _, err := s.Decode(data, &got); !errors.Is(err, ErrIncompatibleCache) {
+		t.Errorf("更高格式版本应返回ErrIncompatibleCache: %v", err)
+	}
+
+	// 类型不兼容
+	data, _ = s.Serialize(map[string]int{"a": 1})
+	if _, err := s.Decode(data, &got); !errors.Is(err, ErrIncompatibleCache) {
+		t.Errorf("类型不兼容应返回ErrIncompatibleCache: %v", err)
+	}
+}
+
+func TestMigrateLocal(t *testing.T) {
+	c := newTestTwoLevelCache(t)
+	c.serializer = NewVersionedSerializer()
+
+	legacy, _ := NewGobSerializer().Serialize(testResults())
+	current, _ := c.serializer.Serialize(testResults())
+	c.local.Set("legacy", legacy, time.Hour)
+	c.local.Set("current", current, time.Hour)
+	c.local.Set("broken", []byte("not gob"), time.Hour)
+	before, _ := c.local.GetLastModified("legacy")
+
+	report, err := c.MigrateLocal(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != (MigrationReport{Scanned: 3, Current: 1, Rewritten: 1, Dropped: 1}) {
+		t.Fatalf("试运行统计错误: %+v", report)
+	}
+	if !c.local.Has("broken") {
+		t.Fatal("试运行不应删除缓存项")
+	}
+
+	if _, err := c.MigrateLocal(false); err != nil {
+		t.Fatal(err)
+	}
+	if c.local.Has("broken") {
+		t.Error("无法解码的缓存项应被删除")
+	}
+	data, _, _ := c.local.Get("legacy")
+	var got []model.SearchResult
+	if stale, err := c.serializer.(*VersionedSerializer).Decode(data, &got); err != nil || stale {
+		t.Errorf("旧格式缓存项应被重写: stale=%v err=%v", stale, err)
+	}
+	if after, _ := c.local.GetLastModified("legacy"); !after.Equal(before) {
+		t.Errorf("重写不应改变最后修改时间: %v -> %v", before, after)
+	}
+
+	report, _ = c.MigrateLocal(true)
+	if report != (MigrationReport{Scanned: 2, Current: 2}) {
+		t.Errorf("迁移后统计错误: %+v", report)
+	}
+}
+
+func TestDecodeMigratesOnRead(t *testing.T) {
+	c := newTestTwoLevelCache(t)
+	c.serializer = NewVersionedSerializer()
+
+	legacy, _ := NewGobSerializer().Serialize(testResults())
+	c.SetBothLevels("k", legacy, time.Hour)
+
+	data, _, _ := c.Get("k")
+	var got []model.SearchResult
+	if err := c.Decode("k", data, &got); err != nil || len(got) != 1 {
+		t.Fatalf("Decode: %v", err)
+	}
+	memData, _ := c.memory.Get("k")
+	diskData, _, _ := c.local.Get("k")
+	for tier, d := range map[string][]byte{"内存": memData, "磁盘": diskData} {
+		if _, _, isEnvelope, _ := parseEnvelope(d); !isEnvelope {
+			t.Errorf("%s中的缓存项未按当前格式重写", tier)
+		}
+	}
+
+	c.SetBothLevels("bad", []byte("not gob"), time.Hour)
+	data, _, _ = c.Get("bad")
+	if err := c.Decode("bad", data, &got); !errors.Is(err, ErrIncompatibleCache) {
+		t.Fatalf("应返回ErrIncompatibleCache: %v", err)
+	}
+	if _, hit, _ := c.Get("bad"); hit {
+		t.Error("不兼容的缓存项应被删除")
+	}
+}
+
+func TestDecodeKeepsSharedEntries(t *testing.T) {
+	c := newTestTwoLevelCache(t)
+	c.serializer = NewVersionedSerializer()
+	// 模拟Redis等共享存储，本地磁盘单独保存
+	shared, err := NewShardedDiskCache(t.TempDir(), 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.disk = shared
+
+	var got []model.SearchResult
+	c.memory.Set("bad", []byte("not gob"), time.Hour)
+	c.local.Set("bad", []byte("not gob"), time.Hour)
+	shared.Set("bad", []byte("not gob"), time.Hour)
+	if err := c.Decode("bad", []byte("not gob"), &got); !errors.Is(err, ErrIncompatibleCache) {
+		t.Fatalf("应返回ErrIncompatibleCache: %v", err)
+	}
+	if c.local.Has("bad") || !shared.Has("bad") {
+		t.Error("不兼容的缓存项只应从本地删除")
+	}
+
+	// 更高版本写入的数据按未命中处理，不删除
+	newer, _ := c.serializer.Serialize(testResults())
+	newer[len(envelopeMagic)] = envelopeVersion + 1
+	c.local.Set("newer", newer, time.Hour)
+	if err := c.Decode("newer", newer, &got); !errors.Is(err, ErrNewerCache) {
+		t.Fatalf("应返回ErrNewerCache: %v", err)
+	}
+	if !c.local.Has("newer") {
+		t.Error("更高版本写入的缓存项不应删除")
+	}
+	report, _ := c.MigrateLocal(false)
+	if report.Newer != 1 || report.Dropped != 0 || !c.local.Has("newer") {
+		t.Errorf("迁移不应删除更高版本的缓存项: %+v", report)
+	}
+}
+
+func TestCompressedDiskTier(t *testing.T) {
+	c := newTestTwoLevelCache(t)
+	c.serializer = NewVersionedSerializer()
+	c.compression = CompressionZstd
+	c.disk = newCompressedBackend(c.local, CompressionZstd)
+	c.memory.SetDiskCacheReference(c.disk)
+
+	data, _ := c.serializer.Serialize(testResults())
+	c.SetBothLevels("k", data, time.Hour)
+
+	// 内存保存未压缩的数据，磁盘保存压缩后的数据
+	if memData, _ := c.memory.Get("k"); string(memData) != string(data) {
+		t.Error("内存中的数据不应压缩")
+	}
+	raw, _, _ := c.local.Get("k")
+	if h, _, _, _ := parseEnvelope(raw); h.compression != CompressionZstd {
+		t.Fatalf("磁盘中的压缩方式 %d，应为zstd", h.compression)
+	}
+
+	// 从磁盘读取时解压
+	loaded, _, _ := c.disk.Get("k")
+	var got []model.SearchResult
+	if stale, err := c.serializer.(*VersionedSerializer).Decode(loaded, &got); err != nil || stale || len(got) != 1 {
+		t.Fatalf("从磁盘加载: stale=%v err=%v", stale, err)
+	}
+
+	// 开启压缩前写入的未压缩数据由迁移命令压缩
+	c.local.Set("plain", data, time.Hour)
+	report, err := c.MigrateLocal(false)
+	if err != nil || report.Rewritten != 1 || report.Current != 1 {
+		t.Fatalf("迁移统计错误: %+v %v", report, err)
+	}
+	raw, _, _ = c.local.Get("plain")
+	if h, _, _, _ := parseEnvelope(raw); h.compression != CompressionZstd {
+		t.Errorf("迁移后压缩方式 %d，应为zstd", h.compression)
+	}
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// 缓存键来源
+const (
+	KeySourceTG     = "tg"
+	KeySourcePlugin = "plugin"
+)
+
+// keyInfoRetention 缓存键信息在登记表中的保留时间
+// 写入缓存时信息已随缓存项保存，登记表只需覆盖从生成键到写入缓存（含插件后台更新）的时间
+const keyInfoRetention = 2 * time.Hour
+
+// KeyInfo 缓存键对应的搜索条件
+// 缓存键是MD5哈希，无法反推关键词，生成键时记录这些信息供缓存管理接口按关键词、插件或频道筛选
+type KeyInfo struct {
+	Keyword string   `json:"keyword"`
+	Source  string   `json:"source"`            // tg 或 plugin
+	Targets []string `json:"targets,omitempty"` // 搜索的频道或插件，为空表示全部
+}
+
+// keyInfoRecord 登记表中的一条记录
+type keyInfoRecord struct {
+	info     *KeyInfo
+	lastSeen int64 // UnixNano
+}
+
+// keyInfoRegistry 缓存键信息登记表
+type keyInfoRegistry struct {
+	records sync.Map // key -> *keyInfoRecord
+	once    sync.Once
+}
+
+var keyInfos = &keyInfoRegistry{}
+
+// record 登记缓存键信息，首次登记时加入全局清理任务
+func (r *keyInfoRegistry) record(key string, info *KeyInfo) {
+	r.once.Do(func() {
+		registerForCleanup(r)
+		startGlobalCleanupTask()
+	})
+	r.records.Store(key, &keyInfoRecord{info: info, lastSeen: time.Now().UnixNano()})
+}
+
+// lookup 查找缓存键信息
+func (r *keyInfoRegistry) lookup(key string) *KeyInfo {
+	if v, ok := r.records.Load(key); ok {
+		return v.(*keyInfoRecord).info
+	}
+	return nil
+}
+
+// CleanExpired 删除长时间未使用的登记记录，符合cleanupTarget接口
+func (r *keyInfoRegistry) CleanExpired() {
+	cutoff := time.Now().Add(-keyInfoRetention).UnixNano()
+	r.records.Range(func(key, value interface{}) bool {
+		if value.(*keyInfoRecord).lastSeen < cutoff {
+			r.records.Delete(key)
+		}
+		return true
+	})
+}
+
+// newKeyInfo 创建缓存键信息，关键词与缓存键使用相同的标准化方式
+func newKeyInfo(source, keyword string, targets []string) *KeyInfo {
+	info := &KeyInfo{
+		Keyword: normalizeKeyword(keyword),
+		Source:  source,
+	}
+	for _, t := range targets {
+		if t != "" {
+			info.Targets = append(info.Targets, t)
+		}
+	}
+	return info
+}
+
+// normalizeKeyword 关键词标准化：去除首尾空格，转为小写
+func normalizeKeyword(keyword string) string {
+	return strings.ToLower(strings.TrimSpace(keyword))
+}
+
+// hasTarget 判断缓存键是否覆盖指定的频道或插件（未指定时覆盖全部）
+func (i *KeyInfo) hasTarget(name string) bool {
+	if len(i.Targets) == 0 {
+		return true
+	}
+	for _, t := range i.Targets {
+		if strings.EqualFold(t, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bloomFalsePositiveRate 布隆过滤器的目标误判率
+// 误判会把有结果的关键词当作无结果，在一个TTL内跳过对应来源，因此取较低的误判率
+const bloomFalsePositiveRate = 0.001
+
+// NegativeCacheStats 无结果缓存统计
+type NegativeCacheStats struct {
+	Items          int     `json:"items"` // 未过期的两代过滤器中记录的次数，删除的记录不扣除
+	MaxItems       int     `json:"max_items"`
+	TTLSeconds     int     `json:"ttl_seconds"`
+	Hits           int64   `json:"hits"`
+	Misses         int64   `json:"misses"`
+	HitRate        float64 `json:"hit_rate"`
+	EarlyRotations int64   `json:"early_rotations"` // 本代记录已满、提前轮换的次数
+}
+
+// NegativeCache 记录搜索无结果的缓存键，在短时间内直接返回空结果，避免重复请求所有上游
+// 只使用两代布隆过滤器，不保存缓存键或指纹：每半个TTL轮换一代，丢弃的一代整体过期，
+// 因此记录的有效期在TTL的一半到TTL之间；本代记录满maxItems时提前轮换，最旧的记录提前失效
+type NegativeCache struct {
+	ttl      time.Duration
+	span     time.Duration // 每代的时长
+	maxItems int
+
+	mu        sync.RWMutex
+	current   *bloomFilter // 本代写入的键
+	previous  *bloomFilter // 上一代写入的键
+	added     int          // 本代记录次数
+	prevAdded int          // 上一代记录次数
+	rotatedAt time.Time
+
+	hits           int64
+	misses         int64
+	earlyRotations int64
+}
+
+// NewNegativeCache 创建无结果缓存
+func NewNegativeCache(ttl time.Duration, maxItems int) *NegativeCache {
+	return &NegativeCache{
+		ttl:       ttl,
+		span:      ttl / 2,
+		maxItems:  maxItems,
+		current:   newBloomFilter(maxItems, bloomFalsePositiveRate),
+		previous:  newBloomFilter(maxItems, bloomFalsePositiveRate),
+		rotatedAt: time.Now(),
+	}
+}
+
+// Contains 判断缓存键最近是否搜索无结果
+func (c *NegativeCache) Contains(key string) bool {
+	h := fingerprint(key)
+
+	c.mu.RLock()
+	current, previous := c.liveLocked(time.Now())
+	found := (current && c.current.mayContain(h)) || (previous && c.previous.mayContain(h))
+	c.mu.RUnlock()
+
+	if found {
+		atomic.AddInt64(&c.hits, 1)
+		return true
+	}
+	atomic.AddInt64(&c.misses, 1)
+	return false
+}
+
+// Add 记录缓存键搜索无结果
+func (c *NegativeCache) Add(key string) {
+	h := fingerprint(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rotateLocked(time.Now())
+	if c.added >= c.maxItems {
+		c.shiftLocked(time.Now())
+		atomic.AddInt64(&c.earlyRotations, 1)
+	}
+	c.current.add(h)
+	c.added++
+}
+
+// Remove 删除缓存键的无结果记录
+// 布隆过滤器无法单独删除一个键，这里清除该键在所在过滤器中的所有位；
+// 共享这些位的其他记录会一并失效，只会多请求一次上游，不会产生误判。
+// 有结果的搜索每次都会调用Remove，未记录的键不清除任何位，避免误删其他记录
+func (c *NegativeCache) Remove(key string) {
+	h := fingerprint(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current.mayContain(h) {
+		c.current.remove(h)
+	}
+	if c.previous.mayContain(h) {
+		c.previous.remove(h)
+	}
+}
+
+// Clear 清空所有记录
+func (c *NegativeCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current.reset()
+	c.previous.reset()
+	c.added, c.prevAdded = 0, 0
+	c.rotatedAt = time.Now()
+}
+
+// liveLocked 返回本代和上一代过滤器是否仍在有效期内（调用方需持有锁）
+// 读取时不轮换，按距上次轮换的时间判断，轮换推迟到下一次写入
+func (c *NegativeCache) liveLocked(now time.Time) (current, previous bool) {
+	elapsed := now.Sub(c.rotatedAt)
+	return elapsed < 2*c.span, elapsed < c.span
+}
+
+// rotateLocked 按时间轮换过滤器，超过两代未写入时两代都已过期（调用方需持有写锁）
+func (c *NegativeCache) rotateLocked(now time.Time) {
+	elapsed := now.Sub(c.rotatedAt)
+	switch {
+	case elapsed >= 2*c.span:
+		c.current.reset()
+		c.previous.reset()
+		c.added, c.prevAdded = 0, 0
+		c.rotatedAt = now
+	case elapsed >= c.span:
+		c.shiftLocked(now)
+	}
+}
+
+// shiftLocked 本代变为上一代，丢弃原来的上一代（调用方需持有写锁）
+func (c *NegativeCache) shiftLocked(now time.Time) {
+	c.previous, c.current = c.current, c.previous
+	c.current.reset()
+	c.prevAdded, c.added = c.added, 0
+	c.rotatedAt = now
+}
+
+// Stats 返回统计信息
+func (c *NegativeCache) Stats() NegativeCacheStats {
+	c.mu.RLock()
+	current, previous := c.liveLocked(time.Now())
+	items := 0
+	if current {
+		items += c.added
+	}
+	if previous {
+		items += c.prevAdded
+	}
+	c.mu.RUnlock()
+
+	stats := NegativeCacheStats{
+		Items:          items,
+		MaxItems:       c.maxItems,
+		TTLSeconds:     int(c.ttl / time.Second),
+		Hits:           atomic.LoadInt64(&c.hits),
+		Misses:         atomic.LoadInt64(&c.misses),
+		EarlyRotations: atomic.LoadInt64(&c.earlyRotations),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// fingerprint 计算缓存键的64位指纹
+func fingerprint(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// bloomFilter 布隆过滤器，使用双重哈希从64位指纹派生k个位置
+type bloomFilter struct {
+	bits []uint64
+	m    uint64 // 位数
+	k    int    // 哈希函数个数
+}
+
+// newBloomFilter 按预计元素数和误判率创建布隆过滤器
+func newBloomFilter(n int, p float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// position 返回指纹对应的第i个位置
+func (f *bloomFilter) position(h uint64, i int) uint64 {
+	h1, h2 := h&0xFFFFFFFF, h>>32|1
+	return (h1 + uint64(i)*h2) % f.m
+}
+
+func (f *bloomFilter) add(h uint64) {
+	for i := 0; i < f.k; i++ {
+		pos := f.position(h, i)
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (f *bloomFilter) mayContain(h uint64) bool {
+	for i := 0; i < f.k; i++ {
+		pos := f.position(h, i)
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) remove(h uint64) {
+	for i := 0; i < f.k; i++ {
+		pos := f.position(h, i)
+		f.bits[pos/64] &^= 1 << (pos % 64)
+	}
+}
+
+func (f *bloomFilter) reset() {
+	for i := range f.bits {
+		f.bits[i] = 0
+	}
+}
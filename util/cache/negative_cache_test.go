@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNegativeCache(t *testing.T) {
+	c := NewNegativeCache(50*time.Millisecond, 100)
+
+	c.Add("empty")
+	if !c.Contains("empty") {
+		t.Fatal("记录后应命中")
+	}
+	if c.Contains("other") {
+		t.Error("未记录的键不应命中")
+	}
+
+	c.Remove("empty")
+	if c.Contains("empty") {
+		t.Error("删除后不应命中")
+	}
+
+	c.Add("expiring")
+	time.Sleep(60 * time.Millisecond)
+	if c.Contains("expiring") {
+		t.Error("过期后不应命中")
+	}
+
+	// 轮换过滤器后，上一周期写入的未过期键仍可命中
+	c.Add("first")
+	time.Sleep(30 * time.Millisecond)
+	c.Add("second")
+	time.Sleep(30 * time.Millisecond)
+	c.Add("third") // 触发轮换
+	if c.Contains("first") || !c.Contains("second") || !c.Contains("third") {
+		t.Error("轮换后命中结果错误")
+	}
+}
+
+func TestNegativeCacheMaxItems(t *testing.T) {
+	c := NewNegativeCache(time.Minute, 10)
+	for i := 0; i < 25; i++ {
+		c.Add(fmt.Sprintf("key%d", i))
+	}
+	stats := c.Stats()
+	if stats.Items != 15 || stats.EarlyRotations != 2 {
+		t.Errorf("items=%d early_rotations=%d", stats.Items, stats.EarlyRotations)
+	}
+	if c.Contains("key0") || !c.Contains("key15") || !c.Contains("key24") {
+		t.Error("已满时应提前轮换，丢弃最旧一代的记录")
+	}
+}
+
+func TestNegativeCacheRemoveKeepsOthers(t *testing.T) {
+	c := NewNegativeCache(time.Minute, 1000)
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("key%d", i))
+	}
+	c.Remove("key0")
+	if c.Contains("key0") {
+		t.Error("删除后不应命中")
+	}
+	kept := 0
+	for i := 1; i < 100; i++ {
+		if c.Contains(fmt.Sprintf("key%d", i)) {
+			kept++
+		}
+	}
+	if kept < 90 {
+		t.Errorf("删除一个键后只剩 %d 个记录命中", kept)
+	}
+}
+
+func TestNegativeCacheRemoveAbsentKey(t *testing.T) {
+	c := NewNegativeCache(time.Minute, 1000)
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("key%d", i))
+	}
+	// 有结果的搜索都会删除记录，未记录的键不应影响其他记录
+	for i := 0; i < 10000; i++ {
+		c.Remove(fmt.Sprintf("found%d", i))
+	}
+	for i := 0; i < 100; i++ {
+		if !c.Contains(fmt.Sprintf("key%d", i)) {
+			t.Fatalf("删除未记录的键后 key%d 不再命中", i)
+		}
+	}
+}
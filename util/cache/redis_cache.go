@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Redis缓存值格式：版本(1字节) + 标志(1字节) + 最后修改时间(8字节，UnixNano) + 数据
+const (
+	redisValueVersion      = 1
+	redisValueHeaderSize   = 10
+	redisFlagGzip          = 1 << 0
+	redisCompressThreshold = 1024 // 超过该大小的数据压缩后存储
+	redisScanCount         = 500  // Clear时每次SCAN的数量
+)
+
+// errCorruptRedisValue Redis中的单个缓存值无法解码，只影响该键，不代表Redis不可用
+var errCorruptRedisValue = errors.New("redis: 无法解码的缓存值")
+
+// RedisCache 基于Redis协议（RESP）的远程缓存，多个实例可共享
+type RedisCache struct {
+	pool   *respPool
+	prefix string
+}
+
+// NewRedisCache 创建Redis缓存，连接在首次使用时建立
+func NewRedisCache(rawURL string, poolSize int, prefix string) (*RedisCache, error) {
+	opts, err := parseRedisURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if poolSize > 0 {
+		opts.poolSize = poolSize
+	}
+	return &RedisCache{pool: newRespPool(opts), prefix: prefix}, nil
+}
+
+// Addr 返回Redis服务地址
+func (c *RedisCache) Addr() string {
+	return c.pool.opts.addr
+}
+
+// Ping 检查Redis是否可用
+func (c *RedisCache) Ping() error {
+	_, err := c.pool.do("PING")
+	return err
+}
+
+// Close 关闭空闲连接
+func (c *RedisCache) Close() {
+	c.pool.close()
+}
+
+// Set 设置缓存
+func (c *RedisCache) Set(key string, data []byte, ttl time.Duration) error {
+	value, err := encodeRedisValue(data, time.Now())
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", c.prefix + key, string(value)}
+	if ttl > 0 {
+		// 不足1毫秒的TTL按1毫秒处理，避免PX 0报错
+		ms := ttl.Milliseconds()
+		if ms <= 0 {
+			ms = 1
+		}
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err = c.pool.do(args...)
+	return err
+}
+
+// Get 获取缓存
+func (c *RedisCache) Get(key string) ([]byte, bool, error) {
+	data, _, hit, err := c.GetWithTimestamp(key)
+	return data, hit, err
+}
+
+// GetWithTimestamp 获取缓存及其最后修改时间
+func (c *RedisCache) GetWithTimestamp(key string) ([]byte, time.Time, bool, error) {
+	reply, err := c.pool.do("GET", c.prefix+key)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	if reply == nil {
+		return nil, time.Time{}, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, time.Time{}, false, fmt.Errorf("redis: GET返回了非预期的类型 %T", reply)
+	}
+	data, lastModified, err := decodeRedisValue(value)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return data, lastModified, true, nil
+}
+
+// GetLastModified 获取缓存项的最后修改时间
+func (c *RedisCache) GetLastModified(key string) (time.Time, bool) {
+	_, lastModified, hit, err := c.GetWithTimestamp(key)
+	if err != nil || !hit {
+		return time.Time{}, false
+	}
+	return lastModified, true
+}
+
+// Delete 删除缓存
+func (c *RedisCache) Delete(key string) error {
+	_, err := c.pool.do("DEL", c.prefix+key)
+	return err
+}
+
+// Clear 删除当前前缀下的所有缓存，不影响同一Redis中的其他数据
+func (c *RedisCache) Clear() error {
+	cursor := "0"
+	for {
+		reply, err := c.pool.do("SCAN", cursor, "MATCH", c.prefix+"*", "COUNT", strconv.Itoa(redisScanCount))
+		if err != nil {
+			return err
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 2 {
+			return errors.New("redis: SCAN返回格式错误")
+		}
+		next, _ := items[0].([]byte)
+		keys, _ := items[1].([]interface{})
+
+		if len(keys) > 0 {
+			args := make([]string, 0, len(keys)+1)
+			args = append(args, "DEL")
+			for _, k := range keys {
+				if b, ok := k.([]byte); ok {
+					args = append(args, string(b))
+				}
+			}
+			if _, err := c.pool.do(args...); err != nil {
+				return err
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// alreadyCompressed 判断数据是否为已经压缩过的信封，这类数据不再重复压缩
+func alreadyCompressed(data []byte) bool {
+	h, _, ok, err := parseEnvelope(data)
+	return ok && err == nil && h.compression != CompressionNone
+}
+
+// encodeRedisValue 编码缓存值，较大的数据使用gzip压缩，已按CACHE_COMPRESSION压缩的信封原样保存
+func encodeRedisValue(data []byte, lastModified time.Time) ([]byte, error) {
+	var flags byte
+	payload := data
+	if len(data) >= redisCompressThreshold && !alreadyCompressed(data) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		// 压缩无收益时保存原始数据
+		if buf.Len() < len(data) {
+			payload = buf.Bytes()
+			flags |= redisFlagGzip
+		}
+	}
+
+	value := make([]byte, redisValueHeaderSize+len(payload))
+	value[0] = redisValueVersion
+	value[1] = flags
+	binary.BigEndian.PutUint64(value[2:redisValueHeaderSize], uint64(lastModified.UnixNano()))
+	copy(value[redisValueHeaderSize:], payload)
+	return value, nil
+}
+
+// decodeRedisValue 解码缓存值
+func decodeRedisValue(value []byte) ([]byte, time.Time, error) {
+	if len(value) < redisValueHeaderSize || value[0] != redisValueVersion {
+		return nil, time.Time{}, fmt.Errorf("%w: 无法识别的格式", errCorruptRedisValue)
+	}
+	flags := value[1]
+	lastModified := time.Unix(0, int64(binary.BigEndian.Uint64(value[2:redisValueHeaderSize])))
+	payload := value[redisValueHeaderSize:]
+
+	if flags&redisFlagGzip != 0 {
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("%w: %v", errCorruptRedisValue, err)
+		}
+		defer zr.Close()
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("%w: %v", errCorruptRedisValue, err)
+		}
+		return data, lastModified, nil
+	}
+	return payload, lastModified, nil
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"pansou/model"
+	"pansou/util/cache/resptest"
+)
+
+// newTestRedis 启动进程内RESP服务并创建连接它的RedisCache
+func newTestRedis(t *testing.T, password string) (*resptest.Server, *RedisCache) {
+	t.Helper()
+	server, err := resptest.NewServerWithPassword(password)
+	if err != nil {
+		t.Fatalf("启动RESP服务失败: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	redisCache, err := NewRedisCache(server.URL(), 2, "test:")
+	if err != nil {
+		t.Fatalf("创建RedisCache失败: %v", err)
+	}
+	t.Cleanup(redisCache.Close)
+	return server, redisCache
+}
+
+func TestRedisCacheSetGet(t *testing.T) {
+	server, redisCache := newTestRedis(t, "secret")
+
+	small := []byte("hello")
+	large := bytes.Repeat([]byte("pansou-search-result;"), 500)
+	before := time.Now()
+
+	if err := redisCache.Set("small", small, time.Minute); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	if err := redisCache.Set("large", large, time.Minute); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+
+	for key, want := range map[string][]byte{"small": small, "large": large} {
+		data, lastModified, hit, err := redisCache.GetWithTimestamp(key)
+		if err != nil || !hit {
+			t.Fatalf("Get(%s) hit=%v err=%v", key, hit, err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("Get(%s) 数据不一致", key)
+		}
+		if lastModified.Before(before) {
+			t.Errorf("Get(%s) 最后修改时间 %v 早于写入时间", key, lastModified)
+		}
+	}
+
+	// 大数据应以压缩形式存储
+	stored, ok := server.Get("test:large")
+	if !ok {
+		t.Fatal("服务端缺少键 test:large")
+	}
+	if len(stored) >= len(large) {
+		t.Errorf("大数据未压缩: 存储 %d 字节，原始 %d 字节", len(stored), len(large))
+	}
+
+	if _, hit, err := redisCache.Get("missing"); hit || err != nil {
+		t.Errorf("Get(missing) hit=%v err=%v", hit, err)
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	_, redisCache := newTestRedis(t, "")
+
+	if err := redisCache.Set("short", []byte("x"), 50*time.Millisecond); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	if _, hit, _ := redisCache.Get("short"); !hit {
+		t.Fatal("过期前应命中")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if _, hit, _ := redisCache.Get("short"); hit {
+		t.Error("过期后不应命中")
+	}
+}
+
+func TestRedisCacheClearKeepsOtherPrefixes(t *testing.T) {
+	server, redisCache := newTestRedis(t, "")
+
+	other, err := NewRedisCache(server.URL(), 1, "other:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	for i := 0; i < 1200; i++ {
+		if err := redisCache.Set(fmt.Sprintf("key%d", i), []byte("v"), time.Minute); err != nil {
+			t.Fatalf("Set失败: %v", err)
+		}
+	}
+	if err := other.Set("keep", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := redisCache.Clear(); err != nil {
+		t.Fatalf("Clear失败: %v", err)
+	}
+	if n := server.Len(); n != 1 {
+		t.Errorf("Clear后剩余 %d 个键，应只剩其他前缀的1个", n)
+	}
+	if _, hit, _ := other.Get("keep"); !hit {
+		t.Error("其他前缀的键被误删")
+	}
+}
+
+func TestFallbackBackendUsesDiskWhenRedisDown(t *testing.T) {
+	server, redisCache := newTestRedis(t, "")
+	disk, err := NewShardedDiskCache(t.TempDir(), 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := NewFallbackBackend("Redis", redisCache, disk)
+
+	if err := backend.Set("shared", []byte("from-redis"), time.Minute); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	if _, hit, _ := disk.Get("shared"); hit {
+		t.Error("Redis可用时不应写入本地磁盘")
+	}
+
+	server.Close()
+
+	// Redis不可用：读取失败回退到本地，写入落到本地
+	if _, hit, err := backend.Get("shared"); hit || err != nil {
+		t.Errorf("Redis不可用时 Get hit=%v err=%v", hit, err)
+	}
+	if err := backend.Set("local", []byte("from-disk"), time.Minute); err != nil {
+		t.Fatalf("回退写入失败: %v", err)
+	}
+	data, hit, err := backend.Get("local")
+	if err != nil || !hit || string(data) != "from-disk" {
+		t.Errorf("回退读取 data=%q hit=%v err=%v", data, hit, err)
+	}
+}
+
+func TestFallbackBackendKeepsRedisOnCorruptValue(t *testing.T) {
+	server, redisCache := newTestRedis(t, "")
+	disk, err := NewShardedDiskCache(t.TempDir(), 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := NewFallbackBackend("Redis", redisCache, disk)
+
+	// 直接写入无法解码的值
+	if _, err := redisCache.pool.do("SET", "test:broken", "garbage"); err != nil {
+		t.Fatal(err)
+	}
+	if _, hit, err := backend.Get("broken"); hit || err != nil {
+		t.Errorf("无法解码的值应按未命中处理 hit=%v err=%v", hit, err)
+	}
+
+	// 单个键损坏不应切换到本地磁盘
+	if err := backend.Set("next", []byte("from-redis"), time.Minute); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	if _, hit, _ := disk.Get("next"); hit {
+		t.Error("单个值损坏后写入不应落到本地磁盘")
+	}
+	if _, ok := server.Get("test:next"); !ok {
+		t.Error("单个值损坏后写入应继续使用Redis")
+	}
+}
+
+func TestRedisCacheSkipsGzipForCompressedEnvelope(t *testing.T) {
+	server, redisCache := newTestRedis(t, "")
+
+	s := NewVersionedSerializer()
+	if err := s.SetCompression(CompressionGzip); err != nil {
+		t.Fatal(err)
+	}
+	results := testResults()
+	for i := 0; i < 200; i++ {
+		results = append(results, model.SearchResult{UniqueID: fmt.Sprintf("tg-%d", i), Title: fmt.Sprintf("凡人修仙传 第%d集", i)})
+	}
+	data, err := s.Serialize(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < redisCompressThreshold {
+		t.Fatalf("测试数据 %d 字节，未达到压缩阈值", len(data))
+	}
+
+	if err := redisCache.Set("packed", data, time.Minute); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	stored, _ := server.Get("test:packed")
+	if len(stored) != redisValueHeaderSize+len(data) || stored[1]&redisFlagGzip != 0 {
+		t.Errorf("已压缩的信封不应再次gzip: 存储 %d 字节，标志 %d", len(stored), stored[1])
+	}
+	got, hit, err := redisCache.Get("packed")
+	if err != nil || !hit || !bytes.Equal(got, data) {
+		t.Errorf("Get hit=%v err=%v", hit, err)
+	}
+}
@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// respError Redis返回的错误回复
+type respError string
+
+// Error 实现error接口
+func (e respError) Error() string {
+	return "redis: " + string(e)
+}
+
+// respOptions RESP连接参数
+type respOptions struct {
+	addr     string
+	username string
+	password string
+	db       int
+	timeout  time.Duration
+	poolSize int
+}
+
+// parseRedisURL 解析 redis://[user:password@]host:port[/db] 格式的地址
+func parseRedisURL(rawURL string) (respOptions, error) {
+	opts := respOptions{timeout: 2 * time.Second, poolSize: 10}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return opts, fmt.Errorf("Redis地址无效: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return opts, fmt.Errorf("Redis地址必须以redis://开头: %s", rawURL)
+	}
+	opts.addr = u.Host
+	if u.Port() == "" {
+		opts.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		opts.username = u.User.Username()
+		opts.password, _ = u.User.Password()
+		// redis://:password@host 形式只有密码
+		if _, hasPassword := u.User.Password(); !hasPassword {
+			opts.password, opts.username = opts.username, ""
+		}
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if opts.db, err = strconv.Atoi(path); err != nil {
+			return opts, fmt.Errorf("Redis数据库编号无效: %s", path)
+		}
+	}
+	return opts, nil
+}
+
+// respConn 单个RESP连接
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// respPool RESP连接池，同时使用的连接数不超过poolSize
+type respPool struct {
+	opts respOptions
+	idle chan *respConn
+	sem  chan struct{}
+}
+
+// newRespPool 创建连接池（不立即建立连接）
+func newRespPool(opts respOptions) *respPool {
+	if opts.poolSize <= 0 {
+		opts.poolSize = 10
+	}
+	return &respPool{
+		opts: opts,
+		idle: make(chan *respConn, opts.poolSize),
+		sem:  make(chan struct{}, opts.poolSize),
+	}
+}
+
+// get 获取连接，优先复用空闲连接
+func (p *respPool) get() (*respConn, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-time.After(p.opts.timeout):
+		return nil, errors.New("redis: 等待连接池超时")
+	}
+
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+
+	c, err := p.dial()
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return c, nil
+}
+
+// put 归还连接，出现网络或协议错误的连接直接关闭
+func (p *respPool) put(c *respConn, broken bool) {
+	defer func() { <-p.sem }()
+	if broken {
+		c.conn.Close()
+		return
+	}
+	select {
+	case p.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+// dial 建立连接并完成认证和选库
+// 认证和选库失败属于连接错误，不以respError返回
+func (p *respPool) dial() (*respConn, error) {
+	conn, err := net.DialTimeout("tcp", p.opts.addr, p.opts.timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	if p.opts.password != "" {
+		args := []string{"AUTH", p.opts.password}
+		if p.opts.username != "" {
+			args = []string{"AUTH", p.opts.username, p.opts.password}
+		}
+		if _, err := c.do(p.opts.timeout, args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis: 认证失败: %v", err)
+		}
+	}
+	if p.opts.db != 0 {
+		if _, err := c.do(p.opts.timeout, "SELECT", strconv.Itoa(p.opts.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis: 选择数据库失败: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// do 从连接池取连接执行一条命令
+func (p *respPool) do(args ...string) (interface{}, error) {
+	c, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.do(p.opts.timeout, args...)
+	// 服务端返回的错误回复不影响连接继续使用
+	var replyErr respError
+	p.put(c, err != nil && !errors.As(err, &replyErr))
+	return reply, err
+}
+
+// close 关闭所有空闲连接
+func (p *respPool) close() {
+	for {
+		select {
+		case c := <-p.idle:
+			c.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// do 发送命令并读取回复
+func (c *respConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if err := writeRespCommand(c.w, args); err != nil {
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readRespReply(c.r)
+}
+
+// writeRespCommand 以RESP数组格式写入命令
+func writeRespCommand(w *bufio.Writer, args []string) error {
+	w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		w.WriteString(arg)
+		if _, err := w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRespReply 读取一个RESP回复
+// 简单字符串返回string，整数返回int64，批量字符串返回[]byte（不存在时为nil），数组返回[]interface{}
+func readRespReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readRespLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: 空回复")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, respError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRespReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: 无法识别的回复: %q", line)
+}
+
+// readRespLine 读取一行并去掉结尾的\r\n
+func readRespLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
@@ -0,0 +1,396 @@
+// Package resptest 提供进程内的RESP（Redis协议）服务，用于在没有Redis的环境中测试缓存
+//
+// 支持PING、AUTH、SELECT、GET、SET（EX/PX）、DEL、EXISTS、PTTL、SCAN、DBSIZE和FLUSHDB，
+// 数据只保存在内存中。
+package resptest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server 进程内RESP服务
+type Server struct {
+	listener net.Listener
+	password string
+
+	mu         sync.Mutex
+	data       map[string]entry
+	cursors    map[int]string // SCAN游标对应的上一批最后一个键
+	nextCursor int
+	conns      map[net.Conn]struct{}
+	wg         sync.WaitGroup
+}
+
+// entry 一个键值
+type entry struct {
+	value  string
+	expiry time.Time // 零值表示不过期
+}
+
+// NewServer 在随机端口启动服务
+func NewServer() (*Server, error) {
+	return NewServerWithPassword("")
+}
+
+// NewServerWithPassword 启动需要AUTH认证的服务
+func NewServerWithPassword(password string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: listener,
+		password: password,
+		data:     make(map[string]entry),
+		cursors:  make(map[int]string),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr 返回监听地址
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// URL 返回redis://形式的连接地址
+func (s *Server) URL() string {
+	if s.password != "" {
+		return "redis://:" + s.password + "@" + s.Addr()
+	}
+	return "redis://" + s.Addr()
+}
+
+// Len 返回未过期的键数量
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.liveKeys())
+}
+
+// Get 直接读取键值，便于测试检查存储内容
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.lookup(key)
+	return e.value, ok
+}
+
+// Close 停止服务并断开所有连接，用于模拟Redis不可用
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// serve 接受连接
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+// handle 处理一个连接上的命令
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	authed := s.password == ""
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if err != io.EOF {
+				writeError(w, "ERR "+err.Error())
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			if args[len(args)-1] == s.password {
+				authed = true
+				writeSimple(w, "OK")
+			} else {
+				writeError(w, "WRONGPASS invalid password")
+			}
+		case !authed:
+			writeError(w, "NOAUTH Authentication required.")
+		default:
+			s.execute(w, cmd, args[1:])
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// execute 执行已认证的命令
+func (s *Server) execute(w *bufio.Writer, cmd string, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch cmd {
+	case "PING":
+		writeSimple(w, "PONG")
+	case "SELECT":
+		writeSimple(w, "OK")
+	case "GET":
+		if len(args) != 1 {
+			writeArgError(w, cmd)
+			return
+		}
+		if e, ok := s.lookup(args[0]); ok {
+			writeBulk(w, e.value)
+		} else {
+			w.WriteString("$-1\r\n")
+		}
+	case "SET":
+		s.set(w, args)
+	case "DEL", "EXISTS":
+		count := 0
+		for _, key := range args {
+			if _, ok := s.lookup(key); ok {
+				count++
+				if cmd == "DEL" {
+					delete(s.data, key)
+				}
+			}
+		}
+		writeInt(w, int64(count))
+	case "PTTL":
+		if len(args) != 1 {
+			writeArgError(w, cmd)
+			return
+		}
+		e, ok := s.lookup(args[0])
+		switch {
+		case !ok:
+			writeInt(w, -2)
+		case e.expiry.IsZero():
+			writeInt(w, -1)
+		default:
+			writeInt(w, time.Until(e.expiry).Milliseconds())
+		}
+	case "SCAN":
+		s.scan(w, args)
+	case "DBSIZE":
+		writeInt(w, int64(len(s.liveKeys())))
+	case "FLUSHDB", "FLUSHALL":
+		s.data = make(map[string]entry)
+		writeSimple(w, "OK")
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", cmd))
+	}
+}
+
+// set 处理 SET key value [EX seconds|PX milliseconds]
+func (s *Server) set(w *bufio.Writer, args []string) {
+	if len(args) < 2 {
+		writeArgError(w, "SET")
+		return
+	}
+	e := entry{value: args[1]}
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		if (option != "EX" && option != "PX") || i+1 >= len(args) {
+			writeError(w, "ERR syntax error")
+			return
+		}
+		n, err := strconv.ParseInt(args[i+1], 10, 64)
+		if err != nil || n <= 0 {
+			writeError(w, "ERR invalid expire time in 'set' command")
+			return
+		}
+		unit := time.Millisecond
+		if option == "EX" {
+			unit = time.Second
+		}
+		e.expiry = time.Now().Add(time.Duration(n) * unit)
+		i++
+	}
+	s.data[args[0]] = e
+	writeSimple(w, "OK")
+}
+
+// scan 处理 SCAN cursor [MATCH pattern] [COUNT count]
+// 游标记录上一批最后一个键，遍历期间删除键不会导致遗漏
+func (s *Server) scan(w *bufio.Writer, args []string) {
+	if len(args) < 1 {
+		writeArgError(w, "SCAN")
+		return
+	}
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil {
+		writeError(w, "ERR invalid cursor")
+		return
+	}
+	pattern, count := "*", 10
+	for i := 1; i+1 < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, _ = strconv.Atoi(args[i+1])
+		}
+	}
+
+	keys := s.liveKeys()
+	start := 0
+	if cursor != 0 {
+		last := s.cursors[cursor]
+		delete(s.cursors, cursor)
+		start = sort.Search(len(keys), func(i int) bool { return keys[i] > last })
+	}
+	end := start + count
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	matched := make([]string, 0)
+	for _, key := range keys[start:end] {
+		if ok, _ := path.Match(pattern, key); ok {
+			matched = append(matched, key)
+		}
+	}
+	next := 0
+	if end < len(keys) {
+		s.nextCursor++
+		next = s.nextCursor
+		s.cursors[next] = keys[end-1]
+	}
+
+	w.WriteString("*2\r\n")
+	writeBulk(w, strconv.Itoa(next))
+	w.WriteString("*" + strconv.Itoa(len(matched)) + "\r\n")
+	for _, key := range matched {
+		writeBulk(w, key)
+	}
+}
+
+// lookup 读取键，已过期的键被删除（调用方需持有s.mu）
+func (s *Server) lookup(key string) (entry, bool) {
+	e, ok := s.data[key]
+	if !ok {
+		return entry{}, false
+	}
+	if !e.expiry.IsZero() && time.Now().After(e.expiry) {
+		delete(s.data, key)
+		return entry{}, false
+	}
+	return e, true
+}
+
+// liveKeys 返回排序后的未过期键（调用方需持有s.mu）
+func (s *Server) liveKeys() []string {
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		if _, ok := s.lookup(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readCommand 读取一条RESP数组格式的命令
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		// 兼容redis-cli等工具发送的内联命令
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, fmt.Errorf("Protocol error: invalid multibulk length")
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("Protocol error: expected '$', got '%s'", header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("Protocol error: invalid bulk length")
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// readLine 读取一行并去掉结尾的\r\n
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	w.WriteString("+" + s + "\r\n")
+}
+
+func writeError(w *bufio.Writer, s string) {
+	w.WriteString("-" + s + "\r\n")
+}
+
+func writeArgError(w *bufio.Writer, cmd string) {
+	writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+func writeInt(w *bufio.Writer, n int64) {
+	w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
@@ -99,6 +99,12 @@ func (c *ShardedDiskCache) Set(key string, data []byte, ttl time.Duration) error
 	return shard.Set(key, data, ttl)
 }
 
+// SetWithFinal 设置缓存，并标记数据是否为完整结果
+func (c *ShardedDiskCache) SetWithFinal(key string, data []byte, ttl time.Duration, final bool) error {
+	shard := c.getShard(key)
+	return shard.SetWithFinal(key, data, ttl, final)
+}
+
 // Get 获取缓存
 func (c *ShardedDiskCache) Get(key string) ([]byte, bool, error) {
 	shard := c.getShard(key)
@@ -132,12 +138,47 @@ func (c *ShardedDiskCache) Clear() error {
 	return lastErr
 }
 
+// Close 关闭所有分片，封存活动分段以便下次快速启动
+func (c *ShardedDiskCache) Close() error {
+	var lastErr error
+	for _, shard := range c.shards {
+		if err := shard.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
 // GetLastModified 获取缓存项的最后修改时间
 func (c *ShardedDiskCache) GetLastModified(key string) (time.Time, bool) {
 	shard := c.getShard(key)
 	return shard.GetLastModified(key)
 }
 
+// GetExpiry 获取缓存项的过期时间
+func (c *ShardedDiskCache) GetExpiry(key string) (time.Time, bool) {
+	return c.getShard(key).GetExpiry(key)
+}
+
+// entries 返回所有分片中未过期缓存项的元数据
+func (c *ShardedDiskCache) entries() []diskCacheMetadata {
+	var result []diskCacheMetadata
+	for _, shard := range c.shards {
+		result = append(result, shard.entries()...)
+	}
+	return result
+}
+
+// readEntry 读取缓存数据和元数据，不更新最后使用时间
+func (c *ShardedDiskCache) readEntry(key string) ([]byte, diskCacheMetadata, bool) {
+	return c.getShard(key).readEntry(key)
+}
+
+// restore 按给定元数据写入缓存项，用于导入快照
+func (c *ShardedDiskCache) restore(meta diskCacheMetadata, data []byte) error {
+	return c.getShard(meta.Key).restore(meta, data)
+}
+
 // cleanExpired 清理所有分片中的过期项
 func (c *ShardedDiskCache) cleanExpired() {
 	// 并行清理所有分片中的过期项
@@ -28,6 +28,8 @@ type shardedMemoryCacheItem struct {
 	lastUsed     int64 // 使用原子操作的时间戳
 	lastModified time.Time
 	size         int
+	final        bool     // 是否为已写入二级缓存的完整结果
+	info         *KeyInfo // 缓存键对应的搜索条件，可能为nil
 }
 
 // 单个分片
@@ -45,8 +47,8 @@ type ShardedMemoryCache struct {
 	maxSize        int64
 	itemsPerShard  int
 	sizePerShard   int64
-	diskCache      *ShardedDiskCache // 磁盘缓存引用
-	diskCacheMutex sync.RWMutex      // 磁盘缓存引用的保护锁
+	diskCache      CacheBackend // 二级缓存引用
+	diskCacheMutex sync.RWMutex // 磁盘缓存引用的保护锁
 }
 
 // 创建新的分片内存缓存
@@ -113,13 +115,24 @@ func (c *ShardedMemoryCache) Set(key string, data []byte, ttl time.Duration) {
 
 // SetWithTimestamp 设置缓存，并指定最后修改时间
 func (c *ShardedMemoryCache) SetWithTimestamp(key string, data []byte, ttl time.Duration, lastModified time.Time) {
+	c.setItem(key, data, ttl, lastModified, true)
+}
+
+// setItem 设置缓存，final标记数据是否为已写入二级缓存的完整结果
+func (c *ShardedMemoryCache) setItem(key string, data []byte, ttl time.Duration, lastModified time.Time, final bool) {
 	shard := c.getShard(key)
 	shard.mutex.Lock()
 	defer shard.mutex.Unlock()
 
+	// 优先使用登记表中的键信息，登记已过期时沿用旧项的信息
+	info := keyInfos.lookup(key)
+
 	// 如果已存在，先减去旧项的大小
 	if item, exists := shard.items[key]; exists {
 		atomic.AddInt64(&shard.currSize, -int64(item.size))
+		if info == nil {
+			info = item.info
+		}
 	}
 
 	// 创建新的缓存项
@@ -130,6 +143,8 @@ func (c *ShardedMemoryCache) SetWithTimestamp(key string, data []byte, ttl time.
 		lastUsed:     now.UnixNano(),
 		lastModified: lastModified,
 		size:         len(data),
+		final:        final,
+		info:         info,
 	}
 
 	// 检查是否需要清理空间
@@ -213,6 +228,19 @@ func (c *ShardedMemoryCache) GetLastModified(key string) (time.Time, bool) {
 	return item.lastModified, true
 }
 
+// GetExpiry 获取缓存项的过期时间
+func (c *ShardedMemoryCache) GetExpiry(key string) (time.Time, bool) {
+	shard := c.getShard(key)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	item, exists := shard.items[key]
+	if !exists || time.Now().After(item.expiry) {
+		return time.Time{}, false
+	}
+	return item.expiry, true
+}
+
 // 从指定分片中驱逐最久未使用的项（带磁盘备份）
 func (c *ShardedMemoryCache) evictFromShard(shard *memoryCacheShard) {
 	var oldestKey string
@@ -234,12 +262,12 @@ func (c *ShardedMemoryCache) evictFromShard(shard *memoryCacheShard) {
 		diskCache := c.getDiskCacheReference()
 		if time.Now().Before(oldestItem.expiry) && diskCache != nil {
 			// 数据还没过期，异步刷新到磁盘保存
-			go func(key string, data []byte, expiry time.Time) {
+			go func(key string, data []byte, expiry time.Time, final bool) {
 				ttl := time.Until(expiry)
 				if ttl > 0 {
-					diskCache.Set(key, data, ttl) // 保持相同TTL
+					setWithFinal(diskCache, key, data, ttl, final) // 保持相同TTL
 				}
-			}(oldestKey, oldestItem.data, oldestItem.expiry)
+			}(oldestKey, oldestItem.data, oldestItem.expiry, oldestItem.final)
 		}
 
 		// 从内存中删除
@@ -336,14 +364,14 @@ func (c *ShardedMemoryCache) StartCleanupTask() {
 }
 
 // SetDiskCacheReference 设置磁盘缓存引用
-func (c *ShardedMemoryCache) SetDiskCacheReference(diskCache *ShardedDiskCache) {
+func (c *ShardedMemoryCache) SetDiskCacheReference(diskCache CacheBackend) {
 	c.diskCacheMutex.Lock()
 	defer c.diskCacheMutex.Unlock()
 	c.diskCache = diskCache
 }
 
 // getDiskCacheReference 获取磁盘缓存引用
-func (c *ShardedMemoryCache) getDiskCacheReference() *ShardedDiskCache {
+func (c *ShardedMemoryCache) getDiskCacheReference() CacheBackend {
 	c.diskCacheMutex.RLock()
 	defer c.diskCacheMutex.RUnlock()
 	return c.diskCache
@@ -351,8 +379,11 @@ func (c *ShardedMemoryCache) getDiskCacheReference() *ShardedDiskCache {
 
 // MemoryCacheItem 内存缓存项结构（用于导出）
 type MemoryCacheItem struct {
-	Data []byte
-	TTL  time.Duration
+	Data         []byte
+	TTL          time.Duration
+	LastModified time.Time
+	Final        bool
+	Info         *KeyInfo
 }
 
 // GetAllItems 获取内存缓存中的所有项
@@ -379,8 +410,11 @@ func (c *ShardedMemoryCache) GetAllItems() map[string]*MemoryCacheItem {
 			}
 
 			result[key] = &MemoryCacheItem{
-				Data: item.data,
-				TTL:  ttl,
+				Data:         item.data,
+				TTL:          ttl,
+				LastModified: item.lastModified,
+				Final:        item.final,
+				Info:         item.info,
 			}
 		}
 		shard.mutex.RUnlock()
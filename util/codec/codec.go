@@ -0,0 +1,211 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩编解码器：缓存数据和HTTP响应共用，名称与HTTP Content-Encoding一致
+
+// 编解码器名称
+const (
+	Gzip   = "gzip"
+	Zstd   = "zstd"
+	Brotli = "br"
+)
+
+// DefaultDictSize 训练zstd字典的默认大小
+const DefaultDictSize = 112640
+
+// Codec 压缩编解码器
+type Codec interface {
+	// Name 返回编解码器名称，即HTTP Content-Encoding的取值
+	Name() string
+	// Compress 压缩数据
+	Compress(data []byte) ([]byte, error)
+	// Decompress 解压数据
+	Decompress(data []byte) ([]byte, error)
+}
+
+var builtin = map[string]Codec{
+	Gzip:   gzipCodec{},
+	Brotli: brotliCodec{},
+}
+
+func init() {
+	z, err := NewZstd(nil)
+	if err != nil {
+		panic(err)
+	}
+	builtin[Zstd] = z
+}
+
+// Get 按名称获取内置编解码器，zstd不使用字典
+func Get(name string) (Codec, bool) {
+	c, ok := builtin[strings.ToLower(strings.TrimSpace(name))]
+	return c, ok
+}
+
+// gzipCodec gzip编解码器，使用最快压缩级别
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return Gzip }
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// brotliCodec brotli编解码器，压缩较慢，只用于HTTP响应
+type brotliCodec struct{}
+
+func (brotliCodec) Name() string { return Brotli }
+
+func (brotliCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := brotli.NewWriterLevel(&buf, 5)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (brotliCodec) Decompress(data []byte) ([]byte, error) {
+	return io.ReadAll(brotli.NewReader(bytes.NewReader(data)))
+}
+
+// zstdCodec zstd编解码器，编码器和解码器可并发使用
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// NewZstd 创建zstd编解码器，dictionary为训练得到的字典，为空表示不使用字典
+// 使用字典时仍能解压不带字典压缩的数据，但用其他字典压缩的数据无法解压
+func NewZstd(dictionary []byte) (Codec, error) {
+	encOpts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+	decOpts := []zstd.DOption{zstd.WithDecoderConcurrency(0)}
+	if len(dictionary) > 0 {
+		if _, err := zstd.InspectDictionary(dictionary); err != nil {
+			return nil, fmt.Errorf("zstd字典格式错误: %v", err)
+		}
+		encOpts = append(encOpts, zstd.WithEncoderDict(dictionary))
+		decOpts = append(decOpts, zstd.WithDecoderDicts(dictionary))
+	}
+
+	encoder, err := zstd.NewWriter(nil, encOpts...)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil, decOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &zstdCodec{encoder: encoder, decoder: decoder}, nil
+}
+
+func (z *zstdCodec) Name() string { return Zstd }
+
+func (z *zstdCodec) Compress(data []byte) ([]byte, error) {
+	return z.encoder.EncodeAll(data, make([]byte, 0, len(data)/4)), nil
+}
+
+func (z *zstdCodec) Decompress(data []byte) ([]byte, error) {
+	return z.decoder.DecodeAll(data, nil)
+}
+
+// TrainZstdDict 用样本训练zstd字典，样本应为未压缩的缓存数据
+func TrainZstdDict(samples [][]byte, size int) ([]byte, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("没有可用于训练的样本")
+	}
+	if size <= 0 {
+		size = DefaultDictSize
+	}
+	return dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize:    size,
+		HashBytes:      6,
+		ZstdDictCompat: true,
+	})
+}
+
+// Negotiate 按Accept-Encoding请求头从supported中选择编解码器名称，supported按服务端偏好排列
+// 选择客户端接受（q>0）的编码中q值最高的一个，q值相同时按服务端偏好；都不接受时返回空字符串
+func Negotiate(acceptEncoding string, supported []string) string {
+	accepted := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			accepted[name] = q
+		}
+	}
+
+	type candidate struct {
+		name string
+		q    float64
+	}
+	var candidates []candidate
+	for _, name := range supported {
+		q, ok := accepted[name]
+		if !ok {
+			q = wildcard
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{name, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].name
+}
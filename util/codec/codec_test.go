@@ -0,0 +1,92 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{Zstd, Brotli, Gzip}
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"gzip, deflate, br, zstd", Zstd},
+		{"gzip, deflate, br", Brotli},
+		{"gzip", Gzip},
+		{"br;q=0.5, gzip;q=0.8", Gzip},
+		{"zstd;q=0, gzip", Gzip},
+		{"*", Zstd},
+		{"*;q=0.1, gzip", Gzip},
+		{"identity", ""},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if got := Negotiate(c.accept, supported); got != c.want {
+			t.Errorf("Negotiate(%q) = %q，应为 %q", c.accept, got, c.want)
+		}
+	}
+
+	if got := Negotiate("zstd, gzip", []string{Gzip, Zstd}); got != Gzip {
+		t.Errorf("q值相同时应按服务端偏好，得到 %q", got)
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"title":"凡人修仙传","links":[{"type":"quark","url":"https://pan.quark.cn/s/abc"}]}`), 50)
+	for _, name := range []string{Gzip, Zstd, Brotli} {
+		c, ok := Get(name)
+		if !ok {
+			t.Fatalf("缺少编解码器 %s", name)
+		}
+		compressed, err := c.Compress(data)
+		if err != nil {
+			t.Fatalf("%s 压缩失败: %v", name, err)
+		}
+		if len(compressed) >= len(data) {
+			t.Errorf("%s 压缩后 %d 字节，未变小", name, len(compressed))
+		}
+		out, err := c.Decompress(compressed)
+		if err != nil || !bytes.Equal(out, data) {
+			t.Fatalf("%s 解压结果不一致: %v", name, err)
+		}
+	}
+}
+
+func TestZstdDictionary(t *testing.T) {
+	var samples [][]byte
+	for i := 0; i < 500; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(
+			`[{"unique_id":"tgsearchers4-%d","channel":"tgsearchers4","title":"资源%d 4K HDR 国语中字","links":[{"type":"quark","url":"https://pan.quark.cn/s/%08x","password":""}],"tags":["电影","4K"]}]`,
+			i, i, i*7919)))
+	}
+	dictionary, err := TrainZstdDict(samples, 8192)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withDict, err := NewZstd(dictionary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := Get(Zstd)
+	sample := samples[42]
+	small, _ := withDict.Compress(sample)
+	large, _ := plain.Compress(sample)
+	if len(small) >= len(large) {
+		t.Errorf("使用字典后 %d 字节，不使用字典 %d 字节", len(small), len(large))
+	}
+	if out, err := withDict.Decompress(small); err != nil || !bytes.Equal(out, sample) {
+		t.Fatalf("使用字典解压失败: %v", err)
+	}
+
+	// 使用字典的解码器也能解压不带字典的数据
+	if out, err := withDict.Decompress(large); err != nil || !bytes.Equal(out, sample) {
+		t.Errorf("解压不带字典的数据失败: %v", err)
+	}
+
+	if _, err := NewZstd([]byte("not a dictionary")); err == nil {
+		t.Error("无效字典应返回错误")
+	}
+}
@@ -2,37 +2,64 @@ package util
 
 import (
 	"bytes"
-	"compress/gzip"
-	"io/ioutil"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"pansou/config"
+	"pansou/util/codec"
 )
 
-// 压缩响应的包装器
-type gzipResponseWriter struct {
+// precompressedContentTypes 本身已经压缩的响应类型，再压缩没有收益
+var precompressedContentTypes = []string{"application/gzip", "application/x-gzip", "application/zstd", "application/zip"}
+
+// bufferedResponseWriter 缓冲响应体，处理完成后再整体压缩
+// 响应类型为SSE时切换为直接写出，保证事件逐条下发；
+// 已压缩的文件下载（如缓存快照）也直接写出，边生成边发送，不在内存中缓冲整个文件
+type bufferedResponseWriter struct {
 	gin.ResponseWriter
-	gzipWriter *gzip.Writer
+	body        *bytes.Buffer
+	passthrough bool
 }
 
-// 实现Write接口
-func (g *gzipResponseWriter) Write(data []byte) (int, error) {
-	return g.gzipWriter.Write(data)
+// streaming 判断响应是否应直接写出而不缓冲压缩
+func (w *bufferedResponseWriter) streaming() bool {
+	contentType := w.Header().Get("Content-Type")
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return true
+	}
+	for _, t := range precompressedContentTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
 }
 
-// 实现WriteString接口
-func (g *gzipResponseWriter) WriteString(s string) (int, error) {
-	return g.gzipWriter.Write([]byte(s))
+// Write 缓冲响应内容，SSE和已压缩的响应直接写出
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	if !w.passthrough && w.streaming() {
+		w.passthrough = true
+		if w.body.Len() > 0 {
+			if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+				return 0, err
+			}
+			w.body.Reset()
+		}
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
 }
 
-// 关闭gzip写入器
-func (g *gzipResponseWriter) Close() {
-	g.gzipWriter.Close()
+// WriteString 缓冲响应内容，SSE和已压缩的响应直接写出
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
 }
 
-// GzipMiddleware 返回一个Gin中间件，用于压缩HTTP响应
-func GzipMiddleware() gin.HandlerFunc {
+// CompressionMiddleware 返回一个Gin中间件，按Accept-Encoding用zstd、br或gzip压缩HTTP响应
+// 可用编码及优先级由COMPRESSION_ENCODINGS配置
+func CompressionMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 如果未启用压缩，直接跳过
 		if !config.AppConfig.EnableCompression {
@@ -40,96 +67,63 @@ func GzipMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 检查客户端是否支持gzip
-		if !strings.Contains(c.Request.Header.Get("Accept-Encoding"), "gzip") {
+		// SSE流式响应需要逐条下发，不能缓冲压缩
+		if strings.Contains(c.Request.Header.Get("Accept"), "text/event-stream") || c.Query("stream") == "true" {
+			c.Next()
+			return
+		}
+
+		// 协商压缩编码，客户端都不支持时不压缩
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := codec.Negotiate(c.Request.Header.Get("Accept-Encoding"), config.AppConfig.CompressionEncodings)
+		compressor, ok := codec.Get(encoding)
+		if !ok {
 			c.Next()
 			return
 		}
 
-		// 创建一个缓冲响应写入器
-		buffer := &bytes.Buffer{}
-		blw := &bodyLogWriter{body: buffer, ResponseWriter: c.Writer}
-		c.Writer = blw
+		// 替换为缓冲响应写入器
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
 
 		// 处理请求
 		c.Next()
 
+		// 请求体中指定stream的流式响应和已压缩的下载已经直接写出
+		if writer.passthrough {
+			return
+		}
+
 		// 获取响应内容
-		responseData := buffer.Bytes()
+		responseData := writer.body.Bytes()
 
-		// 如果响应大小小于最小压缩大小，直接返回原始内容
-		if len(responseData) < config.AppConfig.MinSizeToCompress {
-			c.Writer.Write(responseData)
+		// 如果响应大小小于最小压缩大小或已经被编码，直接返回原始内容
+		if len(responseData) < config.AppConfig.MinSizeToCompress || writer.Header().Get("Content-Encoding") != "" {
+			writer.ResponseWriter.Write(responseData)
 			return
 		}
 
-		// 设置gzip响应头
-		c.Header("Content-Encoding", "gzip")
-		c.Header("Vary", "Accept-Encoding")
-
-		// 创建gzip写入器
-		gz, err := gzip.NewWriterLevel(c.Writer, gzip.BestSpeed)
+		compressed, err := compressor.Compress(responseData)
 		if err != nil {
-			c.Writer.Write(responseData)
+			writer.ResponseWriter.Write(responseData)
 			return
 		}
-		defer gz.Close()
 
-		// 写入压缩内容
-		gz.Write(responseData)
+		// 设置压缩响应头
+		writer.Header().Set("Content-Encoding", compressor.Name())
+		writer.Header().Del("Content-Length")
+		writer.ResponseWriter.Write(compressed)
 	}
 }
 
-// bodyLogWriter 是一个用于记录响应体的写入器
-type bodyLogWriter struct {
-	gin.ResponseWriter
-	body *bytes.Buffer
-}
-
-// Write 实现ResponseWriter接口
-func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
-	return w.ResponseWriter.Write(b)
-}
-
-// WriteString 实现ResponseWriter接口
-func (w bodyLogWriter) WriteString(s string) (int, error) {
-	w.body.WriteString(s)
-	return w.ResponseWriter.WriteString(s)
-}
-
-// CompressData 压缩数据
+// CompressData 使用gzip压缩数据
 func CompressData(data []byte) ([]byte, error) {
-	var buf bytes.Buffer
-
-	// 创建gzip写入器
-	gz, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
-	if err != nil {
-		return nil, err
-	}
-
-	// 写入数据
-	if _, err := gz.Write(data); err != nil {
-		return nil, err
-	}
-
-	// 关闭写入器
-	if err := gz.Close(); err != nil {
-		return nil, err
-	}
-
-	return buf.Bytes(), nil
+	gz, _ := codec.Get(codec.Gzip)
+	return gz.Compress(data)
 }
 
-// DecompressData 解压数据
+// DecompressData 解压gzip数据
 func DecompressData(data []byte) ([]byte, error) {
-	// 创建gzip读取器
-	gz, err := gzip.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-	defer gz.Close()
-
-	// 读取解压后的数据
-	return ioutil.ReadAll(gz)
+	gz, _ := codec.Get(codec.Gzip)
+	return gz.Decompress(data)
 }
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"pansou/config"
+)
+
+func TestCompressionMiddlewareStreamsPrecompressed(t *testing.T) {
+	config.AppConfig = &config.Config{EnableCompression: true, MinSizeToCompress: 1, CompressionEncodings: []string{"gzip"}}
+	gin.SetMode(gin.TestMode)
+
+	rec := httptest.NewRecorder()
+	chunk := strings.Repeat("x", 1024)
+	streamed := false
+	engine := gin.New()
+	engine.Use(CompressionMiddleware())
+	engine.GET("/export", func(c *gin.Context) {
+		c.Header("Content-Type", "application/gzip")
+		c.Status(http.StatusOK)
+		c.Writer.WriteString(chunk)
+		// 第一块应已写出，而不是缓冲到处理结束
+		streamed = rec.Body.Len() == len(chunk)
+		c.Writer.WriteString(chunk)
+	})
+	engine.GET("/json", func(c *gin.Context) {
+		c.String(http.StatusOK, chunk)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	engine.ServeHTTP(rec, req)
+	if !streamed || rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 2*len(chunk) {
+		t.Errorf("已压缩的下载应直接写出: streamed=%v encoding=%q size=%d", streamed, rec.Header().Get("Content-Encoding"), rec.Body.Len())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	engine.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("普通响应应压缩: encoding=%q", rec.Header().Get("Content-Encoding"))
+	}
+}
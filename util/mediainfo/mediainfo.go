@@ -0,0 +1,378 @@
+package mediainfo
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pansou/model"
+	"pansou/util/textnorm"
+)
+
+// 从资源标题中解析分辨率、HDR、编码、音频、季集、完结状态、文件大小和年份。
+// 标题写法五花八门，解析只识别常见写法，未识别的属性留空；同一属性出现多次时取信息量最大的一个
+
+// 分辨率从低到高，用于比较和排序
+var resolutions = []string{"480p", "576p", "720p", "1080p", "1440p", "2160p", "4320p"}
+
+// 分辨率的其他写法
+var resolutionAliases = map[string]string{
+	"8k": "4320p", "4k": "2160p", "uhd": "2160p", "2k": "1440p", "fhd": "1080p",
+	"1080i": "1080p", "4320": "4320p", "2160": "2160p", "1440": "1440p", "1080": "1080p", "720": "720p",
+}
+
+var (
+	resolutionRegex = regexp.MustCompile(`\b(4320|2160|1440|1080|720|576|480)[pi]|\b(8k|4k|2k|uhd|fhd)\b|\b\d{3,4}x(4320|2160|1440|1080|720)\b`)
+
+	hdrRegex = regexp.MustCompile(`\bhdr10(\+|plus)|\bhdr10\b|\bhdr\b|\bhlg\b|\bdv\b|\bdovi\b|dolby ?vision|杜比视界`)
+
+	codecRegex = regexp.MustCompile(`\b(x265|h\.?265|hevc|x264|h\.?264|avc|av1|vp9)\b`)
+
+	// 格式后可以带声道数，如DDP5.1、AAC2.0；DD+为杜比数字+，DD为杜比数字
+	audioRegex = regexp.MustCompile(`\b(dd)\+|\b(atmos|truehd|dts-?hd(?:[ .-]?ma)?|dts-?x|dts|ddp|dd|e-?ac-?3|ac-?3|aac|flac|lpcm|opus)(?:\d\.\d)?\b|(全景声)`)
+
+	// S01E05、S01E01-E10、S01-S03、S01-03
+	seasonEpisodeRegex = regexp.MustCompile(`\bs(\d{1,2})(?:-s?(\d{1,2}))?(?:e(\d{1,4})(?:-e?(\d{1,4}))?)?\b`)
+	// E05、EP05、E01-E10
+	episodeRegex = regexp.MustCompile(`\bep?(\d{1,4})(?:-e?p?(\d{1,4}))?\b`)
+	// 第二季、第1-3季
+	cnSeasonRegex = regexp.MustCompile(`第([0-9零一二三四五六七八九十]{1,3})(?:-([0-9零一二三四五六七八九十]{1,3}))?季`)
+	// 第5集、第1-10集
+	cnEpisodeRegex = regexp.MustCompile(`第(\d{1,4})(?:-(\d{1,4}))?[集话]`)
+	// 更新至12集、更至第12集、更新12集、更新至12
+	updatedRegex = regexp.MustCompile(`更新?至?第?(\d{1,4})[集话]|更新?至第?(\d{1,4})`)
+	// 全40集、共40集、40集全
+	totalRegex = regexp.MustCompile(`[全共](\d{1,4})[集话]|(\d{1,4})[集话]全`)
+
+	completeRegex = regexp.MustCompile(`完结|全集|\bcomplete\b`)
+	ongoingRegex  = regexp.MustCompile(`更新中|连载中|更新至|\bongoing\b`)
+
+	sizeRegex = regexp.MustCompile(`(\d+(?:\.\d+)?) ?(tb|gb|gib|mb|mib|t|g)\b`)
+
+	yearRegex = regexp.MustCompile(`\b(19[2-9]\d|20\d{2})\b`)
+)
+
+// 文件大小单位
+var sizeUnits = map[string]float64{
+	"t": 1 << 40, "tb": 1 << 40,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"mb": 1 << 20, "mib": 1 << 20,
+}
+
+// Parse 解析标题中的媒体属性，没有识别到任何属性时返回nil
+func Parse(title string) *model.MediaInfo {
+	s := normalize(title)
+	if s == "" {
+		return nil
+	}
+
+	info := &model.MediaInfo{
+		Resolution: parseResolution(s),
+		HDR:        parseHDR(s),
+		Codec:      parseCodec(s),
+		Audio:      parseAudio(s),
+		Size:       parseSize(s),
+		Year:       parseYear(s),
+	}
+	parseEpisodes(s, info)
+
+	if info.Resolution == "" && len(info.HDR) == 0 && info.Codec == "" && len(info.Audio) == 0 &&
+		info.Season == 0 && info.Episode == 0 && info.Episodes == 0 && info.Status == "" &&
+		info.Size == 0 && info.Year == 0 {
+		return nil
+	}
+	return info
+}
+
+// normalize 转为简体、半角和小写，保留标点以便识别H.265、DTS-HD、24.5GB等写法
+func normalize(title string) string {
+	var b strings.Builder
+	b.Grow(len(title))
+	for _, r := range textnorm.ToSimplified(title) {
+		if r >= '！' && r <= '～' {
+			r -= 0xFEE0
+		}
+		// 范围统一写作连字符，如E01~E10、第1—3季；尺寸写作3840x2160
+		switch r {
+		case '~', '—', '–':
+			r = '-'
+		case '×':
+			r = 'x'
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToLower(b.String())
+}
+
+// ResolutionRank 返回分辨率的高低顺序，未知分辨率为0
+func ResolutionRank(res string) int {
+	res = strings.ToLower(strings.TrimSpace(res))
+	if alias, ok := resolutionAliases[res]; ok {
+		res = alias
+	}
+	for i, r := range resolutions {
+		if r == res {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// NormalizeResolution 返回分辨率的标准写法，无法识别时返回空
+func NormalizeResolution(res string) string {
+	if rank := ResolutionRank(res); rank > 0 {
+		return resolutions[rank-1]
+	}
+	return ""
+}
+
+// parseResolution 返回标题中最高的分辨率
+func parseResolution(s string) string {
+	best := 0
+	for _, m := range resolutionRegex.FindAllStringSubmatch(s, -1) {
+		name := m[1] + m[2] + m[3]
+		if m[1] != "" {
+			name += "p"
+		}
+		if rank := ResolutionRank(name); rank > best {
+			best = rank
+		}
+	}
+	if best == 0 {
+		return ""
+	}
+	return resolutions[best-1]
+}
+
+// parseHDR 返回标题中的HDR格式，杜比视界在前，HDR10+、HDR10和HDR只保留最具体的一个
+func parseHDR(s string) []string {
+	var dv bool
+	hdr := ""
+	hdrRank := map[string]int{"hlg": 1, "hdr": 2, "hdr10": 3, "hdr10+": 4}
+	for _, m := range hdrRegex.FindAllString(s, -1) {
+		switch {
+		case m == "dv" || m == "dovi" || strings.HasPrefix(m, "dolby") || m == "杜比视界":
+			dv = true
+		case strings.HasPrefix(m, "hdr10+") || strings.HasPrefix(m, "hdr10plus"):
+			m = "hdr10+"
+			fallthrough
+		default:
+			if hdrRank[m] > hdrRank[hdr] {
+				hdr = m
+			}
+		}
+	}
+
+	var formats []string
+	if dv {
+		formats = append(formats, "dv")
+	}
+	if hdr != "" {
+		formats = append(formats, hdr)
+	}
+	return formats
+}
+
+// NormalizeCodec 返回视频编码的标准写法，如x265、HEVC返回h265，无法识别时返回空
+func NormalizeCodec(codec string) string {
+	return parseCodec(normalize(strings.TrimSpace(codec)))
+}
+
+// NormalizeAudio 返回音频格式的标准写法，如DD+返回ddp，无法识别时返回空
+func NormalizeAudio(audio string) string {
+	if formats := parseAudio(normalize(strings.TrimSpace(audio))); len(formats) > 0 {
+		return formats[0]
+	}
+	return ""
+}
+
+// parseCodec 返回标题中第一个视频编码的标准写法
+func parseCodec(s string) string {
+	m := codecRegex.FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	switch strings.ReplaceAll(m[1], ".", "") {
+	case "x265", "h265", "hevc":
+		return "h265"
+	case "x264", "h264", "avc":
+		return "h264"
+	default:
+		return m[1]
+	}
+}
+
+// parseAudio 返回标题中的音频格式，已去重
+func parseAudio(s string) []string {
+	var formats []string
+	for _, m := range audioRegex.FindAllStringSubmatch(s, -1) {
+		format := audioName(m)
+		if !contains(formats, format) {
+			formats = append(formats, format)
+		}
+	}
+	// 同时写了DTS-HD和DTS时只保留DTS-HD
+	if contains(formats, "dts-hd") || contains(formats, "dts-x") {
+		formats = remove(formats, "dts")
+	}
+	return formats
+}
+
+// audioName 返回audioRegex匹配到的音频格式的标准写法
+func audioName(m []string) string {
+	if m[1] != "" {
+		return "ddp"
+	}
+	if m[3] != "" {
+		return "atmos"
+	}
+	compact := strings.NewReplacer("-", "", " ", "", ".", "").Replace(m[2])
+	switch {
+	case strings.HasPrefix(compact, "dtshd"):
+		return "dts-hd"
+	case compact == "dtsx":
+		return "dts-x"
+	case compact == "eac3":
+		return "ddp"
+	case compact == "dd":
+		return "ac3"
+	default:
+		return compact
+	}
+}
+
+// parseEpisodes 解析季、集、总集数和完结状态
+func parseEpisodes(s string, info *model.MediaInfo) {
+	if m := seasonEpisodeRegex.FindStringSubmatch(s); m != nil {
+		info.Season, info.SeasonEnd = atoi(m[1]), atoi(m[2])
+		info.Episode, info.EpisodeEnd = atoi(m[3]), atoi(m[4])
+	} else if m := cnSeasonRegex.FindStringSubmatch(s); m != nil {
+		info.Season, info.SeasonEnd = cnNumber(m[1]), cnNumber(m[2])
+	}
+
+	if info.Episode == 0 {
+		if m := cnEpisodeRegex.FindStringSubmatch(s); m != nil {
+			info.Episode, info.EpisodeEnd = atoi(m[1]), atoi(m[2])
+		} else if m := episodeRegex.FindStringSubmatch(s); m != nil {
+			info.Episode, info.EpisodeEnd = atoi(m[1]), atoi(m[2])
+		} else if m := updatedRegex.FindStringSubmatch(s); m != nil {
+			info.Episode, info.EpisodeEnd = 1, atoi(m[1]+m[2])
+		}
+	}
+	if info.EpisodeEnd != 0 && info.EpisodeEnd <= info.Episode {
+		info.EpisodeEnd = 0
+	}
+	if info.SeasonEnd != 0 && info.SeasonEnd <= info.Season {
+		info.SeasonEnd = 0
+	}
+
+	if m := totalRegex.FindStringSubmatch(s); m != nil {
+		info.Episodes = atoi(m[1] + m[2])
+		info.Status = "complete"
+	}
+	switch {
+	case ongoingRegex.MatchString(s):
+		info.Status = "ongoing"
+	case completeRegex.MatchString(s):
+		info.Status = "complete"
+	}
+}
+
+// parseSize 返回标题中最大的文件大小（字节）
+func parseSize(s string) int64 {
+	var best float64
+	for _, m := range sizeRegex.FindAllStringSubmatch(s, -1) {
+		value, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			continue
+		}
+		if size := value * sizeUnits[m[2]]; size > best {
+			best = size
+		}
+	}
+	return int64(best)
+}
+
+// ParseSize 解析带单位的文件大小，如24.5GB、800MB，无法解析时返回false
+func ParseSize(value string) (int64, bool) {
+	m := sizeRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if m == nil || len(m[0]) != len(strings.TrimSpace(value)) {
+		return 0, false
+	}
+	size, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return int64(size * sizeUnits[m[2]]), true
+}
+
+// parseYear 返回标题中第一个不晚于明年的年份
+func parseYear(s string) int {
+	maxYear := time.Now().Year() + 1
+	for _, m := range yearRegex.FindAllString(s, -1) {
+		if year := atoi(m); year <= maxYear {
+			return year
+		}
+	}
+	return 0
+}
+
+// cnNumber 解析不超过99的阿拉伯数字或中文数字
+func cnNumber(s string) int {
+	if s == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	digits := map[rune]int{'零': 0, '一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+	n, tens := 0, false
+	for _, r := range s {
+		if r == '十' {
+			if n == 0 {
+				n = 1
+			}
+			n *= 10
+			tens = true
+			continue
+		}
+		d, ok := digits[r]
+		if !ok {
+			return 0
+		}
+		if tens {
+			n += d
+		} else {
+			n = n*10 + d
+		}
+	}
+	return n
+}
+
+// atoi 解析数字，空字符串返回0
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// contains 检查切片是否包含字符串
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// remove 移除切片中的字符串
+func remove(values []string, value string) []string {
+	kept := values[:0]
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,74 @@
+package mediainfo
+
+import (
+	"reflect"
+	"testing"
+
+	"pansou/model"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		title string
+		want  model.MediaInfo
+	}{
+		{
+			"沙丘2 Dune.Part.Two.2024.2160p.UHD.BluRay.DV.HDR10+.H.265.TrueHD.Atmos 7.1 [58.3GB]",
+			model.MediaInfo{Resolution: "2160p", HDR: []string{"dv", "hdr10+"}, Codec: "h265", Audio: []string{"truehd", "atmos"}, Size: 62599148339, Year: 2024},
+		},
+		{
+			"繁花 S01E05 1080P WEB-DL HEVC DDP5.1",
+			model.MediaInfo{Resolution: "1080p", Codec: "h265", Audio: []string{"ddp"}, Season: 1, Episode: 5},
+		},
+		{
+			"庆余年 第二季 更新至12集 4K高码率",
+			model.MediaInfo{Resolution: "2160p", Season: 2, Episode: 1, EpisodeEnd: 12, Status: "ongoing"},
+		},
+		{
+			"琅琊榜 全54集 1920×1080 國語中字 24.5G",
+			model.MediaInfo{Resolution: "1080p", Episodes: 54, Status: "complete", Size: 26306674688},
+		},
+		{
+			"The Office S01-S09 Complete x264 AAC",
+			model.MediaInfo{Codec: "h264", Audio: []string{"aac"}, Season: 1, SeasonEnd: 9, Status: "complete"},
+		},
+		{
+			"进击的巨人 第1-3季 E01~E25 DTS-HD MA DTS",
+			model.MediaInfo{Audio: []string{"dts-hd"}, Season: 1, SeasonEnd: 3, Episode: 1, EpisodeEnd: 25},
+		},
+	}
+	for _, c := range cases {
+		got := Parse(c.title)
+		if got == nil || !reflect.DeepEqual(*got, c.want) {
+			t.Errorf("Parse(%q)\n got  %+v\n want %+v", c.title, got, c.want)
+		}
+	}
+
+	if got := Parse("凡人修仙传"); got != nil {
+		t.Errorf("没有媒体属性时应返回nil: %+v", got)
+	}
+}
+
+func TestResolutionAndSize(t *testing.T) {
+	if ResolutionRank("4K") <= ResolutionRank("1080p") || ResolutionRank("unknown") != 0 {
+		t.Error("分辨率比较错误")
+	}
+	if NormalizeResolution("UHD") != "2160p" {
+		t.Error("分辨率别名错误")
+	}
+	if size, ok := ParseSize("1.5GB"); !ok || size != 1610612736 {
+		t.Errorf("ParseSize(1.5GB) = %d, %v", size, ok)
+	}
+	if _, ok := ParseSize("about 2GB"); ok {
+		t.Error("带其他内容的大小不应解析")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if NormalizeCodec("HEVC") != "h265" || NormalizeCodec("H.264") != "h264" || NormalizeCodec("mpeg") != "" {
+		t.Error("NormalizeCodec错误")
+	}
+	if NormalizeAudio("DD+") != "ddp" || NormalizeAudio("DTS-HD MA") != "dts-hd" || NormalizeAudio("全景声") != "atmos" {
+		t.Error("NormalizeAudio错误")
+	}
+}
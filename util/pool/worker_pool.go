@@ -9,6 +9,9 @@ import (
 // Task 表示一个工作任务
 type Task func() interface{}
 
+// ContextTask 表示一个可感知取消的工作任务
+type ContextTask func(ctx context.Context) interface{}
+
 // WorkerPool 工作池结构体
 type WorkerPool struct {
 	maxWorkers int
@@ -149,13 +152,39 @@ func ExecuteBatchWithTimeout(tasks []Task, maxWorkers int, timeout time.Duration
 		return []interface{}{}
 	}
 
+	return executeBatch(context.Background(), tasks, maxWorkers, timeout)
+}
+
+// ExecuteBatchWithContext 批量执行任务，父上下文取消或超时后任务收到的ctx随之取消
+func ExecuteBatchWithContext(ctx context.Context, tasks []ContextTask, maxWorkers int, timeout time.Duration) []interface{} {
+	if len(tasks) == 0 {
+		return []interface{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// 将上下文绑定到每个任务
+	wrapped := make([]Task, 0, len(tasks))
+	for _, task := range tasks {
+		t := task
+		wrapped = append(wrapped, func() interface{} {
+			return t(ctx)
+		})
+	}
+
+	return executeBatch(ctx, wrapped, maxWorkers, timeout)
+}
+
+// executeBatch 在父上下文下批量执行任务
+func executeBatch(parent context.Context, tasks []Task, maxWorkers int, timeout time.Duration) []interface{} {
 	// 如果任务数量少于工作者数量，调整工作者数量
 	if len(tasks) < maxWorkers {
 		maxWorkers = len(tasks)
 	}
 
 	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
 	// 创建工作池
@@ -0,0 +1,482 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"pansou/util/textnorm"
+)
+
+// 搜索关键词的查询语法：
+//   - 空格分隔的词需全部匹配，"带空格的短语"按整体匹配
+//   - -词 或 -"短语" 排除包含该内容的结果
+//   - 词 OR 词 匹配任一分支，每个分支单独向上游搜索
+//   - 字段限定 type:quark、source:tg、plugin:hdr4k、channel:频道名、after:2025-01-01、before:2025-06、res:4k，
+//     同一字段的多个值（或逗号分隔的值）匹配任一即可，字段前加-表示排除
+//
+// 上游（TG频道和插件）只收到普通关键词，排除词和字段限定在本地过滤结果时生效。
+// 本地匹配前关键词和文本都经过textnorm归一化，繁简体、全角半角和标点差异不影响匹配。
+// 模糊匹配模式下关键词按编辑距离计算相似度，达到阈值即视为匹配，用于容忍错别字和拼写错误
+
+// 匹配模式
+const (
+	MatchStrict = "strict" // 关键词需完整出现在文本中
+	MatchFuzzy  = "fuzzy"  // 关键词与文本片段的相似度达到阈值即可
+)
+
+// 结果来源，与 source: 字段的取值一致
+const (
+	SourceTG     = "tg"
+	SourcePlugin = "plugin"
+)
+
+// MaxGroups OR分支的最大数量，每个分支都会向所有来源发起一次搜索
+const MaxGroups = 4
+
+// ErrInvalidQuery 查询语法错误
+var ErrInvalidQuery = errors.New("无效的查询")
+
+// 日期字段支持的格式
+var dateLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+// 分辨率别名，res:4k 同时匹配标题中的 2160p、UHD
+var resolutionAliases = map[string][]string{
+	"8k":    {"8k", "4320p"},
+	"4k":    {"4k", "2160p", "uhd"},
+	"2k":    {"2k", "1440p"},
+	"1080p": {"1080p", "1080i", "fhd"},
+	"720p":  {"720p"},
+}
+
+// Term 普通关键词或短语
+type Term struct {
+	Text   string // 原始写法，发送给上游
+	Phrase bool
+	folded string // 归一化后的写法，用于本地匹配
+}
+
+// newTerm 创建关键词并预先归一化
+func newTerm(text string, phrase bool) Term {
+	return Term{Text: text, Phrase: phrase, folded: textnorm.Fold(text)}
+}
+
+// Filter 某个字段的限定值，已转为小写
+type Filter struct {
+	Include []string // 匹配任一即可
+	Exclude []string // 匹配任一即排除
+}
+
+// Query 解析后的查询
+type Query struct {
+	Raw      string
+	Groups   [][]Term // OR分支，分支内的关键词需全部匹配
+	Exclude  []Term   // 排除词，对所有分支生效
+	Types    Filter   // 网盘类型
+	Sources  Filter   // 来源类型：tg 或 plugin
+	Plugins  Filter   // 插件名
+	Channels Filter   // TG频道名
+	Res      Filter   // 分辨率
+	After    time.Time
+	Before   time.Time
+	fuzzy    float64 // 模糊匹配的相似度阈值，为0时严格匹配
+}
+
+// Document 参与匹配的一条结果或链接
+type Document struct {
+	Text     string    // 标题等文本
+	Type     string    // 网盘类型，为空时不检查 type:
+	Source   string    // SourceTG 或 SourcePlugin
+	Name     string    // 频道名或插件名
+	Datetime time.Time // 发布或更新时间
+}
+
+// token 词法单元
+type token struct {
+	text   string
+	quoted bool
+	negate bool
+}
+
+// Parse 解析查询，不含任何语法的关键词解析为单个分支
+func Parse(raw string) (*Query, error) {
+	q := &Query{Raw: raw}
+	group := []Term{}
+
+	for _, tok := range tokenize(raw) {
+		if !tok.quoted && !tok.negate && (tok.text == "OR" || tok.text == "|") {
+			if len(group) > 0 {
+				q.Groups = append(q.Groups, group)
+				group = []Term{}
+			}
+			continue
+		}
+
+		if !tok.quoted {
+			handled, err := q.parseField(tok)
+			if err != nil {
+				return nil, err
+			}
+			if handled {
+				continue
+			}
+		}
+
+		term := newTerm(tok.text, tok.quoted)
+		if tok.negate {
+			q.Exclude = append(q.Exclude, term)
+		} else {
+			group = append(group, term)
+		}
+	}
+	if len(group) > 0 {
+		q.Groups = append(q.Groups, group)
+	}
+
+	if len(q.Groups) == 0 {
+		return nil, fmt.Errorf("%w: 至少需要一个搜索关键词", ErrInvalidQuery)
+	}
+	if len(q.Keywords()) > MaxGroups {
+		return nil, fmt.Errorf("%w: OR分支不能超过%d个", ErrInvalidQuery, MaxGroups)
+	}
+	return q, nil
+}
+
+// tokenize 按空白切分，引号（包括中文引号）内的空白不切分
+func tokenize(raw string) []token {
+	var tokens []token
+	runes := []rune(raw)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		tok := token{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			tok.negate = true
+			i++
+		}
+
+		if closing, ok := closingQuote(runes[i]); ok {
+			end := i + 1
+			for end < len(runes) && runes[end] != closing {
+				end++
+			}
+			tok.text = strings.TrimSpace(string(runes[i+1 : end]))
+			tok.quoted = true
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			tok.text = string(runes[i:end])
+			i = end
+		}
+
+		if tok.text != "" {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+// closingQuote 返回r作为左引号时对应的右引号
+func closingQuote(r rune) (rune, bool) {
+	switch r {
+	case '"':
+		return '"', true
+	case '“':
+		return '”', true
+	}
+	return 0, false
+}
+
+// parseField 解析字段限定，不是已知字段时返回false，按普通关键词处理
+func (q *Query) parseField(tok token) (bool, error) {
+	name, value, ok := strings.Cut(tok.text, ":")
+	if !ok || value == "" {
+		return false, nil
+	}
+	value = strings.ToLower(value)
+
+	var filter *Filter
+	switch strings.ToLower(name) {
+	case "type":
+		filter = &q.Types
+	case "source":
+		filter = &q.Sources
+		for _, v := range splitValues(value) {
+			if v != SourceTG && v != SourcePlugin {
+				return false, fmt.Errorf("%w: source只能是tg或plugin", ErrInvalidQuery)
+			}
+		}
+	case "plugin":
+		filter = &q.Plugins
+	case "channel":
+		filter = &q.Channels
+	case "res":
+		filter = &q.Res
+	case "after", "before":
+		if tok.negate {
+			return false, fmt.Errorf("%w: %s不支持排除", ErrInvalidQuery, name)
+		}
+		date, err := parseDate(value)
+		if err != nil {
+			return false, err
+		}
+		if strings.ToLower(name) == "after" {
+			q.After = date
+		} else {
+			q.Before = date
+		}
+		return true, nil
+	default:
+		return false, nil
+	}
+
+	if tok.negate {
+		filter.Exclude = append(filter.Exclude, splitValues(value)...)
+	} else {
+		filter.Include = append(filter.Include, splitValues(value)...)
+	}
+	return true, nil
+}
+
+// splitValues 拆分逗号分隔的字段值
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseDate 解析日期字段，按本地时区
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: 无法识别的日期 %s，应为YYYY-MM-DD", ErrInvalidQuery, value)
+}
+
+// Keywords 返回发送给上游的关键词，每个OR分支一个，已去重
+func (q *Query) Keywords() []string {
+	keywords := make([]string, 0, len(q.Groups))
+	seen := make(map[string]bool, len(q.Groups))
+	for _, group := range q.Groups {
+		words := make([]string, len(group))
+		for i, term := range group {
+			words[i] = term.Text
+		}
+		keyword := strings.Join(words, " ")
+		if !seen[keyword] {
+			seen[keyword] = true
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// AddAlias 追加作品别名作为新的OR分支，别名中空格分隔的词需全部匹配
+// 别名分支同样发送给上游，不计入MaxGroups；别名与已有分支相同时不追加，返回false
+func (q *Query) AddAlias(alias string) bool {
+	words := strings.Fields(alias)
+	if len(words) == 0 || contains(q.Keywords(), strings.Join(words, " ")) {
+		return false
+	}
+	group := make([]Term, len(words))
+	for i, word := range words {
+		group[i] = newTerm(word, false)
+	}
+	q.Groups = append(q.Groups, group)
+	return true
+}
+
+// SetFuzzy 启用模糊匹配，threshold为关键词的最低相似度，取值(0,1]，超出范围时保持严格匹配
+func (q *Query) SetFuzzy(threshold float64) {
+	if threshold > 0 && threshold <= 1 {
+		q.fuzzy = threshold
+	}
+}
+
+// Fuzzy 返回是否为模糊匹配模式
+func (q *Query) Fuzzy() bool {
+	return q.fuzzy > 0
+}
+
+// Threshold 返回保留结果需要达到的匹配分数，严格匹配时为1
+func (q *Query) Threshold() float64 {
+	if q.fuzzy > 0 {
+		return q.fuzzy
+	}
+	return 1
+}
+
+// Match 检查文本和字段限定是否都满足
+func (q *Query) Match(doc Document) bool {
+	return q.Score(doc) >= q.Threshold()
+}
+
+// MatchText 检查文本是否满足关键词、排除词和分辨率限定
+func (q *Query) MatchText(text string) bool {
+	return q.ScoreText(text) >= q.Threshold()
+}
+
+// Score 返回文档的匹配分数，不满足字段限定时为0
+func (q *Query) Score(doc Document) float64 {
+	if !q.MatchFields(doc) {
+		return 0
+	}
+	return q.ScoreText(doc.Text)
+}
+
+// ScoreText 返回文本的匹配分数，范围0到1：取得分最高的OR分支，分支得分为其中最不匹配的关键词的得分
+// 关键词完整出现时得1分，严格匹配模式下其他情况得0分，模糊匹配模式下按相似度计分。
+// 包含排除词或不满足分辨率限定时为0
+func (q *Query) ScoreText(text string) float64 {
+	folded := textnorm.Fold(text)
+
+	for _, term := range q.Exclude {
+		// 排除词只按字面匹配，拼音缩写排除的范围难以预料
+		if term.folded != "" && strings.Contains(folded, term.folded) {
+			return 0
+		}
+	}
+	if !q.matchResolution(folded) {
+		return 0
+	}
+
+	best := 0.0
+	for _, group := range q.Groups {
+		if score := q.scoreGroup(folded, group); score > best {
+			best = score
+			if best == 1 {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// scoreGroup 返回分支内得分最低的关键词的得分
+func (q *Query) scoreGroup(folded string, group []Term) float64 {
+	score := 1.0
+	for _, term := range group {
+		s := q.scoreTerm(folded, term)
+		if s < score {
+			score = s
+		}
+		if score == 0 {
+			break
+		}
+	}
+	return score
+}
+
+// scoreTerm 返回单个关键词的得分
+func (q *Query) scoreTerm(folded string, term Term) float64 {
+	if textnorm.Contains(folded, term.folded) {
+		return 1
+	}
+	if q.fuzzy > 0 {
+		return textnorm.Similarity(folded, term.folded)
+	}
+	return 0
+}
+
+// matchResolution 检查文本是否满足分辨率限定
+func (q *Query) matchResolution(folded string) bool {
+	for _, res := range q.Res.Exclude {
+		if containsAny(folded, resolutionNames(res)) {
+			return false
+		}
+	}
+	if len(q.Res.Include) == 0 {
+		return true
+	}
+	for _, res := range q.Res.Include {
+		if containsAny(folded, resolutionNames(res)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolutionNames 返回分辨率在标题中可能的写法
+func resolutionNames(res string) []string {
+	if aliases, ok := resolutionAliases[res]; ok {
+		return aliases
+	}
+	return []string{res}
+}
+
+// containsAny 检查文本是否包含任一子串
+func containsAny(folded string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(folded, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchFields 检查网盘类型、来源和时间限定
+func (q *Query) MatchFields(doc Document) bool {
+	if doc.Type != "" && !q.Types.allows(strings.ToLower(doc.Type)) {
+		return false
+	}
+	if !q.Sources.allows(doc.Source) {
+		return false
+	}
+	if !q.matchName(doc.Source, strings.ToLower(doc.Name)) {
+		return false
+	}
+	if !q.After.IsZero() && (doc.Datetime.IsZero() || doc.Datetime.Before(q.After)) {
+		return false
+	}
+	if !q.Before.IsZero() && (doc.Datetime.IsZero() || !doc.Datetime.Before(q.Before)) {
+		return false
+	}
+	return true
+}
+
+// matchName 检查插件名和频道名限定，plugin:和channel:同时出现时满足任一即可
+func (q *Query) matchName(source, name string) bool {
+	if source == SourcePlugin && contains(q.Plugins.Exclude, name) {
+		return false
+	}
+	if source == SourceTG && contains(q.Channels.Exclude, name) {
+		return false
+	}
+	if len(q.Plugins.Include) == 0 && len(q.Channels.Include) == 0 {
+		return true
+	}
+	return (source == SourcePlugin && contains(q.Plugins.Include, name)) ||
+		(source == SourceTG && contains(q.Channels.Include, name))
+}
+
+// allows 检查值是否满足限定
+func (f Filter) allows(value string) bool {
+	if contains(f.Exclude, value) {
+		return false
+	}
+	return len(f.Include) == 0 || contains(f.Include, value)
+}
+
+// contains 检查切片是否包含值
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`"速度与激情 7" -枪版 type:quark,baidu -source:tg res:4K after:2025-01-01`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"速度与激情 7"}; !reflect.DeepEqual(q.Keywords(), want) {
+		t.Errorf("Keywords() = %q，应为 %q", q.Keywords(), want)
+	}
+	if len(q.Exclude) != 1 || q.Exclude[0].Text != "枪版" {
+		t.Errorf("排除词解析错误: %+v", q.Exclude)
+	}
+	if !reflect.DeepEqual(q.Types.Include, []string{"quark", "baidu"}) || !reflect.DeepEqual(q.Sources.Exclude, []string{"tg"}) {
+		t.Errorf("字段解析错误: types=%+v sources=%+v", q.Types, q.Sources)
+	}
+	if !reflect.DeepEqual(q.Res.Include, []string{"4k"}) {
+		t.Errorf("分辨率解析错误: %+v", q.Res)
+	}
+	if !q.After.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("日期解析错误: %v", q.After)
+	}
+
+	q, _ = Parse("凡人修仙传 OR 仙逆 OR 凡人修仙传 年番")
+	if want := []string{"凡人修仙传", "仙逆", "凡人修仙传 年番"}; !reflect.DeepEqual(q.Keywords(), want) {
+		t.Errorf("Keywords() = %q，应为 %q", q.Keywords(), want)
+	}
+
+	// 未知字段和中间的连字符按普通关键词处理，发送给上游时保留原始写法
+	q, _ = Parse("Spider-Man S01:E01")
+	if want := []string{"Spider-Man S01:E01"}; !reflect.DeepEqual(q.Keywords(), want) {
+		t.Errorf("Keywords() = %q，应为 %q", q.Keywords(), want)
+	}
+
+	// 别名追加为新的OR分支
+	q, _ = Parse("复仇者联盟4 -枪版")
+	if !q.AddAlias("Avengers  Endgame") || q.AddAlias("复仇者联盟4") {
+		t.Error("AddAlias() 返回值错误")
+	}
+	if want := []string{"复仇者联盟4", "Avengers Endgame"}; !reflect.DeepEqual(q.Keywords(), want) {
+		t.Errorf("Keywords() = %q，应为 %q", q.Keywords(), want)
+	}
+	if !q.MatchText("Avengers.Endgame.2019.2160p") || q.MatchText("Avengers Endgame 枪版") {
+		t.Error("别名分支匹配错误")
+	}
+
+	for _, raw := range []string{"", "-枪版 type:quark", "a source:web", "a after:昨天", "a OR b OR c OR d OR e"} {
+		if _, err := Parse(raw); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Parse(%q) 应返回ErrInvalidQuery，得到 %v", raw, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	q, _ := Parse(`"速度与激情 7" OR 玩命关头 -枪版 res:4k type:quark plugin:hdr4k channel:tgsearchers3 after:2025-01`)
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+	base := Document{Text: "速度与激情 7 2160P 国语", Type: "quark", Source: SourcePlugin, Name: "HDR4K", Datetime: date}
+
+	cases := []struct {
+		name string
+		doc  func(Document) Document
+		want bool
+	}{
+		{"全部满足", func(d Document) Document { return d }, true},
+		{"OR分支", func(d Document) Document { d.Text = "玩命关头 UHD"; return d }, true},
+		{"短语不连续", func(d Document) Document { d.Text = "速度与激情 第7部 4K"; return d }, false},
+		{"排除词", func(d Document) Document { d.Text += " 枪版"; return d }, false},
+		{"分辨率", func(d Document) Document { d.Text = "速度与激情 7 1080p"; return d }, false},
+		{"网盘类型", func(d Document) Document { d.Type = "baidu"; return d }, false},
+		{"指定的频道", func(d Document) Document { d.Source, d.Name = SourceTG, "tgsearchers3"; return d }, true},
+		{"其他插件", func(d Document) Document { d.Name = "labi"; return d }, false},
+		{"早于after", func(d Document) Document { d.Datetime = date.AddDate(-1, 0, 0); return d }, false},
+		{"没有时间", func(d Document) Document { d.Datetime = time.Time{}; return d }, false},
+	}
+	for _, c := range cases {
+		if got := q.Match(c.doc(base)); got != c.want {
+			t.Errorf("%s: Match() = %v，应为 %v", c.name, got, c.want)
+		}
+	}
+
+	// 繁简体、全角和标点差异不影响匹配
+	q, _ = Parse("复仇者联盟 -枪版")
+	if !q.MatchText("【復仇者聯盟４：終局之戰】") || q.MatchText("復仇者聯盟 槍版") {
+		t.Error("归一化匹配错误")
+	}
+
+	// 没有字段限定时只检查文本
+	q, _ = Parse("Matrix -sample")
+	if !q.MatchFields(Document{Source: SourceTG}) || !q.MatchText("The MATRIX 1999") || q.MatchText("matrix sample") {
+		t.Error("普通关键词匹配错误")
+	}
+}
+
+func TestScoreFuzzy(t *testing.T) {
+	q, _ := Parse("intersteller 2014 -枪版")
+	text := "Interstellar 星际穿越 2014 1080p"
+	if q.Fuzzy() || q.Threshold() != 1 || q.ScoreText(text) != 0 {
+		t.Fatal("严格匹配模式下拼写错误不应匹配")
+	}
+
+	q.SetFuzzy(0.8)
+	if !q.Fuzzy() || !q.MatchText(text) {
+		t.Fatal("模糊匹配应容忍一个字母的拼写错误")
+	}
+	// 分支得分取最不匹配的关键词
+	if got := q.ScoreText(text); got != 1-1.0/12 {
+		t.Errorf("ScoreText() = %v", got)
+	}
+	if q.ScoreText(text+" 枪版") != 0 || q.MatchText("Interstellar 1080p") {
+		t.Error("排除词仍需严格生效，其他关键词仍需达到阈值")
+	}
+
+	q.SetFuzzy(1.5)
+	if q.Threshold() != 0.8 {
+		t.Error("超出范围的阈值应被忽略")
+	}
+}
@@ -0,0 +1,37 @@
+package textnorm
+
+// Similarity 返回归一化后的关键词与文本中最接近片段的相似度，范围0到1：
+// 1 - 编辑距离/关键词长度，编辑距离按字符计算，取文本所有子串中的最小值，文本包含关键词时为1
+// 空关键词视为完全匹配
+func Similarity(folded, term string) float64 {
+	t := []rune(term)
+	if len(t) == 0 {
+		return 1
+	}
+
+	// 近似子串匹配（Sellers算法）：第一行为0，关键词可以从文本任意位置开始对齐
+	// col[i] 为关键词前i个字符与以当前文本位置结尾的子串的最小编辑距离
+	col := make([]int, len(t)+1)
+	for i := range col {
+		col[i] = i
+	}
+	best := len(t)
+	for _, r := range folded {
+		diag := col[0]
+		for i := 1; i <= len(t); i++ {
+			cost := 1
+			if t[i-1] == r {
+				cost = 0
+			}
+			next := min(col[i]+1, col[i-1]+1, diag+cost)
+			diag, col[i] = col[i], next
+		}
+		if col[len(t)] < best {
+			best = col[len(t)]
+			if best == 0 {
+				return 1
+			}
+		}
+	}
+	return 1 - float64(best)/float64(len(t))
+}
@@ -0,0 +1,240 @@
+package textnorm
+
+import (
+	"bufio"
+	"embed"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+// 关键词匹配前的文本归一化：繁体转简体、全角转半角、转小写，标点、符号和表情替换为空格并合并连续空白，
+// 使"復仇者聯盟：終局之戰"和"复仇者联盟 终局之战"归一化后相同。
+// 开启拼音匹配后，纯字母的关键词还可以匹配连续汉字的全拼或首字母，如 fczlm 匹配"复仇者联盟"
+
+//go:embed data/t2s.txt data/pinyin.txt
+var dataFS embed.FS
+
+var (
+	loadOnce sync.Once
+	t2s      map[rune]rune   // 繁体到简体
+	s2t      map[rune]rune   // 简体到繁体，一简对多繁时取最常用的写法
+	pinyin   map[rune]string // 汉字到拼音
+)
+
+// pinyinMatch 是否启用拼音匹配
+var pinyinMatch atomic.Bool
+
+// minPinyinLen 参与拼音匹配的关键词最短长度，避免单个字母匹配大量结果
+const minPinyinLen = 2
+
+// SetPinyinMatch 设置是否启用拼音和首字母匹配
+func SetPinyinMatch(enabled bool) {
+	pinyinMatch.Store(enabled)
+}
+
+// PinyinMatchEnabled 返回是否启用拼音和首字母匹配
+func PinyinMatchEnabled() bool {
+	return pinyinMatch.Load()
+}
+
+// load 加载内嵌的繁简对照表和拼音表
+func load() {
+	loadOnce.Do(func() {
+		t2s = make(map[rune]rune, 1600)
+		s2t = make(map[rune]rune, 1500)
+		eachLine("data/t2s.txt", func(fields []string) {
+			if len(fields) < 2 {
+				return
+			}
+			trad, simp := []rune(fields[0])[0], []rune(fields[1])[0]
+			t2s[trad] = simp
+			if len(fields) == 2 {
+				s2t[simp] = trad
+			}
+		})
+
+		pinyin = make(map[rune]string, 21000)
+		eachLine("data/pinyin.txt", func(fields []string) {
+			if len(fields) < 2 {
+				return
+			}
+			for _, r := range fields[1] {
+				pinyin[r] = fields[0]
+			}
+		})
+	})
+}
+
+// eachLine 逐行读取内嵌数据文件，跳过空行和#开头的注释
+func eachLine(name string, fn func(fields []string)) {
+	f, err := dataFS.Open(name)
+	if err != nil {
+		panic("textnorm: 缺少内嵌数据文件 " + name)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fn(strings.Fields(line))
+	}
+}
+
+// Fold 归一化文本用于匹配：繁体转简体、全角转半角、转小写，标点、符号和表情替换为空格，合并连续空白
+func Fold(s string) string {
+	load()
+	var b strings.Builder
+	b.Grow(len(s))
+	pendingSpace := false
+	for _, r := range s {
+		r = foldRune(r)
+		if r == ' ' {
+			pendingSpace = b.Len() > 0
+			continue
+		}
+		if pendingSpace {
+			b.WriteByte(' ')
+			pendingSpace = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// foldRune 归一化单个字符，需要去掉的字符返回空格
+func foldRune(r rune) rune {
+	if r >= '！' && r <= '～' {
+		// 全角ASCII字符与半角相差固定偏移，全角空格由unicode.IsSpace处理
+		r -= 0xFEE0
+	}
+
+	if simp, ok := t2s[r]; ok {
+		return simp
+	}
+	if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) || isEmojiComponent(r) {
+		return ' '
+	}
+	return unicode.ToLower(r)
+}
+
+// isEmojiComponent 检查是否为组成表情序列的不可见字符（零宽连接符等格式字符、变体选择符、组合用键帽）
+func isEmojiComponent(r rune) bool {
+	return r == '\u20e3' || unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Variation_Selector, r)
+}
+
+// ToSimplified 将繁体字转为简体，其他字符不变
+func ToSimplified(s string) string {
+	load()
+	return mapRunes(s, t2s)
+}
+
+// ToTraditional 将简体字转为繁体，一简对多繁时使用最常用的写法，其他字符不变
+func ToTraditional(s string) string {
+	load()
+	return mapRunes(s, s2t)
+}
+
+// mapRunes 按对照表替换字符
+func mapRunes(s string, table map[rune]rune) string {
+	return strings.Map(func(r rune) rune {
+		if mapped, ok := table[r]; ok {
+			return mapped
+		}
+		return r
+	}, s)
+}
+
+// Variants 返回关键词的原始、简体和繁体写法，已去重，没有繁简差异时只返回原关键词
+func Variants(s string) []string {
+	variants := []string{s}
+	for _, v := range []string{ToSimplified(s), ToTraditional(s)} {
+		if !containsString(variants, v) {
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// containsString 检查切片是否包含字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains 检查归一化后的文本是否包含归一化后的关键词，启用拼音匹配时纯字母关键词还会匹配汉字的全拼或首字母
+// 空关键词（如只由标点组成）视为匹配
+func Contains(folded, term string) bool {
+	if strings.Contains(folded, term) {
+		return true
+	}
+	return pinyinMatch.Load() && MatchPinyin(folded, term)
+}
+
+// MatchPinyin 检查关键词是否为文本中某段连续汉字的全拼或首字母，关键词需为小写字母
+func MatchPinyin(folded, term string) bool {
+	if len(term) < minPinyinLen || !isLowerLetters(term) {
+		return false
+	}
+	load()
+
+	var syllables []string
+	for _, r := range folded {
+		if py, ok := pinyin[r]; ok {
+			syllables = append(syllables, py)
+			continue
+		}
+		// 非汉字打断连续的汉字
+		if matchSyllables(syllables, term) {
+			return true
+		}
+		syllables = syllables[:0]
+	}
+	return matchSyllables(syllables, term)
+}
+
+// matchSyllables 检查关键词是否等于从某个音节开始的连续音节的全拼或首字母
+func matchSyllables(syllables []string, term string) bool {
+	for start := range syllables {
+		if hasPrefixSyllables(syllables[start:], term, false) || hasPrefixSyllables(syllables[start:], term, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPrefixSyllables 检查关键词是否由音节序列开头的若干个完整音节（或其首字母）拼成
+func hasPrefixSyllables(syllables []string, term string, initials bool) bool {
+	rest := term
+	for _, syllable := range syllables {
+		if initials {
+			syllable = syllable[:1]
+		}
+		if !strings.HasPrefix(rest, syllable) {
+			return false
+		}
+		if rest = rest[len(syllable):]; rest == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isLowerLetters 检查字符串是否只由小写ASCII字母组成
+func isLowerLetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
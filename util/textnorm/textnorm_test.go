@@ -0,0 +1,79 @@
+package textnorm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	cases := map[string]string{
+		"復仇者聯盟：終局之戰":          "复仇者联盟 终局之战",
+		"【４Ｋ】Ｓｐｉｄｅｒ－Ｍａｎ　２０２１": "4k spider man 2021",
+		"  速度与激情7!!🔥🔥 国语 ":    "速度与激情7 国语",
+		"👨‍👩‍👧 家庭 ❤️":         "家庭",
+	}
+	for in, want := range cases {
+		if got := Fold(in); got != want {
+			t.Errorf("Fold(%q) = %q，应为 %q", in, got, want)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	if got, want := Variants("复仇者联盟"), []string{"复仇者联盟", "復仇者聯盟"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Variants() = %q，应为 %q", got, want)
+	}
+	if got, want := Variants("發財"), []string{"發財", "发财"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Variants() = %q，应为 %q", got, want)
+	}
+	// 简体字本身也是常用繁体字时不转换
+	if got := ToTraditional("皇后 关系"); got != "皇后 關系" {
+		t.Errorf("ToTraditional() = %q", got)
+	}
+	if got := Variants("Matrix 1999"); len(got) != 1 {
+		t.Errorf("没有繁简差异时应只返回原关键词: %q", got)
+	}
+}
+
+func TestContainsPinyin(t *testing.T) {
+	text := Fold("復仇者聯盟4：终局之战 长津湖")
+
+	SetPinyinMatch(false)
+	if Contains(text, "fczlm") {
+		t.Error("未启用拼音匹配时不应匹配首字母")
+	}
+
+	SetPinyinMatch(true)
+	defer SetPinyinMatch(false)
+	for _, term := range []string{"fczlm", "fuchouzhelianmeng", "zjzz", "zhongju", "changjinhu", "cjh"} {
+		if !Contains(text, term) {
+			t.Errorf("Contains(%q) 应匹配", term)
+		}
+	}
+	// 跨越非汉字、音节不完整或单个字母都不匹配
+	for _, term := range []string{"lmzj", "fuch", "f", "uchou"} {
+		if Contains(text, term) {
+			t.Errorf("Contains(%q) 不应匹配", term)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	text := Fold("Interstellar 星际穿越 2014 1080p")
+	cases := []struct {
+		term string
+		want float64
+	}{
+		{"interstellar", 1},
+		{"intersteller", 1 - 1.0/12}, // 替换一个字母
+		{"intrstellar", 1 - 1.0/11},  // 少一个字母
+		{"星际穿梭", 0.75},
+		{"", 1},
+		{"盗梦空间", 0},
+	}
+	for _, c := range cases {
+		if got := Similarity(text, c.term); got != c.want {
+			t.Errorf("Similarity(%q) = %v, want %v", c.term, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package ttlpolicy
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"pansou/config"
+	"pansou/model"
+)
+
+// 缓存有效期策略：按插件名、频道名或插件内容分类配置有效期，插件可以根据结果给出建议值；
+// 同一来源和关键词连续刷新得到相同结果时逐步延长有效期，结果变化后恢复基础值
+
+// 来源类型，与缓存键信息中的来源一致
+const (
+	KindTG     = "tg"
+	KindPlugin = "plugin"
+)
+
+// maxObservations 自适应有效期最多跟踪的来源和关键词数
+const maxObservations = 50000
+
+// Source 缓存结果的来源
+type Source struct {
+	Kind     string // KindTG 或 KindPlugin
+	Name     string // 频道名或插件名
+	Category string // 插件内容分类，TG频道为空
+}
+
+// Config 有效期策略配置
+type Config struct {
+	TGDefault     time.Duration            // TG频道的默认有效期
+	PluginDefault time.Duration            // 插件的默认有效期
+	Rules         map[string]time.Duration // 键为 plugin:插件名、channel:频道名 或 category:分类
+	AdaptiveMax   time.Duration            // 自适应延长的上限，不大于基础有效期时不延长
+}
+
+// Policy 缓存有效期策略
+type Policy struct {
+	cfg Config
+
+	mu           sync.Mutex
+	observations map[string]*observation
+}
+
+// observation 某个来源和关键词最近一次的结果
+type observation struct {
+	fingerprint uint64
+	streak      int       // 连续得到相同结果的次数
+	checkedAt   time.Time // 最近一次计入连续次数的时间
+}
+
+// New 创建有效期策略
+func New(cfg Config) *Policy {
+	rules := make(map[string]time.Duration, len(cfg.Rules))
+	for key, ttl := range cfg.Rules {
+		rules[strings.ToLower(key)] = ttl
+	}
+	cfg.Rules = rules
+	return &Policy{
+		cfg:          cfg,
+		observations: make(map[string]*observation),
+	}
+}
+
+var (
+	defaultPolicy     *Policy
+	defaultPolicyOnce sync.Once
+)
+
+// Default 返回按应用配置创建的全局策略
+func Default() *Policy {
+	defaultPolicyOnce.Do(func() {
+		cfg := Config{
+			TGDefault:     time.Hour,
+			PluginDefault: time.Hour,
+		}
+		if config.AppConfig != nil {
+			cfg.TGDefault = time.Duration(config.AppConfig.CacheTTLMinutes) * time.Minute
+			cfg.PluginDefault = time.Duration(config.AppConfig.AsyncCacheTTLHours) * time.Hour
+			cfg.Rules = config.AppConfig.CacheTTLRules
+			cfg.AdaptiveMax = config.AppConfig.CacheTTLAdaptiveMax
+		}
+		defaultPolicy = New(cfg)
+	})
+	return defaultPolicy
+}
+
+// Base 返回不含自适应延长的有效期
+// 优先级：插件名或频道名规则 > 插件建议值 > 内容分类规则 > 默认值
+func (p *Policy) Base(src Source, hint time.Duration) time.Duration {
+	name := strings.ToLower(src.Name)
+	switch src.Kind {
+	case KindTG:
+		if ttl, ok := p.cfg.Rules["channel:"+name]; ok {
+			return ttl
+		}
+		return p.cfg.TGDefault
+	default:
+		if ttl, ok := p.cfg.Rules["plugin:"+name]; ok {
+			return ttl
+		}
+		if hint > 0 {
+			return hint
+		}
+		if src.Category != "" {
+			if ttl, ok := p.cfg.Rules["category:"+strings.ToLower(src.Category)]; ok {
+				return ttl
+			}
+		}
+		return p.cfg.PluginDefault
+	}
+}
+
+// TTL 计算本次结果的有效期，并记录结果用于自适应延长
+// 距上次计入不足半个基础有效期的相同结果（如同一结果的前台和后台写入）不重复计入
+func (p *Policy) TTL(src Source, keyword string, results []model.SearchResult, hint time.Duration) time.Duration {
+	base := p.Base(src, hint)
+	if p.cfg.AdaptiveMax <= base || len(results) == 0 {
+		return base
+	}
+
+	id := src.Kind + ":" + strings.ToLower(src.Name) + ":" + strings.ToLower(strings.TrimSpace(keyword))
+	fp := fingerprint(results)
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	obs, ok := p.observations[id]
+	switch {
+	case !ok:
+		if len(p.observations) >= maxObservations {
+			p.pruneLocked(now)
+		}
+		obs = &observation{fingerprint: fp, checkedAt: now}
+		p.observations[id] = obs
+	case obs.fingerprint != fp:
+		obs.fingerprint = fp
+		obs.streak = 0
+		obs.checkedAt = now
+	case now.Sub(obs.checkedAt) >= base/2 && base<<obs.streak < p.cfg.AdaptiveMax:
+		obs.streak++
+		obs.checkedAt = now
+	}
+
+	ttl := base << obs.streak
+	if ttl > p.cfg.AdaptiveMax {
+		ttl = p.cfg.AdaptiveMax
+	}
+	return ttl
+}
+
+// pruneLocked 删除长时间未更新的记录，仍然超过上限时随机删除一半（调用方需持有锁）
+func (p *Policy) pruneLocked(now time.Time) {
+	cutoff := now.Add(-2 * p.cfg.AdaptiveMax)
+	for id, obs := range p.observations {
+		if obs.checkedAt.Before(cutoff) {
+			delete(p.observations, id)
+		}
+	}
+	for id := range p.observations {
+		if len(p.observations) < maxObservations/2 {
+			break
+		}
+		delete(p.observations, id)
+	}
+}
+
+// fingerprint 计算结果集的指纹，与结果顺序无关
+func fingerprint(results []model.SearchResult) uint64 {
+	var sum uint64
+	for _, r := range results {
+		h := fnv.New64a()
+		h.Write([]byte(r.UniqueID))
+		h.Write([]byte{0})
+		h.Write([]byte(r.Title))
+		for _, link := range r.Links {
+			h.Write([]byte{0})
+			h.Write([]byte(link.URL))
+		}
+		sum += h.Sum64()
+	}
+	return sum ^ uint64(len(results))
+}
@@ -0,0 +1,74 @@
+package ttlpolicy
+
+import (
+	"testing"
+	"time"
+
+	"pansou/model"
+)
+
+func TestBasePrecedence(t *testing.T) {
+	p := New(Config{
+		TGDefault:     time.Hour,
+		PluginDefault: 2 * time.Hour,
+		Rules: map[string]time.Duration{
+			"plugin:Nyaa":     10 * time.Minute,
+			"channel:tgsou":   3 * time.Hour,
+			"category:magnet": 20 * time.Minute,
+		},
+	})
+
+	cases := []struct {
+		name string
+		src  Source
+		hint time.Duration
+		want time.Duration
+	}{
+		{"插件名规则优先于建议值", Source{KindPlugin, "nyaa", "anime"}, 5 * time.Minute, 10 * time.Minute},
+		{"建议值优先于分类规则", Source{KindPlugin, "tpb", "magnet"}, 5 * time.Minute, 5 * time.Minute},
+		{"分类规则", Source{KindPlugin, "tpb", "magnet"}, 0, 20 * time.Minute},
+		{"插件默认值", Source{KindPlugin, "labi", "general"}, 0, 2 * time.Hour},
+		{"频道规则", Source{KindTG, "tgsou", ""}, 0, 3 * time.Hour},
+		{"频道默认值不受建议值影响", Source{KindTG, "other", ""}, 5 * time.Minute, time.Hour},
+	}
+	for _, c := range cases {
+		if got := p.Base(c.src, c.hint); got != c.want {
+			t.Errorf("%s: %v，应为 %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestAdaptiveTTL(t *testing.T) {
+	base := 20 * time.Millisecond
+	p := New(Config{PluginDefault: base, AdaptiveMax: 4 * base})
+	src := Source{Kind: KindPlugin, Name: "labi"}
+	same := []model.SearchResult{{UniqueID: "a", Title: "A"}, {UniqueID: "b", Title: "B"}}
+	reordered := []model.SearchResult{same[1], same[0]}
+	changed := []model.SearchResult{{UniqueID: "c", Title: "C"}}
+
+	if got := p.TTL(src, "kw", same, 0); got != base {
+		t.Fatalf("首次结果有效期 %v，应为 %v", got, base)
+	}
+	// 间隔过短的相同结果不计入
+	if got := p.TTL(src, "kw", same, 0); got != base {
+		t.Fatalf("重复写入有效期 %v，应为 %v", got, base)
+	}
+
+	time.Sleep(base)
+	if got := p.TTL(src, "kw", reordered, 0); got != 2*base {
+		t.Fatalf("结果未变化时有效期 %v，应为 %v", got, 2*base)
+	}
+	time.Sleep(base)
+	p.TTL(src, "kw", same, 0)
+	time.Sleep(base)
+	if got := p.TTL(src, "kw", same, 0); got != 4*base {
+		t.Fatalf("有效期 %v 应封顶于 %v", got, 4*base)
+	}
+
+	if got := p.TTL(src, "kw", changed, 0); got != base {
+		t.Fatalf("结果变化后有效期 %v，应恢复为 %v", got, base)
+	}
+	if got := p.TTL(src, "other", same, 0); got != base {
+		t.Fatalf("不同关键词应单独计算，有效期 %v", got)
+	}
+}